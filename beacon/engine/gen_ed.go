@@ -17,23 +17,24 @@ var _ = (*executableDataMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (e ExecutableData) MarshalJSON() ([]byte, error) {
 	type ExecutableData struct {
-		ParentHash    common.Hash         `json:"parentHash"    gencodec:"required"`
-		FeeRecipient  common.Address      `json:"feeRecipient"  gencodec:"required"`
-		StateRoot     common.Hash         `json:"stateRoot"     gencodec:"required"`
-		ReceiptsRoot  common.Hash         `json:"receiptsRoot"  gencodec:"required"`
-		LogsBloom     hexutil.Bytes       `json:"logsBloom"     gencodec:"required"`
-		Random        common.Hash         `json:"prevRandao"    gencodec:"required"`
-		Number        hexutil.Uint64      `json:"blockNumber"   gencodec:"required"`
-		GasLimit      hexutil.Uint64      `json:"gasLimit"      gencodec:"required"`
-		GasUsed       hexutil.Uint64      `json:"gasUsed"       gencodec:"required"`
-		Timestamp     hexutil.Uint64      `json:"timestamp"     gencodec:"required"`
-		ExtraData     hexutil.Bytes       `json:"extraData"     gencodec:"required"`
-		BaseFeePerGas *hexutil.Big        `json:"baseFeePerGas" gencodec:"required"`
-		BlockHash     common.Hash         `json:"blockHash"     gencodec:"required"`
-		Transactions  []hexutil.Bytes     `json:"transactions"  gencodec:"required"`
-		Withdrawals   []*types.Withdrawal `json:"withdrawals"`
-		BlobGasUsed   *hexutil.Uint64     `json:"blobGasUsed"`
-		ExcessBlobGas *hexutil.Uint64     `json:"excessBlobGas"`
+		ParentHash      common.Hash            `json:"parentHash"    gencodec:"required"`
+		FeeRecipient    common.Address         `json:"feeRecipient"  gencodec:"required"`
+		StateRoot       common.Hash            `json:"stateRoot"     gencodec:"required"`
+		ReceiptsRoot    common.Hash            `json:"receiptsRoot"  gencodec:"required"`
+		LogsBloom       hexutil.Bytes          `json:"logsBloom"     gencodec:"required"`
+		Random          common.Hash            `json:"prevRandao"    gencodec:"required"`
+		Number          hexutil.Uint64         `json:"blockNumber"   gencodec:"required"`
+		GasLimit        hexutil.Uint64         `json:"gasLimit"      gencodec:"required"`
+		GasUsed         hexutil.Uint64         `json:"gasUsed"       gencodec:"required"`
+		Timestamp       hexutil.Uint64         `json:"timestamp"     gencodec:"required"`
+		ExtraData       hexutil.Bytes          `json:"extraData"     gencodec:"required"`
+		BaseFeePerGas   *hexutil.Big           `json:"baseFeePerGas" gencodec:"required"`
+		BlockHash       common.Hash            `json:"blockHash"     gencodec:"required"`
+		Transactions    []hexutil.Bytes        `json:"transactions"  gencodec:"required"`
+		Withdrawals     []*types.Withdrawal    `json:"withdrawals"`
+		BlobGasUsed     *hexutil.Uint64        `json:"blobGasUsed"`
+		ExcessBlobGas   *hexutil.Uint64        `json:"excessBlobGas"`
+		BlockAccessList *types.BlockAccessList `json:"blockAccessList,omitempty"`
 	}
 	var enc ExecutableData
 	enc.ParentHash = e.ParentHash
@@ -58,29 +59,31 @@ func (e ExecutableData) MarshalJSON() ([]byte, error) {
 	enc.Withdrawals = e.Withdrawals
 	enc.BlobGasUsed = (*hexutil.Uint64)(e.BlobGasUsed)
 	enc.ExcessBlobGas = (*hexutil.Uint64)(e.ExcessBlobGas)
+	enc.BlockAccessList = e.BlockAccessList
 	return json.Marshal(&enc)
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (e *ExecutableData) UnmarshalJSON(input []byte) error {
 	type ExecutableData struct {
-		ParentHash    *common.Hash        `json:"parentHash"    gencodec:"required"`
-		FeeRecipient  *common.Address     `json:"feeRecipient"  gencodec:"required"`
-		StateRoot     *common.Hash        `json:"stateRoot"     gencodec:"required"`
-		ReceiptsRoot  *common.Hash        `json:"receiptsRoot"  gencodec:"required"`
-		LogsBloom     *hexutil.Bytes      `json:"logsBloom"     gencodec:"required"`
-		Random        *common.Hash        `json:"prevRandao"    gencodec:"required"`
-		Number        *hexutil.Uint64     `json:"blockNumber"   gencodec:"required"`
-		GasLimit      *hexutil.Uint64     `json:"gasLimit"      gencodec:"required"`
-		GasUsed       *hexutil.Uint64     `json:"gasUsed"       gencodec:"required"`
-		Timestamp     *hexutil.Uint64     `json:"timestamp"     gencodec:"required"`
-		ExtraData     *hexutil.Bytes      `json:"extraData"     gencodec:"required"`
-		BaseFeePerGas *hexutil.Big        `json:"baseFeePerGas" gencodec:"required"`
-		BlockHash     *common.Hash        `json:"blockHash"     gencodec:"required"`
-		Transactions  []hexutil.Bytes     `json:"transactions"  gencodec:"required"`
-		Withdrawals   []*types.Withdrawal `json:"withdrawals"`
-		BlobGasUsed   *hexutil.Uint64     `json:"blobGasUsed"`
-		ExcessBlobGas *hexutil.Uint64     `json:"excessBlobGas"`
+		ParentHash      *common.Hash           `json:"parentHash"    gencodec:"required"`
+		FeeRecipient    *common.Address        `json:"feeRecipient"  gencodec:"required"`
+		StateRoot       *common.Hash           `json:"stateRoot"     gencodec:"required"`
+		ReceiptsRoot    *common.Hash           `json:"receiptsRoot"  gencodec:"required"`
+		LogsBloom       *hexutil.Bytes         `json:"logsBloom"     gencodec:"required"`
+		Random          *common.Hash           `json:"prevRandao"    gencodec:"required"`
+		Number          *hexutil.Uint64        `json:"blockNumber"   gencodec:"required"`
+		GasLimit        *hexutil.Uint64        `json:"gasLimit"      gencodec:"required"`
+		GasUsed         *hexutil.Uint64        `json:"gasUsed"       gencodec:"required"`
+		Timestamp       *hexutil.Uint64        `json:"timestamp"     gencodec:"required"`
+		ExtraData       *hexutil.Bytes         `json:"extraData"     gencodec:"required"`
+		BaseFeePerGas   *hexutil.Big           `json:"baseFeePerGas" gencodec:"required"`
+		BlockHash       *common.Hash           `json:"blockHash"     gencodec:"required"`
+		Transactions    []hexutil.Bytes        `json:"transactions"  gencodec:"required"`
+		Withdrawals     []*types.Withdrawal    `json:"withdrawals"`
+		BlobGasUsed     *hexutil.Uint64        `json:"blobGasUsed"`
+		ExcessBlobGas   *hexutil.Uint64        `json:"excessBlobGas"`
+		BlockAccessList *types.BlockAccessList `json:"blockAccessList,omitempty"`
 	}
 	var dec ExecutableData
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -154,5 +157,8 @@ func (e *ExecutableData) UnmarshalJSON(input []byte) error {
 	if dec.ExcessBlobGas != nil {
 		e.ExcessBlobGas = (*uint64)(dec.ExcessBlobGas)
 	}
+	if dec.BlockAccessList != nil {
+		e.BlockAccessList = dec.BlockAccessList
+	}
 	return nil
 }
@@ -75,6 +75,10 @@ type ExecutableData struct {
 	Withdrawals   []*types.Withdrawal `json:"withdrawals"`
 	BlobGasUsed   *uint64             `json:"blobGasUsed"`
 	ExcessBlobGas *uint64             `json:"excessBlobGas"`
+
+	// BlockAccessList is the experimental block-level access list of the payload,
+	// it is not part of the block hash.
+	BlockAccessList *types.BlockAccessList `json:"blockAccessList,omitempty"`
 }
 
 // JSON type overrides for executableData.
@@ -254,6 +258,9 @@ func ExecutableDataToBlock(params ExecutableData, versionedHashes []common.Hash,
 	if block.Hash() != params.BlockHash {
 		return nil, fmt.Errorf("blockhash mismatch, want %x, got %x", params.BlockHash, block.Hash())
 	}
+	if params.BlockAccessList != nil {
+		block = block.WithAccessList(params.BlockAccessList)
+	}
 	return block, nil
 }
 
@@ -278,6 +285,8 @@ func BlockToExecutableData(block *types.Block, fees *big.Int, sidecars []*types.
 		Withdrawals:   block.Withdrawals(),
 		BlobGasUsed:   block.BlobGasUsed(),
 		ExcessBlobGas: block.ExcessBlobGas(),
+
+		BlockAccessList: block.AccessList(),
 	}
 	bundle := BlobsBundleV1{
 		Commitments: make([]hexutil.Bytes, 0),
@@ -38,6 +38,8 @@ var (
 			discv5CrawlCommand,
 			discv5TestCommand,
 			discv5ListenCommand,
+			discv5RegisterCommand,
+			discv5SearchCommand,
 		},
 	}
 	discv5PingCommand = &cli.Command{
@@ -77,8 +79,30 @@ var (
 		Action: discv5Listen,
 		Flags:  discoveryNodeFlags,
 	}
+	discv5RegisterCommand = &cli.Command{
+		Name:      "register",
+		Usage:     "Runs a node and advertises it under a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5Register,
+		Flags:     discoveryNodeFlags,
+	}
+	discv5SearchCommand = &cli.Command{
+		Name:      "search",
+		Usage:     "Finds nodes advertising a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5Search,
+		Flags: flags.Merge(discoveryNodeFlags, []cli.Flag{
+			topicSearchTimeoutFlag,
+		}),
+	}
 )
 
+var topicSearchTimeoutFlag = &cli.DurationFlag{
+	Name:  "timeout",
+	Usage: "Time limit for the search.",
+	Value: time.Minute,
+}
+
 func discv5Ping(ctx *cli.Context) error {
 	n := getNodeArg(ctx)
 	disc, _ := startV5(ctx)
@@ -138,6 +162,49 @@ func discv5Listen(ctx *cli.Context) error {
 	select {}
 }
 
+func discv5Register(ctx *cli.Context) error {
+	topic, err := getTopicArg(ctx)
+	if err != nil {
+		return err
+	}
+	disc, _ := startV5(ctx)
+	defer disc.Close()
+
+	fmt.Println(disc.Self())
+	reg := disc.RegisterTopic(topic)
+	defer reg.Stop()
+
+	for range time.Tick(10 * time.Second) {
+		fmt.Printf("topic %s registered at %d nodes\n", topic, len(reg.Registrars()))
+	}
+	return nil
+}
+
+func discv5Search(ctx *cli.Context) error {
+	topic, err := getTopicArg(ctx)
+	if err != nil {
+		return err
+	}
+	disc, _ := startV5(ctx)
+	defer disc.Close()
+
+	it := disc.TopicSearch(topic)
+	timer := time.AfterFunc(ctx.Duration(topicSearchTimeoutFlag.Name), it.Close)
+	defer timer.Stop()
+	for it.Next() {
+		fmt.Println(it.Node().String())
+	}
+	return nil
+}
+
+// getTopicArg returns the topic given as the first argument.
+func getTopicArg(ctx *cli.Context) (discover.Topic, error) {
+	if ctx.NArg() < 1 {
+		return discover.Topic{}, errors.New("missing topic argument")
+	}
+	return discover.NewTopic(ctx.Args().First()), nil
+}
+
 // startV5 starts an ephemeral discovery v5 node.
 func startV5(ctx *cli.Context) (*discover.UDPv5, discover.Config) {
 	ln, config := makeDiscoveryConfig(ctx)
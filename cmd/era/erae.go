@@ -0,0 +1,206 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era/execdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/urfave/cli/v2"
+)
+
+// blockReader is the common interface of Era1 and EraE files used by the block
+// command.
+type blockReader interface {
+	GetBlockByNumber(num uint64) (*types.Block, error)
+	Close() error
+}
+
+// isExec reports whether the post-merge EraE format was selected.
+func isExec(ctx *cli.Context) bool {
+	return ctx.String(formatFlag.Name) == "erae"
+}
+
+// openReader opens the archive file of the selected format at a certain epoch.
+func openReader(ctx *cli.Context, epoch uint64) (blockReader, error) {
+	if isExec(ctx) {
+		return openExec(ctx, epoch)
+	}
+	return open(ctx, epoch)
+}
+
+// openExec opens an erae file at a certain epoch.
+func openExec(ctx *cli.Context, epoch uint64) (*execdb.Era, error) {
+	var (
+		dir     = ctx.String(dirFlag.Name)
+		network = ctx.String(networkFlag.Name)
+		prefix  = fmt.Sprintf("%s-%05d-", network, epoch)
+	)
+	entries, err := execdb.ReadDir(dir, network)
+	if err != nil {
+		return nil, fmt.Errorf("error reading era dir: %w", err)
+	}
+	for _, name := range entries {
+		if strings.HasPrefix(name, prefix) {
+			return execdb.Open(filepath.Join(dir, name))
+		}
+	}
+	return nil, fmt.Errorf("epoch %d not found", epoch)
+}
+
+// execInfo prints some high-level information about an erae file.
+func execInfo(ctx *cli.Context, epoch uint64) error {
+	e, err := openExec(ctx, epoch)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+	root, err := e.Accumulator()
+	if err != nil {
+		return fmt.Errorf("error reading accumulator: %w", err)
+	}
+	info := struct {
+		Accumulator common.Hash `json:"accumulator"`
+		StartBlock  uint64      `json:"startBlock"`
+		Count       uint64      `json:"count"`
+	}{
+		root, e.Start(), e.Count(),
+	}
+	b, _ := json.MarshalIndent(info, "", "  ")
+	fmt.Println(string(b))
+	return nil
+}
+
+// verifyExec checks each erae file in a directory to ensure it is well-formed
+// and that the block hash list root matches the expected value.
+func verifyExec(ctx *cli.Context, roots []common.Hash) error {
+	var (
+		dir      = ctx.String(dirFlag.Name)
+		network  = ctx.String(networkFlag.Name)
+		start    = time.Now()
+		reported = time.Now()
+		parent   common.Hash
+	)
+	entries, err := execdb.ReadDir(dir, network)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	if len(entries) != len(roots) {
+		return errors.New("number of erae files should match the number of accumulator hashes")
+	}
+	for i, want := range roots {
+		err := func() error {
+			name := entries[i]
+			e, err := execdb.Open(filepath.Join(dir, name))
+			if err != nil {
+				return fmt.Errorf("error opening erae file %s: %w", name, err)
+			}
+			defer e.Close()
+			if got, err := e.Accumulator(); err != nil {
+				return fmt.Errorf("error retrieving accumulator for %s: %w", name, err)
+			} else if got != want {
+				return fmt.Errorf("invalid root %s: got %s, want %s", name, got, want)
+			}
+			if parent, err = checkExecAccumulator(e, parent); err != nil {
+				return fmt.Errorf("error verify erae file %s: %w", name, err)
+			}
+			if time.Since(reported) >= 8*time.Second {
+				fmt.Printf("Verifying EraE files \t\t verified=%d,\t elapsed=%s\n", i, common.PrettyDuration(time.Since(start)))
+				reported = time.Now()
+			}
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkExecAccumulator verifies the content of an erae file against the block
+// headers and the hash list root. The parent is the hash of the last block of
+// the previous file, if known. The hash of the last block is returned.
+func checkExecAccumulator(e *execdb.Era, parent common.Hash) (common.Hash, error) {
+	want, err := e.Accumulator()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error reading accumulator: %w", err)
+	}
+	it, err := execdb.NewIterator(e)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error making erae iterator: %w", err)
+	}
+	// Every block is checked for:
+	//   1) the block index is constructed correctly
+	//   2) the tx, uncle and withdrawals roots match the header
+	//   3) the receipts root matches the header
+	//   4) the block extends the previous one
+	// The hash list root is recomputed at the end, which verifies the headers.
+	var hashes []common.Hash
+	for it.Next() {
+		// 1) next() walks the block index, so we're able to implicitly verify it.
+		if it.Error() != nil {
+			return common.Hash{}, fmt.Errorf("error reading block %d: %w", it.Number(), it.Error())
+		}
+		block, receipts, err := it.BlockAndReceipts()
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("error reading block %d: %w", it.Number(), err)
+		}
+		// 2) recompute the body roots.
+		if tr := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); tr != block.TxHash() {
+			return common.Hash{}, fmt.Errorf("tx root in block %d mismatch: want %s, got %s", block.NumberU64(), block.TxHash(), tr)
+		}
+		if uh := types.CalcUncleHash(block.Uncles()); uh != block.UncleHash() {
+			return common.Hash{}, fmt.Errorf("uncle hash in block %d mismatch: want %s, got %s", block.NumberU64(), block.UncleHash(), uh)
+		}
+		if want := block.Header().WithdrawalsHash; want != nil {
+			if wr := types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)); wr != *want {
+				return common.Hash{}, fmt.Errorf("withdrawals root in block %d mismatch: want %s, got %s", block.NumberU64(), *want, wr)
+			}
+		} else if block.Withdrawals() != nil {
+			return common.Hash{}, fmt.Errorf("unexpected withdrawals in block %d", block.NumberU64())
+		}
+		// 3) recompute receipt root and check value against block.
+		if rr := types.DeriveSha(receipts, trie.NewStackTrie(nil)); rr != block.ReceiptHash() {
+			return common.Hash{}, fmt.Errorf("receipt root in block %d mismatch: want %s, got %s", block.NumberU64(), block.ReceiptHash(), rr)
+		}
+		// 4) check the chain is contiguous.
+		if parent != (common.Hash{}) && block.ParentHash() != parent {
+			return common.Hash{}, fmt.Errorf("block %d does not extend %s", block.NumberU64(), parent)
+		}
+		parent = block.Hash()
+		hashes = append(hashes, parent)
+	}
+	if it.Error() != nil {
+		return common.Hash{}, it.Error()
+	}
+	got, err := execdb.ComputeAccumulator(hashes)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error computing accumulator: %w", err)
+	}
+	if got != want {
+		return common.Hash{}, fmt.Errorf("expected accumulator root does not match calculated: got %s, want %s", got, want)
+	}
+	return parent, nil
+}
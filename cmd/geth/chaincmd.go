@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/internal/era/execdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -83,6 +84,8 @@ if one is set.  Otherwise it prints the genesis from the datadir.`,
 			utils.SnapshotFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.ParallelExecutionFlag,
+			utils.ParallelWorkersFlag,
 			utils.MetricsEnabledFlag,
 			utils.MetricsEnabledExpensiveFlag,
 			utils.MetricsHTTPFlag,
@@ -133,13 +136,19 @@ be gzipped.`,
 		ArgsUsage: "<dir>",
 		Flags: flags.Merge([]cli.Flag{
 			utils.TxLookupLimitFlag,
+			utils.HistoryMirrorFlag,
+			utils.HistoryMirrorRootsFlag,
 		},
 			utils.DatabaseFlags,
 			utils.NetworkFlags,
 		),
 		Description: `
 The import-history command will import blocks and their corresponding receipts
-from Era archives.
+from Era archives. Pre-merge blocks are read from Era1 files, post-merge blocks
+from EraE files. If --history.mirror is set, missing Era1 files are downloaded
+from the mirror into the directory first. The downloaded files are verified
+against the trusted accumulator roots listed in the --history.mirror.roots file,
+the roots announced by the mirror itself are not trusted.
 `,
 	}
 	exportHistoryCommand = &cli.Command{
@@ -150,7 +159,8 @@ from Era archives.
 		Flags:     flags.Merge(utils.DatabaseFlags),
 		Description: `
 The export-history command will export blocks and their corresponding receipts
-into Era archives. Eras are typically packaged in steps of 8192 blocks.
+into Era archives. Eras are typically packaged in steps of 8192 blocks. Pre-merge
+blocks are written to Era1 files, post-merge blocks to EraE files.
 `,
 	}
 	importPreimagesCommand = &cli.Command{
@@ -417,6 +427,17 @@ func importHistory(ctx *cli.Context) error {
 		dir     = ctx.Args().Get(0)
 		network string
 	)
+	if mirror := ctx.String(utils.HistoryMirrorFlag.Name); mirror != "" {
+		roots, err := utils.MakeHistoryMirrorRoots(ctx)
+		if err != nil {
+			return err
+		}
+		if err := utils.ImportHistoryFromMirror(chain, db, mirror, roots, dir); err != nil {
+			return err
+		}
+		fmt.Printf("Import done in %v\n", time.Since(start))
+		return nil
+	}
 
 	// Determine network.
 	if utils.IsNetworkPreset(ctx) {
@@ -437,12 +458,16 @@ func importHistory(ctx *cli.Context) error {
 			if err != nil {
 				return fmt.Errorf("error reading %s: %w", dir, err)
 			}
-			if len(entries) > 0 {
+			execEntries, err := execdb.ReadDir(dir, n)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", dir, err)
+			}
+			if len(entries) > 0 || len(execEntries) > 0 {
 				networks = append(networks, n)
 			}
 		}
 		if len(networks) == 0 {
-			return fmt.Errorf("no era1 files found in %s", dir)
+			return fmt.Errorf("no era1 or erae files found in %s", dir)
 		}
 		if len(networks) > 1 {
 			return errors.New("multiple networks found, use a network flag to specify desired network")
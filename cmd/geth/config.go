@@ -181,6 +181,21 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
+	// Download the pre-merge history from a mirror before starting to sync.
+	if mirror := ctx.String(utils.HistoryMirrorFlag.Name); mirror != "" && eth != nil {
+		if eth.BlockChain().CurrentSnapBlock().Number.BitLen() == 0 {
+			roots, err := utils.MakeHistoryMirrorRoots(ctx)
+			if err != nil {
+				utils.Fatalf("Failed to import history from mirror: %v", err)
+			}
+			if err := utils.ImportHistoryFromMirror(eth.BlockChain(), eth.ChainDb(), mirror, roots, stack.ResolvePath("era1")); err != nil {
+				utils.Fatalf("Failed to import history from mirror: %v", err)
+			}
+		} else {
+			log.Info("Chain history already present, skipping mirror download")
+		}
+	}
+
 	// Create gauge with geth system and build information
 	if eth != nil { // The 'eth' backend may be nil in light mode
 		var protos []string
@@ -214,6 +229,14 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		}
 		utils.RegisterFullSyncTester(stack, eth, common.BytesToHash(hex))
 	}
+	// Configure checkpoint sync if requested
+	if ctx.IsSet(utils.SyncCheckpointFlag.Name) {
+		hex, err := hexutil.Decode(ctx.String(utils.SyncCheckpointFlag.Name))
+		if err != nil || len(hex) != common.HashLength {
+			utils.Fatalf("invalid sync checkpoint %q, want a block hash", ctx.String(utils.SyncCheckpointFlag.Name))
+		}
+		utils.RegisterCheckpointSyncer(stack, eth, common.BytesToHash(hex))
+	}
 
 	if ctx.IsSet(utils.DeveloperFlag.Name) {
 		// Start dev mode.
@@ -18,7 +18,9 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -36,6 +38,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -78,11 +82,15 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbFreezerConvertCmd,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
 			dbInspectHistoryCmd,
+			dbTuningCmd,
+			dbMigrateCmd,
+			dbFreezerVerifyCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -178,6 +186,22 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbFreezerConvertCmd = &cli.Command{
+		Action:    freezerConvert,
+		Name:      "freezer-convert",
+		Usage:     "Convert a freezer table to another compression",
+		ArgsUsage: "<freezer-type> <table-type> <snappy|zstd>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command re-encodes the items of a compressed freezer table with the given
+compression, e.g. 'geth db freezer-convert chain receipts zstd'. Zstd tables use a
+dictionary trained from a sample of the table's items, which compresses the small and
+similar items of the chain freezer considerably better than snappy.
+
+The table is converted in place, the original is replaced once the conversion is
+complete. The node must not be running during the conversion.`,
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -229,6 +253,86 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command queries the history of the account or storage slot within the specified block range",
 	}
+	dbMigrateCmd = &cli.Command{
+		Action: dbMigrate,
+		Name:   "migrate",
+		Usage:  "Migrate the key-value store to a different database engine",
+		Flags: flags.Merge([]cli.Flag{
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "Database engine to migrate to ('pebble' or 'leveldb')",
+				Required: true,
+			},
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command copies the key-value store of the chain database into a new one
+backed by the requested engine, verifies that the number of entries and their hashes
+match for every data category, then replaces the original database with the copy.
+
+The copy is checkpointed, so an interrupted migration resumes where it left off when
+the command is run again. The ancient store is not modified. The original database
+is kept next to the migrated one and can be deleted once the node runs fine.`,
+	}
+	dbFreezerVerifyCmd = &cli.Command{
+		Action: dbFreezerVerify,
+		Name:   "freezer-verify",
+		Usage:  "Verify the integrity of the chain freezer and optionally repair it",
+		Flags: flags.Merge([]cli.Flag{
+			&cli.Uint64Flag{
+				Name:  "start",
+				Usage: "block number of the range start",
+			},
+			&cli.Uint64Flag{
+				Name:  "end",
+				Usage: "block number of the range end(included), zero means the last frozen block",
+			},
+			&cli.BoolFlag{
+				Name:  "repair",
+				Usage: "replace the damaged items, retrieving them from the era1 files if needed",
+			},
+			&cli.BoolFlag{
+				Name:  "rewind",
+				Usage: "with --repair, drop the chain from the first damaged block if it can't be repaired",
+			},
+			utils.HistoryArchiveFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command decodes every item of the chain freezer tables in the given range and
+cross-checks them: the hashes against the headers, the headers against their parents,
+the bodies and receipts against the roots in the headers, and the total difficulties
+against the header difficulties. The damaged item ranges are reported per table.
+
+With --repair, the damaged items are replaced while all the intact ones are kept. Hashes
+and total difficulties are derived from the headers, damaged headers, bodies and receipts
+are retrieved from the era1 files given with --history.era. As the freezer is append-only,
+the items from the first damaged block on are staged in a temporary directory next to
+the database and written back, which needs as much free space as they take.
+
+If a damaged item can't be retrieved, the freezer is left untouched. With --rewind, the
+chain is dropped from the first damaged block instead, so the node downloads it again
+from its peers on the next start.`,
+	}
+	dbTuningCmd = &cli.Command{
+		Action: dbTuning,
+		Name:   "tuning",
+		Usage:  "Show the effective pebble tuning and estimate the compression savings",
+		Flags: flags.Merge([]cli.Flag{
+			&cli.IntFlag{
+				Name:  "samples",
+				Usage: "number of entries to sample per data category",
+				Value: 100000,
+			},
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command prints the pebble settings resulting from the selected profile and
+overrides, then samples the entries of each data category and estimates their size
+with every supported compression algorithm.
+
+The compression is chosen per level of the LSM tree. The upper levels hold recently
+written data, like fresh trie nodes, while the bulk of the database settles in the
+bottom levels, so the estimates are compared against the bottom level compression.
+Categories with key compression are estimated by compressing their values one by
+one instead, as the key compression does.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -630,6 +734,18 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func freezerConvert(ctx *cli.Context) error {
+	if ctx.NArg() < 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	// Keep the node open to hold the data directory lock while converting
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	return rawdb.ConvertFreezerTable(ancient, ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2))
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -792,6 +908,302 @@ func showMetaData(ctx *cli.Context) error {
 	return nil
 }
 
+func dbMigrate(ctx *cli.Context) error {
+	target := ctx.String("to")
+	if target != "leveldb" && target != "pebble" {
+		return fmt.Errorf("invalid database engine '%s', allowed 'leveldb' or 'pebble'", target)
+	}
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		srcDir  = stack.ResolvePath("chaindata")
+		dstDir  = srcDir + ".migrating"
+		cache   = ctx.Int(utils.CacheFlag.Name) * ctx.Int(utils.CacheDatabaseFlag.Name) / 100
+		handles = utils.MakeDatabaseHandles(ctx.Int(utils.FDLimitFlag.Name))
+	)
+	engine := rawdb.PreexistingDatabase(srcDir)
+	switch engine {
+	case "":
+		return fmt.Errorf("no database found in %s", srcDir)
+	case target:
+		return fmt.Errorf("database in %s already uses %s", srcDir, target)
+	}
+	backupDir := srcDir + "." + engine
+	if _, err := os.Stat(backupDir); err == nil {
+		return fmt.Errorf("previous database backup %s in the way, remove it first", backupDir)
+	}
+	log.Info("Migrating database", "from", engine, "to", target, "path", dstDir)
+
+	// Open the two key-value stores directly, leaving the ancient store alone
+	src, err := rawdb.Open(rawdb.OpenOptions{
+		Type:      engine,
+		Directory: srcDir,
+		Cache:     cache / 2,
+		Handles:   handles / 2,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return err
+	}
+	dst, err := rawdb.Open(rawdb.OpenOptions{
+		Type:      target,
+		Directory: dstDir,
+		Cache:     cache / 2,
+		Handles:   handles / 2,
+		Pebble:    config.Node.Pebble,
+	})
+	if err != nil {
+		src.Close()
+		return err
+	}
+	err = rawdb.MigrateKeyValueStore(src, dst)
+	if err == nil {
+		err = rawdb.VerifyMigration(src, dst)
+	}
+	if err == nil {
+		err = rawdb.FinishMigration(dst)
+	}
+	src.Close()
+	dst.Close()
+	if err != nil {
+		return err
+	}
+	// Swap the databases, moving the ancient store along if it lives within
+	if config.Eth.DatabaseFreezer == "" && ctx.String(utils.AncientFlag.Name) == "" {
+		ancientDir := filepath.Join(srcDir, "ancient")
+		if _, err := os.Stat(ancientDir); err == nil {
+			if err := os.Rename(ancientDir, filepath.Join(dstDir, "ancient")); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(srcDir, backupDir); err != nil {
+		return err
+	}
+	if err := os.Rename(dstDir, srcDir); err != nil {
+		return err
+	}
+	log.Info("Database migrated", "engine", target, "path", srcDir, "original", backupDir)
+	return nil
+}
+
+func dbFreezerVerify(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	repair := ctx.Bool("repair")
+	db := utils.MakeChainDatabase(ctx, stack, !repair)
+	defer db.Close()
+
+	end := uint64(math.MaxUint64)
+	if ctx.IsSet("end") && ctx.Uint64("end") != 0 {
+		end = ctx.Uint64("end")
+	}
+	// Complete an interrupted repair first, the freezer is truncated otherwise
+	staging := stack.ResolvePath("freezer-repair")
+	if repair {
+		if _, err := rawdb.ResumeChainFreezerRepair(db, staging); err != nil {
+			return err
+		}
+	}
+	var receiptsTail uint64
+	if tail := rawdb.ReadReceiptsTail(db); tail != nil {
+		receiptsTail = *tail
+	}
+	result, err := rawdb.VerifyChainFreezer(db, ctx.Uint64("start"), end, receiptsTail, trie.NewStackTrie(nil))
+	if err != nil {
+		return err
+	}
+	first, damaged := result.FirstDamaged()
+	if !damaged {
+		fmt.Printf("Chain freezer blocks #%d-#%d are intact\n", result.Start, result.End)
+		return nil
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Table", "Start", "End", "Reason"})
+	for _, damage := range result.Damages {
+		table.Append([]string{damage.Table, strconv.FormatUint(damage.Start, 10), strconv.FormatUint(damage.End, 10), damage.Reason})
+	}
+	table.Render()
+
+	if !repair {
+		return fmt.Errorf("chain freezer damaged from block #%d, rerun with --repair to fix it", first)
+	}
+	var source rawdb.FreezerRepairSource
+	if ctx.IsSet(utils.HistoryArchiveFlag.Name) {
+		store, err := era.NewStore(ctx.String(utils.HistoryArchiveFlag.Name))
+		if err != nil {
+			return err
+		}
+		source = store
+	}
+	err = rawdb.RepairChainFreezer(db, result, staging, source)
+	if !errors.Is(err, rawdb.ErrFreezerUnrepairable) {
+		return err
+	}
+	if !ctx.Bool("rewind") {
+		return fmt.Errorf("%v, provide era1 files with --%s or rerun with --rewind to drop the chain from block #%d", err, utils.HistoryArchiveFlag.Name, first)
+	}
+	log.Warn("Damaged blocks unavailable, rewinding the chain", "err", err)
+	return rewindChainFreezer(db, first)
+}
+
+// rewindChainFreezer truncates the chain freezer at the given block and rewinds
+// the chain head markers to its parent, deleting the chain segment above the
+// freezer from the key-value store. The dropped blocks are downloaded again by
+// the next sync.
+func rewindChainFreezer(db ethdb.Database, number uint64) error {
+	if number == 0 {
+		return errors.New("genesis block damaged, resync the chain")
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	hash := rawdb.ReadCanonicalHash(db, number-1)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("block #%d unavailable", number-1)
+	}
+	log.Warn("Truncating chain freezer", "number", number, "frozen", frozen)
+	if _, err := db.TruncateHead(number); err != nil {
+		return err
+	}
+	batch := db.NewBatch()
+	rawdb.WriteHeadHeaderHash(batch, hash)
+	rawdb.WriteHeadFastBlockHash(batch, hash)
+	rawdb.WriteHeadBlockHash(batch, hash)
+	for n := frozen; ; n++ {
+		hashes := rawdb.ReadAllHashes(db, n)
+		if len(hashes) == 0 {
+			break
+		}
+		for _, h := range hashes {
+			rawdb.DeleteBody(batch, h, n)
+			rawdb.DeleteReceipts(batch, h, n)
+			rawdb.DeleteTd(batch, h, n)
+			rawdb.DeleteHeader(batch, h, n)
+		}
+		rawdb.DeleteCanonicalHash(batch, n)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Warn("Rewound chain to the last intact block, the rest will be synced from the network", "number", number-1, "hash", hash)
+	return nil
+}
+
+// tuningCategories are the data categories sampled by the tuning report.
+var tuningCategories = []struct {
+	name   string
+	prefix []byte
+}{
+	{"Account trie nodes", rawdb.TrieNodeAccountPrefix},
+	{"Storage trie nodes", rawdb.TrieNodeStoragePrefix},
+	{"Account snapshot", rawdb.SnapshotAccountPrefix},
+	{"Storage snapshot", rawdb.SnapshotStoragePrefix},
+	{"Contract codes", rawdb.CodePrefix},
+	{"All data", nil},
+}
+
+func dbTuning(ctx *cli.Context) error {
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	settings, err := config.Node.Pebble.Settings()
+	if err != nil {
+		return err
+	}
+	var (
+		chaindata = stack.ResolvePath("chaindata")
+		engine    = rawdb.PreexistingDatabase(chaindata)
+	)
+	if engine != "" && engine != "pebble" {
+		log.Warn("Tuning only applies to pebble databases", "engine", engine)
+	}
+	// Key compression can only be enabled when the database is created
+	keyCompression := "none"
+	if len(settings.KeyCompression) > 0 {
+		var keys []string
+		for _, k := range settings.KeyCompression {
+			keys = append(keys, fmt.Sprintf("%v=%s", k.Prefix, k.Compression))
+		}
+		keyCompression = strings.Join(keys, ", ")
+	}
+	keyActive := engine == "" || pebble.HasKeyCompression(chaindata)
+	if !keyActive && len(settings.KeyCompression) > 0 {
+		keyCompression += " (inactive, database created without it)"
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Setting", "Value"})
+	table.AppendBulk([][]string{
+		{"Profile", settings.Profile},
+		{"Compression (bottom levels)", settings.Compression},
+		{"Compression (hot levels)", fmt.Sprintf("%s (L0-L%d)", settings.HotCompression, settings.HotLevels-1)},
+		{"Compression (keys)", keyCompression},
+		{"Bloom bits per key", strconv.Itoa(settings.BloomBits)},
+		{"Block size", common.StorageSize(settings.BlockSize).String()},
+		{"Target file size", common.StorageSize(settings.TargetFileSize).String()},
+		{"Memory tables", strconv.Itoa(settings.MemTables)},
+		{"L0 compaction threshold", strconv.Itoa(settings.L0CompactionThreshold)},
+		{"L0 stop writes threshold", strconv.Itoa(settings.L0StopWritesThreshold)},
+	})
+	table.Render()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	var (
+		samples = ctx.Int("samples")
+		stats   [][]string
+	)
+	percent := func(size, base common.StorageSize) string {
+		if base == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*(1-float64(size)/float64(base)))
+	}
+	for _, category := range tuningCategories {
+		est, err := pebble.EstimateCompression(db, category.prefix, samples, settings.BlockSize)
+		if err != nil {
+			return err
+		}
+		var (
+			compression = settings.Compression
+			configured  = est.Size(compression)
+		)
+		if c, ok := settings.KeyCompressionFor(category.prefix); ok && keyActive {
+			keyEst, err := pebble.EstimateCompression(db, category.prefix, samples, 0)
+			if err != nil {
+				return err
+			}
+			compression, configured = c+" (keys)", keyEst.Size(c)
+		}
+		stats = append(stats, []string{
+			category.name,
+			strconv.Itoa(est.Entries),
+			est.None.String(),
+			est.Snappy.String(),
+			est.Zstd.String(),
+			compression,
+			configured.String(),
+			percent(configured, est.None),
+			percent(configured, est.Snappy),
+		})
+	}
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Category", "Sampled", "Raw", "Snappy", "Zstd", "Configured", "Size", "Saved", "Saved vs default"})
+	table.AppendBulk(stats)
+	table.Render()
+	return nil
+}
+
 func inspectAccount(db *triedb.Database, start uint64, end uint64, address common.Address, raw bool) error {
 	stats, err := db.AccountHistory(address, start, end)
 	if err != nil {
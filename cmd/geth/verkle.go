@@ -22,9 +22,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/overlay"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
@@ -61,6 +63,21 @@ This command takes a root commitment and attempts to rebuild the tree.
 geth verkle dump <state-root> <key 1> [<key 2> ...]
 This command will produce a dot file representing the tree, rooted at <root>.
 in which key1, key2, ... are expanded.
+ `,
+			},
+			{
+				Name:      "convert",
+				Usage:     "Convert the merkle state of the head block into a verkle tree",
+				ArgsUsage: "",
+				Action:    convertVerkle,
+				Flags:     flags.Merge([]cli.Flag{utils.VerkleConversionFlag}, utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth verkle convert [--verkle.conversion <leaves>]
+This command converts the merkle state of the head block into a verkle tree,
+stored next to it in the database, moving the given number of leaves per batch.
+It resumes any conversion previously started by this command or by a node
+running with --verkle.conversion. The preimages of the state keys must have
+been recorded in the database.
  `,
 			},
 		},
@@ -212,3 +229,59 @@ func expandVerkle(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+// defaultConvertBatch is the number of leaves converted per batch by the offline
+// conversion if none is specified.
+const defaultConvertBatch = 100000
+
+func convertVerkle(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		log.Error("Too many arguments given")
+		return errors.New("too many arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	triedb := utils.MakeTrieDatabase(ctx, chaindb, true, true, false)
+	defer triedb.Close()
+
+	leaves := defaultConvertBatch
+	if ctx.IsSet(utils.VerkleConversionFlag.Name) {
+		leaves = ctx.Int(utils.VerkleConversionFlag.Name)
+	}
+	converter, err := overlay.New(chaindb, triedb, leaves)
+	if err != nil {
+		return err
+	}
+	defer converter.Close()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	log.Info("Converting state into verkle tree", "number", headBlock.NumberU64(), "root", headBlock.Root())
+	for {
+		// Step at least once, to sync an already converted tree with the head
+		if err := converter.Step(headBlock.Root(), headBlock.NumberU64()); err != nil {
+			log.Error("Failed to convert state", "err", err)
+			return err
+		}
+		if converter.Done() {
+			break
+		}
+		if time.Since(logged) > 8*time.Second {
+			progress := converter.Progress()
+			log.Info("Converting state into verkle tree", "at", common.BytesToHash(progress.Account), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Converted state into verkle tree", "number", headBlock.NumberU64(), "root", converter.Progress().VerkleRoot, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// kvserver runs a key-value server for the netdb database backend, mostly meant
+// for testing.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/netdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	var (
+		listenAddr = flag.String("addr", "127.0.0.1:7001", "listen address")
+		datadir    = flag.String("datadir", "", "pebble database directory (in-memory store if empty)")
+		cache      = flag.Int("cache", 256, "megabytes of memory allocated to the database cache")
+		verbosity  = flag.Int("verbosity", 3, "log verbosity (0-5)")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.NewTerminalHandler(os.Stderr, false))
+	glogger.Verbosity(log.FromLegacyLevel(*verbosity))
+	log.SetDefault(log.NewLogger(glogger))
+
+	var db ethdb.KeyValueStore
+	if *datadir == "" {
+		log.Warn("Using in-memory store, data will be lost on exit")
+		db = memorydb.New()
+	} else {
+		pdb, err := pebble.New(*datadir, *cache, 0, "kvserver/", false, false)
+		if err != nil {
+			fatalf("Failed to open database: %v", err)
+		}
+		db = pdb
+	}
+	defer db.Close()
+
+	listener, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		fatalf("Failed to listen: %v", err)
+	}
+	server := netdb.NewServer(db)
+	defer server.Close()
+
+	log.Info("Key-value server started", "addr", listener.Addr())
+	go func() {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+		<-sigc
+		log.Info("Shutting down key-value server")
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil {
+		log.Error("Key-value server failed", "err", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Fatal: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -21,6 +21,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -29,6 +30,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -43,6 +45,8 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/internal/era/eradl"
+	"github.com/ethereum/go-ethereum/internal/era/execdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
@@ -241,8 +245,9 @@ func readList(filename string) ([]string, error) {
 	return strings.Split(string(b), "\n"), nil
 }
 
-// ImportHistory imports Era1 files containing historical block information,
-// starting from genesis.
+// ImportHistory imports Era1 and EraE files containing historical block
+// information, starting from genesis. Pre-merge blocks are read from Era1
+// files, post-merge blocks from EraE files.
 func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, network string) error {
 	if chain.CurrentSnapBlock().Number.BitLen() != 0 {
 		return errors.New("history import only supported when starting from genesis")
@@ -251,9 +256,89 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", dir, err)
 	}
-	checksums, err := readList(filepath.Join(dir, "checksums.txt"))
+	execEntries, err := execdb.ReadDir(dir, network)
 	if err != nil {
-		return fmt.Errorf("unable to read checksums.txt: %w", err)
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	if len(entries) > 0 {
+		err := importHistoryFiles(chain, dir, entries, "checksums.txt", func(f *os.File) (historyIterator, error) {
+			e, err := era.From(f)
+			if err != nil {
+				return nil, err
+			}
+			return era.NewIterator(e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(execEntries) > 0 {
+		err := importHistoryFiles(chain, dir, execEntries, execChecksumsFile, func(f *os.File) (historyIterator, error) {
+			e, err := execdb.From(f)
+			if err != nil {
+				return nil, err
+			}
+			return execdb.NewIterator(e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportHistoryFromMirror downloads the Era1 files of the chain's network from
+// an HTTP mirror into dir, verifies them against the trusted accumulator roots
+// and imports them, starting from genesis. This replaces the download of
+// pre-merge history from the network during initial sync.
+func ImportHistoryFromMirror(chain *core.BlockChain, db ethdb.Database, mirror string, roots []common.Hash, dir string) error {
+	network, ok := params.NetworkNames[chain.Config().ChainID.String()]
+	if !ok {
+		return fmt.Errorf("unknown network with chain ID %v", chain.Config().ChainID)
+	}
+	loader, err := eradl.New(mirror, network, roots, 0)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	log.Info("Downloading chain history from mirror", "url", mirror, "network", network, "dir", dir)
+	if err := loader.Download(ctx, dir); err != nil {
+		return err
+	}
+	return ImportHistory(chain, db, dir, network)
+}
+
+// MakeHistoryMirrorRoots reads the trusted accumulator roots of the era1 files
+// downloaded from a history mirror.
+func MakeHistoryMirrorRoots(ctx *cli.Context) ([]common.Hash, error) {
+	file := ctx.String(HistoryMirrorRootsFlag.Name)
+	if file == "" {
+		return nil, fmt.Errorf("--%s requires the trusted accumulator roots given with --%s", HistoryMirrorFlag.Name, HistoryMirrorRootsFlag.Name)
+	}
+	return eradl.ReadRoots(file)
+}
+
+// execChecksumsFile is the name of the file listing the checksums of the EraE
+// files in a history directory.
+const execChecksumsFile = "checksums_erae.txt"
+
+// historyIterator is the common interface of the Era1 and EraE iterators.
+type historyIterator interface {
+	Next() bool
+	Number() uint64
+	Error() error
+	Block() (*types.Block, error)
+	Receipts() (types.Receipts, error)
+}
+
+// importHistoryFiles verifies the given archive files against the checksums
+// file, and imports them into the chain.
+func importHistoryFiles(chain *core.BlockChain, dir string, entries []string, checksumsFile string, open func(*os.File) (historyIterator, error)) error {
+	checksums, err := readList(filepath.Join(dir, checksumsFile))
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", checksumsFile, err)
 	}
 	if len(checksums) != len(entries) {
 		return fmt.Errorf("expected equal number of checksums and entries, have: %d checksums, %d entries", len(checksums), len(entries))
@@ -284,15 +369,11 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 			h.Reset()
 			buf.Reset()
 
-			// Import all block data from Era1.
-			e, err := era.From(f)
+			// Import all block data from the archive.
+			it, err := open(f)
 			if err != nil {
 				return fmt.Errorf("error opening era: %w", err)
 			}
-			it, err := era.NewIterator(e)
-			if err != nil {
-				return fmt.Errorf("error making era reader: %w", err)
-			}
 			for it.Next() {
 				block, err := it.Block()
 				if err != nil {
@@ -322,13 +403,12 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 					reported = time.Now()
 				}
 			}
-			return nil
+			return it.Error()
 		}()
 		if err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -401,8 +481,9 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
-// ExportHistory exports blockchain history into the specified directory,
-// following the Era format.
+// ExportHistory exports blockchain history into the specified directory.
+// Pre-merge blocks are written to Era1 files and post-merge blocks to EraE
+// files. The epoch containing the merge is split across both formats.
 func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) error {
 	log.Info("Exporting blockchain history", "dir", dir)
 	if head := bc.CurrentBlock().Number.Uint64(); head < last {
@@ -417,77 +498,120 @@ func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) er
 		return fmt.Errorf("error creating output directory: %w", err)
 	}
 	var (
-		start     = time.Now()
-		reported  = time.Now()
-		h         = sha256.New()
-		buf       = bytes.NewBuffer(nil)
-		checksums []string
+		start         = time.Now()
+		reported      = time.Now()
+		checksums     []string
+		execChecksums []string
+		isPostMerge   = func(n uint64) bool {
+			header := bc.GetHeaderByNumber(n)
+			return header != nil && header.Difficulty.Sign() == 0
+		}
 	)
 	for i := first; i <= last; i += step {
-		err := func() error {
-			filename := filepath.Join(dir, era.Filename(network, int(i/step), common.Hash{}))
-			f, err := os.Create(filename)
+		var (
+			end   = min(i+step-1, last)
+			epoch = int(i / step)
+			split = i + uint64(sort.Search(int(end-i+1), func(j int) bool { return isPostMerge(i + uint64(j)) }))
+		)
+		if split > i {
+			sum, err := writeHistoryFile(dir, func(root common.Hash) string { return era.Filename(network, epoch, root) }, func(w io.Writer) (common.Hash, error) {
+				b := era.NewBuilder(w)
+				for n := i; n < split; n++ {
+					block, receipts, err := readHistoryBlock(bc, n)
+					if err != nil {
+						return common.Hash{}, err
+					}
+					td := bc.GetTd(block.Hash(), block.NumberU64())
+					if td == nil {
+						return common.Hash{}, fmt.Errorf("export failed on #%d: total difficulty not found", n)
+					}
+					if err := b.Add(block, receipts, td); err != nil {
+						return common.Hash{}, err
+					}
+				}
+				return b.Finalize()
+			})
 			if err != nil {
-				return fmt.Errorf("could not create era file: %w", err)
+				return err
 			}
-			defer f.Close()
-
-			w := era.NewBuilder(f)
-			for j := uint64(0); j < step && j <= last-i; j++ {
-				var (
-					n     = i + j
-					block = bc.GetBlockByNumber(n)
-				)
-				if block == nil {
-					return fmt.Errorf("export failed on #%d: not found", n)
-				}
-				receipts := bc.GetReceiptsByHash(block.Hash())
-				if receipts == nil {
-					return fmt.Errorf("export failed on #%d: receipts not found", n)
-				}
-				td := bc.GetTd(block.Hash(), block.NumberU64())
-				if td == nil {
-					return fmt.Errorf("export failed on #%d: total difficulty not found", n)
-				}
-				if err := w.Add(block, receipts, td); err != nil {
-					return err
+			checksums = append(checksums, sum)
+		}
+		if split <= end {
+			sum, err := writeHistoryFile(dir, func(root common.Hash) string { return execdb.Filename(network, epoch, root) }, func(w io.Writer) (common.Hash, error) {
+				b := execdb.NewBuilder(w)
+				for n := split; n <= end; n++ {
+					block, receipts, err := readHistoryBlock(bc, n)
+					if err != nil {
+						return common.Hash{}, err
+					}
+					if err := b.Add(block, receipts); err != nil {
+						return common.Hash{}, err
+					}
 				}
-			}
-			root, err := w.Finalize()
+				return b.Finalize()
+			})
 			if err != nil {
-				return fmt.Errorf("export failed to finalize %d: %w", step/i, err)
-			}
-			// Set correct filename with root.
-			os.Rename(filename, filepath.Join(dir, era.Filename(network, int(i/step), root)))
-
-			// Compute checksum of entire Era1.
-			if _, err := f.Seek(0, io.SeekStart); err != nil {
 				return err
 			}
-			if _, err := io.Copy(h, f); err != nil {
-				return fmt.Errorf("unable to calculate checksum: %w", err)
-			}
-			checksums = append(checksums, common.BytesToHash(h.Sum(buf.Bytes()[:])).Hex())
-			h.Reset()
-			buf.Reset()
-			return nil
-		}()
-		if err != nil {
-			return err
+			execChecksums = append(execChecksums, sum)
 		}
 		if time.Since(reported) >= 8*time.Second {
 			log.Info("Exporting blocks", "exported", i, "elapsed", common.PrettyDuration(time.Since(start)))
 			reported = time.Now()
 		}
 	}
-
-	os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm)
-
+	if len(checksums) > 0 {
+		os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm)
+	}
+	if len(execChecksums) > 0 {
+		os.WriteFile(filepath.Join(dir, execChecksumsFile), []byte(strings.Join(execChecksums, "\n")), os.ModePerm)
+	}
 	log.Info("Exported blockchain to", "dir", dir)
 
 	return nil
 }
 
+// readHistoryBlock retrieves a canonical block and its receipts for export.
+func readHistoryBlock(bc *core.BlockChain, n uint64) (*types.Block, types.Receipts, error) {
+	block := bc.GetBlockByNumber(n)
+	if block == nil {
+		return nil, nil, fmt.Errorf("export failed on #%d: not found", n)
+	}
+	receipts := bc.GetReceiptsByHash(block.Hash())
+	if receipts == nil {
+		return nil, nil, fmt.Errorf("export failed on #%d: receipts not found", n)
+	}
+	return block, receipts, nil
+}
+
+// writeHistoryFile creates an archive file using write, and renames it to the
+// name derived from the archive root. The checksum of the file is returned.
+func writeHistoryFile(dir string, filename func(common.Hash) string, write func(io.Writer) (common.Hash, error)) (string, error) {
+	path := filepath.Join(dir, filename(common.Hash{}))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("could not create era file: %w", err)
+	}
+	defer f.Close()
+
+	root, err := write(f)
+	if err != nil {
+		return "", fmt.Errorf("export failed to finalize %s: %w", path, err)
+	}
+	// Set correct filename with root.
+	os.Rename(path, filepath.Join(dir, filename(root)))
+
+	// Compute checksum of the entire file.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("unable to calculate checksum: %w", err)
+	}
+	return common.BytesToHash(h.Sum(nil)).Hex(), nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
@@ -54,6 +54,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/ethstats"
 	"github.com/ethereum/go-ethereum/graphql"
@@ -105,6 +106,37 @@ var (
 		Value:    node.DefaultConfig.DBEngine,
 		Category: flags.EthCategory,
 	}
+	PebbleProfileFlag = &cli.StringFlag{
+		Name:     "db.pebble.profile",
+		Usage:    "Pebble tuning profile ('default', 'sync' for write-heavy syncing or 'rpc' for read-heavy serving)",
+		Value:    pebble.ProfileDefault,
+		Category: flags.EthCategory,
+	}
+	PebbleCompressionFlag = &cli.StringFlag{
+		Name:     "db.pebble.compression",
+		Usage:    "Compression of the bottom pebble levels holding the bulk of the data ('none', 'snappy' or 'zstd', default = from profile)",
+		Category: flags.EthCategory,
+	}
+	PebbleHotCompressionFlag = &cli.StringFlag{
+		Name:     "db.pebble.compression.hot",
+		Usage:    "Compression of the upper pebble levels holding recently written data ('none', 'snappy' or 'zstd', default = from profile)",
+		Category: flags.EthCategory,
+	}
+	PebbleKeyCompressionFlag = &cli.StringFlag{
+		Name:     "db.pebble.compression.keys",
+		Usage:    "Compression of the values by key in new pebble databases, on top of the level compression (e.g. 'state=zstd,trie=none', keys: 'trie', 'state', 'code' or a hex key prefix)",
+		Category: flags.EthCategory,
+	}
+	PebbleBloomBitsFlag = &cli.IntFlag{
+		Name:     "db.pebble.bloombits",
+		Usage:    "Bloom filter bits per key of the pebble tables (default = from profile, -1 = disabled)",
+		Category: flags.EthCategory,
+	}
+	PebbleBlockSizeFlag = &cli.IntFlag{
+		Name:     "db.pebble.blocksize",
+		Usage:    "Uncompressed size of the pebble data blocks in bytes (default = from profile)",
+		Category: flags.EthCategory,
+	}
 	AncientFlag = &flags.DirectoryFlag{
 		Name:     "datadir.ancient",
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
@@ -260,6 +292,11 @@ var (
 		Value:    &defaultSyncMode,
 		Category: flags.StateCategory,
 	}
+	SyncCheckpointFlag = &cli.StringFlag{
+		Name:     "sync.checkpoint",
+		Usage:    "Hash of a trusted block to sync to without a consensus client",
+		Category: flags.StateCategory,
+	}
 	GCModeFlag = &cli.StringFlag{
 		Name:     "gcmode",
 		Usage:    `Blockchain garbage collection mode, only relevant in state.scheme=hash ("full", "archive")`,
@@ -283,6 +320,41 @@ var (
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	HistoryExpiryFlag = &cli.BoolFlag{
+		Name:     "history.expiry",
+		Usage:    "Prune block bodies and receipts of pre-merge blocks (EIP-4444)",
+		Category: flags.StateCategory,
+	}
+	HistoryCutoffFlag = &cli.Uint64Flag{
+		Name:     "history.expiry.cutoff",
+		Usage:    "First block whose bodies and receipts are retained when history expiry is enabled (default = merge block)",
+		Category: flags.StateCategory,
+	}
+	ReceiptHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.receipts",
+		Usage:    "Number of recent blocks to download receipts for during snap sync (default = all blocks). Older receipts are regenerated on demand",
+		Category: flags.StateCategory,
+	}
+	HistoryArchiveFlag = &flags.DirectoryFlag{
+		Name:     "history.era",
+		Usage:    "Directory of era1 files used to serve expired chain history",
+		Category: flags.StateCategory,
+	}
+	HistoryMirrorFlag = &cli.StringFlag{
+		Name:     "history.mirror",
+		Usage:    "HTTP mirror to download era1 files from instead of syncing pre-merge history from the network",
+		Category: flags.StateCategory,
+	}
+	HistoryMirrorRootsFlag = &cli.StringFlag{
+		Name:     "history.mirror.roots",
+		Usage:    "File of the trusted accumulator roots of the era1 files, one per line in epoch order (required with --history.mirror)",
+		Category: flags.StateCategory,
+	}
+	VerkleConversionFlag = &cli.IntFlag{
+		Name:     "verkle.conversion",
+		Usage:    "Number of state leaves to convert per block into a verkle tree maintained next to the merkle state (experimental, 0 = disabled)",
+		Category: flags.StateCategory,
+	}
 	// Beacon client light sync settings
 	BeaconApiFlag = &cli.StringSliceFlag{
 		Name:     "beacon.api",
@@ -471,6 +543,16 @@ var (
 		Usage:    "Raise the open file descriptor resource limit (default = system fd limit)",
 		Category: flags.PerfCategory,
 	}
+	ParallelExecutionFlag = &cli.BoolFlag{
+		Name:     "execution.parallel",
+		Usage:    "Execute the transactions of imported blocks in parallel with optimistic concurrency (experimental)",
+		Category: flags.PerfCategory,
+	}
+	ParallelWorkersFlag = &cli.IntFlag{
+		Name:     "execution.parallel.workers",
+		Usage:    "Number of workers executing transactions in parallel (default = number of CPUs)",
+		Category: flags.PerfCategory,
+	}
 	CryptoKZGFlag = &cli.StringFlag{
 		Name:     "crypto.kzg",
 		Usage:    "KZG library implementation to use; gokzg (recommended) or ckzg",
@@ -502,6 +584,11 @@ var (
 		Value:    ethconfig.Defaults.Miner.Recommit,
 		Category: flags.MinerCategory,
 	}
+	MinerAccessListFlag = &cli.BoolFlag{
+		Name:     "miner.accesslist",
+		Usage:    "Embed the block-level access list into the built payloads (experimental)",
+		Category: flags.MinerCategory,
+	}
 	MinerPendingFeeRecipientFlag = &cli.StringFlag{
 		Name:     "miner.pending.feeRecipient",
 		Usage:    "0x prefixed public address for the pending block producer (not used for actual block production)",
@@ -787,7 +874,7 @@ var (
 	}
 	NATFlag = &cli.StringFlag{
 		Name:     "nat",
-		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pmp:<IP>|extip:<IP>)",
+		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pmp:<IP>|pcp|pcp:<IP>|extip:<IP>)",
 		Value:    "any",
 		Category: flags.NetworkingCategory,
 	}
@@ -814,6 +901,11 @@ var (
 		Usage:    "Restricts network communication to the given IP networks (CIDR masks)",
 		Category: flags.NetworkingCategory,
 	}
+	FirewallFlag = &cli.StringFlag{
+		Name:     "firewall",
+		Usage:    "Path to a JSON file containing the peer firewall policy",
+		Category: flags.NetworkingCategory,
+	}
 	DNSDiscoveryFlag = &cli.StringFlag{
 		Name:     "discovery.dns",
 		Usage:    "Sets DNS discovery entry points (use \"\" to disable DNS)",
@@ -825,6 +917,11 @@ var (
 		Value:    30303,
 		Category: flags.NetworkingCategory,
 	}
+	QUICPortFlag = &cli.IntFlag{
+		Name:     "quic.port",
+		Usage:    "Enable the experimental QUIC transport on the given UDP port",
+		Category: flags.NetworkingCategory,
+	}
 
 	// Console
 	JSpathFlag = &flags.DirectoryFlag{
@@ -972,6 +1069,12 @@ var (
 		AncientFlag,
 		RemoteDBFlag,
 		DBEngineFlag,
+		PebbleProfileFlag,
+		PebbleCompressionFlag,
+		PebbleHotCompressionFlag,
+		PebbleKeyCompressionFlag,
+		PebbleBloomBitsFlag,
+		PebbleBlockSizeFlag,
 		StateSchemeFlag,
 		HttpHeaderFlag,
 	}
@@ -1106,6 +1209,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.IsSet(DiscoveryPortFlag.Name) {
 		cfg.DiscAddr = fmt.Sprintf(":%d", ctx.Int(DiscoveryPortFlag.Name))
 	}
+	if ctx.IsSet(QUICPortFlag.Name) {
+		cfg.QUICListenAddr = fmt.Sprintf(":%d", ctx.Int(QUICPortFlag.Name))
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -1383,6 +1489,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.IsSet(FirewallFlag.Name) {
+		cfg.FirewallFile = ctx.String(FirewallFlag.Name)
+	}
 
 	if ctx.Bool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1443,6 +1552,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	setPebble(ctx, cfg)
 	// deprecation notice for log debug flags (TODO: find a more appropriate place to put these?)
 	if ctx.IsSet(LogBacktraceAtFlag.Name) {
 		log.Warn("log.backtrace flag is deprecated")
@@ -1452,6 +1562,74 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setPebble applies the pebble tuning flags to the node config.
+func setPebble(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.IsSet(PebbleProfileFlag.Name) && !ctx.IsSet(PebbleCompressionFlag.Name) && !ctx.IsSet(PebbleHotCompressionFlag.Name) &&
+		!ctx.IsSet(PebbleKeyCompressionFlag.Name) && !ctx.IsSet(PebbleBloomBitsFlag.Name) && !ctx.IsSet(PebbleBlockSizeFlag.Name) {
+		return
+	}
+	if cfg.Pebble == nil {
+		cfg.Pebble = new(pebble.Config)
+	}
+	if ctx.IsSet(PebbleProfileFlag.Name) {
+		cfg.Pebble.Profile = ctx.String(PebbleProfileFlag.Name)
+	}
+	if ctx.IsSet(PebbleCompressionFlag.Name) {
+		cfg.Pebble.Compression = ctx.String(PebbleCompressionFlag.Name)
+	}
+	if ctx.IsSet(PebbleHotCompressionFlag.Name) {
+		cfg.Pebble.HotCompression = ctx.String(PebbleHotCompressionFlag.Name)
+	}
+	if ctx.IsSet(PebbleKeyCompressionFlag.Name) {
+		keys, err := parsePebbleKeyCompression(ctx.String(PebbleKeyCompressionFlag.Name))
+		if err != nil {
+			Fatalf("Invalid pebble key compression: %v", err)
+		}
+		cfg.Pebble.KeyCompression = keys
+	}
+	if ctx.IsSet(PebbleBloomBitsFlag.Name) {
+		cfg.Pebble.BloomBits = ctx.Int(PebbleBloomBitsFlag.Name)
+	}
+	if ctx.IsSet(PebbleBlockSizeFlag.Name) {
+		cfg.Pebble.BlockSize = ctx.Int(PebbleBlockSizeFlag.Name)
+	}
+	if _, err := cfg.Pebble.Settings(); err != nil {
+		Fatalf("Invalid pebble tuning: %v", err)
+	}
+}
+
+// pebbleKeyClasses are the key prefixes of the data which can be compressed by
+// name with the key compression flag.
+var pebbleKeyClasses = map[string][][]byte{
+	"trie":  {rawdb.TrieNodeAccountPrefix, rawdb.TrieNodeStoragePrefix},
+	"state": {rawdb.SnapshotAccountPrefix, rawdb.SnapshotStoragePrefix},
+	"code":  {rawdb.CodePrefix},
+}
+
+// parsePebbleKeyCompression parses a comma separated list of key=compression
+// pairs, where the key is the name of a data class or a hex key prefix.
+func parsePebbleKeyCompression(spec string) ([]pebble.KeyCompression, error) {
+	var keys []pebble.KeyCompression
+	for _, entry := range strings.Split(spec, ",") {
+		key, compression, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry %q, want key=compression", entry)
+		}
+		prefixes, ok := pebbleKeyClasses[strings.ToLower(key)]
+		if !ok {
+			prefix, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+			if err != nil || len(prefix) == 0 {
+				return nil, fmt.Errorf("unknown key %q", key)
+			}
+			prefixes = [][]byte{prefix}
+		}
+		for _, prefix := range prefixes {
+			keys = append(keys, pebble.KeyCompression{Prefix: prefix, Compression: compression})
+		}
+	}
+	return keys, nil
+}
+
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
 	// Skip enabling smartcards if no path is set
 	path := ctx.String(SmartCardDaemonPathFlag.Name)
@@ -1565,6 +1743,9 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		log.Warn("The flag --miner.newpayload-timeout is deprecated and will be removed, please use --miner.recommit")
 		cfg.Recommit = ctx.Duration(MinerNewPayloadTimeoutFlag.Name)
 	}
+	if ctx.IsSet(MinerAccessListFlag.Name) {
+		cfg.AccessList = ctx.Bool(MinerAccessListFlag.Name)
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1641,6 +1822,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	// Avoid conflicting network flags
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, GoerliFlag, SepoliaFlag, HoleskyFlag)
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, SyncTargetFlag, SyncCheckpointFlag, DeveloperFlag)
 
 	// Set configurations from CLI flags
 	setEtherbase(ctx, cfg)
@@ -1695,6 +1877,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.IsSet(ParallelExecutionFlag.Name) {
+		cfg.ParallelExecution = ctx.Bool(ParallelExecutionFlag.Name)
+	}
+	if ctx.IsSet(ParallelWorkersFlag.Name) {
+		cfg.ParallelWorkers = ctx.Int(ParallelWorkersFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.Bool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1719,6 +1907,25 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Warn("The flag --txlookuplimit is deprecated and will be removed, please use --history.transactions")
 		cfg.TransactionHistory = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(HistoryExpiryFlag.Name) {
+		cfg.HistoryExpiry = ctx.Bool(HistoryExpiryFlag.Name)
+	}
+	if ctx.IsSet(HistoryCutoffFlag.Name) {
+		cfg.HistoryCutoff = ctx.Uint64(HistoryCutoffFlag.Name)
+	}
+	if ctx.IsSet(HistoryArchiveFlag.Name) {
+		cfg.HistoryArchive = ctx.String(HistoryArchiveFlag.Name)
+	}
+	if ctx.IsSet(ReceiptHistoryFlag.Name) {
+		cfg.ReceiptHistory = ctx.Uint64(ReceiptHistoryFlag.Name)
+	}
+	if ctx.IsSet(VerkleConversionFlag.Name) {
+		cfg.VerkleConversion = ctx.Int(VerkleConversionFlag.Name)
+	}
+	if cfg.VerkleConversion > 0 && !cfg.Preimages {
+		cfg.Preimages = true
+		log.Info("Enabling recording of key preimages since verkle conversion is used")
+	}
 	if ctx.String(GCModeFlag.Name) == "archive" && cfg.TransactionHistory != 0 {
 		cfg.TransactionHistory = 0
 		log.Warn("Disabled transaction unindexing for archive node")
@@ -1972,6 +2179,12 @@ func RegisterFullSyncTester(stack *node.Node, eth *eth.Ethereum, target common.H
 	log.Info("Registered full-sync tester", "hash", target)
 }
 
+// RegisterCheckpointSyncer adds the checkpoint sync service into node.
+func RegisterCheckpointSyncer(stack *node.Node, eth *eth.Ethereum, checkpoint common.Hash) {
+	catalyst.RegisterCheckpointSyncer(stack, eth, checkpoint)
+	log.Info("Registered checkpoint syncer", "hash", checkpoint)
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -2164,6 +2377,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	cache := &core.CacheConfig{
 		TrieCleanLimit:      ethconfig.Defaults.TrieCleanCache,
 		TrieCleanNoPrefetch: ctx.Bool(CacheNoPrefetchFlag.Name),
+		ParallelExecution:   ctx.Bool(ParallelExecutionFlag.Name),
+		ParallelWorkers:     ctx.Int(ParallelWorkersFlag.Name),
 		TrieDirtyLimit:      ethconfig.Defaults.TrieDirtyCache,
 		TrieDirtyDisabled:   ctx.String(GCModeFlag.Name) == "archive",
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
@@ -2171,11 +2386,16 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
 		StateScheme:         scheme,
 		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+		VerkleConversion:    ctx.Int(VerkleConversionFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
 		log.Info("Enabling recording of key preimages since archive mode is used")
 	}
+	if cache.VerkleConversion > 0 && !cache.Preimages {
+		cache.Preimages = true
+		log.Info("Enabling recording of key preimages since verkle conversion is used")
+	}
 	if !ctx.Bool(SnapshotFlag.Name) {
 		cache.SnapshotLimit = 0 // Disabled
 	}
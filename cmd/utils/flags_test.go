@@ -20,6 +20,9 @@ package utils
 import (
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 )
 
 func Test_SplitTagsFlag(t *testing.T) {
@@ -65,3 +68,24 @@ func Test_SplitTagsFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePebbleKeyCompression(t *testing.T) {
+	t.Parallel()
+	keys, err := parsePebbleKeyCompression("state=zstd, 0x63=snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pebble.KeyCompression{
+		{Prefix: rawdb.SnapshotAccountPrefix, Compression: "zstd"},
+		{Prefix: rawdb.SnapshotStoragePrefix, Compression: "zstd"},
+		{Prefix: []byte{0x63}, Compression: "snappy"},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("key compression mismatch: have %v, want %v", keys, want)
+	}
+	for _, spec := range []string{"state", "blocks=zstd", "0x=zstd"} {
+		if _, err := parsePebbleKeyCompression(spec); err == nil {
+			t.Errorf("invalid spec %q accepted", spec)
+		}
+	}
+}
@@ -21,12 +21,16 @@ import (
 	"crypto/sha256"
 	"io"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -34,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/internal/era/execdb"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/triedb"
@@ -182,3 +187,159 @@ func TestHistoryImportAndExport(t *testing.T) {
 		t.Fatalf("imported chain does not match expected, have (%d, %s) want (%d, %s)", have.Number, have.Hash(), want.Number, want.Hash())
 	}
 }
+
+func TestHistoryImportAndExportPostMerge(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		config  = *params.TestChainConfig
+		genesis = &core.Genesis{
+			Config: &config,
+			Alloc:  types.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		signer  = types.LatestSigner(genesis.Config)
+		engine  = beacon.New(ethash.NewFaker())
+		mergeAt = uint64(40)
+	)
+	// Generate a chain which transitions to proof-of-stake in the middle of an epoch.
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, engine, int(count), func(i int, g *core.BlockGen) {
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   genesis.Config.ChainID,
+			Nonce:     uint64(i),
+			GasFeeCap: g.BaseFee(),
+			Gas:       21000,
+			To:        &common.Address{0xaa},
+			Value:     big.NewInt(int64(i)),
+		})
+		if err != nil {
+			t.Fatalf("error creating tx: %v", err)
+		}
+		g.AddTx(tx)
+		if g.Number().Uint64() >= mergeAt {
+			g.SetPoS()
+		}
+	})
+	td := new(big.Int).Set(genesis.ToBlock().Difficulty())
+	for _, block := range blocks[:mergeAt-1] {
+		td.Add(td, block.Difficulty())
+	}
+	config.TerminalTotalDifficulty = td
+
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+
+	// Export history and check the merge epoch is split across both formats.
+	dir := t.TempDir()
+	if err := ExportHistory(chain, dir, 0, count, step); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	entries, err := era.ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	execEntries, err := execdb.ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int(mergeAt/step) + 1; len(entries) != want {
+		t.Fatalf("wrong number of era1 files: have %d, want %d", len(entries), want)
+	}
+	if want := int(count/step) - int(mergeAt/step) + 1; len(execEntries) != want {
+		t.Fatalf("wrong number of erae files: have %d, want %d", len(execEntries), want)
+	}
+	e, err := execdb.Open(filepath.Join(dir, execEntries[0]))
+	if err != nil {
+		t.Fatalf("error opening erae: %v", err)
+	}
+	if e.Start() != mergeAt || e.Count() != step-mergeAt%step {
+		t.Fatalf("wrong merge epoch: start %d, count %d", e.Start(), e.Count())
+	}
+	e.Close()
+
+	// Import into a fresh database.
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	imported, err := core.NewBlockChain(db2, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer imported.Stop()
+	if err := ImportHistory(imported, db2, dir, "mainnet"); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if have, want := imported.CurrentHeader(), chain.CurrentHeader(); have.Hash() != want.Hash() {
+		t.Fatalf("imported chain does not match expected, have (%d, %s) want (%d, %s)", have.Number, have.Hash(), want.Number, want.Hash())
+	}
+	if have := imported.GetReceiptsByHash(imported.CurrentHeader().Hash()); len(have) != 1 {
+		t.Fatalf("wrong number of receipts at head: %d", len(have))
+	}
+}
+
+func TestHistoryImportFromMirror(t *testing.T) {
+	genesis := &core.Genesis{Config: params.TestChainConfig}
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), int(count), nil)
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	// Serve the exported history over HTTP.
+	mirror := t.TempDir()
+	if err := ExportHistory(chain, mirror, 0, count, step); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(mirror)))
+	defer srv.Close()
+
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	imported, err := core.NewBlockChain(db2, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer imported.Stop()
+	// The accumulator roots are taken from the trusted local export
+	entries, err := era.ReadDir(mirror, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roots []common.Hash
+	for _, entry := range entries {
+		e, err := era.Open(filepath.Join(mirror, entry))
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := e.Accumulator()
+		e.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+	untrusted := slices.Clone(roots)
+	untrusted[len(untrusted)-1] = common.Hash{0x01}
+	if err := ImportHistoryFromMirror(imported, db2, srv.URL, untrusted, t.TempDir()); err == nil || !strings.Contains(err.Error(), "accumulator root mismatch") {
+		t.Fatalf("wrong error for untrusted accumulator root: %v", err)
+	}
+	if err := ImportHistoryFromMirror(imported, db2, srv.URL, roots, t.TempDir()); err != nil {
+		t.Fatalf("failed to import history from mirror: %v", err)
+	}
+	if have, want := imported.CurrentHeader(), chain.CurrentHeader(); have.Hash() != want.Hash() {
+		t.Fatalf("imported chain does not match expected, have (%d, %s) want (%d, %s)", have.Number, have.Hash(), want.Number, want.Hash())
+	}
+}
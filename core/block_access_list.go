@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// accessListTracker tracks the accesses of the execution phases of a block into
+// its block-level access list. A nil tracker tracks nothing.
+type accessListTracker struct {
+	builder     *state.BlockAccessListBuilder
+	rwset       *state.ReadWriteSet
+	deleteEmpty bool
+}
+
+// newAccessListTracker creates a tracker for a block, deleting the empty
+// accounts at the end of each phase if EIP-158 is active.
+func newAccessListTracker(deleteEmpty bool) *accessListTracker {
+	return &accessListTracker{
+		builder:     state.NewBlockAccessListBuilder(),
+		deleteEmpty: deleteEmpty,
+	}
+}
+
+// start starts tracking the accesses of the next execution phase.
+func (t *accessListTracker) start(statedb *state.StateDB) {
+	if t == nil {
+		return
+	}
+	t.rwset = state.NewReadWriteSet()
+	statedb.SetReadWriteSet(t.rwset)
+}
+
+// end finalises the state and stops tracking the accesses of the execution
+// phase at the given block access index.
+func (t *accessListTracker) end(statedb *state.StateDB, index uint64) {
+	if t == nil {
+		return
+	}
+	statedb.Finalise(t.deleteEmpty)
+	t.builder.Add(index, t.rwset, statedb.Writes())
+	statedb.SetReadWriteSet(nil)
+}
+
+// add tracks the accesses of an execution phase tracked elsewhere.
+func (t *accessListTracker) add(index uint64, rwset *state.ReadWriteSet, writes map[common.Address]*state.AccountWrite) {
+	if t == nil {
+		return
+	}
+	t.builder.Add(index, rwset, writes)
+}
+
+// build returns the block-level access list of the tracked phases, nil if
+// nothing was tracked.
+func (t *accessListTracker) build() *types.BlockAccessList {
+	if t == nil {
+		return nil
+	}
+	return t.builder.Build()
+}
+
+// AccessList re-executes a block on top of its parent state and returns its
+// block-level access list.
+func (bc *BlockChain) AccessList(block *types.Block) (*types.BlockAccessList, error) {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	statedb, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	// Reuse the chain processor for its parallel execution settings
+	processor, ok := bc.processor.(*StateProcessor)
+	if !ok {
+		processor = NewStateProcessor(bc.chainConfig, bc, bc.engine)
+	}
+	receipts, _, usedGas, list, err := processor.ProcessWithAccessList(block, statedb, bc.vmConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
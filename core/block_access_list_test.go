@@ -0,0 +1,147 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func TestBlockAccessListImport(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		counter = common.HexToAddress("0xc0")
+		drained = common.HexToAddress("0xdd")
+		engine  = beacon.New(ethash.NewFaker())
+		gspec   = &Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				// counter increments slot 0
+				counter: {Code: common.FromHex("600054600101600055")},
+			},
+		}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, func(i int, gen *BlockGen) {
+		gen.SetPoS()
+		signer := types.MakeSigner(gspec.Config, gen.Number(), gen.Timestamp())
+		for j := 0; j < 3; j++ {
+			tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+				Nonce:    gen.TxNonce(sender),
+				To:       &counter,
+				Gas:      100000,
+				GasPrice: big.NewInt(2 * params.InitialBaseFee),
+			})
+			gen.AddTx(tx)
+		}
+		gen.AddWithdrawal(&types.Withdrawal{Validator: 1, Address: drained, Amount: 1})
+	})
+	source, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer source.Stop()
+
+	if n, err := source.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert: %v", n, err)
+	}
+	// Ensure the generated access lists track the changes of every phase
+	withLists := make([]*types.Block, len(blocks))
+	for i, block := range blocks {
+		list, err := source.AccessList(block)
+		if err != nil {
+			t.Fatalf("block %d: failed to generate access list: %v", block.NumberU64(), err)
+		}
+		withLists[i] = block.WithAccessList(list)
+
+		var found int
+		for _, access := range *list {
+			switch access.Address {
+			case counter:
+				found++
+				if len(access.StorageChanges) != 1 || len(access.StorageChanges[0].Changes) != 3 {
+					t.Fatalf("block %d: counter changes mismatch: %v", block.NumberU64(), access.StorageChanges)
+				}
+				for j, change := range access.StorageChanges[0].Changes {
+					if want := (common.Hash{31: byte(3*i + j + 1)}); change.Index != uint64(j+1) || change.Value != want {
+						t.Fatalf("block %d: counter change %d mismatch: have %d:%x, want %d:%x", block.NumberU64(), j, change.Index, change.Value, j+1, want)
+					}
+				}
+			case drained:
+				found++
+				want := new(uint256.Int).Mul(uint256.NewInt(uint64(i+1)), uint256.NewInt(params.GWei))
+				if len(access.BalanceChanges) != 1 || access.BalanceChanges[0].Index != 4 || !access.BalanceChanges[0].Balance.Eq(want) {
+					t.Fatalf("block %d: withdrawal changes mismatch: %v", block.NumberU64(), access.BalanceChanges)
+				}
+			}
+		}
+		if found != 2 {
+			t.Fatalf("block %d: missing accounts in access list", block.NumberU64())
+		}
+	}
+	// Import the blocks along with their access lists, sequentially and in parallel
+	for _, parallel := range []bool{false, true} {
+		cacheConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+		cacheConfig.ParallelExecution = parallel
+
+		chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		if n, err := chain.InsertChain(withLists); err != nil {
+			t.Fatalf("parallel %v: block %d: failed to insert with access list: %v", parallel, n, err)
+		}
+		chain.Stop()
+	}
+	// Ensure blocks carrying a mismatching access list are imported regardless,
+	// as the access list is not part of the block
+	tampered := make(types.BlockAccessList, len(*withLists[0].AccessList()))
+	copy(tampered, *withLists[0].AccessList())
+	tampered = tampered[1:]
+
+	for _, parallel := range []bool{false, true} {
+		cacheConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+		cacheConfig.ParallelExecution = parallel
+
+		db := rawdb.NewMemoryDatabase()
+		chain, err := NewBlockChain(db, cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		if _, err := chain.InsertChain(types.Blocks{blocks[0].WithAccessList(&tampered)}); err != nil {
+			t.Fatalf("parallel %v: failed to insert with tampered access list: %v", parallel, err)
+		}
+		if head := chain.CurrentBlock(); head.Hash() != blocks[0].Hash() {
+			t.Fatalf("parallel %v: head mismatch: have %x, want %x", parallel, head.Hash(), blocks[0].Hash())
+		}
+		if bad := rawdb.ReadAllBadBlocks(db); len(bad) != 0 {
+			t.Fatalf("parallel %v: block with tampered access list reported as bad", parallel)
+		}
+		chain.Stop()
+	}
+}
@@ -34,14 +34,17 @@ import (
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/overlay"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/syncx"
 	"github.com/ethereum/go-ethereum/internal/version"
 	"github.com/ethereum/go-ethereum/log"
@@ -100,6 +103,7 @@ const (
 	blockCacheLimit    = 256
 	receiptsCacheLimit = 32
 	txLookupCacheLimit = 1024
+	nodeCacheLimit     = 64 * 1024 * 1024 // Memory allowance of the trie node cache shared within a block
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
@@ -139,9 +143,16 @@ type CacheConfig struct {
 	Preimages           bool          // Whether to store preimage of trie key to the disk
 	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
 	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	VerkleConversion    int           // Number of state leaves converted into a verkle tree per block, zero to disable
+	ParallelExecution   bool          // Whether to execute the transactions of blocks in parallel
+	ParallelWorkers     int           // Number of workers executing transactions in parallel, zero for one per CPU
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
+
+	HistoryExpiry  bool   // Whether to prune block bodies and receipts below the history cutoff
+	HistoryCutoff  uint64 // First block whose history is retained, zero means the merge block
+	HistoryArchive string // Directory of era1 files used to serve expired history
 }
 
 // triedbConfig derives the configures for trie database.
@@ -219,6 +230,16 @@ type BlockChain struct {
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
 	txIndexer     *txIndexer                       // Transaction indexer, might be nil if not enabled
 
+	historyTail    atomic.Uint64  // Oldest block whose body and receipts are retained
+	historyPruner  *historyPruner // History pruner, might be nil if not enabled
+	historyArchive *era.Store     // Era1 archive for serving expired history, might be nil
+	receiptsTail   atomic.Uint64  // Oldest block whose receipts were not skipped during sync
+
+	statePruner     *pruner.OnlinePruner // Last online state pruning run, nil if none yet
+	statePrunerLock sync.Mutex
+
+	converter *overlay.Converter // Merkle to verkle state converter, nil if not enabled
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -307,13 +328,35 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
-	bc.processor = NewStateProcessor(chainConfig, bc, engine)
+	if cacheConfig.ParallelExecution {
+		workers := cacheConfig.ParallelWorkers
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		if workers < 2 {
+			log.Warn("Parallel execution needs at least two workers, executing sequentially", "workers", workers)
+		}
+		bc.processor = NewParallelStateProcessor(chainConfig, bc, engine, workers)
+	} else {
+		bc.processor = NewStateProcessor(chainConfig, bc, engine)
+	}
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
 	if err != nil {
 		return nil, err
 	}
+	bc.historyTail.Store(rawdb.ReadHistoryExpiryTail(db))
+	if tail := rawdb.ReadReceiptsTail(db); tail != nil {
+		bc.receiptsTail.Store(*tail)
+	}
+	if cacheConfig.HistoryArchive != "" {
+		bc.historyArchive, err = era.NewStore(cacheConfig.HistoryArchive)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Serving expired history from era1 archive", "dir", cacheConfig.HistoryArchive, "files", bc.historyArchive.Files())
+	}
 	bc.genesisBlock = bc.GetBlockByNumber(0)
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
@@ -459,10 +502,20 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		rawdb.WriteChainConfig(db, genesisHash, chainConfig)
 	}
 
+	// Start the verkle conversion of the state if it's enabled.
+	if cacheConfig.VerkleConversion > 0 {
+		if bc.converter, err = overlay.New(bc.db, bc.triedb, cacheConfig.VerkleConversion); err != nil {
+			return nil, err
+		}
+	}
 	// Start tx indexer if it's enabled.
 	if txLookupLimit != nil {
 		bc.txIndexer = newTxIndexer(*txLookupLimit, bc)
 	}
+	// Start history pruner if it's enabled.
+	if cacheConfig.HistoryExpiry {
+		bc.historyPruner = newHistoryPruner(bc, cacheConfig.HistoryCutoff)
+	}
 	return bc, nil
 }
 
@@ -1066,6 +1119,13 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block.Header())
 	headBlockGauge.Update(int64(block.NumberU64()))
+
+	// Advance the verkle conversion on top of the new head state
+	if bc.converter != nil && bc.HasState(block.Root()) {
+		if err := bc.converter.Step(block.Root(), block.NumberU64()); err != nil {
+			log.Error("Failed to convert state into verkle tree", "number", block.Number(), "err", err)
+		}
+	}
 }
 
 // stopWithoutSaving stops the blockchain service. If any imports are currently in progress
@@ -1082,6 +1142,12 @@ func (bc *BlockChain) stopWithoutSaving() {
 	if bc.txIndexer != nil {
 		bc.txIndexer.close()
 	}
+	// Signal shutdown history pruner.
+	if bc.historyPruner != nil {
+		bc.historyPruner.close()
+	}
+	// Signal shutdown online state pruning, releasing the retained states.
+	bc.abortStatePruning()
 	// Unsubscribe all subscriptions registered from blockchain.
 	bc.scope.Close()
 
@@ -1151,6 +1217,12 @@ func (bc *BlockChain) Stop() {
 			}
 		}
 	}
+	// Release the converted verkle tree, its progress is persisted every block.
+	if bc.converter != nil {
+		if err := bc.converter.Close(); err != nil {
+			log.Error("Failed to close verkle converter", "err", err)
+		}
+	}
 	// Allow tracers to clean-up and release resources.
 	if bc.logger != nil && bc.logger.OnClose != nil {
 		bc.logger.OnClose()
@@ -1183,6 +1255,14 @@ const (
 	SideStatTy
 )
 
+// SetReceiptsTail marks the receipts of all blocks below the given number as
+// skipped. It is used by snap sync to avoid downloading old receipts, which
+// can be regenerated on demand by re-executing the blocks.
+func (bc *BlockChain) SetReceiptsTail(number uint64) {
+	rawdb.WriteReceiptsTail(bc.db, number)
+	bc.receiptsTail.Store(number)
+}
+
 // InsertReceiptChain attempts to complete an already existing header chain with
 // transaction and receipt data.
 func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain []types.Receipts, ancientLimit uint64) (int, error) {
@@ -1799,8 +1879,13 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		if parent == nil {
 			parent = bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
 		}
+		// Resolve the trie nodes of the block through a fresh cache, shared by the
+		// block processing, the prefetchers and the snapshot generator.
+		bc.triedb.SetNodeCache(triedb.NewNodeCache(nodeCacheLimit))
+
 		statedb, err := state.New(parent.Root, bc.stateCache, bc.snaps)
 		if err != nil {
+			bc.triedb.SetNodeCache(nil)
 			return it.index, err
 		}
 		statedb.SetLogger(bc.logger)
@@ -1832,11 +1917,21 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 					}
 				}(time.Now(), followup, throwaway)
 			}
+			// If the block carries an access list, pull in all the state it accesses
+			// ahead of the execution reaching it.
+			if list := block.AccessList(); list != nil {
+				throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
+				go bc.prefetcher.PrefetchAccessList(*list, throwaway, &followupInterrupt)
+			}
 		}
 
 		// The traced section of block import.
 		res, err := bc.processBlock(block, statedb, start, setHead)
 		followupInterrupt.Store(true)
+
+		// Drop the node cache of the block, so that the nodes it holds aren't
+		// retained after the processing, e.g. by readers opened over RPC.
+		bc.triedb.SetNodeCache(nil)
 		if err != nil {
 			return it.index, err
 		}
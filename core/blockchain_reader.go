@@ -106,7 +106,11 @@ func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
 	}
 	body := rawdb.ReadBody(bc.db, hash, *number)
 	if body == nil {
-		return nil
+		block := bc.readArchivedBlock(hash, *number)
+		if block == nil {
+			return nil
+		}
+		body = block.Body()
 	}
 	// Cache the found body for next time and return
 	bc.bodyCache.Add(hash, body)
@@ -164,7 +168,9 @@ func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	}
 	block := rawdb.ReadBlock(bc.db, hash, number)
 	if block == nil {
-		return nil
+		if block = bc.readArchivedBlock(hash, number); block == nil {
+			return nil
+		}
 	}
 	// Cache the found block for next time and return
 	bc.blockCache.Add(block.Hash(), block)
@@ -222,7 +228,16 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	if header == nil {
 		return nil
 	}
-	receipts := rawdb.ReadReceipts(bc.db, hash, *number, header.Time, bc.chainConfig)
+	var receipts types.Receipts
+	if bc.missingReceipts(header) {
+		// Receipts were skipped during sync, an empty placeholder is stored
+		receipts = bc.readArchivedReceipts(header)
+	} else {
+		receipts = rawdb.ReadReceipts(bc.db, hash, *number, header.Time, bc.chainConfig)
+		if receipts == nil {
+			receipts = bc.readArchivedReceipts(header)
+		}
+	}
 	if receipts == nil {
 		return nil
 	}
@@ -230,6 +245,19 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	return receipts
 }
 
+// ReceiptsTail returns the number of the oldest block whose receipts were
+// downloaded during snap sync. The receipts of older, non-empty blocks were
+// skipped and are not available locally.
+func (bc *BlockChain) ReceiptsTail() uint64 {
+	return bc.receiptsTail.Load()
+}
+
+// missingReceipts reports whether the receipts of the given block were skipped
+// during snap sync.
+func (bc *BlockChain) missingReceipts(header *types.Header) bool {
+	return header.Number.Uint64() < bc.receiptsTail.Load() && !header.EmptyReceipts()
+}
+
 // GetUnclesInChain retrieves all the uncles from a given block backwards until
 // a specific distance is reached.
 func (bc *BlockChain) GetUnclesInChain(block *types.Block, length int) []*types.Header {
@@ -4221,3 +4221,62 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+func TestVerkleConversion(t *testing.T) {
+	testVerkleConversion(t, rawdb.HashScheme)
+	testVerkleConversion(t, rawdb.PathScheme)
+}
+
+func testVerkleConversion(t *testing.T, scheme string) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		engine = beacon.NewFaker()
+		gspec  = &Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer  = types.LatestSigner(gspec.Config)
+		targets []common.Address
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 16, func(i int, b *BlockGen) {
+		target := common.Address{byte(i + 1)}
+		targets = append(targets, target)
+
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), target, big.NewInt(int64(i+1)), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	cacheConfig := DefaultCacheConfigWithScheme(scheme)
+	cacheConfig.Preimages = true
+	cacheConfig.VerkleConversion = 2
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if !chain.converter.Done() {
+		t.Fatalf("conversion not done: %+v", chain.converter.Progress())
+	}
+	if root := chain.converter.Progress().Root; root != chain.CurrentBlock().Root {
+		t.Fatalf("conversion out of sync: have %x, want %x", root, chain.CurrentBlock().Root)
+	}
+	tree, err := chain.converter.Tree()
+	if err != nil {
+		t.Fatalf("failed to open converted tree: %v", err)
+	}
+	statedb, _ := chain.State()
+	for _, account := range append(targets, addr) {
+		acc, err := tree.GetAccount(account)
+		if err != nil || acc == nil {
+			t.Fatalf("account %x missing from converted tree: %v", account, err)
+		}
+		if !acc.Balance.Eq(statedb.GetBalance(account)) || acc.Nonce != statedb.GetNonce(account) {
+			t.Errorf("account %x mismatch: have %v/%d, want %v/%d", account, acc.Balance, acc.Nonce, statedb.GetBalance(account), statedb.GetNonce(account))
+		}
+	}
+}
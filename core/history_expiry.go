@@ -0,0 +1,209 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// historyPruneInterval is the frequency at which the history pruner checks
+// whether more blocks have been moved into the ancient store and can be expired.
+const historyPruneInterval = time.Minute
+
+// historyPruner removes the bodies and receipts of blocks below the history
+// cutoff from the ancient store, as specified by EIP-4444. Headers are always
+// retained, so the chain remains verifiable.
+type historyPruner struct {
+	chain  *BlockChain
+	cutoff uint64 // first block to retain, zero means the merge block
+	merge  uint64 // number of the first post-merge block, zero if not known yet
+
+	term   chan struct{}
+	closed chan struct{}
+}
+
+// newHistoryPruner starts the history pruner.
+func newHistoryPruner(chain *BlockChain, cutoff uint64) *historyPruner {
+	p := &historyPruner{
+		chain:  chain,
+		cutoff: cutoff,
+		term:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go p.loop()
+
+	if cutoff == 0 {
+		log.Info("Enabled chain history expiry", "cutoff", "merge")
+	} else {
+		log.Info("Enabled chain history expiry", "cutoff", cutoff)
+	}
+	return p
+}
+
+// loop periodically expires the history below the cutoff.
+func (p *historyPruner) loop() {
+	defer close(p.closed)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := p.prune(); err != nil {
+				log.Error("Failed to prune chain history", "err", err)
+			}
+			timer.Reset(historyPruneInterval)
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// close terminates the pruner and waits for it to exit.
+func (p *historyPruner) close() {
+	close(p.term)
+	<-p.closed
+}
+
+// prune removes the bodies and receipts of all frozen blocks below the cutoff.
+// Blocks which are still in the key-value store are expired once the freezer
+// has moved them into the ancient store.
+func (p *historyPruner) prune() error {
+	db := p.chain.db
+	frozen, err := db.Ancients()
+	if err != nil {
+		return nil // no ancient store, nothing to prune
+	}
+	target, ok := p.target(frozen)
+	if !ok {
+		return nil
+	}
+	if target > frozen {
+		target = frozen
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return err
+	}
+	if target <= tail {
+		return nil
+	}
+	start := time.Now()
+
+	// Record the expired range before removing the data, so it is never
+	// reported as available after a crash.
+	rawdb.WriteHistoryExpiryTail(db, target)
+	p.chain.historyTail.Store(target)
+
+	if _, err := db.TruncateTail(target); err != nil {
+		return err
+	}
+	log.Info("Pruned chain history", "tail", target, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// target returns the number of the first block whose history must be retained.
+// False is returned if it can't be determined yet.
+func (p *historyPruner) target(frozen uint64) (uint64, bool) {
+	if p.cutoff != 0 {
+		return p.cutoff, true
+	}
+	if p.merge != 0 {
+		return p.merge, true
+	}
+	if p.chain.Config().TerminalTotalDifficulty == nil {
+		return 0, false // chain will never transition to proof-of-stake
+	}
+	if frozen == 0 {
+		return 0, false
+	}
+	// Headers are retained, look for the first frozen post-merge header. If all
+	// frozen blocks are pre-merge, they can all be expired.
+	isMerged := func(number uint64) bool {
+		header := p.chain.GetHeaderByNumber(number)
+		return header != nil && header.Difficulty.Sign() == 0
+	}
+	if !isMerged(frozen - 1) {
+		return frozen, true
+	}
+	merge := uint64(sort.Search(int(frozen), func(i int) bool { return isMerged(uint64(i)) }))
+	p.merge = merge
+	return merge, true
+}
+
+// HistoryPruningCutoff returns the number of the oldest block whose body and
+// receipts are available in the local database. Older blocks have expired and
+// can only be served from the era1 archive, if one is configured.
+func (bc *BlockChain) HistoryPruningCutoff() uint64 {
+	return bc.historyTail.Load()
+}
+
+// isExpired reports whether the body and receipts of the given block have been
+// removed from the local database.
+func (bc *BlockChain) isExpired(number uint64) bool {
+	return number < bc.historyTail.Load()
+}
+
+// readArchivedBlock retrieves an expired block from the era1 archive.
+func (bc *BlockChain) readArchivedBlock(hash common.Hash, number uint64) *types.Block {
+	if bc.historyArchive == nil || !bc.isExpired(number) {
+		return nil
+	}
+	block, err := bc.historyArchive.GetBlock(number, hash)
+	if err != nil {
+		if !errors.Is(err, era.ErrNotFound) {
+			log.Warn("Failed to read block from era1 archive", "number", number, "hash", hash, "err", err)
+		}
+		return nil
+	}
+	return block
+}
+
+// readArchivedReceipts retrieves the receipts of an expired block from the era1
+// archive and derives their metadata fields.
+func (bc *BlockChain) readArchivedReceipts(header *types.Header) types.Receipts {
+	hash, number := header.Hash(), header.Number.Uint64()
+	block := bc.readArchivedBlock(hash, number)
+	if block == nil {
+		return nil
+	}
+	receipts, err := bc.historyArchive.GetReceipts(number, hash)
+	if err != nil {
+		log.Warn("Failed to read receipts from era1 archive", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	var blobGasPrice *big.Int
+	if header.ExcessBlobGas != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*header.ExcessBlobGas)
+	}
+	if err := receipts.DeriveFields(bc.chainConfig, hash, number, header.Time, header.BaseFee, blobGasPrice, block.Transactions()); err != nil {
+		log.Error("Failed to derive archived receipts fields", "hash", hash, "number", number, "err", err)
+		return nil
+	}
+	return receipts
+}
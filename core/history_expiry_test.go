@@ -0,0 +1,154 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the history pruner expires the bodies and receipts of pre-merge
+// blocks, and that expired blocks can be served from an era1 archive.
+func TestHistoryExpiry(t *testing.T) {
+	var (
+		config  = *params.TestChainConfig
+		engine  = beacon.New(ethash.NewFaker())
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: &config, Alloc: types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+		signer  = types.LatestSigner(gspec.Config)
+		mergeAt = 12
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, engine, 20, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{0xaa}, big.NewInt(1), params.TxGas, gen.header.BaseFee, nil), signer, key)
+		gen.AddTx(tx)
+		if gen.header.Number.Uint64() >= uint64(mergeAt) {
+			gen.SetPoS()
+		}
+	})
+	// Set the terminal total difficulty to the difficulty of the last PoW block.
+	td := new(big.Int).Set(gspec.ToBlock().Difficulty())
+	for _, block := range blocks[:mergeAt-1] {
+		td.Add(td, block.Difficulty())
+	}
+	config.TerminalTotalDifficulty = td
+
+	// Import the chain into the ancient store.
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+	chain, err := NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, uint64(len(blocks))); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+
+	// Expire the history up to a custom cutoff first, then up to the merge.
+	for _, cutoff := range []uint64{5, 0} {
+		p := &historyPruner{chain: chain, cutoff: cutoff}
+		if err := p.prune(); err != nil {
+			t.Fatalf("failed to prune history: %v", err)
+		}
+		want := cutoff
+		if cutoff == 0 {
+			want = uint64(mergeAt)
+		}
+		if have := chain.HistoryPruningCutoff(); have != want {
+			t.Fatalf("wrong cutoff: have %d, want %d", have, want)
+		}
+		if have := rawdb.ReadHistoryExpiryTail(db); have != want {
+			t.Fatalf("wrong stored cutoff: have %d, want %d", have, want)
+		}
+	}
+	for _, block := range blocks {
+		number, hash := block.NumberU64(), block.Hash()
+		if chain.GetHeaderByNumber(number) == nil {
+			t.Fatalf("header %d missing", number)
+		}
+		expired := number < uint64(mergeAt)
+		if have := chain.GetBlock(hash, number); (have == nil) != expired {
+			t.Fatalf("block %d: available %t, expired %t", number, have != nil, expired)
+		}
+		if have := chain.GetReceiptsByHash(hash); (have == nil) != expired {
+			t.Fatalf("receipts %d: available %t, expired %t", number, have != nil, expired)
+		}
+	}
+	if chain.GetBlockByNumber(0) == nil {
+		t.Fatal("genesis block expired")
+	}
+
+	// Serve the expired blocks from an era1 archive.
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "test-00000-00000000.era1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := era.NewBuilder(f)
+	if err := builder.Add(chain.GetBlockByNumber(0), nil, chain.GetTd(chain.Genesis().Hash(), 0)); err != nil {
+		t.Fatal(err)
+	}
+	for i, block := range blocks[:mergeAt-1] {
+		if err := builder.Add(block, receipts[i], chain.GetTd(block.Hash(), block.NumberU64())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := builder.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if chain.historyArchive, err = era.NewStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	for i, block := range blocks[:mergeAt-1] {
+		number, hash := block.NumberU64(), block.Hash()
+		if have := chain.GetBlock(hash, number); have == nil || have.Hash() != hash {
+			t.Fatalf("block %d not served from archive", number)
+		}
+		have := chain.GetReceiptsByHash(hash)
+		if len(have) != len(receipts[i]) {
+			t.Fatalf("receipts %d not served from archive", number)
+		}
+		if have[0].TxHash != block.Transactions()[0].Hash() || have[0].BlockNumber.Uint64() != number {
+			t.Fatalf("receipts %d: fields not derived", number)
+		}
+	}
+}
@@ -0,0 +1,558 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package overlay implements the incremental conversion of the merkle-patricia
+// state into a verkle tree, maintained as an overlay next to the merkle state.
+package overlay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// pointCacheSize is the number of address points cached by the converter.
+const pointCacheSize = 4096
+
+// Progress is the persisted state of the conversion. The merkle leaves are
+// converted in the order of their hashes, every leaf up to the markers is
+// present in the verkle tree and kept in sync with the merkle state.
+type Progress struct {
+	Root       common.Hash // Merkle state root the verkle tree is in sync with
+	VerkleRoot common.Hash // Root of the converted verkle tree
+	Account    []byte      // Hash of the last converted account, empty if none yet
+	Storage    []byte      // Hash of the last converted slot of the account, empty if none yet
+	Pending    bool        // Whether the storage of the last account is partially converted
+	Done       bool        // Whether all the merkle leaves have been converted
+}
+
+// accountConverted reports whether the leaf of the given account is present in
+// the verkle tree.
+func (p *Progress) accountConverted(account common.Hash) bool {
+	if p.Done {
+		return true
+	}
+	return len(p.Account) > 0 && bytes.Compare(account[:], p.Account) <= 0
+}
+
+// slotConverted reports whether the given storage slot is present in the verkle
+// tree.
+func (p *Progress) slotConverted(account common.Hash, slot common.Hash) bool {
+	if p.Done {
+		return true
+	}
+	if len(p.Account) == 0 {
+		return false
+	}
+	switch bytes.Compare(account[:], p.Account) {
+	case -1:
+		return true
+	case 0:
+		return !p.Pending || (len(p.Storage) > 0 && bytes.Compare(slot[:], p.Storage) <= 0)
+	default:
+		return false
+	}
+}
+
+// Converter incrementally moves the leaves of the merkle-patricia state into a
+// verkle tree, a fixed number of leaves per step. Between steps, the changes
+// made to the already converted leaves are carried over from the merkle state,
+// so once all leaves are moved, the verkle tree mirrors the merkle state.
+//
+// The verkle tree is stored in a separate namespace of the database, and the
+// conversion requires the preimages of the merkle state keys.
+type Converter struct {
+	diskdb   ethdb.Database    // Key-value store holding the merkle state and the progress
+	merkle   *triedb.Database  // Trie database of the merkle state to convert
+	verkle   *triedb.Database  // Trie database of the converted verkle tree
+	cache    *utils.PointCache // Cache of the verkle address points
+	leaves   int               // Number of merkle leaves to convert per step
+	progress *Progress         // Current state of the conversion
+}
+
+// New creates a converter of the merkle state held in the given database,
+// resuming any previously persisted conversion.
+func New(diskdb ethdb.Database, merkle *triedb.Database, leaves int) (*Converter, error) {
+	if merkle.IsVerkle() {
+		return nil, errors.New("state is already verkle")
+	}
+	if leaves <= 0 {
+		return nil, fmt.Errorf("invalid number of leaves per step: %d", leaves)
+	}
+	c := &Converter{
+		diskdb: diskdb,
+		merkle: merkle,
+		verkle: openVerkle(diskdb),
+		cache:  utils.NewPointCache(pointCacheSize),
+		leaves: leaves,
+	}
+	progress := new(Progress)
+	if blob := rawdb.ReadVerkleConversion(diskdb); len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, progress); err != nil {
+			log.Warn("Failed to decode verkle conversion progress, restarting", "err", err)
+			return c, c.reset()
+		}
+	}
+	c.progress = progress
+
+	// The tree nodes and the progress are not persisted atomically, restart
+	// the conversion if they went out of sync.
+	if _, err := c.Tree(); err != nil {
+		log.Warn("Verkle conversion out of sync with the tree, restarting", "err", err)
+		return c, c.reset()
+	}
+	if progress.Done {
+		log.Info("Loaded converted verkle tree", "root", progress.VerkleRoot)
+	} else if len(progress.Account) > 0 {
+		log.Info("Resuming verkle conversion", "root", progress.VerkleRoot, "account", common.BytesToHash(progress.Account))
+	}
+	return c, nil
+}
+
+// openVerkle opens the trie database of the verkle tree in its own namespace.
+func openVerkle(diskdb ethdb.Database) *triedb.Database {
+	return triedb.NewDatabase(rawdb.NewTable(diskdb, string(rawdb.VerklePrefix)), &triedb.Config{
+		IsVerkle: true,
+		PathDB:   pathdb.Defaults,
+	})
+}
+
+// reset drops the converted verkle tree along with the conversion progress, so
+// the conversion restarts from scratch.
+func (c *Converter) reset() error {
+	if err := c.verkle.Close(); err != nil {
+		return err
+	}
+	batch := c.diskdb.NewBatch()
+	it := c.diskdb.NewIterator(rawdb.VerklePrefix, nil)
+	for it.Next() {
+		batch.Delete(it.Key())
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				it.Release()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	rawdb.DeleteVerkleConversion(batch)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	c.verkle = openVerkle(c.diskdb)
+	c.progress = new(Progress)
+	return nil
+}
+
+// Progress returns a copy of the current state of the conversion.
+func (c *Converter) Progress() Progress {
+	return *c.progress
+}
+
+// Done reports whether all the merkle leaves have been converted.
+func (c *Converter) Done() bool {
+	return c.progress.Done
+}
+
+// Tree opens the converted verkle tree.
+func (c *Converter) Tree() (*trie.VerkleTrie, error) {
+	return trie.NewVerkleTrie(c.verkleRoot(), c.verkle, c.cache)
+}
+
+// verkleRoot returns the root of the converted verkle tree, the empty tree is
+// identified by the empty merkle root by the trie database.
+func (c *Converter) verkleRoot() common.Hash {
+	if c.progress.VerkleRoot == types.EmptyVerkleHash {
+		return types.EmptyRootHash
+	}
+	return c.progress.VerkleRoot
+}
+
+// Step brings the converted leaves in sync with the merkle state of the given
+// root, converts the next batch of leaves from it and persists the outcome.
+// The merkle state the previous step was run on must still be available, the
+// conversion is restarted otherwise.
+func (c *Converter) Step(root common.Hash, block uint64) error {
+	if c.progress.Root != (common.Hash{}) && c.progress.Root != root {
+		if _, err := trie.NewStateTrie(trie.StateTrieID(c.progress.Root), c.merkle); err != nil {
+			log.Warn("Merkle state of the verkle conversion unavailable, restarting", "root", c.progress.Root, "err", err)
+			if err := c.reset(); err != nil {
+				return err
+			}
+		}
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return err
+	}
+	if c.progress.Root != (common.Hash{}) && c.progress.Root != root {
+		if err := c.sync(tree, c.progress.Root, root); err != nil {
+			return err
+		}
+	}
+	if !c.progress.Done {
+		if err := c.convert(tree, root); err != nil {
+			return err
+		}
+		if c.progress.Done {
+			log.Info("Converted merkle state into verkle tree", "number", block, "root", tree.Hash())
+		}
+	}
+	c.progress.Root = root
+	return c.commit(tree, block)
+}
+
+// commit persists the verkle tree and the progress of the conversion.
+func (c *Converter) commit(tree *trie.VerkleTrie, block uint64) error {
+	root, nodes, err := tree.Commit(false)
+	if err != nil {
+		return err
+	}
+	if parent := c.verkleRoot(); root != c.progress.VerkleRoot {
+		states := triestate.New(make(map[common.Address][]byte), make(map[common.Address]map[common.Hash][]byte))
+		if err := c.verkle.Update(root, parent, block, trienode.NewWithNodeSet(nodes), states); err != nil {
+			return err
+		}
+		if err := c.verkle.Commit(root, false); err != nil {
+			return err
+		}
+		c.progress.VerkleRoot = root
+	}
+	blob, err := rlp.EncodeToBytes(c.progress)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteVerkleConversion(c.diskdb, blob)
+	return nil
+}
+
+// Close releases the resources held by the converter. The tree is persisted
+// by every step, only the empty journal is written to mark a clean shutdown.
+func (c *Converter) Close() error {
+	if err := c.verkle.Journal(c.verkleRoot()); err != nil {
+		log.Warn("Failed to journal verkle tree", "err", err)
+	}
+	return c.verkle.Close()
+}
+
+// convert moves the next batch of leaves of the merkle state into the verkle
+// tree, advancing the conversion markers.
+func (c *Converter) convert(tree *trie.VerkleTrie, root common.Hash) error {
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), c.merkle)
+	if err != nil {
+		return err
+	}
+	// Resume within the last account if its storage is pending, or right
+	// after it otherwise
+	start := c.progress.Account
+	if len(start) > 0 && !c.progress.Pending {
+		if start = increaseKey(common.CopyBytes(start)); start == nil {
+			c.progress.Done = true
+			return nil
+		}
+	}
+	nodeIt, err := tr.NodeIterator(start)
+	if err != nil {
+		return err
+	}
+	it := trie.NewIterator(nodeIt)
+	for budget := c.leaves; budget > 0; {
+		if !it.Next() {
+			if it.Err != nil {
+				return it.Err
+			}
+			c.progress.Done = true
+			return nil
+		}
+		var (
+			hash = common.BytesToHash(it.Key)
+			acc  types.StateAccount
+		)
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			return err
+		}
+		addr, err := c.address(hash)
+		if err != nil {
+			return err
+		}
+		if !c.progress.Pending || !bytes.Equal(c.progress.Account, hash[:]) {
+			if err := c.updateAccount(tree, addr, nil, &acc); err != nil {
+				return err
+			}
+			c.progress.Account = hash[:]
+			c.progress.Storage = nil
+			c.progress.Pending = acc.Root != types.EmptyRootHash
+			budget--
+		}
+		if c.progress.Pending && budget > 0 {
+			converted, err := c.convertStorage(tree, root, hash, addr, acc.Root, budget)
+			if err != nil {
+				return err
+			}
+			budget -= converted
+		}
+	}
+	return nil
+}
+
+// convertStorage moves up to the given number of slots of the pending account
+// into the verkle tree, returning the number of slots moved.
+func (c *Converter) convertStorage(tree *trie.VerkleTrie, root common.Hash, account common.Hash, addr common.Address, storageRoot common.Hash, budget int) (int, error) {
+	tr, err := trie.NewStateTrie(trie.StorageTrieID(root, account, storageRoot), c.merkle)
+	if err != nil {
+		return 0, err
+	}
+	var start []byte
+	if len(c.progress.Storage) > 0 {
+		if start = increaseKey(common.CopyBytes(c.progress.Storage)); start == nil {
+			c.progress.Storage, c.progress.Pending = nil, false
+			return 0, nil
+		}
+	}
+	nodeIt, err := tr.NodeIterator(start)
+	if err != nil {
+		return 0, err
+	}
+	it := trie.NewIterator(nodeIt)
+	for converted := 0; converted < budget; converted++ {
+		if !it.Next() {
+			if it.Err != nil {
+				return converted, it.Err
+			}
+			c.progress.Storage, c.progress.Pending = nil, false
+			return converted, nil
+		}
+		if err := c.updateSlot(tree, addr, common.BytesToHash(it.Key), it.Value); err != nil {
+			return converted, err
+		}
+		c.progress.Storage = common.CopyBytes(it.Key)
+	}
+	return budget, nil
+}
+
+// sync carries the changes between two merkle states over to the converted
+// leaves of the verkle tree.
+func (c *Converter) sync(tree *trie.VerkleTrie, oldRoot common.Hash, newRoot common.Hash) error {
+	oldTrie, err := trie.NewStateTrie(trie.StateTrieID(oldRoot), c.merkle)
+	if err != nil {
+		return err
+	}
+	newTrie, err := trie.NewStateTrie(trie.StateTrieID(newRoot), c.merkle)
+	if err != nil {
+		return err
+	}
+	// Apply the created and modified accounts, tracking them so their old
+	// leaves are not mistaken for deletions afterwards
+	updated := make(map[common.Hash]struct{})
+	err = diffLeaves(oldTrie, newTrie, func(hash common.Hash, blob []byte) (bool, error) {
+		if !c.progress.accountConverted(hash) {
+			return false, nil
+		}
+		updated[hash] = struct{}{}
+
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return false, err
+		}
+		prev, err := oldTrie.GetAccountByHash(hash)
+		if err != nil {
+			return false, err
+		}
+		addr, err := c.address(hash)
+		if err != nil {
+			return false, err
+		}
+		if err := c.updateAccount(tree, addr, prev, &acc); err != nil {
+			return false, err
+		}
+		prevRoot := types.EmptyRootHash
+		if prev != nil {
+			prevRoot = prev.Root
+		}
+		return true, c.syncStorage(tree, hash, addr, oldRoot, prevRoot, newRoot, acc.Root)
+	})
+	if err != nil {
+		return err
+	}
+	// Apply the deleted accounts
+	return diffLeaves(newTrie, oldTrie, func(hash common.Hash, blob []byte) (bool, error) {
+		if !c.progress.accountConverted(hash) {
+			return false, nil
+		}
+		if _, ok := updated[hash]; ok {
+			return true, nil
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return false, err
+		}
+		addr, err := c.address(hash)
+		if err != nil {
+			return false, err
+		}
+		if err := tree.DeleteAccount(addr); err != nil {
+			return false, err
+		}
+		return true, c.syncStorage(tree, hash, addr, oldRoot, acc.Root, newRoot, types.EmptyRootHash)
+	})
+}
+
+// syncStorage carries the changes between two storage tries of an account over
+// to the converted slots of the verkle tree.
+func (c *Converter) syncStorage(tree *trie.VerkleTrie, account common.Hash, addr common.Address, oldStateRoot, oldRoot, newStateRoot, newRoot common.Hash) error {
+	if oldRoot == newRoot {
+		return nil
+	}
+	oldTrie, err := trie.NewStateTrie(trie.StorageTrieID(oldStateRoot, account, oldRoot), c.merkle)
+	if err != nil {
+		return err
+	}
+	newTrie, err := trie.NewStateTrie(trie.StorageTrieID(newStateRoot, account, newRoot), c.merkle)
+	if err != nil {
+		return err
+	}
+	updated := make(map[common.Hash]struct{})
+	err = diffLeaves(oldTrie, newTrie, func(slot common.Hash, blob []byte) (bool, error) {
+		if !c.progress.slotConverted(account, slot) {
+			return false, nil
+		}
+		updated[slot] = struct{}{}
+		return true, c.updateSlot(tree, addr, slot, blob)
+	})
+	if err != nil {
+		return err
+	}
+	return diffLeaves(newTrie, oldTrie, func(slot common.Hash, blob []byte) (bool, error) {
+		if !c.progress.slotConverted(account, slot) {
+			return false, nil
+		}
+		if _, ok := updated[slot]; ok {
+			return true, nil
+		}
+		key, err := c.preimage(slot)
+		if err != nil {
+			return false, err
+		}
+		return true, tree.DeleteStorage(addr, key)
+	})
+}
+
+// diffLeaves iterates the leaves present in b but not in a in the order of
+// their keys, until the callback returns false or fails.
+func diffLeaves(a, b *trie.StateTrie, onLeaf func(key common.Hash, blob []byte) (bool, error)) error {
+	aIt, err := a.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	bIt, err := b.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	diff, _ := trie.NewDifferenceIterator(aIt, bIt)
+	it := trie.NewIterator(diff)
+	for it.Next() {
+		ok, err := onLeaf(common.BytesToHash(it.Key), it.Value)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return it.Err
+}
+
+// updateAccount writes an account leaf into the verkle tree, along with its
+// code if it changed.
+func (c *Converter) updateAccount(tree *trie.VerkleTrie, addr common.Address, prev, acc *types.StateAccount) error {
+	if err := tree.UpdateAccount(addr, acc); err != nil {
+		return err
+	}
+	codeHash := common.BytesToHash(acc.CodeHash)
+	if codeHash == types.EmptyCodeHash || (prev != nil && bytes.Equal(prev.CodeHash, acc.CodeHash)) {
+		return nil
+	}
+	code := rawdb.ReadCode(c.diskdb, codeHash)
+	if len(code) == 0 {
+		return fmt.Errorf("missing code %x of account %x", codeHash, addr)
+	}
+	return tree.UpdateContractCode(addr, codeHash, code)
+}
+
+// updateSlot writes a merkle storage leaf into the verkle tree.
+func (c *Converter) updateSlot(tree *trie.VerkleTrie, addr common.Address, slot common.Hash, blob []byte) error {
+	key, err := c.preimage(slot)
+	if err != nil {
+		return err
+	}
+	_, value, _, err := rlp.Split(blob)
+	if err != nil {
+		return err
+	}
+	return tree.UpdateStorage(addr, key, value)
+}
+
+// address resolves the address of an account from its hash.
+func (c *Converter) address(hash common.Hash) (common.Address, error) {
+	preimage, err := c.preimage(hash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(preimage) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("invalid account preimage %x of %x", preimage, hash)
+	}
+	return common.BytesToAddress(preimage), nil
+}
+
+// preimage resolves the key of a merkle leaf from its hash.
+func (c *Converter) preimage(hash common.Hash) ([]byte, error) {
+	if preimage := c.merkle.Preimage(hash); preimage != nil {
+		return preimage, nil
+	}
+	if preimage := rawdb.ReadPreimage(c.diskdb, hash); preimage != nil {
+		return preimage, nil
+	}
+	return nil, fmt.Errorf("missing preimage of %x, the conversion requires preimages to be recorded", hash)
+}
+
+// increaseKey increases the input key by one bit, returning nil if the entire
+// key space is exhausted.
+func increaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]++
+		if key[i] != 0x0 {
+			return key
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package overlay
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+// testState is a merkle state mutated block by block alongside the conversion.
+type testState struct {
+	t       *testing.T
+	db      state.Database
+	root    common.Hash
+	block   uint64
+	storage map[common.Address]map[common.Hash]common.Hash
+	deleted map[common.Address]struct{}
+}
+
+func newTestState(t *testing.T, diskdb ethdb.Database, scheme string) *testState {
+	config := &triedb.Config{Preimages: true}
+	if scheme == rawdb.HashScheme {
+		config.HashDB = triedb.HashDefaults.HashDB
+	} else {
+		config.PathDB = pathdb.Defaults
+	}
+	return &testState{
+		t:       t,
+		db:      state.NewDatabaseWithConfig(diskdb, config),
+		root:    types.EmptyRootHash,
+		storage: make(map[common.Address]map[common.Hash]common.Hash),
+		deleted: make(map[common.Address]struct{}),
+	}
+}
+
+// update applies a batch of state mutations in a new block.
+func (s *testState) update(fn func(statedb *state.StateDB)) {
+	statedb, err := state.New(s.root, s.db, nil)
+	if err != nil {
+		s.t.Fatalf("failed to open state: %v", err)
+	}
+	fn(statedb)
+
+	s.block++
+	root, err := statedb.Commit(s.block, true)
+	if err != nil {
+		s.t.Fatalf("failed to commit state: %v", err)
+	}
+	s.root = root
+}
+
+func (s *testState) setState(statedb *state.StateDB, addr common.Address, key, value common.Hash) {
+	statedb.SetState(addr, key, value)
+	if s.storage[addr] == nil {
+		s.storage[addr] = make(map[common.Hash]common.Hash)
+	}
+	s.storage[addr][key] = value
+}
+
+func testAccount(i int) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte{byte(i)}))
+}
+
+func TestConverterHashScheme(t *testing.T) { testConverter(t, rawdb.HashScheme) }
+func TestConverterPathScheme(t *testing.T) { testConverter(t, rawdb.PathScheme) }
+
+func testConverter(t *testing.T, scheme string) {
+	diskdb := rawdb.NewMemoryDatabase()
+	s := newTestState(t, diskdb, scheme)
+
+	s.update(func(statedb *state.StateDB) {
+		for i := 0; i < 32; i++ {
+			addr := testAccount(i)
+			statedb.SetBalance(addr, uint256.NewInt(uint64(i+1)), tracing.BalanceChangeUnspecified)
+			statedb.SetNonce(addr, uint64(i))
+			if i%4 == 0 {
+				statedb.SetCode(addr, bytes.Repeat([]byte{byte(i), 0x5b}, 40*(i+1)))
+				for j := 0; j < 8; j++ {
+					s.setState(statedb, addr, common.Hash{byte(j)}, common.Hash{byte(i), byte(j + 1)})
+				}
+			}
+		}
+	})
+	converter, err := New(diskdb, s.db.TrieDB(), 5)
+	if err != nil {
+		t.Fatalf("failed to create converter: %v", err)
+	}
+	for i := 0; !converter.Done(); i++ {
+		if err := converter.Step(s.root, s.block); err != nil {
+			t.Fatalf("step %d: failed to convert: %v", i, err)
+		}
+		// Mutate all accounts every few steps, converted or not
+		if i%3 == 1 {
+			s.update(func(statedb *state.StateDB) {
+				for j := 0; j < 32; j += 3 {
+					addr := testAccount(j)
+					if _, ok := s.deleted[addr]; ok {
+						continue
+					}
+					statedb.AddBalance(addr, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+					if j%4 == 0 {
+						s.setState(statedb, addr, common.Hash{byte(i)}, common.Hash{byte(i), 0xff})
+						s.setState(statedb, addr, common.Hash{byte(j % 8)}, common.Hash{})
+					}
+				}
+				if i < 32 {
+					addr := testAccount(i)
+					statedb.SelfDestruct(addr)
+					s.deleted[addr] = struct{}{}
+					delete(s.storage, addr)
+				}
+				statedb.SetBalance(testAccount(100+i), uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+			})
+		}
+		// Restart the converter midway, it must resume where it left off
+		if i == 10 {
+			converter.Close()
+			if converter, err = New(diskdb, s.db.TrieDB(), 5); err != nil {
+				t.Fatalf("failed to reopen converter: %v", err)
+			}
+			if converter.Progress().Root == (common.Hash{}) {
+				t.Fatal("converter progress lost")
+			}
+		}
+	}
+	defer converter.Close()
+
+	// Keep the tree in sync after the conversion is done
+	s.update(func(statedb *state.StateDB) {
+		statedb.SetBalance(testAccount(31), uint256.NewInt(1000), tracing.BalanceChangeUnspecified)
+	})
+	if err := converter.Step(s.root, s.block); err != nil {
+		t.Fatalf("failed to sync converted tree: %v", err)
+	}
+	// Every leaf of the final merkle state must be present in the verkle tree
+	tree, err := converter.Tree()
+	if err != nil {
+		t.Fatalf("failed to open converted tree: %v", err)
+	}
+	statedb, err := state.New(s.root, s.db, nil)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	for i := 0; i < 150; i++ {
+		addr := testAccount(i)
+		acc, err := tree.GetAccount(addr)
+		if err != nil {
+			t.Fatalf("failed to read account %x: %v", addr, err)
+		}
+		if !statedb.Exist(addr) {
+			if acc != nil && (acc.Nonce != 0 || !acc.Balance.IsZero()) {
+				t.Errorf("account %x: deleted account present", addr)
+			}
+			continue
+		}
+		if acc == nil {
+			t.Errorf("account %x: missing", addr)
+			continue
+		}
+		if acc.Nonce != statedb.GetNonce(addr) || !acc.Balance.Eq(statedb.GetBalance(addr)) || common.BytesToHash(acc.CodeHash) != statedb.GetCodeHash(addr) {
+			t.Errorf("account %x: mismatch: have %d/%v/%x, want %d/%v/%x", addr, acc.Nonce, acc.Balance, acc.CodeHash,
+				statedb.GetNonce(addr), statedb.GetBalance(addr), statedb.GetCodeHash(addr))
+		}
+	}
+	for addr, slots := range s.storage {
+		for key, value := range slots {
+			have, err := tree.GetStorage(addr, key[:])
+			if err != nil {
+				t.Fatalf("failed to read slot %x of %x: %v", key, addr, err)
+			}
+			if common.BytesToHash(have) != value {
+				t.Errorf("slot %x of %x: have %x, want %x", key, addr, have, value)
+			}
+		}
+	}
+}
+
+func TestConverterMissingPreimages(t *testing.T) {
+	diskdb := rawdb.NewMemoryDatabase()
+	db := state.NewDatabase(diskdb)
+	statedb, _ := state.New(types.EmptyRootHash, db, nil)
+	statedb.SetBalance(testAccount(0), uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	root, _ := statedb.Commit(1, true)
+
+	converter, err := New(diskdb, db.TrieDB(), 5)
+	if err != nil {
+		t.Fatalf("failed to create converter: %v", err)
+	}
+	defer converter.Close()
+	if err := converter.Step(root, 1); err == nil {
+		t.Fatal("conversion without preimages succeeded")
+	}
+}
@@ -277,6 +277,43 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadHistoryExpiryTail retrieves the number of the oldest block whose body
+// and receipts are retained. Zero is returned if no history has been pruned.
+func ReadHistoryExpiryTail(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(historyExpiryTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteHistoryExpiryTail stores the number of the oldest block whose body
+// and receipts are retained.
+func WriteHistoryExpiryTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(historyExpiryTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the history expiry tail", "err", err)
+	}
+}
+
+// ReadReceiptsTail retrieves the number of the oldest block whose receipts were
+// downloaded during snap sync, or nil if receipts were not skipped.
+func ReadReceiptsTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(receiptsTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteReceiptsTail stores the number of the oldest block whose receipts were
+// downloaded during snap sync.
+func WriteReceiptsTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(receiptsTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the receipts tail", "err", err)
+	}
+}
+
 // ReadHeaderRange returns the rlp-encoded headers, starting at 'number', and going
 // backwards towards genesis. This method assumes that the caller already has
 // placed a cap on count, to prevent DoS issues.
@@ -432,7 +469,11 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(ChainFreezerBodiesTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
+			// The body may have been pruned from the ancient store. The
+			// genesis block is retained in leveldb, so check it below.
 		}
 		// If not, try reading from leveldb
 		data, _ = db.Get(blockBodyKey(number, hash))
@@ -575,7 +616,11 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(ChainFreezerReceiptTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
+			// The receipts may have been pruned from the ancient store. The
+			// genesis block is retained in leveldb, so check it below.
 		}
 		// If not, try reading from leveldb
 		data, _ = db.Get(blockReceiptsKey(number, hash))
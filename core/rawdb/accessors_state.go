@@ -264,3 +264,26 @@ func WriteStateHistory(db ethdb.AncientWriter, id uint64, meta []byte, accountIn
 		return nil
 	})
 }
+
+// ReadVerkleConversion retrieves the serialized progress of the merkle to
+// verkle state conversion.
+func ReadVerkleConversion(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(verkleConversionKey)
+	return data
+}
+
+// WriteVerkleConversion stores the serialized progress of the merkle to verkle
+// state conversion.
+func WriteVerkleConversion(db ethdb.KeyValueWriter, progress []byte) {
+	if err := db.Put(verkleConversionKey, progress); err != nil {
+		log.Crit("Failed to store verkle conversion progress", "err", err)
+	}
+}
+
+// DeleteVerkleConversion deletes the serialized progress of the merkle to
+// verkle state conversion.
+func DeleteVerkleConversion(db ethdb.KeyValueWriter) {
+	if err := db.Delete(verkleConversionKey); err != nil {
+		log.Crit("Failed to remove verkle conversion progress", "err", err)
+	}
+}
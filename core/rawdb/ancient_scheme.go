@@ -40,14 +40,27 @@ const (
 	ChainFreezerDifficultyTable = "diffs"
 )
 
-// chainFreezerNoSnappy configures whether compression is disabled for the ancient-tables.
-// Hashes and difficulties don't compress well.
-var chainFreezerNoSnappy = map[string]bool{
-	ChainFreezerHeaderTable:     false,
-	ChainFreezerHashTable:       true,
-	ChainFreezerBodiesTable:     false,
-	ChainFreezerReceiptTable:    false,
-	ChainFreezerDifficultyTable: true,
+// chainFreezerTableConfigs configures the settings for tables in the chain freezer.
+// Hashes and difficulties don't compress well. Block bodies and receipts can be
+// removed from the tail when history expiry is enabled, while headers, hashes
+// and difficulties are always retained.
+//
+// New bodies and receipts tables, which make up the bulk of the freezer, are
+// compressed with zstd, the headers with snappy. Existing tables keep their
+// compression until converted with ConvertFreezerTable.
+var chainFreezerTableConfigs = map[string]freezerTableConfig{
+	ChainFreezerHeaderTable:     {noSnappy: false, prunable: false},
+	ChainFreezerHashTable:       {noSnappy: true, prunable: false},
+	ChainFreezerBodiesTable:     {noSnappy: false, zstd: true, prunable: true},
+	ChainFreezerReceiptTable:    {noSnappy: false, zstd: true, prunable: true},
+	ChainFreezerDifficultyTable: {noSnappy: true, prunable: false},
+}
+
+// freezerTableConfig contains the settings for a freezer table.
+type freezerTableConfig struct {
+	noSnappy bool // disables item compression
+	zstd     bool // compresses new tables with zstd instead of snappy
+	prunable bool // true for tables that can be pruned by TruncateTail
 }
 
 const (
@@ -62,18 +75,20 @@ const (
 	stateHistoryStorageData  = "storage.data"
 )
 
-var stateFreezerNoSnappy = map[string]bool{
-	stateHistoryMeta:         true,
-	stateHistoryAccountIndex: false,
-	stateHistoryStorageIndex: false,
-	stateHistoryAccountData:  false,
-	stateHistoryStorageData:  false,
+// stateFreezerTableConfigs configures the settings for tables in the state freezer.
+var stateFreezerTableConfigs = map[string]freezerTableConfig{
+	stateHistoryMeta:         {noSnappy: true, prunable: true},
+	stateHistoryAccountIndex: {noSnappy: false, prunable: true},
+	stateHistoryStorageIndex: {noSnappy: false, prunable: true},
+	stateHistoryAccountData:  {noSnappy: false, prunable: true},
+	stateHistoryStorageData:  {noSnappy: false, prunable: true},
 }
 
 // The list of identifiers of ancient stores.
 var (
-	ChainFreezerName = "chain" // the folder name of chain segment ancient store.
-	StateFreezerName = "state" // the folder name of reverse diff ancient store.
+	ChainFreezerName       = "chain"        // the folder name of chain segment ancient store.
+	StateFreezerName       = "state"        // the folder name of reverse diff ancient store.
+	VerkleStateFreezerName = "state_verkle" // the folder name of reverse diff ancient store of the verkle tree.
 )
 
 // freezers the collections of all builtin freezers.
@@ -85,9 +100,16 @@ var freezers = []string{ChainFreezerName, StateFreezerName}
 //     state freezer (e.g. dev mode).
 //   - if non-empty directory is given, initializes the regular file-based
 //     state freezer.
-func NewStateFreezer(ancientDir string, readOnly bool) (ethdb.ResettableAncientStore, error) {
+//
+// The state histories of a verkle tree are kept in a separate freezer, so the
+// tree can be maintained alongside the merkle state.
+func NewStateFreezer(ancientDir string, verkle bool, readOnly bool) (ethdb.ResettableAncientStore, error) {
 	if ancientDir == "" {
-		return NewMemoryFreezer(readOnly, stateFreezerNoSnappy), nil
+		return NewMemoryFreezer(readOnly, stateFreezerTableConfigs), nil
+	}
+	name, namespace := StateFreezerName, "eth/db/state"
+	if verkle {
+		name, namespace = VerkleStateFreezerName, "eth/db/state_verkle"
 	}
-	return newResettableFreezer(filepath.Join(ancientDir, StateFreezerName), "eth/db/state", readOnly, stateHistoryTableSize, stateFreezerNoSnappy)
+	return newResettableFreezer(filepath.Join(ancientDir, name), namespace, readOnly, stateHistoryTableSize, stateFreezerTableConfigs)
 }
@@ -18,10 +18,14 @@ package rawdb
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 type tableSize struct {
@@ -51,7 +55,7 @@ func (info *freezerInfo) size() common.StorageSize {
 	return total
 }
 
-func inspect(name string, order map[string]bool, reader ethdb.AncientReader) (freezerInfo, error) {
+func inspect(name string, order map[string]freezerTableConfig, reader ethdb.AncientReader) (freezerInfo, error) {
 	info := freezerInfo{name: name}
 	for t := range order {
 		size, err := reader.AncientSize(t)
@@ -82,7 +86,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	for _, freezer := range freezers {
 		switch freezer {
 		case ChainFreezerName:
-			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, db)
+			info, err := inspect(ChainFreezerName, chainFreezerTableConfigs, db)
 			if err != nil {
 				return nil, err
 			}
@@ -93,13 +97,13 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 			if err != nil {
 				return nil, err
 			}
-			f, err := NewStateFreezer(datadir, true)
+			f, err := NewStateFreezer(datadir, false, true)
 			if err != nil {
 				continue // might be possible the state freezer is not existent
 			}
 			defer f.Close()
 
-			info, err := inspect(freezer, stateFreezerNoSnappy, f)
+			info, err := inspect(freezer, stateFreezerTableConfigs, f)
 			if err != nil {
 				return nil, err
 			}
@@ -112,35 +116,201 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	return infos, nil
 }
 
-// InspectFreezerTable dumps out the index of a specific freezer table. The passed
-// ancient indicates the path of root ancient directory where the chain freezer can
-// be opened. Start and end specify the range for dumping out indexes.
-// Note this function can only be used for debugging purposes.
-func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
+// resolveFreezerTable returns the directory and the configuration of a table of
+// the given freezer.
+func resolveFreezerTable(ancient string, freezerName string, tableName string) (string, freezerTableConfig, error) {
 	var (
 		path   string
-		tables map[string]bool
+		tables map[string]freezerTableConfig
 	)
 	switch freezerName {
 	case ChainFreezerName:
-		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+		path, tables = resolveChainFreezerDir(ancient), chainFreezerTableConfigs
 	case StateFreezerName:
-		path, tables = filepath.Join(ancient, freezerName), stateFreezerNoSnappy
+		path, tables = filepath.Join(ancient, freezerName), stateFreezerTableConfigs
 	default:
-		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
+		return "", freezerTableConfig{}, fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
-	noSnappy, exist := tables[tableName]
+	config, exist := tables[tableName]
 	if !exist {
 		var names []string
 		for name := range tables {
 			names = append(names, name)
 		}
-		return fmt.Errorf("unknown table, supported ones: %v", names)
+		return "", freezerTableConfig{}, fmt.Errorf("unknown table, supported ones: %v", names)
+	}
+	return path, config, nil
+}
+
+// InspectFreezerTable dumps out the index of a specific freezer table. The passed
+// ancient indicates the path of root ancient directory where the chain freezer can
+// be opened. Start and end specify the range for dumping out indexes.
+// Note this function can only be used for debugging purposes.
+func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
+	path, config, err := resolveFreezerTable(ancient, freezerName, tableName)
+	if err != nil {
+		return err
 	}
-	table, err := newFreezerTable(path, tableName, noSnappy, true)
+	table, err := newFreezerTable(path, tableName, config.noSnappy, true)
 	if err != nil {
 		return err
 	}
 	table.dumpIndexStdout(start, end)
 	return nil
 }
+
+// ConvertFreezerTable re-encodes the items of a freezer table with the given
+// compression, either "snappy" or "zstd". Zstd tables get a dictionary trained
+// from a sample of the items. The passed ancient indicates the path of the root
+// ancient directory, the freezer must not be in use while converting.
+//
+// The converted table is assembled next to the original one, which is replaced
+// only once the conversion is complete. An interrupted conversion leaves either
+// the original or the converted table intact, and can be restarted.
+func ConvertFreezerTable(ancient string, freezerName string, tableName string, compression string) error {
+	path, config, err := resolveFreezerTable(ancient, freezerName, tableName)
+	if err != nil {
+		return err
+	}
+	if config.noSnappy {
+		return fmt.Errorf("table %s is not compressed", tableName)
+	}
+	var target freezerCompression
+	switch compression {
+	case "snappy":
+		target = compressionSnappy
+	case "zstd":
+		target = compressionZstd
+	default:
+		return fmt.Errorf("unknown compression %q, supported ones: snappy, zstd", compression)
+	}
+	return convertTable(path, tableName, target)
+}
+
+// convertTable re-encodes the items of a freezer table with the compression.
+func convertTable(path string, name string, target freezerCompression) error {
+	src, err := newFreezerTable(path, name, false, true)
+	if err != nil {
+		return err
+	}
+	srcClosed := false
+	defer func() {
+		if !srcClosed {
+			src.Close()
+		}
+	}()
+
+	if src.compression == target {
+		return fmt.Errorf("table %s is already compressed with %v", name, target)
+	}
+	// Start over if a previous conversion was interrupted
+	convPath := filepath.Join(path, "conversion")
+	if err := os.RemoveAll(convPath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(convPath, 0755); err != nil {
+		return err
+	}
+	// Items below the tail are already deleted, the converted table starts at
+	// the first visible item while keeping the item numbering.
+	var (
+		first = src.itemHidden.Load()
+		items = src.items.Load()
+	)
+	idxSuffix, _ := target.suffixes()
+	tail := indexEntry{filenum: 0, offset: uint32(first)}
+	if err := os.WriteFile(filepath.Join(convPath, fmt.Sprintf("%s.%s", name, idxSuffix)), tail.append(nil), 0644); err != nil {
+		return err
+	}
+	if target == compressionZstd {
+		samples, err := src.sampleItems(zstdDictSampleItems, zstdDictSampleBytes)
+		if err != nil {
+			return err
+		}
+		if dict := trainZstdDict(samples, zstdDictSize); dict != nil {
+			if err := writeZstdDict(convPath, name, dict); err != nil {
+				return err
+			}
+		}
+	}
+	dst, err := openTable(convPath, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, src.maxFileSize, freezerTableConfig{zstd: target == compressionZstd}, false)
+	if err != nil {
+		return err
+	}
+	var (
+		batch  = dst.newBatch()
+		start  = time.Now()
+		logged = time.Now()
+	)
+	log.Info("Converting freezer table", "table", name, "from", src.compression, "to", target, "items", items-first)
+	for i := first; i < items; {
+		data, err := src.RetrieveItems(i, 1024, 1024*1024)
+		if err != nil {
+			dst.Close()
+			return err
+		}
+		for j, item := range data {
+			if err := batch.AppendRaw(i+uint64(j), item); err != nil {
+				dst.Close()
+				return err
+			}
+		}
+		i += uint64(len(data))
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Converting freezer table", "table", name, "item", i, "items", items, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.commit(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	oldIdx, oldData := src.compression.suffixes()
+	src.Close()
+	srcClosed = true
+
+	// Move the converted table in place. The index is moved after the data it
+	// refers to, and the converted index takes precedence over the original
+	// one, which is deleted right after, before the original data files.
+	_, newData := target.suffixes()
+	files, err := filepath.Glob(filepath.Join(convPath, fmt.Sprintf("%s.*.%s", name, newData)))
+	if err != nil {
+		return err
+	}
+	if target == compressionZstd {
+		files = append(files, zstdDictPath(convPath, name))
+	}
+	files = append(files,
+		filepath.Join(convPath, fmt.Sprintf("%s.%s", name, idxSuffix)),
+		filepath.Join(convPath, fmt.Sprintf("%s.meta", name)),
+	)
+	for _, file := range files {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue // untrained dictionary
+		}
+		if err := os.Rename(file, filepath.Join(path, filepath.Base(file))); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(path, fmt.Sprintf("%s.%s", name, oldIdx))); err != nil {
+		return err
+	}
+	stale, err := filepath.Glob(filepath.Join(path, fmt.Sprintf("%s.*.%s", name, oldData)))
+	if err != nil {
+		return err
+	}
+	if src.compression == compressionZstd {
+		stale = append(stale, zstdDictPath(path, name))
+	}
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	log.Info("Converted freezer table", "table", name, "compression", target, "elapsed", common.PrettyDuration(time.Since(start)))
+	return os.RemoveAll(convPath)
+}
@@ -85,6 +85,7 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          []byte // zstd compression buffer
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -95,7 +96,7 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	if t.compression == compressionSnappy {
 		batch.sb = new(snappyBuffer)
 	}
 	batch.reset()
@@ -123,11 +124,7 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
-	}
-	return batch.appendItem(encItem)
+	return batch.appendItem(batch.compress(batch.encBuffer.data))
 }
 
 // AppendRaw injects a binary blob at the end of the freezer table. The item number is a
@@ -138,11 +135,20 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	return batch.appendItem(batch.compress(blob))
+}
+
+// compress compresses an item according to the compression of the table.
+func (batch *freezerTableBatch) compress(data []byte) []byte {
+	switch batch.t.compression {
+	case compressionSnappy:
+		return batch.sb.compress(data)
+	case compressionZstd:
+		batch.zb = batch.t.zstd.Load().compress(batch.zb, data)
+		return batch.zb
+	default:
+		return data
 	}
-	return batch.appendItem(encItem)
 }
 
 func (batch *freezerTableBatch) appendItem(data []byte) error {
@@ -213,6 +219,13 @@ func (batch *freezerTableBatch) commit() error {
 	// Update metrics.
 	batch.t.sizeGauge.Inc(dataSize + indexSize)
 	batch.t.writeMeter.Mark(dataSize + indexSize)
+
+	// Train the dictionary of new zstd tables once there are enough samples
+	if batch.t.compression == compressionZstd && !batch.t.zstdTrained {
+		if err := batch.t.maybeTrainDict(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
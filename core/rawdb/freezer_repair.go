@@ -0,0 +1,369 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ErrFreezerUnrepairable is returned by RepairChainFreezer if a damaged item
+// can't be retrieved from the repair source.
+var ErrFreezerUnrepairable = errors.New("damaged chain freezer item unavailable")
+
+// FreezerRepairSource retrieves the blocks and receipts replacing the damaged
+// items of the chain freezer, e.g. from era1 files.
+type FreezerRepairSource interface {
+	GetBlock(number uint64, hash common.Hash) (*types.Block, error)
+	GetReceipts(number uint64, hash common.Hash) (types.Receipts, error)
+}
+
+// repairMarker is the file in the staging directory recording the range of a
+// fully staged chain freezer repair, which can be restored from the staging
+// freezer.
+const repairMarker = "repair.json"
+
+// repairRange is the content of the repair marker.
+type repairRange struct {
+	First  uint64 `json:"first"`  // First block replaced by the staged items
+	Frozen uint64 `json:"frozen"` // Number of frozen items after the repair
+}
+
+// RepairChainFreezer replaces the damaged items of a chain freezer verification
+// result, keeping all the intact ones. Hashes and total difficulties are derived
+// from the headers, the other items are retrieved from the source, which may be
+// nil if there are none to retrieve.
+//
+// As the freezer is append-only, the items from the first damaged block on are
+// staged into a temporary freezer in the given directory first, then appended
+// back in place of the originals. The freezer is left untouched if any damaged
+// item is unavailable, in which case ErrFreezerUnrepairable is returned.
+//
+// The staging directory is only removed once the repair succeeded. If it fails
+// after the originals were dropped, the repair is completed from the staged
+// items by ResumeChainFreezerRepair.
+func RepairChainFreezer(db ethdb.Database, result *FreezerVerifyResult, staging string, source FreezerRepairSource) error {
+	first, damaged := result.FirstDamaged()
+	if !damaged {
+		return nil
+	}
+	frozen, replaced, err := stageChainFreezerRepair(db, result, first, staging, source)
+	if err != nil {
+		return err
+	}
+	// Replace the items from the first damaged block with the staged ones
+	log.Warn("Rewriting chain freezer", "number", first, "frozen", frozen, "replaced", replaced)
+	if err := restoreChainFreezer(db, staging, first, frozen); err != nil {
+		return err
+	}
+	log.Info("Repaired chain freezer", "replaced", replaced)
+	return nil
+}
+
+// stageChainFreezerRepair stages the items of the chain freezer from the first
+// damaged block on into the staging directory, replacing the damaged ones. The
+// range of the staged items is recorded once all are written. It returns the
+// number of frozen items and the number of replaced items.
+func stageChainFreezerRepair(db ethdb.Database, result *FreezerVerifyResult, first uint64, staging string, source FreezerRepairSource) (uint64, int, error) {
+	if _, err := readRepairMarker(staging); err == nil {
+		return 0, 0, fmt.Errorf("unfinished chain freezer repair in %s", staging)
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		return 0, 0, err
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return 0, 0, err
+	}
+	if first < tail {
+		return 0, 0, fmt.Errorf("damaged block #%d is below the history tail #%d", first, tail)
+	}
+	var td *big.Int
+	if first > 0 {
+		blob, err := db.Ancient(ChainFreezerDifficultyTable, first-1)
+		if err != nil {
+			return 0, 0, fmt.Errorf("total difficulty of block #%d unavailable: %v", first-1, err)
+		}
+		td = new(big.Int)
+		if err := rlp.DecodeBytes(blob, td); err != nil {
+			return 0, 0, fmt.Errorf("invalid total difficulty of block #%d: %v", first-1, err)
+		}
+	}
+	// Leftovers of an incomplete staging are useless, drop them
+	if err := os.RemoveAll(staging); err != nil {
+		return 0, 0, err
+	}
+	stage, err := NewFreezer(staging, "", false, freezerTableSize, chainFreezerTableConfigs)
+	if err != nil {
+		return 0, 0, err
+	}
+	var replaced int
+	for from := first; from < frozen; from += freezerVerifyBatch {
+		count := min(freezerVerifyBatch, frozen-from)
+		_, err := stage.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for number := from; number < from+count; number++ {
+				items, n, err := repairedItems(db, result, number, td, source)
+				if err != nil {
+					return err
+				}
+				for _, table := range chainFreezerTables {
+					if err := op.AppendRaw(table, number-first, items[table]); err != nil {
+						return err
+					}
+				}
+				td = new(big.Int)
+				if err := rlp.DecodeBytes(items[ChainFreezerDifficultyTable], td); err != nil {
+					return fmt.Errorf("invalid total difficulty of block #%d: %v", number, err)
+				}
+				replaced += n
+			}
+			return nil
+		})
+		if err != nil {
+			stage.Close()
+			return 0, 0, fmt.Errorf("%w, the freezer is unchanged, incomplete staged items are in %s", err, staging)
+		}
+	}
+	err = stage.Sync()
+	if err == nil {
+		err = writeRepairMarker(staging, &repairRange{First: first, Frozen: frozen})
+	}
+	stage.Close()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stage repair: %v, the freezer is unchanged, incomplete staged items are in %s", err, staging)
+	}
+	return frozen, replaced, nil
+}
+
+// ResumeChainFreezerRepair completes a chain freezer repair which failed or was
+// interrupted after the staging finished, restoring the freezer from the staged
+// items. It reports whether there was a repair to complete. Incomplete stagings
+// are discarded, as the freezer wasn't touched yet.
+func ResumeChainFreezerRepair(db ethdb.Database, staging string) (bool, error) {
+	if _, err := os.Stat(staging); os.IsNotExist(err) {
+		return false, nil
+	}
+	r, err := readRepairMarker(staging)
+	if err != nil {
+		log.Warn("Discarding incomplete chain freezer repair", "dir", staging, "err", err)
+		return false, os.RemoveAll(staging)
+	}
+	log.Warn("Resuming chain freezer repair", "number", r.First, "frozen", r.Frozen)
+	if err := restoreChainFreezer(db, staging, r.First, r.Frozen); err != nil {
+		return true, err
+	}
+	log.Info("Resumed chain freezer repair")
+	return true, nil
+}
+
+// restoreChainFreezer replaces the items of the chain freezer from the given
+// block on with the staged ones, and removes the staging directory afterwards.
+// It may be invoked repeatedly until it succeeds.
+func restoreChainFreezer(db ethdb.Database, staging string, first, frozen uint64) error {
+	stage, err := NewFreezer(staging, "", false, freezerTableSize, chainFreezerTableConfigs)
+	if err != nil {
+		return fmt.Errorf("failed to open staged items in %s: %v", staging, err)
+	}
+	defer stage.Close()
+
+	if staged, err := stage.Ancients(); err != nil || staged != frozen-first {
+		return fmt.Errorf("staged items in %s incomplete: have %d, want %d", staging, staged, frozen-first)
+	}
+	current, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if current < first {
+		return fmt.Errorf("freezer truncated below the staged items: have %d, want %d, the staged items are kept in %s", current, first, staging)
+	}
+	if _, err := db.TruncateHead(first); err != nil {
+		return fmt.Errorf("failed to truncate freezer: %v, the staged items are kept in %s", err, staging)
+	}
+	for from := first; from < frozen; from += freezerVerifyBatch {
+		count := min(freezerVerifyBatch, frozen-from)
+		_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for number := from; number < from+count; number++ {
+				for _, table := range chainFreezerTables {
+					blob, err := stage.Ancient(table, number-first)
+					if err != nil {
+						return err
+					}
+					if err := op.AppendRaw(table, number, blob); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore block #%d: %v, the staged items are kept in %s", from, err, staging)
+		}
+	}
+	if err := db.Sync(); err != nil {
+		return fmt.Errorf("failed to sync freezer: %v, the staged items are kept in %s", err, staging)
+	}
+	stage.Close()
+	return os.RemoveAll(staging)
+}
+
+// readRepairMarker reads the range of a fully staged repair.
+func readRepairMarker(staging string) (*repairRange, error) {
+	blob, err := os.ReadFile(filepath.Join(staging, repairMarker))
+	if err != nil {
+		return nil, err
+	}
+	r := new(repairRange)
+	if err := json.Unmarshal(blob, r); err != nil {
+		return nil, err
+	}
+	if r.First > r.Frozen {
+		return nil, fmt.Errorf("invalid repair range %d-%d", r.First, r.Frozen)
+	}
+	return r, nil
+}
+
+// writeRepairMarker atomically records the range of a fully staged repair.
+func writeRepairMarker(staging string, r *repairRange) error {
+	blob, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(staging, repairMarker)
+	if err := os.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// repairedItems returns the raw chain freezer items of a block, replacing the
+// damaged ones, along with the number of items replaced. The total difficulty
+// of the parent is nil for the genesis block.
+func repairedItems(db ethdb.Database, result *FreezerVerifyResult, number uint64, parentTd *big.Int, source FreezerRepairSource) (map[string][]byte, int, error) {
+	var (
+		items   = make(map[string][]byte)
+		missing = make(map[string]bool)
+	)
+	for _, table := range chainFreezerTables {
+		if !result.Damaged(table, number) {
+			if blob, err := db.Ancient(table, number); err == nil {
+				items[table] = blob
+				continue
+			}
+		}
+		missing[table] = true
+	}
+	// Retrieve the damaged headers, bodies and receipts from the source
+	if missing[ChainFreezerHeaderTable] || missing[ChainFreezerBodiesTable] || missing[ChainFreezerReceiptTable] {
+		hash, ok := frozenBlockHash(db, result, number)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: hash of block #%d unknown", ErrFreezerUnrepairable, number)
+		}
+		if source == nil {
+			return nil, 0, fmt.Errorf("%w: block #%d", ErrFreezerUnrepairable, number)
+		}
+		block, err := source.GetBlock(number, hash)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: block #%d: %v", ErrFreezerUnrepairable, number, err)
+		}
+		if block.Hash() != hash {
+			return nil, 0, fmt.Errorf("%w: block #%d hash mismatch: have %x, want %x", ErrFreezerUnrepairable, number, block.Hash(), hash)
+		}
+		if missing[ChainFreezerHeaderTable] {
+			if items[ChainFreezerHeaderTable], err = rlp.EncodeToBytes(block.Header()); err != nil {
+				return nil, 0, err
+			}
+		}
+		if missing[ChainFreezerBodiesTable] {
+			if items[ChainFreezerBodiesTable], err = rlp.EncodeToBytes(block.Body()); err != nil {
+				return nil, 0, err
+			}
+		}
+		if missing[ChainFreezerReceiptTable] {
+			receipts, err := source.GetReceipts(number, hash)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%w: receipts of block #%d: %v", ErrFreezerUnrepairable, number, err)
+			}
+			stored := make([]*types.ReceiptForStorage, len(receipts))
+			for i, receipt := range receipts {
+				stored[i] = (*types.ReceiptForStorage)(receipt)
+			}
+			if items[ChainFreezerReceiptTable], err = rlp.EncodeToBytes(stored); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	// Derive the damaged hashes and total difficulties from the headers
+	if missing[ChainFreezerHashTable] {
+		items[ChainFreezerHashTable] = crypto.Keccak256(items[ChainFreezerHeaderTable])
+	}
+	if missing[ChainFreezerDifficultyTable] {
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(items[ChainFreezerHeaderTable], header); err != nil {
+			return nil, 0, fmt.Errorf("invalid header of block #%d: %v", number, err)
+		}
+		td := new(big.Int).Set(header.Difficulty)
+		if parentTd != nil {
+			td.Add(td, parentTd)
+		}
+		blob, err := rlp.EncodeToBytes(td)
+		if err != nil {
+			return nil, 0, err
+		}
+		items[ChainFreezerDifficultyTable] = blob
+	}
+	return items, len(missing), nil
+}
+
+// frozenBlockHash returns the hash of a frozen block from the intact items of
+// the hash table, the header table or the successor header.
+func frozenBlockHash(db ethdb.Database, result *FreezerVerifyResult, number uint64) (common.Hash, bool) {
+	if !result.Damaged(ChainFreezerHashTable, number) {
+		if blob, err := db.Ancient(ChainFreezerHashTable, number); err == nil {
+			return common.BytesToHash(blob), true
+		}
+	}
+	if !result.Damaged(ChainFreezerHeaderTable, number) {
+		if blob, err := db.Ancient(ChainFreezerHeaderTable, number); err == nil {
+			return crypto.Keccak256Hash(blob), true
+		}
+	}
+	if result.Damaged(ChainFreezerHeaderTable, number+1) {
+		return common.Hash{}, false
+	}
+	blob, err := db.Ancient(ChainFreezerHeaderTable, number+1)
+	if err != nil {
+		// The successor is the first block of the key-value store
+		blob = ReadHeaderRLP(db, ReadCanonicalHash(db, number+1), number+1)
+	}
+	var header types.Header
+	if err := rlp.DecodeBytes(blob, &header); err != nil {
+		return common.Hash{}, false
+	}
+	return header.ParentHash, true
+}
@@ -84,8 +84,64 @@ func (i *indexEntry) bounds(end *indexEntry) (startOffset, endOffset, fileId uin
 	return i.offset, end.offset, end.filenum
 }
 
+// freezerCompression is the compression applied to the items of a freezer table.
+type freezerCompression uint8
+
+const (
+	compressionNone   freezerCompression = iota // items stored as is
+	compressionSnappy                           // items compressed with snappy
+	compressionZstd                             // items compressed with zstd, with a per-table dictionary
+)
+
+// String implements fmt.Stringer.
+func (c freezerCompression) String() string {
+	switch c {
+	case compressionNone:
+		return "none"
+	case compressionSnappy:
+		return "snappy"
+	case compressionZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// suffixes returns the file name suffixes of the index and the data files of
+// tables with the compression.
+func (c freezerCompression) suffixes() (string, string) {
+	switch c {
+	case compressionSnappy:
+		return "cidx", "cdat"
+	case compressionZstd:
+		return "zidx", "zdat"
+	default:
+		return "ridx", "rdat"
+	}
+}
+
+// detectCompression determines the compression of a table from the index file
+// present on disk, falling back to the configured one for new tables. This way
+// tables converted to another compression keep working without changing the
+// configuration.
+func detectCompression(path, name string, config freezerTableConfig) freezerCompression {
+	if config.noSnappy {
+		return compressionNone
+	}
+	for _, c := range []freezerCompression{compressionZstd, compressionSnappy} {
+		idx, _ := c.suffixes()
+		if _, err := os.Stat(filepath.Join(path, fmt.Sprintf("%s.%s", name, idx))); err == nil {
+			return c
+		}
+	}
+	if config.zstd {
+		return compressionZstd
+	}
+	return compressionSnappy
+}
+
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
-// It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
+// It consists of a data file (compressed or raw arbitrary data blobs) and an indexEntry
 // file (uncompressed 64 bit indices into the data file).
 type freezerTable struct {
 	items      atomic.Uint64 // Number of items stored in the table (including items removed from tail)
@@ -98,11 +154,13 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden atomic.Uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
-	readonly      bool
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	compression freezerCompression        // Item compression. Note: does not work retroactively
+	zstd        atomic.Pointer[zstdCodec] // Codec of zstd tables, replaced once the dictionary is trained
+	zstdTrained bool                      // Whether the dictionary training was attempted
+	readonly    bool
+	maxFileSize uint32 // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -129,16 +187,20 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, freezerTableConfig{noSnappy: noCompression}, readonly)
+}
+
+// openTable opens a freezer table with the given configuration. The compression
+// of existing tables is determined by the files on disk.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, config freezerTableConfig, readonly bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
-	} else {
-		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
-	}
+	compression := detectCompression(path, name, config)
+	idxSuffix, _ := compression.suffixes()
+	idxName := fmt.Sprintf("%s.%s", name, idxSuffix)
+
 	var (
 		err   error
 		index *os.File
@@ -166,18 +228,32 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         index,
-		meta:          meta,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		readonly:      readonly,
-		maxFileSize:   maxFilesize,
+		index:       index,
+		meta:        meta,
+		files:       make(map[uint32]*os.File),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		compression: compression,
+		readonly:    readonly,
+		maxFileSize: maxFilesize,
+	}
+	if compression == compressionZstd {
+		dict, err := loadZstdDict(path, name)
+		if err != nil {
+			tab.Close()
+			return nil, err
+		}
+		codec, err := newZstdCodec(dict)
+		if err != nil {
+			tab.Close()
+			return nil, err
+		}
+		tab.zstd.Store(codec)
+		tab.zstdTrained = dict != nil
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -635,12 +711,8 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
+		_, suffix := t.compression.suffixes()
+		name := fmt.Sprintf("%s.%04d.%s", t.name, num, suffix)
 		f, err = opener(filepath.Join(t.path, name))
 		if err != nil {
 			return nil, err
@@ -752,19 +824,29 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
 		decompressedSize := diskSize
-		if !t.noCompression {
+		switch t.compression {
+		case compressionSnappy:
 			decompressedSize, _ = snappy.DecodedLen(item)
+		case compressionZstd:
+			decompressedSize = zstdDecodedLen(item)
 		}
 		if i > 0 && maxBytes != 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
+		switch t.compression {
+		case compressionSnappy:
 			data, err := snappy.Decode(nil, item)
 			if err != nil {
 				return nil, err
 			}
 			output = append(output, data)
-		} else {
+		case compressionZstd:
+			data, err := t.zstd.Load().decompress(item)
+			if err != nil {
+				return nil, err
+			}
+			output = append(output, data)
+		default:
 			output = append(output, item)
 		}
 		outputSize += decompressedSize
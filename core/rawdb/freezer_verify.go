@@ -0,0 +1,382 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// freezerVerifyBatch is the number of items loaded from each table at once
+// while verifying the chain freezer.
+const freezerVerifyBatch = 1024
+
+// chainFreezerTables is the order in which the damages of the chain freezer
+// tables are reported.
+var chainFreezerTables = []string{
+	ChainFreezerHashTable,
+	ChainFreezerHeaderTable,
+	ChainFreezerBodiesTable,
+	ChainFreezerReceiptTable,
+	ChainFreezerDifficultyTable,
+}
+
+// FreezerDamage is a range of consecutive damaged items in a freezer table.
+type FreezerDamage struct {
+	Table  string
+	Start  uint64 // First damaged item
+	End    uint64 // Last damaged item
+	Reason string // Failure of the first damaged item
+}
+
+// FreezerVerifyResult is the outcome of a chain freezer verification.
+type FreezerVerifyResult struct {
+	Start   uint64          // First verified block
+	End     uint64          // Last verified block
+	Damages []FreezerDamage // Damaged item ranges, ordered by table and number
+}
+
+// FirstDamaged returns the lowest damaged block number, or false if the chain
+// freezer is intact.
+func (r *FreezerVerifyResult) FirstDamaged() (uint64, bool) {
+	if len(r.Damages) == 0 {
+		return 0, false
+	}
+	first := r.Damages[0].Start
+	for _, damage := range r.Damages[1:] {
+		if damage.Start < first {
+			first = damage.Start
+		}
+	}
+	return first, true
+}
+
+// Damaged reports whether the item of the given table and block number is
+// damaged.
+func (r *FreezerVerifyResult) Damaged(table string, number uint64) bool {
+	for _, damage := range r.Damages {
+		if damage.Table == table && damage.Start <= number && number <= damage.End {
+			return true
+		}
+	}
+	return false
+}
+
+// damageTracker accumulates the damaged items of the freezer tables, merging
+// consecutive ones into ranges.
+type damageTracker struct {
+	ranges map[string][]FreezerDamage
+}
+
+// add records a damaged item.
+func (d *damageTracker) add(table string, number uint64, reason string) {
+	ranges := d.ranges[table]
+	if n := len(ranges); n > 0 && ranges[n-1].End+1 == number {
+		ranges[n-1].End = number
+		return
+	}
+	d.ranges[table] = append(ranges, FreezerDamage{Table: table, Start: number, End: number, Reason: reason})
+}
+
+// damages returns the damaged ranges of a table in ascending order. Items can
+// be recorded out of order, as decoding and cross-checking happen separately,
+// so adjacent ranges are merged here again.
+func (d *damageTracker) damages(table string) []FreezerDamage {
+	ranges := slices.Clone(d.ranges[table])
+	slices.SortStableFunc(ranges, func(a, b FreezerDamage) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	var merged []FreezerDamage
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].End+1 >= r.Start {
+			merged[n-1].End = max(merged[n-1].End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// verifiedBlock is the decoded content of a block loaded from the freezer. The
+// fields are nil if the corresponding item is damaged.
+type verifiedBlock struct {
+	hash     *common.Hash
+	header   *types.Header
+	headHash common.Hash // Hash of the raw header
+	body     *types.Body
+	receipts []*types.ReceiptForStorage
+	td       *big.Int
+}
+
+// VerifyChainFreezer validates the chain freezer items of the blocks in the
+// range [start, end]. Every item is decompressed and decoded, and the tables
+// are cross-checked against each other: the hashes against the headers, the
+// headers against their successors, the bodies and receipts against the roots
+// in the headers, and the total difficulties against the header difficulties.
+//
+// Bodies and receipts below the freezer tail are expired and not checked, nor
+// are the receipts below the receipts tail, which were skipped during sync and
+// stored empty. The hasher is used to compute the transaction and receipt roots.
+func VerifyChainFreezer(db ethdb.AncientReader, start, end, receiptsTail uint64, hasher types.TrieHasher) (*FreezerVerifyResult, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	if frozen == 0 {
+		return nil, fmt.Errorf("chain freezer is empty")
+	}
+	if end >= frozen {
+		end = frozen - 1
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid range %d-%d, freezer holds %d blocks", start, end, frozen)
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return nil, err
+	}
+	tails := map[string]uint64{
+		ChainFreezerBodiesTable:  tail,
+		ChainFreezerReceiptTable: max(tail, receiptsTail),
+	}
+	var (
+		damages = &damageTracker{ranges: make(map[string][]FreezerDamage)}
+		prev    *verifiedBlock
+		begin   = time.Now()
+		logged  = time.Now()
+	)
+	// Preload the predecessor of the first block for the cross-table checks
+	if start > 0 {
+		blocks := loadFrozenBlocks(db, start-1, 1, tails, &damageTracker{ranges: make(map[string][]FreezerDamage)})
+		prev = blocks[0]
+	}
+	for from := start; from <= end; from += freezerVerifyBatch {
+		count := uint64(freezerVerifyBatch)
+		if from+count > end+1 {
+			count = end + 1 - from
+		}
+		blocks := loadFrozenBlocks(db, from, count, tails, damages)
+
+		// Load the successor of the batch too, its header is used to tell apart
+		// damaged headers and damaged hashes. Its damages are recorded with the
+		// next batch.
+		if next := from + count; next < frozen {
+			blocks = append(blocks, loadFrozenBlocks(db, next, 1, tails, &damageTracker{ranges: make(map[string][]FreezerDamage)})...)
+		}
+		for i := uint64(0); i < count; i++ {
+			var next *verifiedBlock
+			if i+1 < uint64(len(blocks)) {
+				next = blocks[i+1]
+			}
+			verifyFrozenBlock(from+i, prev, blocks[i], next, tails, hasher, damages)
+			prev = blocks[i]
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain freezer", "number", from+count-1, "end", end, "elapsed", common.PrettyDuration(time.Since(begin)))
+			logged = time.Now()
+		}
+	}
+	result := &FreezerVerifyResult{Start: start, End: end}
+	for _, table := range chainFreezerTables {
+		result.Damages = append(result.Damages, damages.damages(table)...)
+	}
+	log.Info("Verified chain freezer", "start", start, "end", end, "damaged", len(result.Damages), "elapsed", common.PrettyDuration(time.Since(begin)))
+	return result, nil
+}
+
+// loadFrozenBlocks retrieves and decodes a batch of blocks from the freezer,
+// recording the items that fail to load. The items of the tables below their
+// tail are not loaded.
+func loadFrozenBlocks(db ethdb.AncientReader, start, count uint64, tails map[string]uint64, damages *damageTracker) []*verifiedBlock {
+	blocks := make([]*verifiedBlock, count)
+	for i := range blocks {
+		blocks[i] = new(verifiedBlock)
+	}
+	for _, table := range chainFreezerTables {
+		from, n := start, count
+		if tail := tails[table]; from < tail {
+			if from+n <= tail {
+				continue // expired or skipped history
+			}
+			n -= tail - from
+			from = tail
+		}
+		for i, item := range loadFrozenItems(db, table, from, n, damages) {
+			if item == nil {
+				continue
+			}
+			block, number := blocks[from-start+uint64(i)], from+uint64(i)
+			if err := decodeFrozenItem(table, item, block); err != nil {
+				damages.add(table, number, err.Error())
+			}
+		}
+	}
+	return blocks
+}
+
+// loadFrozenItems retrieves a range of raw items from a freezer table. If the
+// range can't be loaded at once, the items are loaded one by one to find the
+// damaged ones, which are left nil.
+func loadFrozenItems(db ethdb.AncientReader, table string, start, count uint64, damages *damageTracker) [][]byte {
+	items, err := db.AncientRange(table, start, count, 0)
+	if err == nil && uint64(len(items)) == count {
+		return items
+	}
+	items = make([][]byte, count)
+	for i := range items {
+		item, err := db.Ancient(table, start+uint64(i))
+		if err != nil {
+			damages.add(table, start+uint64(i), err.Error())
+			continue
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// decodeFrozenItem decodes a raw freezer item into the block.
+func decodeFrozenItem(table string, item []byte, block *verifiedBlock) error {
+	switch table {
+	case ChainFreezerHashTable:
+		if len(item) != common.HashLength {
+			return fmt.Errorf("invalid hash length %d", len(item))
+		}
+		hash := common.BytesToHash(item)
+		block.hash = &hash
+
+	case ChainFreezerHeaderTable:
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(item, header); err != nil {
+			return fmt.Errorf("invalid header: %v", err)
+		}
+		block.header, block.headHash = header, crypto.Keccak256Hash(item)
+
+	case ChainFreezerBodiesTable:
+		body := new(types.Body)
+		if err := rlp.DecodeBytes(item, body); err != nil {
+			return fmt.Errorf("invalid body: %v", err)
+		}
+		block.body = body
+
+	case ChainFreezerReceiptTable:
+		var receipts []*types.ReceiptForStorage
+		if err := rlp.DecodeBytes(item, &receipts); err != nil {
+			return fmt.Errorf("invalid receipts: %v", err)
+		}
+		block.receipts = receipts
+
+	case ChainFreezerDifficultyTable:
+		td := new(big.Int)
+		if err := rlp.DecodeBytes(item, td); err != nil {
+			return fmt.Errorf("invalid total difficulty: %v", err)
+		}
+		block.td = td
+	}
+	return nil
+}
+
+// verifyFrozenBlock cross-checks the decoded items of a block.
+func verifyFrozenBlock(number uint64, prev, block, next *verifiedBlock, tails map[string]uint64, hasher types.TrieHasher, damages *damageTracker) {
+	header := block.header
+	if header != nil && header.Number.Uint64() != number {
+		damages.add(ChainFreezerHeaderTable, number, fmt.Sprintf("header number mismatch: have %d", header.Number))
+		header = nil
+	}
+	// Tell apart damaged hashes and damaged headers by the parent hash of the
+	// successor header, if it's available.
+	if header != nil && block.hash != nil && *block.hash != block.headHash {
+		if next != nil && next.header != nil && next.header.ParentHash == block.headHash {
+			damages.add(ChainFreezerHashTable, number, fmt.Sprintf("hash mismatch: have %x, want %x", *block.hash, block.headHash))
+		} else {
+			damages.add(ChainFreezerHeaderTable, number, fmt.Sprintf("hash mismatch: have %x, want %x", block.headHash, *block.hash))
+			header = nil
+		}
+	}
+	if header == nil {
+		return // nothing to check the rest against
+	}
+	if number >= tails[ChainFreezerBodiesTable] {
+		if body := block.body; body != nil {
+			if hash := deriveRoot(types.Transactions(body.Transactions), types.EmptyTxsHash, hasher); hash != header.TxHash {
+				damages.add(ChainFreezerBodiesTable, number, fmt.Sprintf("transaction root mismatch: have %x, want %x", hash, header.TxHash))
+			} else if hash := types.CalcUncleHash(body.Uncles); hash != header.UncleHash {
+				damages.add(ChainFreezerBodiesTable, number, fmt.Sprintf("uncle hash mismatch: have %x, want %x", hash, header.UncleHash))
+			} else if header.WithdrawalsHash != nil && body.Withdrawals == nil {
+				damages.add(ChainFreezerBodiesTable, number, "missing withdrawals")
+			} else if header.WithdrawalsHash != nil {
+				if hash := deriveRoot(types.Withdrawals(body.Withdrawals), types.EmptyWithdrawalsHash, hasher); hash != *header.WithdrawalsHash {
+					damages.add(ChainFreezerBodiesTable, number, fmt.Sprintf("withdrawals root mismatch: have %x, want %x", hash, *header.WithdrawalsHash))
+				}
+			}
+		}
+		// Receipts are stored without their type, which is needed to compute
+		// the root, so they can only be checked along with an intact body.
+		if number >= tails[ChainFreezerReceiptTable] && block.receipts != nil && block.body != nil {
+			if len(block.receipts) != len(block.body.Transactions) {
+				damages.add(ChainFreezerReceiptTable, number, fmt.Sprintf("receipt count mismatch: have %d, want %d", len(block.receipts), len(block.body.Transactions)))
+			} else if hash := receiptsRoot(block.receipts, block.body.Transactions, hasher); hash != header.ReceiptHash {
+				damages.add(ChainFreezerReceiptTable, number, fmt.Sprintf("receipt root mismatch: have %x, want %x", hash, header.ReceiptHash))
+			}
+		}
+	}
+	if block.td != nil {
+		var want *big.Int
+		if number == 0 {
+			want = header.Difficulty
+		} else if prev != nil && prev.td != nil {
+			want = new(big.Int).Add(prev.td, header.Difficulty)
+		}
+		// Continue from the expected total difficulty, so a single damaged item
+		// isn't reported for all the subsequent blocks too.
+		if want != nil && block.td.Cmp(want) != 0 {
+			damages.add(ChainFreezerDifficultyTable, number, fmt.Sprintf("total difficulty mismatch: have %v, want %v", block.td, want))
+			block.td = want
+		}
+	}
+}
+
+// receiptsRoot computes the root hash of stored receipts, using the types of
+// the transactions they belong to.
+func receiptsRoot(stored []*types.ReceiptForStorage, txs []*types.Transaction, hasher types.TrieHasher) common.Hash {
+	receipts := make(types.Receipts, len(stored))
+	for i, r := range stored {
+		receipt := (*types.Receipt)(r)
+		receipt.Type = txs[i].Type()
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts[i] = receipt
+	}
+	return deriveRoot(receipts, types.EmptyReceiptsHash, hasher)
+}
+
+// deriveRoot computes the root hash of a list, short-circuiting empty ones the
+// same way the block construction does.
+func deriveRoot(list types.DerivableList, empty common.Hash, hasher types.TrieHasher) common.Hash {
+	if list.Len() == 0 {
+		return empty
+	}
+	return types.DeriveSha(list, hasher)
+}
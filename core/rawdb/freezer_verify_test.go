@@ -0,0 +1,386 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// makeVerifiableChain creates a chain of consistent blocks and receipts.
+func makeVerifiableChain(t *testing.T, n int) ([]*types.Block, []types.Receipts) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	var (
+		blocks   = make([]*types.Block, n)
+		receipts = make([]types.Receipts, n)
+		parent   common.Hash
+	)
+	for i := 0; i < n; i++ {
+		var txs []*types.Transaction
+		for j := 0; j < i%3; j++ {
+			tx, err := types.SignNewTx(key, signer, &types.LegacyTx{
+				Nonce:    uint64(i*3 + j),
+				GasPrice: big.NewInt(1),
+				Gas:      21000,
+				To:       &common.Address{byte(i)},
+			})
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			txs = append(txs, tx)
+			receipt := &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: uint64(21000 * (j + 1)),
+				Logs:              []*types.Log{},
+			}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipts[i] = append(receipts[i], receipt)
+		}
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Difficulty: big.NewInt(int64(i + 1)),
+			Extra:      []byte("test block"),
+		}
+		blocks[i] = types.NewBlock(header, &types.Body{Transactions: txs}, receipts[i], newTestHasher())
+		parent = blocks[i].Hash()
+	}
+	return blocks, receipts
+}
+
+// openVerifiableFreezer opens the chain freezer in the given directory.
+func openVerifiableFreezer(t *testing.T, dir string) *Freezer {
+	t.Helper()
+
+	f, err := NewFreezer(dir, "", false, freezerTableSize, chainFreezerTableConfigs)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	return f
+}
+
+// corruptFreezerFile overwrites a few bytes of a freezer table data file.
+func corruptFreezerFile(t *testing.T, dir string, name string, offset int64, data []byte) {
+	t.Helper()
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", name, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(data, offset); err != nil {
+		t.Fatalf("failed to corrupt %s: %v", name, err)
+	}
+}
+
+func TestVerifyChainFreezer(t *testing.T) {
+	blocks, receipts := makeVerifiableChain(t, 50)
+
+	dir := t.TempDir()
+	f := openVerifiableFreezer(t, dir)
+	if _, err := WriteAncientBlocks(f, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write blocks: %v", err)
+	}
+	// Ensure the intact freezer passes the verification
+	result, err := VerifyChainFreezer(f, 0, 100, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if result.Start != 0 || result.End != 49 {
+		t.Fatalf("unexpected range: have %d-%d, want 0-49", result.Start, result.End)
+	}
+	if len(result.Damages) != 0 {
+		t.Fatalf("intact freezer reported damaged: %v", result.Damages)
+	}
+	if _, damaged := result.FirstDamaged(); damaged {
+		t.Fatal("intact freezer reported damaged")
+	}
+	f.Close()
+
+	// Corrupt a couple of hashes and a total difficulty, both stored raw
+	corruptFreezerFile(t, dir, "hashes.0000.rdat", 10*common.HashLength, []byte{0xff})
+	corruptFreezerFile(t, dir, "hashes.0000.rdat", 11*common.HashLength+5, []byte{0xff})
+
+	td, _ := rlp.EncodeToBytes(big.NewInt(1))
+	var offset int64
+	for i := 0; i < 30; i++ {
+		enc, _ := rlp.EncodeToBytes(new(big.Int).SetUint64(uint64((i + 1) * (i + 2) / 2)))
+		offset += int64(len(enc))
+	}
+	corruptFreezerFile(t, dir, "diffs.0000.rdat", offset, td)
+
+	f = openVerifiableFreezer(t, dir)
+	defer f.Close()
+
+	result, err = VerifyChainFreezer(f, 0, 49, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	want := []struct {
+		table      string
+		start, end uint64
+	}{
+		{ChainFreezerHashTable, 10, 11},
+		{ChainFreezerDifficultyTable, 30, 30},
+	}
+	if len(result.Damages) != len(want) {
+		t.Fatalf("damage count mismatch: have %v, want %v", result.Damages, want)
+	}
+	for i, damage := range result.Damages {
+		if damage.Table != want[i].table || damage.Start != want[i].start || damage.End != want[i].end {
+			t.Errorf("damage %d mismatch: have %s %d-%d, want %s %d-%d", i, damage.Table, damage.Start, damage.End, want[i].table, want[i].start, want[i].end)
+		}
+	}
+	if first, damaged := result.FirstDamaged(); !damaged || first != 10 {
+		t.Fatalf("first damaged block mismatch: have %d, want 10", first)
+	}
+	// Verifying a subrange only reports its own damages
+	result, err = VerifyChainFreezer(f, 11, 20, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) != 1 || result.Damages[0].Start != 11 || result.Damages[0].End != 11 {
+		t.Fatalf("unexpected damages in subrange: %v", result.Damages)
+	}
+}
+
+func TestVerifyChainFreezerBodies(t *testing.T) {
+	blocks, receipts := makeVerifiableChain(t, 30)
+
+	dir := t.TempDir()
+	f := openVerifiableFreezer(t, dir)
+	if _, err := WriteAncientBlocks(f, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write blocks: %v", err)
+	}
+	f.Close()
+
+	// Overwrite the middle of the compressed bodies, whatever the damage looks
+	// like it must be attributed to the bodies.
+	info, err := os.Stat(filepath.Join(dir, "bodies.0000.zdat"))
+	if err != nil {
+		t.Fatalf("failed to stat bodies: %v", err)
+	}
+	corruptFreezerFile(t, dir, "bodies.0000.zdat", info.Size()/2, []byte{0xde, 0xad, 0xbe, 0xef})
+
+	f = openVerifiableFreezer(t, dir)
+	defer f.Close()
+
+	result, err := VerifyChainFreezer(f, 0, 29, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) == 0 {
+		t.Fatal("damaged bodies not detected")
+	}
+	for _, damage := range result.Damages {
+		if damage.Table != ChainFreezerBodiesTable {
+			t.Errorf("unexpected damage: %+v", damage)
+		}
+	}
+}
+
+func TestVerifyChainFreezerSkippedReceipts(t *testing.T) {
+	blocks, receipts := makeVerifiableChain(t, 30)
+
+	// Receipts skipped during sync are stored empty
+	for i := 0; i < 20; i++ {
+		receipts[i] = nil
+	}
+	f := openVerifiableFreezer(t, t.TempDir())
+	defer f.Close()
+	if _, err := WriteAncientBlocks(f, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write blocks: %v", err)
+	}
+	result, err := VerifyChainFreezer(f, 0, 29, 20, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) != 0 {
+		t.Fatalf("skipped receipts reported damaged: %v", result.Damages)
+	}
+	// Without the receipts tail, the skipped receipts are damaged
+	result, err = VerifyChainFreezer(f, 0, 29, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) == 0 || result.Damages[0].Table != ChainFreezerReceiptTable {
+		t.Fatalf("skipped receipts not reported without receipts tail: %v", result.Damages)
+	}
+}
+
+// testRepairSource serves the blocks and receipts of a test chain.
+type testRepairSource struct {
+	blocks   []*types.Block
+	receipts []types.Receipts
+}
+
+func (s *testRepairSource) GetBlock(number uint64, hash common.Hash) (*types.Block, error) {
+	if number >= uint64(len(s.blocks)) || s.blocks[number].Hash() != hash {
+		return nil, errors.New("block not found")
+	}
+	return s.blocks[number], nil
+}
+
+func (s *testRepairSource) GetReceipts(number uint64, hash common.Hash) (types.Receipts, error) {
+	if number >= uint64(len(s.blocks)) || s.blocks[number].Hash() != hash {
+		return nil, errors.New("receipts not found")
+	}
+	return s.receipts[number], nil
+}
+
+func TestRepairChainFreezer(t *testing.T) {
+	blocks, receipts := makeVerifiableChain(t, 30)
+
+	dir := t.TempDir()
+	f := openVerifiableFreezer(t, filepath.Join(dir, ChainFreezerName))
+	if _, err := WriteAncientBlocks(f, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write blocks: %v", err)
+	}
+	f.Close()
+
+	// Damage a hash and some bodies in the middle of the chain
+	corruptFreezerFile(t, filepath.Join(dir, ChainFreezerName), "hashes.0000.rdat", 10*common.HashLength, []byte{0xff})
+	info, err := os.Stat(filepath.Join(dir, ChainFreezerName, "bodies.0000.zdat"))
+	if err != nil {
+		t.Fatalf("failed to stat bodies: %v", err)
+	}
+	corruptFreezerFile(t, filepath.Join(dir, ChainFreezerName), "bodies.0000.zdat", info.Size()/2, []byte{0xde, 0xad, 0xbe, 0xef})
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), dir, "", false)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	result, err := VerifyChainFreezer(db, 0, 29, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) < 2 {
+		t.Fatalf("damages not detected: %v", result.Damages)
+	}
+	// Without a source for the bodies, the freezer is left untouched
+	staging := filepath.Join(t.TempDir(), "staging")
+	if err := RepairChainFreezer(db, result, staging, nil); !errors.Is(err, ErrFreezerUnrepairable) {
+		t.Fatalf("repair error mismatch: have %v, want %v", err, ErrFreezerUnrepairable)
+	}
+	if frozen, _ := db.Ancients(); frozen != 30 {
+		t.Fatalf("freezer modified by failed repair: %d items", frozen)
+	}
+	// Repair the freezer from the source, keeping the intact items
+	if err := RepairChainFreezer(db, result, staging, &testRepairSource{blocks, receipts}); err != nil {
+		t.Fatalf("failed to repair freezer: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 30 {
+		t.Fatalf("frozen item count mismatch: have %d, want 30", frozen)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Fatal("staging freezer not removed")
+	}
+	result, err = VerifyChainFreezer(db, 0, 29, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) != 0 {
+		t.Fatalf("repaired freezer reported damaged: %v", result.Damages)
+	}
+	for i, block := range blocks {
+		if hash := ReadCanonicalHash(db, uint64(i)); hash != block.Hash() {
+			t.Fatalf("block %d: hash mismatch: have %x, want %x", i, hash, block.Hash())
+		}
+		if td := ReadTd(db, block.Hash(), uint64(i)); td == nil || td.Uint64() != uint64((i+1)*(i+2)/2) {
+			t.Fatalf("block %d: total difficulty mismatch: have %v", i, td)
+		}
+	}
+}
+
+// Tests that a repair interrupted while rewriting the freezer is completed from
+// the staged items, and that an incomplete staging is discarded.
+func TestResumeChainFreezerRepair(t *testing.T) {
+	blocks, receipts := makeVerifiableChain(t, 30)
+
+	dir := t.TempDir()
+	f := openVerifiableFreezer(t, filepath.Join(dir, ChainFreezerName))
+	if _, err := WriteAncientBlocks(f, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write blocks: %v", err)
+	}
+	f.Close()
+	corruptFreezerFile(t, filepath.Join(dir, ChainFreezerName), "hashes.0000.rdat", 10*common.HashLength, []byte{0xff})
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), dir, "", false)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	result, err := VerifyChainFreezer(db, 0, 29, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	first, damaged := result.FirstDamaged()
+	if !damaged {
+		t.Fatal("damage not detected")
+	}
+	// An incomplete staging is discarded without touching the freezer
+	staging := filepath.Join(t.TempDir(), "staging")
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if resumed, err := ResumeChainFreezerRepair(db, staging); resumed || err != nil {
+		t.Fatalf("incomplete staging resumed: %v, %v", resumed, err)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Fatal("incomplete staging not removed")
+	}
+	// Stage the repair and interrupt the rewrite halfway through
+	frozen, _, err := stageChainFreezerRepair(db, result, first, staging, &testRepairSource{blocks, receipts})
+	if err != nil {
+		t.Fatalf("failed to stage repair: %v", err)
+	}
+	if _, err := db.TruncateHead(first + 5); err != nil {
+		t.Fatalf("failed to truncate freezer: %v", err)
+	}
+	if err := RepairChainFreezer(db, result, staging, &testRepairSource{blocks, receipts}); err == nil {
+		t.Fatal("repair restarted over an unfinished one")
+	}
+	if resumed, err := ResumeChainFreezerRepair(db, staging); !resumed || err != nil {
+		t.Fatalf("failed to resume repair: %v, %v", resumed, err)
+	}
+	if have, _ := db.Ancients(); have != frozen {
+		t.Fatalf("frozen item count mismatch: have %d, want %d", have, frozen)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Fatal("staging freezer not removed")
+	}
+	result, err = VerifyChainFreezer(db, 0, 29, 0, newTestHasher())
+	if err != nil {
+		t.Fatalf("failed to verify freezer: %v", err)
+	}
+	if len(result.Damages) != 0 {
+		t.Fatalf("repaired freezer reported damaged: %v", result.Damages)
+	}
+}
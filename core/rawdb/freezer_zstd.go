@@ -0,0 +1,293 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// zstdDictSize is the maximum size of a trained zstd dictionary.
+	zstdDictSize = 64 * 1024
+
+	// zstdDictSampleItems is the number of items sampled to train a zstd
+	// dictionary. Tables created with zstd compression train their dictionary
+	// once they hold this many items, the ones written before are compressed
+	// without a dictionary.
+	zstdDictSampleItems = 4096
+
+	// zstdDictSampleBytes is the maximum amount of sample data used to train a
+	// zstd dictionary.
+	zstdDictSampleBytes = 8 * 1024 * 1024
+
+	// zstdDictSegment is the length of the data segments the dictionary is
+	// assembled from, zstdDictKmer is the length of the substrings used to
+	// score them.
+	zstdDictSegment = 64
+	zstdDictKmer    = 8
+)
+
+// zstdCodec compresses and decompresses the items of a zstd freezer table,
+// using the table's dictionary if it has one. Items compressed without the
+// dictionary remain readable after it's trained.
+type zstdCodec struct {
+	dict    []byte // Raw content dictionary, nil if not trained yet
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// newZstdCodec creates a codec with the given raw content dictionary.
+func newZstdCodec(dict []byte) (*zstdCodec, error) {
+	eopts := []zstd.EOption{
+		zstd.WithEncoderLevel(zstd.SpeedDefault),
+		zstd.WithEncoderConcurrency(1),
+	}
+	dopts := []zstd.DOption{
+		zstd.WithDecoderConcurrency(0),
+	}
+	if len(dict) > 0 {
+		id := zstdDictID(dict)
+		eopts = append(eopts, zstd.WithEncoderDictRaw(id, dict))
+		dopts = append(dopts, zstd.WithDecoderDictRaw(id, dict))
+	}
+	encoder, err := zstd.NewWriter(nil, eopts...)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	return &zstdCodec{dict: dict, encoder: encoder, decoder: decoder}, nil
+}
+
+// compress appends the compressed data to dst, reusing its storage.
+func (c *zstdCodec) compress(dst, data []byte) []byte {
+	return c.encoder.EncodeAll(data, dst[:0])
+}
+
+// decompress returns the decompressed content of an item.
+func (c *zstdCodec) decompress(item []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(item, nil)
+}
+
+// zstdDecodedLen returns the decompressed size of an item as recorded in its
+// frame header, or the compressed size if the header doesn't carry it.
+func zstdDecodedLen(item []byte) int {
+	var header zstd.Header
+	if err := header.Decode(item); err == nil && header.HasFCS {
+		return int(header.FrameContentSize)
+	}
+	return len(item)
+}
+
+// zstdDictID derives the identifier of a raw content dictionary from its
+// content. The identifier is stored in the frame headers and lies outside the
+// ranges reserved by the zstd format.
+func zstdDictID(dict []byte) uint32 {
+	const low, high = 1 << 15, 1 << 31
+	return low + crc32.ChecksumIEEE(dict)%(high-low)
+}
+
+// zstdDictPath returns the path of the dictionary file of a table.
+func zstdDictPath(path, name string) string {
+	return filepath.Join(path, name+".zdict")
+}
+
+// loadZstdDict reads the dictionary of a table, nil if it has none.
+func loadZstdDict(path, name string) ([]byte, error) {
+	dict, err := os.ReadFile(zstdDictPath(path, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return dict, err
+}
+
+// writeZstdDict persists the dictionary of a table. It must be durable before
+// any item is compressed with it, the items are unreadable without it.
+func writeZstdDict(path, name string, dict []byte) error {
+	var (
+		file = zstdDictPath(path, name)
+		tmp  = file + ".tmp"
+	)
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(dict); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// trainZstdDict assembles a raw content dictionary of at most the given size
+// from sample items. It's a simplified version of the cover algorithm used by
+// the zstd reference implementation: the samples are split into epochs, and
+// the segment of each epoch containing the most frequent substrings is picked
+// into the dictionary. Nil is returned if there's too little sample data.
+func trainZstdDict(samples [][]byte, size int) []byte {
+	var data []byte
+	for _, sample := range samples {
+		data = append(data, sample...)
+	}
+	if len(data) < 8*zstdDictSegment {
+		return nil
+	}
+	// Count the occurrences of every substring in the samples. The substrings
+	// are hashed into a fixed number of buckets, collisions only slightly skew
+	// the scores.
+	const bucketBits = 20
+
+	freqs := make([]uint32, 1<<bucketBits)
+	kmer := func(pos int) uint64 {
+		return (binary.LittleEndian.Uint64(data[pos:]) * 0x9e3779b97f4a7c15) >> (64 - bucketBits)
+	}
+	for pos := 0; pos+zstdDictKmer <= len(data); pos++ {
+		freqs[kmer(pos)]++
+	}
+	score := func(pos int) uint64 {
+		if pos+zstdDictKmer > len(data) {
+			return 0
+		}
+		return uint64(freqs[kmer(pos)])
+	}
+	// Pick the best segment from every epoch
+	epochs := size / zstdDictSegment
+	if epochs > len(data)/zstdDictSegment {
+		epochs = len(data) / zstdDictSegment
+	}
+	epochLen := len(data) / epochs
+
+	type segment struct {
+		start int
+		score uint64
+	}
+	var picked []segment
+	for epoch := 0; epoch < epochs; epoch++ {
+		var (
+			start = epoch * epochLen
+			end   = start + epochLen
+			best  = segment{start: -1}
+			cur   uint64
+		)
+		windows := zstdDictSegment - zstdDictKmer + 1
+		for pos := start; pos < start+windows; pos++ {
+			cur += score(pos)
+		}
+		for pos := start; pos+zstdDictSegment <= end; pos++ {
+			if cur > best.score {
+				best = segment{start: pos, score: cur}
+			}
+			cur -= score(pos)
+			cur += score(pos + windows)
+		}
+		if best.start < 0 {
+			continue
+		}
+		picked = append(picked, best)
+
+		// Don't reward the same content again in later epochs
+		for pos := best.start; pos+zstdDictKmer <= best.start+zstdDictSegment; pos++ {
+			freqs[kmer(pos)] = 0
+		}
+	}
+	if len(picked) == 0 {
+		return nil
+	}
+	// Recent history is cheaper to reference, so place the most valuable
+	// segments at the end of the dictionary.
+	sort.SliceStable(picked, func(i, j int) bool {
+		return picked[i].score < picked[j].score
+	})
+	dict := make([]byte, 0, len(picked)*zstdDictSegment)
+	for _, seg := range picked {
+		dict = append(dict, data[seg.start:seg.start+zstdDictSegment]...)
+	}
+	return dict
+}
+
+// maybeTrainDict trains the dictionary of a zstd table from its items once it
+// holds enough of them. The dictionary is persisted before it's used, and the
+// training is attempted only once per table.
+func (t *freezerTable) maybeTrainDict() error {
+	if t.zstdTrained || t.items.Load()-t.itemHidden.Load() < zstdDictSampleItems {
+		return nil
+	}
+	t.zstdTrained = true
+
+	samples, err := t.sampleItems(zstdDictSampleItems, zstdDictSampleBytes)
+	if err != nil {
+		return err
+	}
+	dict := trainZstdDict(samples, zstdDictSize)
+	if dict == nil {
+		return nil
+	}
+	if err := writeZstdDict(t.path, t.name, dict); err != nil {
+		return err
+	}
+	codec, err := newZstdCodec(dict)
+	if err != nil {
+		return err
+	}
+	t.zstd.Store(codec)
+	t.logger.Info("Trained zstd dictionary", "samples", len(samples), "size", len(dict))
+	return nil
+}
+
+// sampleItems retrieves up to the given number of items evenly spread across
+// the table, stopping once their total size reaches the limit.
+func (t *freezerTable) sampleItems(count int, limit int) ([][]byte, error) {
+	var (
+		first = t.itemHidden.Load()
+		items = t.items.Load()
+		step  = uint64(1)
+		size  int
+	)
+	if items <= first {
+		return nil, nil
+	}
+	if n := (items - first) / uint64(count); n > 1 {
+		step = n
+	}
+	var samples [][]byte
+	for i := first; i < items && len(samples) < count && size < limit; i += step {
+		item, err := t.Retrieve(i)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, item)
+		size += len(item)
+	}
+	return samples, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// zstdTestItem creates a structured item, similar to each other like the items
+// of the chain freezer tables.
+func zstdTestItem(i int) []byte {
+	type log struct {
+		Address [20]byte
+		Topics  [][32]byte
+		Data    []byte
+	}
+	var logs []log
+	for j := 0; j < 1+i%4; j++ {
+		l := log{Topics: make([][32]byte, 3), Data: make([]byte, 64)}
+		l.Address[0], l.Address[19] = 0xde, byte(j)
+		l.Topics[0][0], l.Topics[0][31] = 0xdd, 0xf2
+		l.Topics[1][31], l.Topics[2][31] = byte(i), byte(i>>8)
+		l.Data[63] = byte(i * j)
+		logs = append(logs, l)
+	}
+	blob, _ := rlp.EncodeToBytes([]interface{}{uint64(1), uint64(21000 * i), logs})
+	return blob
+}
+
+func TestFreezerTableZstd(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *freezerTable {
+		table, err := openTable(dir, "test", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 16*1024, freezerTableConfig{zstd: true}, false)
+		if err != nil {
+			t.Fatalf("failed to open table: %v", err)
+		}
+		return table
+	}
+	table := open()
+	if table.compression != compressionZstd {
+		t.Fatalf("unexpected compression: %v", table.compression)
+	}
+	// Write enough items to get the dictionary trained halfway
+	items := zstdDictSampleItems + 1000
+	batch := table.newBatch()
+	for i := 0; i < items; i++ {
+		if err := batch.AppendRaw(uint64(i), zstdTestItem(i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+		if i == zstdDictSampleItems-1 {
+			if err := batch.commit(); err != nil {
+				t.Fatalf("failed to commit: %v", err)
+			}
+		}
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if table.zstd.Load().dict == nil {
+		t.Fatal("dictionary not trained")
+	}
+	if _, err := os.Stat(zstdDictPath(dir, "test")); err != nil {
+		t.Fatalf("dictionary not persisted: %v", err)
+	}
+	check := func(table *freezerTable) {
+		t.Helper()
+		for i := 0; i < items; i++ {
+			blob, err := table.Retrieve(uint64(i))
+			if err != nil {
+				t.Fatalf("failed to retrieve item %d: %v", i, err)
+			}
+			if !bytes.Equal(blob, zstdTestItem(i)) {
+				t.Fatalf("item %d mismatch", i)
+			}
+		}
+		// The size limit of range retrievals must account for the content size
+		size := len(zstdTestItem(0)) + len(zstdTestItem(1))
+		blobs, err := table.RetrieveItems(0, 10, uint64(size))
+		if err != nil {
+			t.Fatalf("failed to retrieve items: %v", err)
+		}
+		if len(blobs) != 2 {
+			t.Fatalf("unexpected item count: have %d, want 2", len(blobs))
+		}
+	}
+	check(table)
+	table.Close()
+
+	// Items compressed with and without the dictionary must be readable after
+	// reopening the table.
+	table = open()
+	defer table.Close()
+	check(table)
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "test.*.zdat")); len(files) < 2 {
+		t.Fatalf("unexpected data files: %v", files)
+	}
+}
+
+func TestTrainZstdDict(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, zstdTestItem(i))
+	}
+	dict := trainZstdDict(samples, zstdDictSize)
+	if len(dict) == 0 || len(dict) > zstdDictSize {
+		t.Fatalf("unexpected dictionary size: %d", len(dict))
+	}
+	if trainZstdDict(samples[:1], zstdDictSize) != nil {
+		t.Fatal("dictionary trained from too little data")
+	}
+	plain, _ := newZstdCodec(nil)
+	trained, _ := newZstdCodec(dict)
+
+	var plainSize, trainedSize int
+	for i := 1000; i < 1100; i++ {
+		item := zstdTestItem(i)
+		plainSize += len(plain.compress(nil, item))
+
+		enc := trained.compress(nil, item)
+		trainedSize += len(enc)
+		if dec, err := trained.decompress(enc); err != nil || !bytes.Equal(dec, item) {
+			t.Fatalf("item %d round trip failed: %v", i, err)
+		}
+	}
+	if trainedSize >= plainSize {
+		t.Fatalf("dictionary doesn't improve compression: %d with, %d without", trainedSize, plainSize)
+	}
+}
+
+func TestConvertFreezerTable(t *testing.T) {
+	blocks, receipts := makeVerifiableChain(t, 200)
+
+	ancient := t.TempDir()
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), ancient, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write blocks: %v", err)
+	}
+	if _, err := db.TruncateTail(10); err != nil {
+		t.Fatalf("failed to truncate tail: %v", err)
+	}
+	db.Close()
+
+	check := func(compression string) {
+		t.Helper()
+
+		dir := resolveChainFreezerDir(ancient)
+		suffix := map[string]string{"snappy": "cdat", "zstd": "zdat"}[compression]
+		for _, table := range []string{ChainFreezerBodiesTable, ChainFreezerReceiptTable} {
+			files, _ := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%s.*.*dat", table)))
+			for _, file := range files {
+				if filepath.Ext(file) != "."+suffix {
+					t.Fatalf("stale data file %s", file)
+				}
+			}
+			if len(files) == 0 {
+				t.Fatalf("no %s data files of %s", suffix, table)
+			}
+		}
+		db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), ancient, "", true)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		if tail, _ := db.Tail(); tail != 10 {
+			t.Fatalf("tail mismatch: have %d, want 10", tail)
+		}
+		for _, block := range blocks[10:] {
+			want, _ := rlp.EncodeToBytes(block.Body())
+			if body := ReadBodyRLP(db, block.Hash(), block.NumberU64()); !bytes.Equal(body, want) {
+				t.Fatalf("body %d mismatch", block.NumberU64())
+			}
+			if have, want := len(ReadRawReceipts(db, block.Hash(), block.NumberU64())), len(receipts[block.NumberU64()]); have != want {
+				t.Fatalf("receipts %d mismatch: have %d, want %d", block.NumberU64(), have, want)
+			}
+		}
+		if ReadBody(db, blocks[5].Hash(), 5) != nil {
+			t.Fatal("body below the tail retrievable")
+		}
+	}
+	// New bodies and receipts tables are compressed with zstd
+	check("zstd")
+
+	for _, compression := range []string{"snappy", "zstd"} {
+		for _, table := range []string{ChainFreezerBodiesTable, ChainFreezerReceiptTable} {
+			if err := ConvertFreezerTable(ancient, ChainFreezerName, table, compression); err != nil {
+				t.Fatalf("failed to convert %s to %s: %v", table, compression, err)
+			}
+		}
+		check(compression)
+	}
+	if err := ConvertFreezerTable(ancient, ChainFreezerName, ChainFreezerBodiesTable, "zstd"); err == nil {
+		t.Fatal("converted table to its own compression")
+	}
+	if err := ConvertFreezerTable(ancient, ChainFreezerName, ChainFreezerHashTable, "zstd"); err == nil {
+		t.Fatal("converted uncompressed table")
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/olekukonko/tablewriter"
+)
+
+// migrationBatchSize is the amount of data copied between two checkpoints of
+// a database migration.
+const migrationBatchSize = 16 * 1024 * 1024
+
+// migrationProgress is the checkpoint of a database migration, stored in the
+// destination database along with the data copied so far.
+type migrationProgress struct {
+	Last  []byte // Last key copied, nil if none yet
+	Count uint64 // Number of entries copied
+	Size  uint64 // Number of key and value bytes copied
+	Done  bool   // Whether all entries were copied
+}
+
+// readMigrationProgress retrieves the checkpoint of a database migration.
+func readMigrationProgress(db ethdb.KeyValueReader) (*migrationProgress, error) {
+	blob, err := db.Get(databaseMigrationKey)
+	if err != nil || len(blob) == 0 {
+		return nil, nil
+	}
+	progress := new(migrationProgress)
+	if err := rlp.DecodeBytes(blob, progress); err != nil {
+		return nil, fmt.Errorf("invalid migration checkpoint: %v", err)
+	}
+	return progress, nil
+}
+
+// writeMigrationProgress stores the checkpoint of a database migration.
+func writeMigrationProgress(db ethdb.KeyValueWriter, progress *migrationProgress) error {
+	blob, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		return err
+	}
+	return db.Put(databaseMigrationKey, blob)
+}
+
+// MigrateKeyValueStore copies all entries of the source key-value store into the
+// destination, which is expected to use a different database engine. The copy
+// is checkpointed in the destination, so an interrupted migration is resumed
+// where it left off. The ancient store is not touched.
+func MigrateKeyValueStore(src ethdb.KeyValueStore, dst ethdb.KeyValueStore) error {
+	progress, err := readMigrationProgress(dst)
+	if err != nil {
+		return err
+	}
+	if progress == nil {
+		progress = new(migrationProgress)
+	}
+	if progress.Done {
+		log.Info("Database already copied", "count", progress.Count, "size", common.StorageSize(progress.Size))
+		return nil
+	}
+	// Resume right after the last key copied, if any
+	var start []byte
+	if progress.Last != nil {
+		start = append(common.CopyBytes(progress.Last), 0x00)
+		log.Info("Resuming database migration", "count", progress.Count, "size", common.StorageSize(progress.Size), "last", common.Bytes2Hex(progress.Last))
+	}
+	it := src.NewIterator(nil, start)
+	defer it.Release()
+
+	var (
+		batch   = dst.NewBatch()
+		begin   = time.Now()
+		logged  = time.Now()
+		copied  uint64
+		pending int
+	)
+	commit := func() error {
+		if err := writeMigrationProgress(batch, progress); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		pending = 0
+		return nil
+	}
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if err := batch.Put(key, value); err != nil {
+			return err
+		}
+		progress.Last = common.CopyBytes(key)
+		progress.Count++
+		progress.Size += uint64(len(key) + len(value))
+		copied += uint64(len(key) + len(value))
+		pending += len(key) + len(value)
+
+		if pending >= migrationBatchSize {
+			if err := commit(); err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			elapsed := time.Since(begin)
+			log.Info("Migrating database", "count", progress.Count, "size", common.StorageSize(progress.Size),
+				"speed", fmt.Sprintf("%v/s", common.StorageSize(float64(copied)/elapsed.Seconds())),
+				"last", common.Bytes2Hex(progress.Last), "elapsed", common.PrettyDuration(elapsed))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	progress.Done = true
+	if err := commit(); err != nil {
+		return err
+	}
+	log.Info("Copied database", "count", progress.Count, "size", common.StorageSize(progress.Size), "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
+
+// FinishMigration removes the migration checkpoint from the destination database
+// once the migrated data has been verified.
+func FinishMigration(db ethdb.KeyValueStore) error {
+	progress, err := readMigrationProgress(db)
+	if err != nil {
+		return err
+	}
+	if progress == nil || !progress.Done {
+		return fmt.Errorf("database migration not completed")
+	}
+	return db.Delete(databaseMigrationKey)
+}
+
+// categoryDigest is the number of entries and the cumulative hash of a key
+// category, used to verify a database migration.
+type categoryDigest struct {
+	count  uint64
+	hasher crypto.KeccakState
+}
+
+// digestKeyValueStore computes the digest of every key category of the given
+// store, as reported by the database inspection. Keys equal to skip are left
+// out.
+func digestKeyValueStore(db ethdb.KeyValueStore, skip []byte) (map[string]*categoryDigest, error) {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	var (
+		digests = make(map[string]*categoryDigest)
+		count   uint64
+		start   = time.Now()
+		logged  = time.Now()
+		length  [8]byte
+	)
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if skip != nil && bytes.Equal(key, skip) {
+			continue
+		}
+		category := keyCategory(key, value)
+		digest := digests[category]
+		if digest == nil {
+			digest = &categoryDigest{hasher: crypto.NewKeccakState()}
+			digests[category] = digest
+		}
+		digest.count++
+
+		// Length-prefix the key and value to avoid ambiguities between entries
+		binary.BigEndian.PutUint64(length[:], uint64(len(key)))
+		digest.hasher.Write(length[:])
+		digest.hasher.Write(key)
+		binary.BigEndian.PutUint64(length[:], uint64(len(value)))
+		digest.hasher.Write(length[:])
+		digest.hasher.Write(value)
+
+		count++
+		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
+			log.Info("Hashing database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return digests, it.Error()
+}
+
+// VerifyMigration checks that the source and destination databases of a
+// migration hold the same data, comparing the number of entries and a hash of
+// the contents of every key category.
+func VerifyMigration(src ethdb.KeyValueStore, dst ethdb.KeyValueStore) error {
+	log.Info("Hashing source database")
+	srcDigests, err := digestKeyValueStore(src, nil)
+	if err != nil {
+		return err
+	}
+	log.Info("Hashing migrated database")
+	dstDigests, err := digestKeyValueStore(dst, databaseMigrationKey)
+	if err != nil {
+		return err
+	}
+	var (
+		stats    [][]string
+		mismatch int
+	)
+	names := make([]string, 0, len(inspectCategories)+1)
+	for _, category := range inspectCategories {
+		names = append(names, category.name)
+	}
+	names = append(names, categoryUnaccounted)
+
+	for _, name := range names {
+		srcDigest, dstDigest := srcDigests[name], dstDigests[name]
+		if srcDigest == nil && dstDigest == nil {
+			continue
+		}
+		var (
+			srcCount, dstCount uint64
+			srcHash, dstHash   common.Hash
+		)
+		if srcDigest != nil {
+			srcCount = srcDigest.count
+			srcDigest.hasher.Read(srcHash[:])
+		}
+		if dstDigest != nil {
+			dstCount = dstDigest.count
+			dstDigest.hasher.Read(dstHash[:])
+		}
+		status := "ok"
+		if srcCount != dstCount || srcHash != dstHash {
+			status = "MISMATCH"
+			mismatch++
+			log.Error("Migrated database mismatch", "category", name, "srccount", srcCount, "dstcount", dstCount, "srchash", srcHash, "dsthash", dstHash)
+		}
+		stats = append(stats, []string{name, fmt.Sprintf("%d", srcCount), fmt.Sprintf("%d", dstCount), srcHash.TerminalString(), status})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Category", "Source items", "Migrated items", "Hash", "Status"})
+	table.AppendBulk(stats)
+	table.Render()
+
+	if mismatch > 0 {
+		return fmt.Errorf("%d categories differ between the source and migrated database", mismatch)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// Tests that a database migration copies all entries, resumes from its
+// checkpoint and detects differences between the databases.
+func TestMigrateKeyValueStore(t *testing.T) {
+	src := NewMemoryDatabase()
+	for i := uint64(0); i < 500; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i), Extra: []byte("migration")}
+		WriteHeader(src, header)
+		WriteCanonicalHash(src, header.Hash(), i)
+		code := []byte{byte(i), byte(i >> 8)}
+		WriteCode(src, crypto.Keccak256Hash(code), code)
+		WriteAccountSnapshot(src, crypto.Keccak256Hash(code), bytes.Repeat(code, 10))
+	}
+	WriteHeadHeaderHash(src, common.Hash{0x01})
+	src.Put([]byte("unknown-key"), []byte{0x01})
+
+	// Simulate an interrupted migration, which copied the first few entries
+	dst := NewDatabase(memorydb.New())
+	it := src.NewIterator(nil, nil)
+	progress := new(migrationProgress)
+	for i := 0; i < 100 && it.Next(); i++ {
+		dst.Put(it.Key(), it.Value())
+		progress.Last = common.CopyBytes(it.Key())
+		progress.Count++
+	}
+	it.Release()
+	writeMigrationProgress(dst, progress)
+
+	if err := FinishMigration(dst); err == nil {
+		t.Fatal("unfinished migration finalized")
+	}
+	if err := MigrateKeyValueStore(src, dst); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if progress, _ := readMigrationProgress(dst); progress == nil || !progress.Done || progress.Count != 2502 {
+		t.Fatalf("migration progress mismatch: %+v", progress)
+	}
+	if err := VerifyMigration(src, dst); err != nil {
+		t.Fatalf("failed to verify migration: %v", err)
+	}
+	// Corrupt a single entry and ensure it's detected
+	WriteCode(dst, crypto.Keccak256Hash([]byte{0, 0}), []byte{0xff})
+	if err := VerifyMigration(src, dst); err == nil {
+		t.Fatal("corrupted migration verified")
+	}
+	WriteCode(dst, crypto.Keccak256Hash([]byte{0, 0}), []byte{0, 0})
+
+	if err := FinishMigration(dst); err != nil {
+		t.Fatalf("failed to finish migration: %v", err)
+	}
+	if ok, _ := dst.Has(databaseMigrationKey); ok {
+		t.Fatal("migration checkpoint not removed")
+	}
+}
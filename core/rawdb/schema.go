@@ -79,6 +79,19 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// historyExpiryTailKey tracks the oldest block whose body and receipts are
+	// retained. Everything below it has been pruned from the ancient store.
+	historyExpiryTailKey = []byte("HistoryExpiryTail")
+
+	// receiptsTailKey tracks the oldest block whose receipts were downloaded
+	// during snap sync. Receipts of older blocks were skipped.
+	receiptsTailKey = []byte("ReceiptsTail")
+
+	// databaseMigrationKey tracks the progress of copying a database into one
+	// with a different engine. It only exists in the destination database until
+	// the migration is finished.
+	databaseMigrationKey = []byte("DatabaseMigration")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	// This flag is deprecated, it's kept to avoid reporting errors when inspect
 	// database.
@@ -96,6 +109,10 @@ var (
 	// snapSyncStatusFlagKey flags that status of snap sync.
 	snapSyncStatusFlagKey = []byte("SnapSyncStatus")
 
+	// verkleConversionKey tracks the progress of the merkle to verkle state
+	// conversion across restarts.
+	verkleConversionKey = []byte("VerkleConversion")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -117,6 +134,10 @@ var (
 	TrieNodeStoragePrefix = []byte("O") // TrieNodeStoragePrefix + accountHash + hexPath -> trie node
 	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
 
+	// VerklePrefix is the database prefix of the verkle tree converted from the
+	// merkle state, holding its trie nodes, state ids and journal.
+	VerklePrefix = []byte("v")
+
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
 	genesisPrefix  = []byte("ethereum-genesis-") // genesis state prefix for the db
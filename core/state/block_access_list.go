@@ -0,0 +1,182 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// accountAccesses is the access of an account in a block being assembled.
+type accountAccesses struct {
+	reads    map[common.Hash]struct{}
+	slots    map[common.Hash][]*types.StorageChange
+	balances []*types.BalanceChange
+	nonces   []*types.NonceChange
+	codes    []*types.CodeChange
+}
+
+// BlockAccessListBuilder assembles the block-level access list of a block from
+// the read/write sets its execution phases were tracked into.
+type BlockAccessListBuilder struct {
+	accounts map[common.Address]*accountAccesses
+}
+
+// NewBlockAccessListBuilder creates an empty block-level access list builder.
+func NewBlockAccessListBuilder() *BlockAccessListBuilder {
+	return &BlockAccessListBuilder{
+		accounts: make(map[common.Address]*accountAccesses),
+	}
+}
+
+// account returns the accesses of an account, creating them if needed.
+func (b *BlockAccessListBuilder) account(addr common.Address) *accountAccesses {
+	acc := b.accounts[addr]
+	if acc == nil {
+		acc = &accountAccesses{
+			reads: make(map[common.Hash]struct{}),
+			slots: make(map[common.Hash][]*types.StorageChange),
+		}
+		b.accounts[addr] = acc
+	}
+	return acc
+}
+
+// Add tracks the accesses of the execution phase at the given block access
+// index, from the read/write set it was tracked into and the resulting writes.
+// The phases must be added in order.
+func (b *BlockAccessListBuilder) Add(index uint64, rw *ReadWriteSet, writes map[common.Address]*AccountWrite) {
+	for addr := range rw.origins {
+		b.account(addr)
+	}
+	// Written storage slots are only changed if their value differs from the
+	// one prior to the phase, they are read otherwise
+	for addr, slots := range rw.Storage {
+		acc := b.account(addr)
+		for key, value := range slots {
+			if write := writes[addr]; write != nil {
+				if post, ok := write.Storage[key]; ok && post != value {
+					acc.slots[key] = append(acc.slots[key], &types.StorageChange{Index: index, Value: post})
+					continue
+				}
+			}
+			acc.reads[key] = struct{}{}
+		}
+	}
+	for addr, write := range writes {
+		var (
+			acc    = b.account(addr)
+			origin = rw.origins[addr]
+		)
+		if write.Deleted {
+			if !origin.balance.IsZero() {
+				acc.balances = append(acc.balances, &types.BalanceChange{Index: index, Balance: new(uint256.Int)})
+			}
+			if origin.nonce != 0 {
+				acc.nonces = append(acc.nonces, &types.NonceChange{Index: index, Nonce: 0})
+			}
+			if !bytes.Equal(origin.codeHash, types.EmptyCodeHash.Bytes()) {
+				acc.codes = append(acc.codes, &types.CodeChange{Index: index})
+			}
+			continue
+		}
+		// The balance of the credited accounts was not read, it might have been
+		// different prior to the phase but is changed in any case.
+		changed := !write.Balance.Eq(origin.balance)
+		if write.Credit != nil {
+			changed = !write.Credit.IsZero()
+		}
+		if changed {
+			acc.balances = append(acc.balances, &types.BalanceChange{Index: index, Balance: new(uint256.Int).Set(write.Balance)})
+		}
+		if write.Nonce != origin.nonce {
+			acc.nonces = append(acc.nonces, &types.NonceChange{Index: index, Nonce: write.Nonce})
+		}
+		if write.Fields&AccountCode != 0 && !bytes.Equal(crypto.Keccak256(write.Code), origin.codeHash) {
+			acc.codes = append(acc.codes, &types.CodeChange{Index: index, Code: write.Code})
+		}
+	}
+}
+
+// Build returns the block-level access list of all the phases added.
+func (b *BlockAccessListBuilder) Build() *types.BlockAccessList {
+	list := make(types.BlockAccessList, 0, len(b.accounts))
+	for addr, acc := range b.accounts {
+		access := &types.AccountAccess{
+			Address:        addr,
+			BalanceChanges: acc.balances,
+			NonceChanges:   acc.nonces,
+			CodeChanges:    acc.codes,
+		}
+		for key, changes := range acc.slots {
+			access.StorageChanges = append(access.StorageChanges, &types.SlotChanges{Slot: key, Changes: changes})
+		}
+		slices.SortFunc(access.StorageChanges, func(a, b *types.SlotChanges) int {
+			return a.Slot.Cmp(b.Slot)
+		})
+		for key := range acc.reads {
+			if _, ok := acc.slots[key]; !ok {
+				access.StorageReads = append(access.StorageReads, key)
+			}
+		}
+		slices.SortFunc(access.StorageReads, common.Hash.Cmp)
+		list = append(list, access)
+	}
+	slices.SortFunc(list, func(a, b *types.AccountAccess) int {
+		return a.Address.Cmp(b.Address)
+	})
+	return &list
+}
+
+// ApplyAccessList applies the post-values of the changes of a block-level access
+// list made before the given block access index, as a prediction of the state
+// at that index.
+func (s *StateDB) ApplyAccessList(list types.BlockAccessList, index uint64) {
+	for _, access := range list {
+		addr := access.Address
+		for _, slot := range access.StorageChanges {
+			if i := lastChange(len(slot.Changes), func(i int) uint64 { return slot.Changes[i].Index }, index); i >= 0 {
+				s.SetState(addr, slot.Slot, slot.Changes[i].Value)
+			}
+		}
+		if i := lastChange(len(access.BalanceChanges), func(i int) uint64 { return access.BalanceChanges[i].Index }, index); i >= 0 {
+			s.SetBalance(addr, new(uint256.Int).Set(access.BalanceChanges[i].Balance), tracing.BalanceChangeUnspecified)
+		}
+		if i := lastChange(len(access.NonceChanges), func(i int) uint64 { return access.NonceChanges[i].Index }, index); i >= 0 {
+			s.SetNonce(addr, access.NonceChanges[i].Nonce)
+		}
+		if i := lastChange(len(access.CodeChanges), func(i int) uint64 { return access.CodeChanges[i].Index }, index); i >= 0 {
+			s.SetCode(addr, access.CodeChanges[i].Code)
+		}
+	}
+}
+
+// lastChange returns the position of the last of n changes sorted by block
+// access index made before the given one, -1 if there is none.
+func lastChange(n int, indexAt func(int) uint64, index uint64) int {
+	last := -1
+	for i := 0; i < n && indexAt(i) < index; i++ {
+		last = i
+	}
+	return last
+}
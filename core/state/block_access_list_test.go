@@ -0,0 +1,108 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+func TestBlockAccessListBuilder(t *testing.T) {
+	var (
+		sender   = common.Address{0x01}
+		credited = common.Address{0x02}
+		deployed = common.Address{0x03}
+	)
+	base, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	base.SetBalance(sender, uint256.NewInt(10), tracing.BalanceChangeUnspecified)
+	base.SetState(sender, common.Hash{0x1}, common.Hash{0x1})
+	base.SetState(sender, common.Hash{0x3}, common.Hash{0x3})
+	base.SetBalance(credited, uint256.NewInt(5), tracing.BalanceChangeUnspecified)
+	base.Finalise(true)
+
+	var (
+		statedb = base.Copy()
+		builder = NewBlockAccessListBuilder()
+	)
+	phase := func(index uint64, run func()) {
+		rwset := NewReadWriteSet()
+		statedb.SetReadWriteSet(rwset)
+		run()
+		statedb.Finalise(true)
+		builder.Add(index, rwset, statedb.Writes())
+		statedb.SetReadWriteSet(nil)
+	}
+	phase(1, func() {
+		statedb.SubBalance(sender, uint256.NewInt(3), tracing.BalanceChangeUnspecified)
+		statedb.SetNonce(sender, 1)
+		statedb.SetState(sender, common.Hash{0x1}, common.Hash{0x2})
+		statedb.SetState(sender, common.Hash{0x3}, common.Hash{0x3})
+		statedb.GetState(sender, common.Hash{0x2})
+		statedb.AddBalance(credited, uint256.NewInt(2), tracing.BalanceChangeUnspecified)
+	})
+	phase(2, func() {
+		statedb.SetState(sender, common.Hash{0x1}, common.Hash{0x5})
+		statedb.SetCode(deployed, []byte{0x60, 0x00})
+	})
+	have := builder.Build()
+	want := &types.BlockAccessList{
+		{
+			Address: sender,
+			StorageChanges: []*types.SlotChanges{{
+				Slot:    common.Hash{0x1},
+				Changes: []*types.StorageChange{{Index: 1, Value: common.Hash{0x2}}, {Index: 2, Value: common.Hash{0x5}}},
+			}},
+			StorageReads:   []common.Hash{{0x2}, {0x3}},
+			BalanceChanges: []*types.BalanceChange{{Index: 1, Balance: uint256.NewInt(7)}},
+			NonceChanges:   []*types.NonceChange{{Index: 1, Nonce: 1}},
+		},
+		{
+			Address:        credited,
+			BalanceChanges: []*types.BalanceChange{{Index: 1, Balance: uint256.NewInt(7)}},
+		},
+		{
+			Address:     deployed,
+			CodeChanges: []*types.CodeChange{{Index: 2, Code: []byte{0x60, 0x00}}},
+		},
+	}
+	if have.Hash() != want.Hash() {
+		haveJSON, _ := json.Marshal(have)
+		wantJSON, _ := json.Marshal(want)
+		t.Fatalf("access list mismatch:\nhave %s\nwant %s", haveJSON, wantJSON)
+	}
+	// Applying the access list on top of the base state predicts the state at
+	// any block access index
+	for index, expect := range map[uint64]uint64{1: 0, 2: 1, math.MaxUint64: 1} {
+		predicted := base.Copy()
+		predicted.ApplyAccessList(*have, index)
+		if nonce := predicted.GetNonce(sender); nonce != expect {
+			t.Errorf("index %d: nonce mismatch: have %d, want %d", index, nonce, expect)
+		}
+	}
+	predicted := base.Copy()
+	predicted.ApplyAccessList(*have, math.MaxUint64)
+	if have, want := predicted.IntermediateRoot(true), statedb.IntermediateRoot(true); have != want {
+		t.Fatalf("predicted root mismatch: have %x, want %x", have, want)
+	}
+}
@@ -139,6 +139,10 @@ type Trie interface {
 	// with the node that proves the absence of the key.
 	Prove(key []byte, proofDb ethdb.KeyValueWriter) error
 
+	// Witness returns the set of trie nodes resolved from the database since
+	// the trie was opened, nil for tries that are proven as a whole.
+	Witness() map[string]struct{}
+
 	// IsVerkle returns true if the trie is verkle-tree based
 	IsVerkle() bool
 }
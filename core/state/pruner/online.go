@@ -0,0 +1,579 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+// Phases of an online pruning run.
+const (
+	PhaseIdle       = "idle"
+	PhaseMarking    = "marking"
+	PhaseSweeping   = "sweeping"
+	PhaseCompacting = "compacting"
+	PhaseDone       = "done"
+	PhaseAborted    = "aborted"
+	PhaseFailed     = "failed"
+)
+
+// errAborted is returned if the online pruning was interrupted.
+var errAborted = errors.New("pruning aborted")
+
+// OnlineConfig includes all the configurations for online pruning.
+type OnlineConfig struct {
+	BloomSize uint64        // The Megabytes of memory allocated to bloom-filter
+	BatchSize int           // Number of stale trie nodes deleted in one go
+	Throttle  time.Duration // Pause between two deletion batches
+}
+
+// DefaultOnlineConfig contains the default settings for online pruning.
+var DefaultOnlineConfig = OnlineConfig{
+	BloomSize: 2048,
+	BatchSize: 10000,
+	Throttle:  100 * time.Millisecond,
+}
+
+// OnlineProgress is a snapshot of the status of an online pruning run.
+type OnlineProgress struct {
+	Phase   string
+	Root    common.Hash // Persisted state root marked in full
+	Roots   int         // Number of in-memory state roots marked
+	Started time.Time
+	Elapsed time.Duration
+
+	Marked  uint64             // Trie nodes marked by walking the retained states
+	Flushed uint64             // Trie nodes marked when persisted during the run
+	Swept   uint64             // Trie nodes checked against the marked set
+	Deleted uint64             // Stale trie nodes deleted
+	Size    common.StorageSize // Approximate size of the stale trie nodes
+
+	Err string // Failure reason if the run failed
+}
+
+// OnlinePruner removes the stale trie nodes of a hash-based state database
+// while the node keeps running. Contrary to the offline pruner it can't rely on
+// the snapshot, as that keeps moving while blocks are imported. Instead:
+//
+//   - the live trie nodes are marked in a bloom filter, walking a persisted
+//     state in full and the recent in-memory states as a difference against it
+//   - every trie node flushed from the memory database while the pruning is
+//     running is marked too, so the new states are retained
+//   - all unmarked trie nodes on disk are deleted in throttled batches, in
+//     lockstep with the flushes of the memory database
+//
+// Contract codes are not pruned. The codes stored under the legacy scheme share
+// the key space of the trie nodes though, so the codes of the live states are
+// marked as well.
+type OnlinePruner struct {
+	config OnlineConfig
+	db     ethdb.Database
+	triedb *triedb.Database
+
+	bloom     *stateBloom
+	bloomLock sync.Mutex // Protects the bloom, written by the marker and the flush hook
+
+	lock     sync.Mutex
+	progress OnlineProgress // Phase and timing of the run, the counters are tracked below
+	ended    time.Time
+
+	marked  atomic.Uint64
+	flushed atomic.Uint64
+	swept   atomic.Uint64
+	deleted atomic.Uint64
+	size    atomic.Uint64
+
+	pinned []common.Hash // State roots referenced in the memory database until marked
+	abort  chan struct{}
+	done   chan struct{}
+	err    error
+}
+
+// NewOnlinePruner creates an online pruner for the given hash-based database.
+func NewOnlinePruner(db ethdb.Database, triedb *triedb.Database, config OnlineConfig) (*OnlinePruner, error) {
+	if triedb.Scheme() != rawdb.HashScheme {
+		return nil, errors.New("online pruning is only supported by the hash scheme")
+	}
+	// Sanitize the bloom filter size if it's too small.
+	if config.BloomSize < 256 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+		config.BloomSize = 256
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultOnlineConfig.BatchSize
+	}
+	bloom, err := newStateBloomWithSize(config.BloomSize)
+	if err != nil {
+		return nil, err
+	}
+	return &OnlinePruner{
+		config:   config,
+		db:       db,
+		triedb:   triedb,
+		bloom:    bloom,
+		progress: OnlineProgress{Phase: PhaseIdle},
+		abort:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start begins the pruning in the background. The base state, which must be
+// persisted on disk, is marked in full. The other given states are marked as a
+// difference against it, they are pinned in the memory database until then.
+//
+// The caller must ensure that no trie nodes are flushed between selecting the
+// states to retain and Start returning.
+func (p *OnlinePruner) Start(base common.Hash, roots []common.Hash) error {
+	if !rawdb.HasLegacyTrieNode(p.db, base) {
+		return fmt.Errorf("state %x is not persisted", base)
+	}
+	if err := p.triedb.SetFlushHook(p.onFlush); err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if err := p.triedb.Reference(root, common.Hash{}); err != nil {
+			p.triedb.SetFlushHook(nil)
+			p.unpin()
+			return err
+		}
+		p.pinned = append(p.pinned, root)
+	}
+	p.lock.Lock()
+	p.progress = OnlineProgress{
+		Phase:   PhaseMarking,
+		Root:    base,
+		Roots:   len(roots),
+		Started: time.Now(),
+	}
+	p.lock.Unlock()
+
+	log.Info("Started online state pruning", "root", base, "roots", len(roots))
+	go p.run(base, roots)
+	return nil
+}
+
+// Progress returns the current status of the pruning.
+func (p *OnlinePruner) Progress() OnlineProgress {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	progress := p.progress
+	switch {
+	case !p.ended.IsZero():
+		progress.Elapsed = p.ended.Sub(progress.Started)
+	case !progress.Started.IsZero():
+		progress.Elapsed = time.Since(progress.Started)
+	}
+	progress.Marked = p.marked.Load()
+	progress.Flushed = p.flushed.Load()
+	progress.Swept = p.swept.Load()
+	progress.Deleted = p.deleted.Load()
+	progress.Size = common.StorageSize(p.size.Load())
+	return progress
+}
+
+// Abort interrupts the pruning and waits for it to stop. Aborting is safe at
+// any point, the nodes deleted so far are all stale.
+func (p *OnlinePruner) Abort() {
+	select {
+	case <-p.abort:
+	default:
+		close(p.abort)
+	}
+	p.Wait()
+}
+
+// Wait blocks until the pruning terminates and returns its result.
+func (p *OnlinePruner) Wait() error {
+	p.lock.Lock()
+	started := !p.progress.Started.IsZero()
+	p.lock.Unlock()
+
+	if !started {
+		return nil
+	}
+	<-p.done
+	return p.err
+}
+
+// Running reports whether the pruning is still in progress.
+func (p *OnlinePruner) Running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		return !p.progress.Started.IsZero()
+	}
+}
+
+// run is the body of the pruning, executed on its own goroutine.
+func (p *OnlinePruner) run(base common.Hash, roots []common.Hash) {
+	defer close(p.done)
+
+	err := p.prune(base, roots)
+
+	// Stop tracking the flushes and release any pinned states left
+	p.triedb.SetFlushHook(nil)
+	p.unpin()
+
+	p.lock.Lock()
+	p.ended = time.Now()
+	switch {
+	case err == nil:
+		p.progress.Phase = PhaseDone
+		log.Info("Online state pruning finished", "deleted", p.deleted.Load(), "size", common.StorageSize(p.size.Load()), "elapsed", common.PrettyDuration(p.ended.Sub(p.progress.Started)))
+	case errors.Is(err, errAborted):
+		p.progress.Phase = PhaseAborted
+		log.Warn("Online state pruning aborted", "deleted", p.deleted.Load(), "size", common.StorageSize(p.size.Load()))
+	default:
+		p.progress.Phase = PhaseFailed
+		p.progress.Err = err.Error()
+		log.Error("Online state pruning failed", "err", err)
+	}
+	p.lock.Unlock()
+	p.err = err
+}
+
+// setPhase updates the phase of the pruning.
+func (p *OnlinePruner) setPhase(phase string) {
+	p.lock.Lock()
+	p.progress.Phase = phase
+	p.lock.Unlock()
+}
+
+// prune marks the retained states, then sweeps and compacts the database.
+func (p *OnlinePruner) prune(base common.Hash, roots []common.Hash) error {
+	start := time.Now()
+
+	// Mark the in-memory states first, releasing each as soon as it's done so
+	// the memory database can garbage collect it as usual. Nodes shared with
+	// the base state are skipped, they are marked along with the base.
+	for _, root := range roots {
+		if err := p.markDifference(base, root); err != nil {
+			return fmt.Errorf("failed to mark state %x: %w", root, err)
+		}
+		p.triedb.Dereference(p.pinned[0])
+		p.pinned = p.pinned[1:]
+	}
+	if err := p.markTrie(base); err != nil {
+		return fmt.Errorf("failed to mark state %x: %w", base, err)
+	}
+	// The genesis state is always retained, same as by the offline pruner
+	if hash := rawdb.ReadCanonicalHash(p.db, 0); hash != (common.Hash{}) {
+		if header := rawdb.ReadHeader(p.db, hash, 0); header != nil && rawdb.HasLegacyTrieNode(p.db, header.Root) {
+			if err := p.markTrie(header.Root); err != nil {
+				return fmt.Errorf("failed to mark genesis state: %w", err)
+			}
+		}
+	}
+	log.Info("Marked live state", "nodes", p.marked.Load(), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// All retained states are marked, new ones are tracked by the flush hook
+	p.setPhase(PhaseSweeping)
+	if err := p.sweep(); err != nil {
+		return err
+	}
+	if p.deleted.Load() < rangeCompactionThreshold {
+		return nil
+	}
+	p.setPhase(PhaseCompacting)
+	return p.compact()
+}
+
+// onFlush is the flush hook of the memory database, marking the nodes persisted
+// while the pruning is running.
+func (p *OnlinePruner) onFlush(hash common.Hash) {
+	p.bloomLock.Lock()
+	p.bloom.Put(hash.Bytes(), nil)
+	p.bloomLock.Unlock()
+
+	p.flushed.Add(1)
+}
+
+// mark adds a live trie node to the bloom filter.
+func (p *OnlinePruner) mark(hash common.Hash) {
+	p.bloomLock.Lock()
+	p.bloom.Put(hash.Bytes(), nil)
+	p.bloomLock.Unlock()
+
+	p.marked.Add(1)
+}
+
+// live reports whether a trie node is possibly live. It's invoked by the memory
+// database while holding its lock.
+func (p *OnlinePruner) live(hash common.Hash) bool {
+	p.bloomLock.Lock()
+	defer p.bloomLock.Unlock()
+
+	return p.bloom.Contain(hash.Bytes())
+}
+
+// aborted reports whether the pruning was requested to stop.
+func (p *OnlinePruner) aborted() bool {
+	select {
+	case <-p.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// unpin releases the references held on the retained states.
+func (p *OnlinePruner) unpin() {
+	for _, root := range p.pinned {
+		p.triedb.Dereference(root)
+	}
+	p.pinned = nil
+}
+
+// markNodes marks all the nodes reached by the iterator. If an account callback
+// is given, it's invoked for every leaf.
+func (p *OnlinePruner) markNodes(it trie.NodeIterator, onAccount func(key []byte, account *types.StateAccount) error) error {
+	for it.Next(true) {
+		if p.aborted() {
+			return errAborted
+		}
+		// Embedded nodes don't have hash.
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			p.mark(hash)
+		}
+		if it.Leaf() && onAccount != nil {
+			var account types.StateAccount
+			if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+				return err
+			}
+			if err := onAccount(it.LeafKey(), &account); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
+
+// markTrie marks all the nodes of the given state, including the storage tries.
+func (p *OnlinePruner) markTrie(root common.Hash) error {
+	t, err := trie.New(trie.StateTrieID(root), p.triedb)
+	if err != nil {
+		return err
+	}
+	it, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	return p.markNodes(it, func(key []byte, account *types.StateAccount) error {
+		p.markCode(account)
+		return p.markStorage(root, common.BytesToHash(key), types.EmptyRootHash, account.Root)
+	})
+}
+
+// markDifference marks the nodes of the given state which are not present at
+// the same position in the base state. Subtries shared by the two states are
+// skipped.
+func (p *OnlinePruner) markDifference(base common.Hash, root common.Hash) error {
+	if root == base {
+		return nil
+	}
+	rootTrie, err := trie.New(trie.StateTrieID(root), p.triedb)
+	if err != nil {
+		// The state might have been dropped from memory before it could be
+		// pinned. It's not retained anymore, so there's nothing to mark.
+		log.Debug("Skipping unavailable state", "root", root, "err", err)
+		return nil
+	}
+	baseTrie, err := trie.New(trie.StateTrieID(base), p.triedb)
+	if err != nil {
+		return err
+	}
+	// A separate trie is used to look up the base accounts, the one above is
+	// being iterated.
+	lookup, err := trie.New(trie.StateTrieID(base), p.triedb)
+	if err != nil {
+		return err
+	}
+	a, err := baseTrie.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	b, err := rootTrie.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	it, _ := trie.NewDifferenceIterator(a, b)
+	return p.markNodes(it, func(key []byte, account *types.StateAccount) error {
+		p.markCode(account)
+
+		baseRoot := types.EmptyRootHash
+		blob, err := lookup.Get(key)
+		if err != nil {
+			return err
+		}
+		if len(blob) > 0 {
+			var baseAccount types.StateAccount
+			if err := rlp.DecodeBytes(blob, &baseAccount); err != nil {
+				return err
+			}
+			baseRoot = baseAccount.Root
+		}
+		return p.markStorage(root, common.BytesToHash(key), baseRoot, account.Root)
+	})
+}
+
+// markCode marks the contract code of an account, which might be stored under
+// the legacy scheme, keyed by its raw hash.
+func (p *OnlinePruner) markCode(account *types.StateAccount) {
+	if !bytes.Equal(account.CodeHash, types.EmptyCodeHash.Bytes()) {
+		p.mark(common.BytesToHash(account.CodeHash))
+	}
+}
+
+// markStorage marks the nodes of a storage trie which are not present at the
+// same position in the base storage trie of the account.
+func (p *OnlinePruner) markStorage(stateRoot common.Hash, owner common.Hash, base common.Hash, root common.Hash) error {
+	if root == types.EmptyRootHash || root == base {
+		return nil
+	}
+	t, err := trie.New(trie.StorageTrieID(stateRoot, owner, root), p.triedb)
+	if err != nil {
+		return err
+	}
+	it, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	if base != types.EmptyRootHash {
+		baseTrie, err := trie.New(trie.StorageTrieID(stateRoot, owner, base), p.triedb)
+		if err != nil {
+			return err
+		}
+		baseIt, err := baseTrie.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		it, _ = trie.NewDifferenceIterator(baseIt, it)
+	}
+	return p.markNodes(it, nil)
+}
+
+// sweep deletes all the trie nodes on disk which are not marked.
+func (p *OnlinePruner) sweep() error {
+	var (
+		pending []common.Hash
+		logged  = time.Now()
+		start   = time.Now()
+		iter    = p.db.NewIterator(nil, nil)
+	)
+	defer func() { iter.Release() }()
+
+	flush := func() error {
+		// The candidates are checked again while holding the lock of the
+		// memory database, as some might have been flushed in the meantime.
+		n, err := p.triedb.DeleteNodes(pending, p.live)
+		if err != nil {
+			return err
+		}
+		p.deleted.Add(uint64(n))
+		pending = pending[:0]
+
+		// Give way to the chain import and the database compactor
+		select {
+		case <-p.abort:
+			return errAborted
+		case <-time.After(p.config.Throttle):
+			return nil
+		}
+	}
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		p.swept.Add(1)
+
+		hash := common.BytesToHash(key)
+		if p.live(hash) {
+			continue
+		}
+		pending = append(pending, hash)
+		p.size.Add(uint64(len(key) + len(iter.Value())))
+
+		if len(pending) >= p.config.BatchSize {
+			// Release the iterator while deleting to allow the compactor to
+			// drop the entries, recreate it right after the last checked key.
+			next := append(common.CopyBytes(key), 0x00)
+			iter.Release()
+
+			if err := flush(); err != nil {
+				return err
+			}
+			iter = p.db.NewIterator(nil, next)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "nodes", p.deleted.Load(), "size", common.StorageSize(p.size.Load()), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	log.Info("Pruned state data", "nodes", p.deleted.Load(), "size", common.StorageSize(p.size.Load()), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// compact runs a range compaction over the whole database to release the space
+// of the deleted nodes.
+func (p *OnlinePruner) compact() error {
+	start := time.Now()
+	for b := 0x00; b <= 0xf0; b += 0x10 {
+		if p.aborted() {
+			return errAborted
+		}
+		var (
+			from = []byte{byte(b)}
+			to   = []byte{byte(b + 0x10)}
+		)
+		if b == 0xf0 {
+			to = nil
+		}
+		log.Info("Compacting database", "range", fmt.Sprintf("%#x-%#x", from, to), "elapsed", common.PrettyDuration(time.Since(start)))
+		if err := p.db.Compact(from, to); err != nil {
+			return err
+		}
+	}
+	log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
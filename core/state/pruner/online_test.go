@@ -0,0 +1,291 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+// updateState applies a round of modifications on top of the given state and
+// commits the result into the memory database.
+func updateState(t *testing.T, sdb state.Database, root common.Hash, round int) common.Hash {
+	statedb, err := state.New(root, sdb, nil)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	for i := 0; i < 200; i++ {
+		addr := common.BytesToAddress([]byte{byte(i), byte(i >> 8)})
+		if i%(round+1) != 0 {
+			continue
+		}
+		statedb.SetBalance(addr, uint256.NewInt(uint64(round*1000+i)), 0)
+		statedb.SetNonce(addr, uint64(round))
+		if i%10 == 0 {
+			for j := 0; j < 20; j++ {
+				statedb.SetState(addr, common.BytesToHash([]byte{byte(j)}), common.BytesToHash([]byte{byte(round), byte(i), byte(j)}))
+			}
+		}
+	}
+	root, err = statedb.Commit(uint64(round), false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	return root
+}
+
+// countTrieNodes returns the number of legacy trie nodes on disk.
+func countTrieNodes(db ethdb.Database) int {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	var count int
+	for it.Next() {
+		if len(it.Key()) == common.HashLength {
+			count++
+		}
+	}
+	return count
+}
+
+// checkState ensures that all the nodes of a state are available.
+func checkState(t *testing.T, tdb *triedb.Database, root common.Hash) {
+	t.Helper()
+
+	tr, err := trie.New(trie.StateTrieID(root), tdb)
+	if err != nil {
+		t.Fatalf("state %x is not available: %v", root, err)
+	}
+	it, err := tr.NodeIterator(nil)
+	if err != nil {
+		t.Fatalf("failed to iterate state %x: %v", root, err)
+	}
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			t.Fatalf("invalid account: %v", err)
+		}
+		if account.Root == types.EmptyRootHash {
+			continue
+		}
+		st, err := trie.New(trie.StorageTrieID(root, common.BytesToHash(it.LeafKey()), account.Root), tdb)
+		if err != nil {
+			t.Fatalf("storage of %x is not available: %v", it.LeafKey(), err)
+		}
+		sit, err := st.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("failed to iterate storage of %x: %v", it.LeafKey(), err)
+		}
+		for sit.Next(true) {
+		}
+		if err := sit.Error(); err != nil {
+			t.Fatalf("storage of %x is incomplete: %v", it.LeafKey(), err)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("state %x is incomplete: %v", root, err)
+	}
+}
+
+func TestOnlinePruning(t *testing.T) {
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		tdb = triedb.NewDatabase(db, triedb.HashDefaults)
+		sdb = state.NewDatabaseWithNodeDB(db, tdb)
+	)
+	// Persist a couple of states, only the last of which is retained
+	var stale []common.Hash
+	root := types.EmptyRootHash
+	for round := 1; round <= 3; round++ {
+		root = updateState(t, sdb, root, round)
+		if err := tdb.Commit(root, false); err != nil {
+			t.Fatalf("failed to persist state: %v", err)
+		}
+		stale = append(stale, root)
+	}
+	base := stale[len(stale)-1]
+	stale = stale[:len(stale)-1]
+
+	// Keep a few more recent states in memory only
+	var recent []common.Hash
+	for round := 4; round <= 6; round++ {
+		root = updateState(t, sdb, root, round)
+		tdb.Reference(root, common.Hash{})
+		recent = append(recent, root)
+	}
+	before := countTrieNodes(db)
+
+	p, err := NewOnlinePruner(db, tdb, OnlineConfig{BloomSize: 256, BatchSize: 16})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := p.Start(base, recent); err != nil {
+		t.Fatalf("failed to start pruning: %v", err)
+	}
+	// Flush new states while the pruning is running, they must be retained
+	var flushed []common.Hash
+	for round := 7; round <= 9; round++ {
+		root = updateState(t, sdb, root, round)
+		if err := tdb.Commit(root, false); err != nil {
+			t.Fatalf("failed to persist state: %v", err)
+		}
+		flushed = append(flushed, root)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("pruning failed: %v", err)
+	}
+	progress := p.Progress()
+	if progress.Phase != PhaseDone {
+		t.Fatalf("unexpected phase: have %s, want %s", progress.Phase, PhaseDone)
+	}
+	if progress.Deleted == 0 || progress.Deleted >= uint64(before) {
+		t.Fatalf("unexpected deletions: %d out of %d nodes", progress.Deleted, before)
+	}
+	// All retained states must be complete, the stale ones gone
+	for _, root := range append(append([]common.Hash{base}, recent...), flushed...) {
+		checkState(t, tdb, root)
+	}
+	for _, root := range stale {
+		if rawdb.HasLegacyTrieNode(db, root) {
+			t.Errorf("stale state %x not pruned", root)
+		}
+	}
+}
+
+// Tests that contract codes stored under the legacy scheme, keyed by their raw
+// hash like the trie nodes, are retained for the live states.
+func TestOnlinePruningLegacyCode(t *testing.T) {
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		tdb = triedb.NewDatabase(db, triedb.HashDefaults)
+		sdb = state.NewDatabaseWithNodeDB(db, tdb)
+	)
+	// deploy sets the code of a contract and moves it to the legacy scheme once
+	// the state is committed.
+	deploy := func(root common.Hash, addr common.Address, code []byte) common.Hash {
+		statedb, err := state.New(root, sdb, nil)
+		if err != nil {
+			t.Fatalf("failed to open state %x: %v", root, err)
+		}
+		statedb.SetCode(addr, code)
+		root, err = statedb.Commit(0, false)
+		if err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		hash := crypto.Keccak256Hash(code)
+		rawdb.DeleteCode(db, hash)
+		if err := db.Put(hash.Bytes(), code); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+	var (
+		baseCode   = []byte{0x60, 0x01}
+		recentCode = []byte{0x60, 0x02}
+	)
+	root := updateState(t, sdb, types.EmptyRootHash, 1)
+	base := deploy(root, common.Address{0xaa}, baseCode)
+	if err := tdb.Commit(base, false); err != nil {
+		t.Fatalf("failed to persist state: %v", err)
+	}
+	// The recent state is marked as a difference against the base
+	recent := deploy(base, common.Address{0xbb}, recentCode)
+	tdb.Reference(recent, common.Hash{})
+
+	p, err := NewOnlinePruner(db, tdb, OnlineConfig{BloomSize: 256})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := p.Start(base, []common.Hash{recent}); err != nil {
+		t.Fatalf("failed to start pruning: %v", err)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("pruning failed: %v", err)
+	}
+	for _, code := range [][]byte{baseCode, recentCode} {
+		if have := rawdb.ReadCode(db, crypto.Keccak256Hash(code)); !bytes.Equal(have, code) {
+			t.Errorf("legacy code %x pruned", code)
+		}
+	}
+	checkState(t, tdb, base)
+	checkState(t, tdb, recent)
+}
+
+func TestOnlinePruningAbort(t *testing.T) {
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		tdb = triedb.NewDatabase(db, triedb.HashDefaults)
+		sdb = state.NewDatabaseWithNodeDB(db, tdb)
+	)
+	root := types.EmptyRootHash
+	for round := 1; round <= 3; round++ {
+		root = updateState(t, sdb, root, round)
+		if err := tdb.Commit(root, false); err != nil {
+			t.Fatalf("failed to persist state: %v", err)
+		}
+	}
+	recent := updateState(t, sdb, root, 4)
+	tdb.Reference(recent, common.Hash{})
+
+	p, err := NewOnlinePruner(db, tdb, OnlineConfig{BloomSize: 256})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := p.Start(root, []common.Hash{recent}); err != nil {
+		t.Fatalf("failed to start pruning: %v", err)
+	}
+	p.Abort()
+
+	if p.Running() {
+		t.Fatal("pruning still running after abort")
+	}
+	if phase := p.Progress().Phase; phase != PhaseAborted && phase != PhaseDone {
+		t.Fatalf("unexpected phase: %s", phase)
+	}
+	// The pinned state must have been released, the memory database should
+	// be empty once the chain drops its own reference too.
+	tdb.Dereference(recent)
+	if _, nodes, _ := tdb.Size(); nodes != 0 {
+		t.Fatalf("dangling nodes after abort: %v", nodes)
+	}
+	checkState(t, tdb, root)
+}
+
+func TestOnlinePruningPathScheme(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	tdb := triedb.NewDatabase(db, &triedb.Config{PathDB: pathdb.Defaults})
+	defer tdb.Close()
+
+	if _, err := NewOnlinePruner(db, tdb, OnlineConfig{BloomSize: 256}); err == nil {
+		t.Fatal("online pruning accepted the path scheme")
+	}
+}
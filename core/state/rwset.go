@@ -0,0 +1,319 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// AccountFields is a bitmask of the fields of an account.
+type AccountFields uint8
+
+const (
+	AccountBalance   AccountFields = 1 << iota // Balance of the account
+	AccountNonce                               // Nonce of the account
+	AccountCode                                // Code of the account
+	AccountExistence                           // Whether the account exists
+	AccountStorage                             // Storage of the account as a whole
+
+	AccountAll = AccountBalance | AccountNonce | AccountCode | AccountExistence | AccountStorage
+)
+
+// accountOrigin is the state of an account prior to its first access.
+type accountOrigin struct {
+	exist    bool
+	balance  *uint256.Int
+	nonce    uint64
+	codeHash []byte
+	root     common.Hash
+}
+
+// matches reports whether the given fields of the origin are the same as the
+// ones of the given account, nil if it doesn't exist.
+func (origin *accountOrigin) matches(obj *stateObject, fields AccountFields) bool {
+	var (
+		exist    = obj != nil
+		balance  = common.U2560
+		nonce    uint64
+		codeHash = types.EmptyCodeHash.Bytes()
+		root     common.Hash
+	)
+	if exist {
+		balance, nonce, codeHash, root = obj.Balance(), obj.Nonce(), obj.CodeHash(), obj.Root()
+	}
+	switch {
+	case fields&AccountExistence != 0 && exist != origin.exist:
+		return false
+	case fields&AccountBalance != 0 && !balance.Eq(origin.balance):
+		return false
+	case fields&AccountNonce != 0 && nonce != origin.nonce:
+		return false
+	case fields&AccountCode != 0 && !bytes.Equal(codeHash, origin.codeHash):
+		return false
+	case fields&AccountStorage != 0 && root != origin.root:
+		return false
+	}
+	return true
+}
+
+// ReadWriteSet records the state read and mutated through a StateDB, typically
+// during the execution of a single transaction. Reads are tracked per account
+// field and storage slot along with the values prior to the tracking, and a
+// mutation of a field counts as a read of it, except for balance credits: these
+// are commutative, so an account which is credited without its balance being
+// read can be credited on top of any other balance.
+type ReadWriteSet struct {
+	Accounts map[common.Address]AccountFields               // Account fields read
+	Storage  map[common.Address]map[common.Hash]common.Hash // Storage slots read, with their original values
+
+	origins map[common.Address]*accountOrigin           // State of the accessed accounts prior to the first access
+	created map[common.Address]struct{}                 // Accounts (re)created, wiping their storage
+	slots   map[common.Address]map[common.Hash]struct{} // Storage slots written
+}
+
+// NewReadWriteSet creates an empty read/write set.
+func NewReadWriteSet() *ReadWriteSet {
+	return &ReadWriteSet{
+		Accounts: make(map[common.Address]AccountFields),
+		Storage:  make(map[common.Address]map[common.Hash]common.Hash),
+		origins:  make(map[common.Address]*accountOrigin),
+		created:  make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// readAccount tracks the read of some account fields.
+func (rw *ReadWriteSet) readAccount(addr common.Address, fields AccountFields) {
+	rw.Accounts[addr] |= fields
+}
+
+// writeSlot tracks the write of a storage slot.
+func (rw *ReadWriteSet) writeSlot(addr common.Address, key common.Hash) {
+	slots := rw.slots[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]struct{})
+		rw.slots[addr] = slots
+	}
+	slots[key] = struct{}{}
+}
+
+// AccountWrite is the post-state of an account mutated by the writes of a
+// read/write set.
+type AccountWrite struct {
+	Fields  AccountFields // Account fields altered by the writes
+	Deleted bool          // Whether the account was deleted
+	Created bool          // Whether the account was (re)created, wiping its storage
+
+	Balance *uint256.Int                // Balance of the account
+	Credit  *uint256.Int                // Amount credited to the account, nil if its balance was read
+	Nonce   uint64                      // Nonce of the account
+	Code    []byte                      // Code of the account
+	Storage map[common.Hash]common.Hash // Values of the written storage slots
+}
+
+// SetReadWriteSet starts tracking the state read and mutated into the given
+// set, nil to stop tracking.
+func (s *StateDB) SetReadWriteSet(rw *ReadWriteSet) {
+	s.rwset = rw
+}
+
+// trackRead tracks the read of some account fields, if tracking is enabled.
+func (s *StateDB) trackRead(addr common.Address, fields AccountFields) {
+	if s.rwset != nil {
+		s.trackOrigin(addr)
+		s.rwset.readAccount(addr, fields)
+	}
+}
+
+// trackWrite tracks an upcoming mutation of an account, along with the fields
+// the mutation depends on, if tracking is enabled.
+func (s *StateDB) trackWrite(addr common.Address, fields AccountFields) {
+	if s.rwset == nil {
+		return
+	}
+	s.trackOrigin(addr)
+	if fields != 0 {
+		s.rwset.readAccount(addr, fields)
+	}
+}
+
+// trackOrigin captures the state of an account prior to its first access.
+func (s *StateDB) trackOrigin(addr common.Address) {
+	if _, ok := s.rwset.origins[addr]; ok {
+		return
+	}
+	origin := &accountOrigin{
+		balance:  new(uint256.Int),
+		codeHash: types.EmptyCodeHash.Bytes(),
+	}
+	if obj := s.getStateObject(addr); obj != nil {
+		origin.exist = true
+		origin.balance = new(uint256.Int).Set(obj.Balance())
+		origin.nonce = obj.Nonce()
+		origin.codeHash = obj.CodeHash()
+		origin.root = obj.Root()
+	}
+	s.rwset.origins[addr] = origin
+}
+
+// trackSlot tracks the read of a storage slot, capturing its value prior to
+// the first access, if tracking is enabled.
+func (s *StateDB) trackSlot(addr common.Address, key common.Hash) {
+	if s.rwset == nil {
+		return
+	}
+	slots := s.rwset.Storage[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]common.Hash)
+		s.rwset.Storage[addr] = slots
+	}
+	if _, ok := slots[key]; ok {
+		return
+	}
+	// The storage of the accounts (re)created since the tracking started is
+	// empty, regardless of what it was before.
+	var value common.Hash
+	if _, ok := s.rwset.created[addr]; !ok {
+		if obj := s.getStateObject(addr); obj != nil {
+			value = obj.GetCommittedState(key)
+		}
+	}
+	slots[key] = value
+}
+
+// ValidateReads reports whether the state read into the given set has the same
+// values in this state as it had prior to the tracking. If so, the writes of
+// the set can be applied on top of this state as they are.
+func (s *StateDB) ValidateReads(rw *ReadWriteSet) bool {
+	for addr, fields := range rw.Accounts {
+		if !rw.origins[addr].matches(s.getStateObject(addr), fields) {
+			return false
+		}
+	}
+	for addr, slots := range rw.Storage {
+		obj := s.getStateObject(addr)
+		for key, value := range slots {
+			var have common.Hash
+			if obj != nil {
+				have = obj.GetCommittedState(key)
+			}
+			if have != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Writes returns the post-state of all the accounts mutated since the read/write
+// set tracking started. It must be called after the state is finalised. As the
+// deletion of an account depends on all its fields, these are considered read.
+func (s *StateDB) Writes() map[common.Address]*AccountWrite {
+	if s.rwset == nil {
+		return nil
+	}
+	writes := make(map[common.Address]*AccountWrite, len(s.rwset.origins))
+	for addr, origin := range s.rwset.origins {
+		var (
+			obj       = s.getStateObject(addr)
+			_, create = s.rwset.created[addr]
+		)
+		if obj == nil {
+			if origin.exist {
+				writes[addr] = &AccountWrite{Fields: AccountAll, Deleted: true}
+				s.rwset.readAccount(addr, AccountAll)
+			}
+			continue
+		}
+		write := &AccountWrite{
+			Created: create,
+			Balance: new(uint256.Int).Set(obj.Balance()),
+			Nonce:   obj.Nonce(),
+		}
+		if create {
+			write.Fields = AccountAll
+		} else {
+			if !origin.exist {
+				write.Fields |= AccountExistence
+			}
+			if !write.Balance.Eq(origin.balance) {
+				write.Fields |= AccountBalance
+			}
+			if write.Nonce != origin.nonce {
+				write.Fields |= AccountNonce
+			}
+			if !bytes.Equal(obj.CodeHash(), origin.codeHash) {
+				write.Fields |= AccountCode
+			}
+		}
+		if write.Fields&AccountCode != 0 {
+			write.Code = obj.Code()
+		}
+		if s.rwset.Accounts[addr]&AccountBalance == 0 && !create {
+			write.Credit = new(uint256.Int).Sub(write.Balance, origin.balance)
+		}
+		if slots := s.rwset.slots[addr]; len(slots) > 0 {
+			write.Fields |= AccountStorage
+			write.Storage = make(map[common.Hash]common.Hash, len(slots))
+			for key := range slots {
+				write.Storage[key] = obj.GetState(key)
+			}
+		}
+		if write.Fields != 0 {
+			writes[addr] = write
+		}
+	}
+	return writes
+}
+
+// ApplyWrites applies the post-state of accounts mutated on top of another
+// state. The writes are only valid if the state read to produce them is the
+// same in both states, except for the balance of the credited accounts, which
+// is updated in the writes to the resulting one.
+func (s *StateDB) ApplyWrites(writes map[common.Address]*AccountWrite) {
+	for addr, write := range writes {
+		if write.Deleted {
+			s.SelfDestruct(addr)
+			continue
+		}
+		if write.Created {
+			s.CreateAccount(addr)
+		}
+		obj := s.getOrNewStateObject(addr)
+		if write.Credit != nil {
+			obj.AddBalance(write.Credit, tracing.BalanceChangeUnspecified)
+			write.Balance = new(uint256.Int).Set(obj.Balance())
+		} else if write.Fields&AccountBalance != 0 {
+			obj.SetBalance(write.Balance, tracing.BalanceChangeUnspecified)
+		}
+		if write.Fields&AccountNonce != 0 {
+			obj.SetNonce(write.Nonce)
+		}
+		if write.Fields&AccountCode != 0 && len(write.Code) > 0 {
+			obj.SetCode(crypto.Keccak256Hash(write.Code), write.Code)
+		}
+		for key, value := range write.Storage {
+			obj.SetState(key, value)
+		}
+	}
+}
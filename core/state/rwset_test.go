@@ -0,0 +1,125 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+func TestReadWriteSet(t *testing.T) {
+	var (
+		credited = common.Address{0x01}
+		sender   = common.Address{0x02}
+		storage  = common.Address{0x03}
+		deleted  = common.Address{0x04}
+		created  = common.Address{0x05}
+	)
+	base, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	base.SetBalance(credited, uint256.NewInt(10), tracing.BalanceChangeUnspecified)
+	base.SetBalance(sender, uint256.NewInt(10), tracing.BalanceChangeUnspecified)
+	base.SetState(storage, common.Hash{0x1}, common.Hash{0x1})
+	base.SetNonce(storage, 1)
+	base.SetBalance(deleted, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	base.Finalise(true)
+
+	// Mutate a copy of the state while tracking the accesses
+	var (
+		statedb = base.Copy()
+		rwset   = NewReadWriteSet()
+	)
+	statedb.SetReadWriteSet(rwset)
+	statedb.AddBalance(credited, uint256.NewInt(5), tracing.BalanceChangeUnspecified)
+	statedb.SubBalance(sender, uint256.NewInt(5), tracing.BalanceChangeUnspecified)
+	statedb.SetNonce(sender, 1)
+	statedb.GetState(storage, common.Hash{0x2})
+	statedb.SetState(storage, common.Hash{0x1}, common.Hash{0x2})
+	statedb.SelfDestruct(deleted)
+	statedb.CreateAccount(created)
+	statedb.SetCode(created, []byte{0x1})
+	statedb.Finalise(true)
+
+	writes := statedb.Writes()
+	if fields := rwset.Accounts[credited]; fields != 0 {
+		t.Errorf("credited account read: %b", fields)
+	}
+	if fields := rwset.Accounts[sender]; fields != AccountBalance|AccountNonce {
+		t.Errorf("sender fields read mismatch: have %b, want %b", fields, AccountBalance|AccountNonce)
+	}
+	if len(rwset.Storage[storage]) != 2 || rwset.Storage[storage][common.Hash{0x1}] != (common.Hash{0x1}) {
+		t.Errorf("storage slots read mismatch: %v", rwset.Storage[storage])
+	}
+	if w := writes[credited]; w == nil || w.Credit == nil || w.Credit.Uint64() != 5 {
+		t.Errorf("credit mismatch: %+v", w)
+	}
+	if w := writes[sender]; w == nil || w.Credit != nil || w.Fields != AccountBalance|AccountNonce {
+		t.Errorf("sender write mismatch: %+v", w)
+	}
+	if w := writes[storage]; w == nil || w.Fields != AccountStorage || w.Storage[common.Hash{0x1}] != (common.Hash{0x2}) {
+		t.Errorf("storage write mismatch: %+v", w)
+	}
+	if w := writes[deleted]; w == nil || !w.Deleted {
+		t.Errorf("deletion mismatch: %+v", w)
+	}
+	if w := writes[created]; w == nil || !w.Created || w.Fields != AccountAll {
+		t.Errorf("creation mismatch: %+v", w)
+	}
+	// The reads are only invalidated by changes of the values read
+	other := base.Copy()
+	other.AddBalance(credited, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	other.SetState(storage, common.Hash{0x3}, common.Hash{0x3})
+	other.SetNonce(storage, 2)
+	other.Finalise(true)
+	if !other.ValidateReads(rwset) {
+		t.Fatal("reads invalidated by unrelated changes")
+	}
+	for i, mutate := range []func(*StateDB){
+		func(s *StateDB) { s.SetNonce(sender, 5) },
+		func(s *StateDB) { s.SetState(storage, common.Hash{0x2}, common.Hash{0x1}) },
+		func(s *StateDB) { s.SetCode(deleted, []byte{0x1}) },
+		func(s *StateDB) { s.SetBalance(created, uint256.NewInt(1), tracing.BalanceChangeUnspecified) },
+	} {
+		invalid := other.Copy()
+		mutate(invalid)
+		invalid.Finalise(true)
+		if invalid.ValidateReads(rwset) {
+			t.Fatalf("mutation %d: reads not invalidated by changes of the values read", i)
+		}
+	}
+	// Apply the writes on top of a state where the credited account changed,
+	// which must be retained.
+	other = base.Copy()
+	other.AddBalance(credited, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	other.Finalise(true)
+	other.ApplyWrites(writes)
+	if have := writes[credited].Balance.Uint64(); have != 115 {
+		t.Fatalf("credited balance mismatch: have %d, want 115", have)
+	}
+	other.Finalise(true)
+
+	expect := statedb.Copy()
+	expect.AddBalance(credited, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	expect.Finalise(true)
+	if have, want := other.IntermediateRoot(true), expect.IntermediateRoot(true); have != want {
+		t.Fatalf("root mismatch: have %x, want %x", have, want)
+	}
+}
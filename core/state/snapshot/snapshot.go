@@ -845,6 +845,12 @@ func (t *Tree) diskRoot() common.Hash {
 	return disklayer.Root()
 }
 
+// Generating reports whether the snapshot is still being constructed, in which
+// case it can't be iterated yet.
+func (t *Tree) Generating() (bool, error) {
+	return t.generating()
+}
+
 // generating is an internal helper function which reports whether the snapshot
 // is still under the construction.
 func (t *Tree) generating() (bool, error) {
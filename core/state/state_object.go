@@ -194,13 +194,18 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 		s.originStorage[key] = common.Hash{} // track the empty slot as origin value
 		return common.Hash{}
 	}
+	// Track the slot leaf for the execution witness, regardless of where the
+	// slot is loaded from.
+	if s.db.witness != nil && s.db.accessEvents != nil {
+		s.db.accessEvents.SlotGas(s.address, key, false)
+	}
 	// If no live objects are available, attempt to use snapshots
 	var (
 		enc   []byte
 		err   error
 		value common.Hash
 	)
-	if s.db.snap != nil {
+	if s.db.snap != nil && s.db.witness == nil {
 		start := time.Now()
 		enc, err = s.db.snap.Storage(s.addrHash, crypto.Keccak256Hash(key.Bytes()))
 		s.db.SnapshotStorageReads += time.Since(start)
@@ -214,7 +219,7 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 		}
 	}
 	// If the snapshot is unavailable or reading from it fails, load from the database.
-	if s.db.snap == nil || err != nil {
+	if s.db.snap == nil || s.db.witness != nil || err != nil {
 		start := time.Now()
 		tr, err := s.getTrie()
 		if err != nil {
@@ -322,7 +327,11 @@ func (s *stateObject) updateTrie() (Trie, error) {
 	// Retrieve a pretecher populated trie, or fall back to the database
 	tr := s.getPrefetchedTrie()
 	if tr != nil {
-		// Prefetcher returned a live trie, swap it out for the current one
+		// Prefetcher returned a live trie, swap it out for the current one,
+		// retaining the nodes resolved by the former for the witness
+		if s.db.witness != nil && s.trie != nil {
+			s.db.witness.AddState(s.trie.Witness())
+		}
 		s.trie = tr
 	} else {
 		// Fetcher not running or empty trie, fallback to the database trie
@@ -552,6 +561,9 @@ func (s *stateObject) Code() []byte {
 	if err != nil {
 		s.db.setError(fmt.Errorf("can't load code hash %x: %v", s.CodeHash(), err))
 	}
+	if s.db.witness != nil {
+		s.db.witness.AddCode(code)
+	}
 	s.code = code
 	return code
 }
@@ -566,6 +578,11 @@ func (s *stateObject) CodeSize() int {
 	if bytes.Equal(s.CodeHash(), types.EmptyCodeHash.Bytes()) {
 		return 0
 	}
+	// The stateless execution needs the code to know its size, load it fully
+	// into the witness.
+	if s.db.witness != nil {
+		return len(s.Code())
+	}
 	size, err := s.db.db.ContractCodeSize(s.address, common.BytesToHash(s.CodeHash()))
 	if err != nil {
 		s.db.setError(fmt.Errorf("can't load code size %x: %v", s.CodeHash(), err))
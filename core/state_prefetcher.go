@@ -17,6 +17,7 @@
 package core
 
 import (
+	"math"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/consensus"
@@ -82,6 +83,28 @@ func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, c
 	}
 }
 
+// PrefetchAccessList reads all the state accessed in a block-level access list
+// from the statedb and applies its changes, any changes are discarded. The only
+// goal is to pre-cache the accessed state and the trie nodes needed to hash it.
+func (p *statePrefetcher) PrefetchAccessList(list types.BlockAccessList, statedb *state.StateDB, interrupt *atomic.Bool) {
+	for _, access := range list {
+		// If block precaching was interrupted, abort
+		if interrupt != nil && interrupt.Load() {
+			return
+		}
+		statedb.GetBalance(access.Address)
+		for _, slot := range access.StorageChanges {
+			statedb.GetState(access.Address, slot.Slot)
+		}
+		for _, slot := range access.StorageReads {
+			statedb.GetState(access.Address, slot)
+		}
+	}
+	// Pre-load the trie nodes for the final root hash
+	statedb.ApplyAccessList(list, math.MaxUint64)
+	statedb.IntermediateRoot(true)
+}
+
 // precacheTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. The goal is not to execute
 // the transaction successfully, rather to warm up touched data slots.
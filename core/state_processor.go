@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -37,8 +38,17 @@ import (
 // StateProcessor implements Processor.
 type StateProcessor struct {
 	config *params.ChainConfig // Chain configuration options
-	bc     *BlockChain         // Canonical block chain
+	bc     processorChain      // Canonical block chain
 	engine consensus.Engine    // Consensus engine used for block rewards
+
+	workers int // Number of workers executing transactions in parallel, sequential execution if below two
+}
+
+// processorChain is the chain access required to process a block: the ancestor
+// headers for the EVM and the chain reader for the consensus engine.
+type processorChain interface {
+	ChainContext
+	consensus.ChainHeaderReader
 }
 
 // NewStateProcessor initialises a new StateProcessor.
@@ -50,6 +60,18 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 	}
 }
 
+// NewParallelStateProcessor initialises a new StateProcessor executing the
+// transactions of the blocks speculatively in parallel on the given number of
+// workers.
+func NewParallelStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine, workers int) *StateProcessor {
+	return &StateProcessor{
+		config:  config,
+		bc:      bc,
+		engine:  engine,
+		workers: workers,
+	}
+}
+
 // Process processes the state changes according to the Ethereum rules by running
 // the transaction messages using the statedb and applying any rewards to both
 // the processor (coinbase) and any included uncles.
@@ -57,7 +79,36 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 // Process returns the receipts and logs accumulated during the process and
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
+//
+// If the block carries a block-level access list, it is validated against the
+// one resulting from the execution. As the access list is not covered by the
+// block hash, a mismatching one is only reported and discarded: it can't make
+// an otherwise valid block invalid.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	want := block.AccessList()
+	if want == nil {
+		receipts, logs, usedGas, _, err := p.process(block, statedb, cfg, nil)
+		return receipts, logs, usedGas, err
+	}
+	receipts, logs, usedGas, list, err := p.ProcessWithAccessList(block, statedb, cfg)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if have, want := list.Hash(), want.Hash(); have != want {
+		log.Warn("Discarding mismatching block access list", "number", block.Number(), "hash", block.Hash(), "have", have, "want", want)
+	}
+	return receipts, logs, usedGas, nil
+}
+
+// ProcessWithAccessList processes the state changes like Process, additionally
+// returning the block-level access list of the block.
+func (p *StateProcessor) ProcessWithAccessList(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, *types.BlockAccessList, error) {
+	return p.process(block, statedb, cfg, newAccessListTracker(p.config.IsEIP158(block.Number())))
+}
+
+// process processes the state changes of a block, tracking the accesses of its
+// execution phases into the given tracker, if any.
+func (p *StateProcessor) process(block *types.Block, statedb *state.StateDB, cfg vm.Config, tracker *accessListTracker) (types.Receipts, []*types.Log, uint64, *types.BlockAccessList, error) {
 	var (
 		receipts    types.Receipts
 		usedGas     = new(uint64)
@@ -66,7 +117,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		blockNumber = block.Number()
 		allLogs     []*types.Log
 		gp          = new(GasPool).AddGas(block.GasLimit())
+		txs         = block.Transactions()
 	)
+	tracker.start(statedb)
 
 	// Mutate the block and state according to any hard-fork specs
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
@@ -80,30 +133,45 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	if beaconRoot := block.BeaconRoot(); beaconRoot != nil {
 		ProcessBeaconBlockRoot(*beaconRoot, vmenv, statedb)
 	}
-	// Iterate over and process the individual transactions
-	for i, tx := range block.Transactions() {
-		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
-		if err != nil {
-			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+	tracker.end(statedb, 0)
+
+	// Iterate over and process the individual transactions, in parallel if
+	// enabled and supported by the execution environment
+	if p.parallel(block, statedb, cfg) {
+		var err error
+		if receipts, allLogs, err = p.applyParallel(block, statedb, vmenv, signer, gp, usedGas, tracker); err != nil {
+			return nil, nil, 0, nil, err
 		}
-		statedb.SetTxContext(tx.Hash(), i)
+	} else {
+		for i, tx := range txs {
+			msg, err := TransactionToMessage(tx, signer, header.BaseFee)
+			if err != nil {
+				return nil, nil, 0, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			statedb.SetTxContext(tx.Hash(), i)
+			tracker.start(statedb)
 
-		receipt, err := ApplyTransactionWithEVM(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
-		if err != nil {
-			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			receipt, err := ApplyTransactionWithEVM(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+			if err != nil {
+				return nil, nil, 0, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			tracker.end(statedb, uint64(i+1))
+
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
 		}
-		receipts = append(receipts, receipt)
-		allLogs = append(allLogs, receipt.Logs...)
 	}
 	// Fail if Shanghai not enabled and len(withdrawals) is non-zero.
 	withdrawals := block.Withdrawals()
 	if len(withdrawals) > 0 && !p.config.IsShanghai(block.Number(), block.Time()) {
-		return nil, nil, 0, errors.New("withdrawals before shanghai")
+		return nil, nil, 0, nil, errors.New("withdrawals before shanghai")
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	tracker.start(statedb)
 	p.engine.Finalize(p.bc, header, statedb, block.Body())
+	tracker.end(statedb, uint64(len(txs)+1))
 
-	return receipts, allLogs, *usedGas, nil
+	return receipts, allLogs, *usedGas, tracker.build(), nil
 }
 
 // ApplyTransactionWithEVM attempts to apply a transaction to the given state database
@@ -127,6 +195,7 @@ func ApplyTransactionWithEVM(msg *Message, config *params.ChainConfig, gp *GasPo
 	if err != nil {
 		return nil, err
 	}
+	mergeAccessEvents(statedb, evm)
 
 	// Update the state with pending changes.
 	var root []byte
@@ -137,9 +206,14 @@ func ApplyTransactionWithEVM(msg *Message, config *params.ChainConfig, gp *GasPo
 	}
 	*usedGas += result.UsedGas
 
+	return MakeReceipt(evm, result, statedb, blockNumber, blockHash, tx, *usedGas, root), nil
+}
+
+// MakeReceipt generates the receipt object for a transaction given its execution result.
+func MakeReceipt(evm *vm.EVM, result *ExecutionResult, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas uint64, root []byte) *types.Receipt {
 	// Create a new receipt for the transaction, storing the intermediate root and gas used
 	// by the tx.
-	receipt = &types.Receipt{Type: tx.Type(), PostState: root, CumulativeGasUsed: *usedGas}
+	receipt := &types.Receipt{Type: tx.Type(), PostState: root, CumulativeGasUsed: usedGas}
 	if result.Failed() {
 		receipt.Status = types.ReceiptStatusFailed
 	} else {
@@ -154,7 +228,7 @@ func ApplyTransactionWithEVM(msg *Message, config *params.ChainConfig, gp *GasPo
 	}
 
 	// If the transaction created a contract, store the creation address in the receipt.
-	if msg.To == nil {
+	if tx.To() == nil {
 		receipt.ContractAddress = crypto.CreateAddress(evm.TxContext.Origin, tx.Nonce())
 	}
 
@@ -164,7 +238,7 @@ func ApplyTransactionWithEVM(msg *Message, config *params.ChainConfig, gp *GasPo
 	receipt.BlockHash = blockHash
 	receipt.BlockNumber = blockNumber
 	receipt.TransactionIndex = uint(statedb.TxIndex())
-	return receipt, err
+	return receipt
 }
 
 // ApplyTransaction attempts to apply a transaction to the given state database
@@ -207,5 +281,15 @@ func ProcessBeaconBlockRoot(beaconRoot common.Hash, vmenv *vm.EVM, statedb *stat
 	vmenv.Reset(NewEVMTxContext(msg), statedb)
 	statedb.AddAddressToAccessList(params.BeaconRootsAddress)
 	_, _, _ = vmenv.Call(vm.AccountRef(msg.From), *msg.To, msg.Data, 30_000_000, common.U2560)
+	mergeAccessEvents(statedb, vmenv)
 	statedb.Finalise(true)
 }
+
+// mergeAccessEvents merges the verkle access events of the last message executed
+// by the EVM into the block-wide ones, to collect all the leaves accessed by the
+// block for its execution witness.
+func mergeAccessEvents(statedb *state.StateDB, evm *vm.EVM) {
+	if events := statedb.AccessEvents(); events != nil && evm.AccessEvents != nil {
+		events.Merge(evm.AccessEvents)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	parallelCommitMeter   = metrics.NewRegisteredMeter("chain/execution/parallel/commit", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/execution/parallel/conflict", nil)
+)
+
+// speculation is the outcome of the execution of a transaction on top of a
+// prediction of the state prior to it.
+type speculation struct {
+	msg    *Message
+	evm    *vm.EVM
+	result *ExecutionResult
+	err    error
+
+	rwset     *state.ReadWriteSet
+	writes    map[common.Address]*state.AccountWrite
+	logs      []*types.Log
+	preimages map[common.Hash][]byte
+}
+
+// parallel reports whether the transactions of a block can be executed in
+// parallel. Tracing, witness collection and verkle access events all rely on
+// observing the execution in order, they are only supported sequentially.
+func (p *StateProcessor) parallel(block *types.Block, statedb *state.StateDB, cfg vm.Config) bool {
+	return p.workers > 1 && len(block.Transactions()) > 1 && cfg.Tracer == nil && statedb.Witness() == nil && statedb.AccessEvents() == nil
+}
+
+// applyParallel applies the transactions of a block with optimistic concurrency.
+// All transactions are executed speculatively in parallel on top of the state
+// prior to the first one, tracking the state they read and write. If the block
+// carries an access list, the speculations are instead executed on top of the
+// state predicted by it for each transaction. The results are then applied in
+// order: if the state read by a speculation differs from the actual one, the
+// transaction is executed again on the actual state, otherwise its writes are
+// applied as they are. The resulting state, receipts and logs are identical to
+// the ones of the sequential execution.
+func (p *StateProcessor) applyParallel(block *types.Block, statedb *state.StateDB, vmenv *vm.EVM, signer types.Signer, gp *GasPool, usedGas *uint64, tracker *accessListTracker) (types.Receipts, []*types.Log, error) {
+	var (
+		txs         = block.Transactions()
+		list        = block.AccessList()
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		base        = statedb.Copy()
+		results     = make([]*speculation, len(txs))
+		ready       = make([]chan struct{}, len(txs))
+		next        atomic.Int64
+		abort       atomic.Bool
+		wg          sync.WaitGroup
+	)
+	for i := range ready {
+		ready[i] = make(chan struct{})
+	}
+	workers := min(p.workers, len(txs))
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			// The block context caches ancestor hashes, it can't be shared
+			context := NewEVMBlockContext(header, p.bc, nil)
+			for !abort.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(txs) {
+					return
+				}
+				results[i] = p.speculate(base, list, context, vmenv.Config, signer, header, txs[i], i)
+				close(ready[i])
+			}
+		}()
+	}
+	defer func() {
+		abort.Store(true)
+		wg.Wait()
+	}()
+
+	var (
+		receipts = make(types.Receipts, 0, len(txs))
+		allLogs  []*types.Log
+	)
+	for i, tx := range txs {
+		<-ready[i]
+		spec := results[i]
+		if spec.msg == nil {
+			return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), spec.err)
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+
+		// Apply the speculation if it's valid on top of the actual state. Failed
+		// speculations are executed again to surface the actual error.
+		var receipt *types.Receipt
+		if spec.err == nil && gp.Gas() >= spec.msg.GasLimit && statedb.ValidateReads(spec.rwset) {
+			gp.SubGas(spec.result.UsedGas)
+
+			statedb.ApplyWrites(spec.writes)
+			for _, log := range spec.logs {
+				statedb.AddLog(&types.Log{Address: log.Address, Topics: log.Topics, Data: log.Data})
+			}
+			for hash, preimage := range spec.preimages {
+				statedb.AddPreimage(hash, preimage)
+			}
+			var root []byte
+			if p.config.IsByzantium(blockNumber) {
+				statedb.Finalise(true)
+			} else {
+				root = statedb.IntermediateRoot(p.config.IsEIP158(blockNumber)).Bytes()
+			}
+			*usedGas += spec.result.UsedGas
+			receipt = MakeReceipt(spec.evm, spec.result, statedb, blockNumber, blockHash, tx, *usedGas, root)
+			tracker.add(uint64(i+1), spec.rwset, spec.writes)
+
+			parallelCommitMeter.Mark(1)
+		} else {
+			tracker.start(statedb)
+
+			var err error
+			receipt, err = ApplyTransactionWithEVM(spec.msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			tracker.end(statedb, uint64(i+1))
+
+			parallelConflictMeter.Mark(1)
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	return receipts, allLogs, nil
+}
+
+// speculate executes a transaction on a copy of the given state, tracking the
+// state it reads and writes. If an access list is given, the changes it lists
+// for the preceding transactions are applied to the state beforehand.
+func (p *StateProcessor) speculate(base *state.StateDB, list *types.BlockAccessList, context vm.BlockContext, cfg vm.Config, signer types.Signer, header *types.Header, tx *types.Transaction, index int) *speculation {
+	msg, err := TransactionToMessage(tx, signer, header.BaseFee)
+	if err != nil {
+		return &speculation{err: err}
+	}
+	var (
+		rwset   = state.NewReadWriteSet()
+		statedb = base.Copy()
+	)
+	if list != nil {
+		statedb.ApplyAccessList(*list, uint64(index+1))
+		statedb.Finalise(false)
+	}
+	statedb.SetTxContext(tx.Hash(), index)
+	statedb.SetReadWriteSet(rwset)
+
+	evm := vm.NewEVM(context, NewEVMTxContext(msg), statedb, p.config, cfg)
+	result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(header.GasLimit))
+	if err == nil {
+		err = statedb.Error()
+	}
+	if err != nil {
+		return &speculation{msg: msg, err: err}
+	}
+	statedb.Finalise(p.config.IsEIP158(header.Number))
+
+	spec := &speculation{
+		msg:    msg,
+		evm:    evm,
+		result: result,
+		rwset:  rwset,
+		writes: statedb.Writes(),
+		logs:   statedb.GetLogs(tx.Hash(), header.Number.Uint64(), common.Hash{}),
+	}
+	if cfg.EnablePreimageRecording {
+		spec.preimages = statedb.Preimages()
+	}
+	return spec
+}
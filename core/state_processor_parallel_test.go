@@ -0,0 +1,209 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestParallelProcessingFrontier(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		HomesteadBlock: big.NewInt(0),
+		Ethash:         new(params.EthashConfig),
+	}
+	testParallelProcessing(t, config, ethash.NewFaker(), false)
+}
+
+func TestParallelProcessingCancun(t *testing.T) {
+	testParallelProcessing(t, params.MergedTestChainConfig, beacon.New(ethash.NewFaker()), true)
+}
+
+func testParallelProcessing(t *testing.T, config *params.ChainConfig, engine consensus.Engine, pos bool) {
+	var (
+		keys    = make([]*ecdsa.PrivateKey, 10)
+		senders = make([]common.Address, 10)
+		alloc   = make(types.GenesisAlloc)
+
+		recipient  = common.HexToAddress("0xaaaa")
+		counter    = common.HexToAddress("0xc0")
+		reader     = common.HexToAddress("0xba")
+		reverter   = common.HexToAddress("0xfd")
+		destructor = common.HexToAddress("0xdd")
+	)
+	for i := range keys {
+		keys[i], _ = crypto.ToECDSA(crypto.Keccak256([]byte{byte(i)}))
+		senders[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[senders[i]] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	// counter increments slot 0 and emits an empty log
+	alloc[counter] = types.Account{Code: common.FromHex("60005460010160005560006000a000")}
+	// reader stores the balance of the recipient in slot 1
+	alloc[reader] = types.Account{Code: append(append([]byte{0x73}, recipient.Bytes()...), common.FromHex("3160015500")...)}
+	alloc[recipient] = types.Account{Balance: big.NewInt(1)}
+	// reverter reverts any call
+	alloc[reverter] = types.Account{Code: common.FromHex("60006000fd")}
+	// destructor self-destructs to the caller
+	alloc[destructor] = types.Account{Code: common.FromHex("33ff"), Balance: big.NewInt(1)}
+
+	gspec := &Genesis{Config: config, Alloc: alloc}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 4, func(i int, gen *BlockGen) {
+		if pos {
+			gen.SetPoS()
+		}
+		signer := types.MakeSigner(config, gen.Number(), gen.Timestamp())
+		send := func(sender int, to *common.Address, value int64, data []byte) {
+			tx := &types.LegacyTx{
+				Nonce:    gen.TxNonce(senders[sender]),
+				To:       to,
+				Value:    big.NewInt(value),
+				Gas:      100000,
+				GasPrice: big.NewInt(2 * params.InitialBaseFee),
+				Data:     data,
+			}
+			gen.AddTx(types.MustSignNewTx(keys[sender], signer, tx))
+		}
+		fresh := common.BytesToAddress(crypto.Keccak256([]byte{0xf0, byte(i)}))
+		touched := common.BytesToAddress(crypto.Keccak256([]byte{0xf1, byte(i)}))
+
+		send(0, &recipient, 1, nil)                      // credits the recipient
+		send(1, &recipient, 1, nil)                      // credits the recipient independently
+		send(2, &counter, 0, nil)                        // increments the counter
+		send(3, &counter, 0, nil)                        // conflicts on the counter slot
+		send(0, &fresh, 1, nil)                          // conflicts on the sender nonce
+		send(4, &reader, 0, nil)                         // conflicts on the recipient balance
+		send(5, &reverter, 1, nil)                       // reverts the value transfer
+		send(6, nil, 0, common.FromHex("600160005500"))  // creates a contract with storage
+		send(7, &destructor, 1, nil)                     // self-destructs the first time
+		send(8, &touched, 0, nil)                        // touches an empty account
+		send(9, &senders[2], 1, nil)                     // credits the sender of a later tx
+		send(2, &counter, 0, common.FromHex("deadbeef")) // conflicts on the sender nonce and balance
+	})
+	// Import the chain with parallel processing, validating the state roots,
+	// receipt roots, blooms and gas against the sequentially generated blocks
+	cacheConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	cacheConfig.ParallelExecution = true
+	cacheConfig.ParallelWorkers = 4
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, ok := chain.processor.(*StateProcessor); !ok || chain.processor.(*StateProcessor).workers != 4 {
+		t.Fatal("parallel processor not configured")
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert: %v", n, err)
+	}
+	// Ensure the receipts and logs are identical, including the derived fields
+	var (
+		sequential = NewStateProcessor(config, chain, engine)
+		parallel   = NewParallelStateProcessor(config, chain, engine, 4)
+	)
+	for _, block := range blocks {
+		parent := chain.GetHeaderByHash(block.ParentHash())
+
+		seqdb, _ := chain.StateAt(parent.Root)
+		seqReceipts, seqLogs, seqGas, err := sequential.Process(block, seqdb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: sequential processing failed: %v", block.NumberU64(), err)
+		}
+		pardb, _ := chain.StateAt(parent.Root)
+		parReceipts, parLogs, parGas, err := parallel.Process(block, pardb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: parallel processing failed: %v", block.NumberU64(), err)
+		}
+		if seqGas != parGas {
+			t.Errorf("block %d: gas mismatch: sequential %d, parallel %d", block.NumberU64(), seqGas, parGas)
+		}
+		have, _ := json.Marshal(parReceipts)
+		want, _ := json.Marshal(seqReceipts)
+		if !bytes.Equal(have, want) {
+			t.Errorf("block %d: receipts mismatch:\nhave %s\nwant %s", block.NumberU64(), have, want)
+		}
+		have, _ = json.Marshal(parLogs)
+		want, _ = json.Marshal(seqLogs)
+		if !bytes.Equal(have, want) {
+			t.Errorf("block %d: logs mismatch:\nhave %s\nwant %s", block.NumberU64(), have, want)
+		}
+		deleteEmpty := config.IsEIP158(block.Number())
+		if have, want := pardb.IntermediateRoot(deleteEmpty), seqdb.IntermediateRoot(deleteEmpty); have != want {
+			t.Errorf("block %d: root mismatch: sequential %x, parallel %x", block.NumberU64(), want, have)
+		}
+		// Ensure the access lists are identical, and that speculating on top of
+		// the state predicted by them yields the same results
+		seqdb, _ = chain.StateAt(parent.Root)
+		_, _, _, seqList, err := sequential.ProcessWithAccessList(block, seqdb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: sequential access list generation failed: %v", block.NumberU64(), err)
+		}
+		pardb, _ = chain.StateAt(parent.Root)
+		_, _, _, parList, err := parallel.ProcessWithAccessList(block, pardb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: parallel access list generation failed: %v", block.NumberU64(), err)
+		}
+		if have, want := parList.Hash(), seqList.Hash(); have != want {
+			have, _ := json.Marshal(parList)
+			want, _ := json.Marshal(seqList)
+			t.Errorf("block %d: access list mismatch:\nhave %s\nwant %s", block.NumberU64(), have, want)
+		}
+		pardb, _ = chain.StateAt(parent.Root)
+		if _, _, _, err := parallel.Process(block.WithAccessList(seqList), pardb, vm.Config{}); err != nil {
+			t.Fatalf("block %d: processing with access list failed: %v", block.NumberU64(), err)
+		}
+		if have, want := pardb.IntermediateRoot(deleteEmpty), seqdb.IntermediateRoot(deleteEmpty); have != want {
+			t.Errorf("block %d: root mismatch with access list: sequential %x, parallel %x", block.NumberU64(), want, have)
+		}
+	}
+	// Ensure the speculations are only discarded if the state they read changed
+	var (
+		block   = blocks[0]
+		base, _ = chain.StateAt(gspec.ToBlock().Root())
+		actual  = base.Copy()
+		context = NewEVMBlockContext(block.Header(), chain, nil)
+		signer  = types.MakeSigner(config, block.Number(), block.Time())
+		gp      = new(GasPool).AddGas(block.GasLimit())
+		usedGas uint64
+	)
+	conflicts := []bool{false, false, false, true, true, true, false, false, false, false, false, true}
+	for i, tx := range block.Transactions() {
+		spec := parallel.speculate(base, nil, context, vm.Config{}, signer, block.Header(), tx, i)
+		if conflict := spec.err != nil || !actual.ValidateReads(spec.rwset); conflict != conflicts[i] {
+			t.Errorf("tx %d: conflict mismatch: have %v, want %v (err %v)", i, conflict, conflicts[i], spec.err)
+		}
+		actual.SetTxContext(tx.Hash(), i)
+		if _, err := ApplyTransaction(config, chain, nil, gp, actual, block.Header(), tx, &usedGas, vm.Config{}); err != nil {
+			t.Fatalf("tx %d: failed to apply: %v", i, err)
+		}
+	}
+}
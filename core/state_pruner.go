@@ -0,0 +1,159 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+)
+
+var (
+	// errStatePruningRunning is returned if a state pruning is requested while
+	// another one is in progress.
+	errStatePruningRunning = errors.New("state pruning already running")
+
+	// errStatePruningNotRunning is returned if there's no state pruning to abort.
+	errStatePruningNotRunning = errors.New("state pruning not running")
+)
+
+// StartStatePruning starts removing the stale trie nodes from the database in
+// the background, while the chain keeps importing blocks. The latest persisted
+// state, the recent in-memory states, the snapshot base and the genesis state
+// are retained, along with every state created while the pruning is running.
+//
+// It's only supported by non-archive nodes using the hash-based state scheme.
+func (bc *BlockChain) StartStatePruning(config pruner.OnlineConfig) error {
+	if bc.triedb.Scheme() != rawdb.HashScheme {
+		return errors.New("state pruning is only supported by the hash scheme")
+	}
+	if bc.cacheConfig.TrieDirtyDisabled {
+		return errors.New("state pruning is not supported by archive nodes")
+	}
+	bc.statePrunerLock.Lock()
+	defer bc.statePrunerLock.Unlock()
+
+	if bc.statePruner != nil && bc.statePruner.Running() {
+		return errStatePruningRunning
+	}
+	p, err := pruner.NewOnlinePruner(bc.db, bc.triedb, config)
+	if err != nil {
+		return err
+	}
+	// Block the chain modifications, so no trie nodes are flushed to disk until
+	// the pruner starts tracking them.
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	base, roots, err := bc.retainedStates()
+	if err != nil {
+		return err
+	}
+	if err := p.Start(base, roots); err != nil {
+		return err
+	}
+	bc.statePruner = p
+	return nil
+}
+
+// retainedStates returns the latest state persisted on disk and the other
+// states to retain while pruning. This function expects the chain mutex to be
+// held.
+func (bc *BlockChain) retainedStates() (common.Hash, []common.Hash, error) {
+	var (
+		base  common.Hash
+		roots []common.Hash
+		seen  = make(map[common.Hash]struct{})
+		head  = bc.CurrentBlock().Number.Uint64()
+	)
+	retain := func(root common.Hash) {
+		if _, ok := seen[root]; !ok {
+			seen[root] = struct{}{}
+			roots = append(roots, root)
+		}
+	}
+	// Retain all the recent states which are tracked in memory, then keep going
+	// back until the latest persisted one is found, needed to recover from a
+	// crash.
+	for number := head; ; number-- {
+		header := bc.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		persisted := rawdb.HasLegacyTrieNode(bc.db, header.Root)
+		if persisted && base == (common.Hash{}) {
+			base = header.Root
+			seen[base] = struct{}{}
+		} else if head-number < state.TriesInMemory {
+			retain(header.Root)
+		}
+		if (base != common.Hash{} && head-number >= state.TriesInMemory-1) || number == 0 {
+			break
+		}
+	}
+	if base == (common.Hash{}) {
+		return common.Hash{}, nil, errors.New("no persisted state found")
+	}
+	if bc.snaps != nil {
+		if root := bc.snaps.DiskRoot(); root != (common.Hash{}) {
+			retain(root)
+		}
+	}
+	return base, roots, nil
+}
+
+// StatePruningProgress returns the status of the running or the last finished
+// state pruning, nil if none was started yet.
+func (bc *BlockChain) StatePruningProgress() *pruner.OnlineProgress {
+	bc.statePrunerLock.Lock()
+	defer bc.statePrunerLock.Unlock()
+
+	if bc.statePruner == nil {
+		return nil
+	}
+	progress := bc.statePruner.Progress()
+	return &progress
+}
+
+// AbortStatePruning interrupts the running state pruning and waits for it to
+// stop.
+func (bc *BlockChain) AbortStatePruning() error {
+	bc.statePrunerLock.Lock()
+	defer bc.statePrunerLock.Unlock()
+
+	if bc.statePruner == nil || !bc.statePruner.Running() {
+		return errStatePruningNotRunning
+	}
+	bc.statePruner.Abort()
+	return nil
+}
+
+// abortStatePruning interrupts the state pruning if it's running, used when the
+// chain is stopped.
+func (bc *BlockChain) abortStatePruning() {
+	bc.statePrunerLock.Lock()
+	defer bc.statePrunerLock.Unlock()
+
+	if bc.statePruner != nil {
+		bc.statePruner.Abort()
+	}
+}
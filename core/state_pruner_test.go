@@ -0,0 +1,117 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that the state can be pruned while the chain keeps importing blocks,
+// retaining the recent states and the ones created during the pruning.
+func TestOnlineStatePruning(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{Config: params.TestChainConfig, Alloc: types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2*state.TriesInMemory+20, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{byte(i), byte(i >> 8)}, big.NewInt(1), params.TxGas, gen.header.BaseFee, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Persist a state after every block, leaving plenty of stale nodes behind
+	chain.SetTrieFlushInterval(0)
+
+	imported := 2 * state.TriesInMemory
+	if n, err := chain.InsertChain(blocks[:imported]); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	if err := chain.StartStatePruning(pruner.OnlineConfig{BloomSize: 256, BatchSize: 16}); err != nil {
+		t.Fatalf("failed to start pruning: %v", err)
+	}
+	if err := chain.StartStatePruning(pruner.OnlineConfig{BloomSize: 256}); err != nil && err != errStatePruningRunning {
+		t.Fatalf("unexpected error on concurrent pruning: %v", err)
+	}
+	// Keep importing while the pruning is running
+	if n, err := chain.InsertChain(blocks[imported:]); err != nil {
+		t.Fatalf("failed to insert block %d: %v", imported+n, err)
+	}
+	chain.statePrunerLock.Lock()
+	p := chain.statePruner
+	chain.statePrunerLock.Unlock()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("pruning failed: %v", err)
+	}
+	progress := chain.StatePruningProgress()
+	if progress == nil || progress.Phase != pruner.PhaseDone {
+		t.Fatalf("unexpected pruning progress: %+v", progress)
+	}
+	if progress.Deleted == 0 {
+		t.Fatal("no stale nodes deleted")
+	}
+	if err := chain.AbortStatePruning(); err != errStatePruningNotRunning {
+		t.Fatalf("unexpected abort error: have %v, want %v", err, errStatePruningNotRunning)
+	}
+	// The recent states must be complete
+	head := chain.CurrentBlock().Number.Uint64()
+	for number := head - state.TriesInMemory + 1; number <= head; number++ {
+		root := chain.GetHeaderByNumber(number).Root
+		tr, err := trie.New(trie.StateTrieID(root), chain.triedb)
+		if err != nil {
+			t.Fatalf("state of block %d unavailable: %v", number, err)
+		}
+		it, err := tr.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("failed to iterate state of block %d: %v", number, err)
+		}
+		for it.Next(true) {
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("state of block %d incomplete: %v", number, err)
+		}
+	}
+	// Old states are gone, except the base of the snapshot
+	for number := uint64(1); number <= 10; number++ {
+		root := chain.GetHeaderByNumber(number).Root
+		if root == chain.snaps.DiskRoot() {
+			continue
+		}
+		if chain.HasState(root) {
+			t.Fatalf("stale state of block %d not pruned", number)
+		}
+	}
+}
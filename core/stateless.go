@@ -0,0 +1,292 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// ExecutionWitness re-executes a block on top of its parent state and returns
+// the witness required to execute it statelessly with ExecuteStateless.
+func (bc *BlockChain) ExecutionWitness(block *types.Block) (*stateless.Witness, error) {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	statedb, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	witness := stateless.NewWitness()
+	statedb.SetWitness(witness)
+
+	var (
+		chain     = &headerRecorder{BlockChain: bc, oldest: parent.Number.Uint64()}
+		processor = &StateProcessor{config: bc.chainConfig, bc: chain, engine: bc.engine}
+	)
+	receipts, _, usedGas, err := processor.Process(block, statedb, bc.vmConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return nil, err
+	}
+	// Include the parent and all the ancestors accessed by the execution
+	for header := parent; ; {
+		witness.Headers = append(witness.Headers, header)
+		if header.Number.Uint64() <= chain.oldest {
+			break
+		}
+		if header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+	}
+	// The merkle trie nodes are gathered by the state during the validation,
+	// the verkle leaves are proven against the pre-state, carrying their
+	// post-state values along.
+	if !bc.triedb.IsVerkle() {
+		return witness, nil
+	}
+	pre, err := statedb.Database().OpenTrie(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	pretrie, ok := pre.(*trie.VerkleTrie)
+	if !ok {
+		return nil, fmt.Errorf("unexpected pre-state trie type %T", pre)
+	}
+	posttrie, ok := statedb.GetTrie().(*trie.VerkleTrie)
+	if !ok {
+		return nil, fmt.Errorf("unexpected post-state trie type %T", statedb.GetTrie())
+	}
+	if keys := statedb.AccessEvents().Keys(); len(keys) > 0 {
+		witness.VerkleProof, witness.StateDiff, err = pretrie.Proof(posttrie, keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return witness, nil
+}
+
+// ExecuteStateless executes a block on top of the state proven by its execution
+// witness, and validates the resulting state, receipts and gas usage against the
+// block header. Besides the witness, only the chain configuration and consensus
+// engine are required, the block's seal and consensus header fields are not
+// verified.
+func ExecuteStateless(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *stateless.Witness) error {
+	chain, err := newWitnessChain(config, engine, block, witness)
+	if err != nil {
+		return err
+	}
+	// Ensure the block body matches the header, the header is what's validated
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+		return fmt.Errorf("transaction root hash mismatch (header value %x, calculated %x)", block.TxHash(), hash)
+	}
+	if block.Withdrawals() != nil {
+		if want := block.Header().WithdrawalsHash; want == nil || *want != types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)) {
+			return errors.New("withdrawals root hash mismatch")
+		}
+	}
+	// Build the state backed by the witness only
+	memdb := rawdb.NewMemoryDatabase()
+	for code := range witness.Codes {
+		rawdb.WriteCode(memdb, crypto.Keccak256Hash([]byte(code)), []byte(code))
+	}
+	var (
+		root    = chain.parent.Root
+		statedb *state.StateDB
+	)
+	if config.IsVerkle(block.Number(), block.Time()) {
+		db := state.NewDatabaseWithConfig(memdb, &triedb.Config{IsVerkle: true, PathDB: pathdb.Defaults})
+		defer db.TrieDB().Close()
+
+		tree, err := trie.NewVerkleTrieFromProof(root, witness.VerkleProof, witness.StateDiff, db.PointCache())
+		if err != nil {
+			return fmt.Errorf("invalid witness: %w", err)
+		}
+		statedb, err = state.New(root, &witnessDatabase{Database: db, root: root, tree: tree}, nil)
+		if err != nil {
+			return err
+		}
+	} else {
+		// The trie nodes are stored by hash, any node missing from the witness
+		// surfaces as a missing trie node error during the execution.
+		for node := range witness.State {
+			rawdb.WriteLegacyTrieNode(memdb, crypto.Keccak256Hash([]byte(node)), []byte(node))
+		}
+		db := state.NewDatabaseWithConfig(memdb, triedb.HashDefaults)
+		defer db.TrieDB().Close()
+
+		statedb, err = state.New(root, db, nil)
+		if err != nil {
+			return fmt.Errorf("invalid witness: %w", err)
+		}
+	}
+	processor := &StateProcessor{config: config, bc: chain, engine: engine}
+	receipts, _, usedGas, err := processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		return err
+	}
+	validator := &BlockValidator{config: config, engine: engine}
+	if err := validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return err
+	}
+	// A read of state missing from the witness doesn't necessarily alter the
+	// resulting root, ensure none failed.
+	if err := statedb.Error(); err != nil {
+		return fmt.Errorf("invalid witness: %w", err)
+	}
+	return nil
+}
+
+// headerRecorder wraps the chain to track the oldest ancestor header accessed
+// during block processing, to be included in the execution witness.
+type headerRecorder struct {
+	*BlockChain
+	oldest uint64
+}
+
+func (r *headerRecorder) track(header *types.Header) *types.Header {
+	if header != nil && header.Number.Uint64() < r.oldest {
+		r.oldest = header.Number.Uint64()
+	}
+	return header
+}
+
+// GetHeader retrieves a block header by hash and number, tracking it.
+func (r *headerRecorder) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return r.track(r.BlockChain.GetHeader(hash, number))
+}
+
+// GetHeaderByHash retrieves a block header by hash, tracking it.
+func (r *headerRecorder) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.track(r.BlockChain.GetHeaderByHash(hash))
+}
+
+// GetHeaderByNumber retrieves a block header by number, tracking it.
+func (r *headerRecorder) GetHeaderByNumber(number uint64) *types.Header {
+	return r.track(r.BlockChain.GetHeaderByNumber(number))
+}
+
+// witnessChain serves the ancestor headers of an execution witness during the
+// stateless block processing.
+type witnessChain struct {
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	parent  *types.Header
+	headers map[common.Hash]*types.Header
+	numbers map[uint64]*types.Header
+}
+
+// newWitnessChain validates that the headers of a witness form the chain of
+// ancestors of the block, and makes them accessible for processing it.
+func newWitnessChain(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *stateless.Witness) (*witnessChain, error) {
+	parent := witness.Parent()
+	if parent == nil {
+		return nil, errors.New("witness without parent header")
+	}
+	if parent.Hash() != block.ParentHash() || parent.Number.Uint64()+1 != block.NumberU64() {
+		return nil, fmt.Errorf("witness parent %d (%x) mismatch", parent.Number, parent.Hash())
+	}
+	chain := &witnessChain{
+		config:  config,
+		engine:  engine,
+		parent:  parent,
+		headers: make(map[common.Hash]*types.Header, len(witness.Headers)),
+		numbers: make(map[uint64]*types.Header, len(witness.Headers)),
+	}
+	for i, header := range witness.Headers {
+		if i > 0 {
+			child := witness.Headers[i-1]
+			if header.Hash() != child.ParentHash || header.Number.Uint64()+1 != child.Number.Uint64() {
+				return nil, fmt.Errorf("witness header %d (%x) not an ancestor", header.Number, header.Hash())
+			}
+		}
+		chain.headers[header.Hash()] = header
+		chain.numbers[header.Number.Uint64()] = header
+	}
+	return chain, nil
+}
+
+// Config retrieves the chain's configuration.
+func (c *witnessChain) Config() *params.ChainConfig { return c.config }
+
+// Engine retrieves the chain's consensus engine.
+func (c *witnessChain) Engine() consensus.Engine { return c.engine }
+
+// CurrentHeader retrieves the parent header of the executed block.
+func (c *witnessChain) CurrentHeader() *types.Header { return c.parent }
+
+// GetHeader retrieves a witness header by hash and number.
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// GetHeaderByHash retrieves a witness header by hash.
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+// GetHeaderByNumber retrieves a witness header by number.
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.numbers[number]
+}
+
+// GetTd is not available in the stateless execution.
+func (c *witnessChain) GetTd(hash common.Hash, number uint64) *big.Int {
+	return nil
+}
+
+// witnessDatabase serves the partial verkle tree built from an execution
+// witness as the state to execute a block on.
+type witnessDatabase struct {
+	state.Database
+	root common.Hash
+	tree *trie.VerkleTrie
+}
+
+// OpenTrie opens the tree of the witness.
+func (db *witnessDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	if root != db.root {
+		return nil, fmt.Errorf("state %x not in witness", root)
+	}
+	return db.tree, nil
+}
+
+// OpenStorageTrie returns the tree of the witness itself, verkle trees don't
+// have separate storage tries.
+func (db *witnessDatabase) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self state.Trie) (state.Trie, error) {
+	return self, nil
+}
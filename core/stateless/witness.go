@@ -0,0 +1,153 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stateless contains the execution witness, the data required to
+// execute a block without access to the state database.
+package stateless
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-verkle"
+)
+
+// Witness encompasses the state required to execute a block on top of its
+// parent: the ancestor headers accessed by the execution, the bytecodes it
+// loaded and the pre-state of the accessed state. For merkle-patricia state it
+// is the set of trie nodes resolved during the execution, for verkle state the
+// accessed leaves along with their proof.
+type Witness struct {
+	Headers []*types.Header     // Ancestor headers in reverse order, the parent first
+	Codes   map[string]struct{} // Set of bytecodes loaded during the execution
+	State   map[string]struct{} // Set of merkle-patricia trie nodes resolved during the execution
+
+	VerkleProof *verkle.VerkleProof // Proof of the accessed leaves in the parent's verkle tree
+	StateDiff   verkle.StateDiff    // Pre- and post-state values of the accessed verkle leaves
+
+	lock sync.Mutex // Lock to allow concurrent code and state insertions
+}
+
+// NewWitness creates an empty witness to be filled during block execution.
+func NewWitness() *Witness {
+	return &Witness{
+		Codes: make(map[string]struct{}),
+		State: make(map[string]struct{}),
+	}
+}
+
+// AddCode adds a bytecode to the witness.
+func (w *Witness) AddCode(code []byte) {
+	if len(code) == 0 {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.Codes[string(code)] = struct{}{}
+}
+
+// AddState adds a set of trie nodes to the witness.
+func (w *Witness) AddState(nodes map[string]struct{}) {
+	if len(nodes) == 0 {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for node := range nodes {
+		w.State[node] = struct{}{}
+	}
+}
+
+// Parent returns the header of the parent block, nil if the witness has none.
+func (w *Witness) Parent() *types.Header {
+	if len(w.Headers) == 0 {
+		return nil
+	}
+	return w.Headers[0]
+}
+
+// Root returns the pre-state root the witness was built against.
+func (w *Witness) Root() common.Hash {
+	if parent := w.Parent(); parent != nil {
+		return parent.Root
+	}
+	return common.Hash{}
+}
+
+// extWitness is the JSON representation of a witness.
+type extWitness struct {
+	Headers     []*types.Header     `json:"headers"`
+	Codes       []hexutil.Bytes     `json:"codes"`
+	State       []hexutil.Bytes     `json:"state,omitempty"`
+	VerkleProof *verkle.VerkleProof `json:"verkleProof,omitempty"`
+	StateDiff   verkle.StateDiff    `json:"stateDiff,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The codes and trie nodes are sorted to
+// make the encoding deterministic.
+func (w *Witness) MarshalJSON() ([]byte, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	ext := extWitness{
+		Headers:     w.Headers,
+		Codes:       make([]hexutil.Bytes, 0, len(w.Codes)),
+		VerkleProof: w.VerkleProof,
+		StateDiff:   w.StateDiff,
+	}
+	for code := range w.Codes {
+		ext.Codes = append(ext.Codes, hexutil.Bytes(code))
+	}
+	for node := range w.State {
+		ext.State = append(ext.State, hexutil.Bytes(node))
+	}
+	compare := func(a, b hexutil.Bytes) int {
+		return slices.Compare(a, b)
+	}
+	slices.SortFunc(ext.Codes, compare)
+	slices.SortFunc(ext.State, compare)
+	return json.Marshal(&ext)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (w *Witness) UnmarshalJSON(input []byte) error {
+	var ext extWitness
+	if err := json.Unmarshal(input, &ext); err != nil {
+		return err
+	}
+	if len(ext.Headers) == 0 {
+		return errors.New("witness without parent header")
+	}
+	w.Headers = ext.Headers
+	w.Codes = make(map[string]struct{}, len(ext.Codes))
+	for _, code := range ext.Codes {
+		w.Codes[string(code)] = struct{}{}
+	}
+	w.State = make(map[string]struct{}, len(ext.State))
+	for _, node := range ext.State {
+		w.State[string(node)] = struct{}{}
+	}
+	w.VerkleProof = ext.VerkleProof
+	w.StateDiff = ext.StateDiff
+	return nil
+}
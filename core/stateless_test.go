@@ -0,0 +1,288 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+// witnessTestCode stores the hash of the block three blocks back in slot 0,
+// requiring the header of the grandparent, and increments slot 1.
+var witnessTestCode = common.FromHex("4360039003406000556001546001016001550000")
+
+func TestExecuteStatelessVerkle(t *testing.T) {
+	var (
+		config = &params.ChainConfig{
+			ChainID:                       big.NewInt(1),
+			HomesteadBlock:                big.NewInt(0),
+			EIP150Block:                   big.NewInt(0),
+			EIP155Block:                   big.NewInt(0),
+			EIP158Block:                   big.NewInt(0),
+			ByzantiumBlock:                big.NewInt(0),
+			ConstantinopleBlock:           big.NewInt(0),
+			PetersburgBlock:               big.NewInt(0),
+			IstanbulBlock:                 big.NewInt(0),
+			MuirGlacierBlock:              big.NewInt(0),
+			BerlinBlock:                   big.NewInt(0),
+			LondonBlock:                   big.NewInt(0),
+			Ethash:                        new(params.EthashConfig),
+			ShanghaiTime:                  u64(0),
+			VerkleTime:                    u64(0),
+			TerminalTotalDifficulty:       common.Big0,
+			TerminalTotalDifficultyPassed: true,
+		}
+		signer   = types.LatestSigner(config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		engine   = beacon.New(ethash.NewFaker())
+		gspec    = &Genesis{
+			Config: config,
+			Alloc: types.GenesisAlloc{
+				sender:   {Balance: big.NewInt(params.Ether)},
+				contract: {Code: witnessTestCode, Storage: map[common.Hash]common.Hash{{1}: {1}}},
+			},
+		}
+	)
+	cacheConfig := DefaultCacheConfigWithScheme(rawdb.PathScheme)
+	cacheConfig.SnapshotLimit = 0
+	blockchain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	// Generate the blocks one by one, so that the BLOCKHASH lookups of the
+	// transactions can be served by the chain.
+	var (
+		gendb  = rawdb.NewMemoryDatabase()
+		gentdb = triedb.NewDatabase(gendb, cacheConfig.triedbConfig(true))
+		parent = gspec.MustCommit(gendb, gentdb)
+		blocks []*types.Block
+	)
+	defer gentdb.Close()
+
+	for i := 0; i < 4; i++ {
+		generated, _, _, _ := GenerateVerkleChain(config, parent, engine, gendb, gentdb, 1, func(_ int, gen *BlockGen) {
+			gen.SetPoS()
+
+			nonce := gen.TxNonce(sender)
+			tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{byte(i), 2, 3}, big.NewInt(999), params.TxGas, gen.BaseFee(), nil), signer, key)
+			gen.AddTx(tx)
+			tx, _ = types.SignTx(types.NewTransaction(nonce+1, contract, big.NewInt(0), 500000, gen.BaseFee(), nil), signer, key)
+			gen.AddTxWithChain(blockchain, tx)
+			if i == 2 {
+				tx, _ = types.SignTx(types.NewContractCreation(nonce+2, big.NewInt(16), 3000000, gen.BaseFee(), code), signer, key)
+				gen.AddTx(tx)
+			}
+			gen.AddWithdrawal(&types.Withdrawal{Validator: 1, Address: common.Address{0xee}, Amount: 1})
+		})
+		if _, err := blockchain.InsertChain(generated); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", i+1, err)
+		}
+		parent = generated[0]
+		blocks = append(blocks, parent)
+	}
+	for _, block := range blocks {
+		witness, err := blockchain.ExecutionWitness(block)
+		if err != nil {
+			t.Fatalf("block %d: failed to create witness: %v", block.NumberU64(), err)
+		}
+		if witness.VerkleProof == nil || len(witness.StateDiff) == 0 {
+			t.Fatalf("block %d: witness without state", block.NumberU64())
+		}
+		if len(witness.Codes) != 1 {
+			t.Fatalf("block %d: unexpected code count %d", block.NumberU64(), len(witness.Codes))
+		}
+		// The BLOCKHASH lookup must pull in the header of the grandparent
+		want := 1
+		if block.NumberU64() > 2 {
+			want = 2
+		}
+		if len(witness.Headers) != want {
+			t.Fatalf("block %d: unexpected header count: have %d, want %d", block.NumberU64(), len(witness.Headers), want)
+		}
+		// Execute the block on the witness after a round trip through JSON
+		blob, err := json.Marshal(witness)
+		if err != nil {
+			t.Fatalf("block %d: failed to encode witness: %v", block.NumberU64(), err)
+		}
+		witness = new(stateless.Witness)
+		if err := json.Unmarshal(blob, witness); err != nil {
+			t.Fatalf("block %d: failed to decode witness: %v", block.NumberU64(), err)
+		}
+		if err := ExecuteStateless(config, engine, block, witness); err != nil {
+			t.Fatalf("block %d: stateless execution failed: %v", block.NumberU64(), err)
+		}
+	}
+	// Ensure invalid witnesses are rejected
+	block := blocks[3]
+	for name, tamper := range map[string]func(w *stateless.Witness){
+		"missing code": func(w *stateless.Witness) {
+			w.Codes = make(map[string]struct{})
+		},
+		"missing header": func(w *stateless.Witness) {
+			w.Headers = w.Headers[:1]
+		},
+		"wrong parent": func(w *stateless.Witness) {
+			w.Headers = w.Headers[1:]
+		},
+		"wrong pre-value": func(w *stateless.Witness) {
+			for i, stem := range w.StateDiff {
+				for j, suffix := range stem.SuffixDiffs {
+					if suffix.CurrentValue != nil {
+						value := *suffix.CurrentValue
+						value[0]++
+						w.StateDiff[i].SuffixDiffs[j].CurrentValue = &value
+						return
+					}
+				}
+			}
+		},
+	} {
+		witness, err := blockchain.ExecutionWitness(block)
+		if err != nil {
+			t.Fatalf("failed to create witness: %v", err)
+		}
+		tamper(witness)
+		if err := ExecuteStateless(config, engine, block, witness); err == nil {
+			t.Errorf("%s: invalid witness accepted", name)
+		}
+	}
+}
+
+func TestExecuteStatelessMerkle(t *testing.T) {
+	var (
+		config   = params.MergedTestChainConfig
+		signer   = types.LatestSigner(config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		clearer  = common.HexToAddress("0xc1ea")
+		engine   = beacon.New(ethash.NewFaker())
+		gspec    = &Genesis{
+			Config: config,
+			Alloc: types.GenesisAlloc{
+				sender:   {Balance: big.NewInt(params.Ether)},
+				contract: {Code: witnessTestCode, Storage: map[common.Hash]common.Hash{{1}: {1}}},
+				clearer: {
+					Code:    common.FromHex("60006000556002545061beef315000"), // clears slot 0, reads slot 2 and balance of 0xbeef
+					Storage: map[common.Hash]common.Hash{{}: {1}, {1}: {1}, {2}: {1}},
+				},
+				common.HexToAddress("0xbeef"): {Balance: big.NewInt(1)},
+			},
+		}
+	)
+	// Keep the snapshot enabled, the witness must be collected regardless
+	blockchain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	var (
+		gendb  = rawdb.NewMemoryDatabase()
+		parent = gspec.MustCommit(gendb, triedb.NewDatabase(gendb, triedb.HashDefaults))
+		blocks []*types.Block
+	)
+	for i := 0; i < 4; i++ {
+		generated, _ := GenerateChain(config, parent, engine, gendb, 1, func(_ int, gen *BlockGen) {
+			gen.SetPoS()
+
+			nonce := gen.TxNonce(sender)
+			tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{byte(i), 2, 3}, big.NewInt(999), params.TxGas, gen.BaseFee(), nil), signer, key)
+			gen.AddTx(tx)
+			tx, _ = types.SignTx(types.NewTransaction(nonce+1, contract, big.NewInt(0), 500000, gen.BaseFee(), nil), signer, key)
+			gen.AddTxWithChain(blockchain, tx)
+			if i == 2 {
+				tx, _ = types.SignTx(types.NewTransaction(nonce+2, clearer, big.NewInt(0), 500000, gen.BaseFee(), nil), signer, key)
+				gen.AddTx(tx)
+			}
+			gen.AddWithdrawal(&types.Withdrawal{Validator: 1, Address: common.Address{0xee}, Amount: 1})
+		})
+		if _, err := blockchain.InsertChain(generated); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", i+1, err)
+		}
+		parent = generated[0]
+		blocks = append(blocks, parent)
+	}
+	for _, block := range blocks {
+		witness, err := blockchain.ExecutionWitness(block)
+		if err != nil {
+			t.Fatalf("block %d: failed to create witness: %v", block.NumberU64(), err)
+		}
+		if len(witness.State) == 0 || witness.VerkleProof != nil {
+			t.Fatalf("block %d: witness without trie nodes", block.NumberU64())
+		}
+		want := 1
+		if block.NumberU64() == 3 {
+			want = 2
+		}
+		if len(witness.Codes) != want {
+			t.Fatalf("block %d: unexpected code count: have %d, want %d", block.NumberU64(), len(witness.Codes), want)
+		}
+		want = 1
+		if block.NumberU64() > 2 {
+			want = 2
+		}
+		if len(witness.Headers) != want {
+			t.Fatalf("block %d: unexpected header count: have %d, want %d", block.NumberU64(), len(witness.Headers), want)
+		}
+		blob, err := json.Marshal(witness)
+		if err != nil {
+			t.Fatalf("block %d: failed to encode witness: %v", block.NumberU64(), err)
+		}
+		witness = new(stateless.Witness)
+		if err := json.Unmarshal(blob, witness); err != nil {
+			t.Fatalf("block %d: failed to decode witness: %v", block.NumberU64(), err)
+		}
+		if err := ExecuteStateless(config, engine, block, witness); err != nil {
+			t.Fatalf("block %d: stateless execution failed: %v", block.NumberU64(), err)
+		}
+	}
+	// Ensure incomplete witnesses are rejected, dropping any single trie node
+	// must fail the execution.
+	block := blocks[2]
+	witness, err := blockchain.ExecutionWitness(block)
+	if err != nil {
+		t.Fatalf("failed to create witness: %v", err)
+	}
+	for node := range witness.State {
+		partial := stateless.NewWitness()
+		partial.Headers = witness.Headers
+		partial.Codes = witness.Codes
+		for other := range witness.State {
+			if other != node {
+				partial.State[other] = struct{}{}
+			}
+		}
+		if err := ExecuteStateless(config, engine, block, partial); err == nil {
+			t.Errorf("witness without node %x accepted", crypto.Keccak256([]byte(node)))
+		}
+	}
+	witness.Codes = make(map[string]struct{})
+	if err := ExecuteStateless(config, engine, block, witness); err == nil {
+		t.Error("witness without codes accepted")
+	}
+}
@@ -83,6 +83,10 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 	if head == 0 {
 		return
 	}
+	// Blocks below the history expiry tail have no bodies anymore, so their
+	// transactions can't be indexed.
+	expired := rawdb.ReadHistoryExpiryTail(indexer.db)
+
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks in the chain (part of them may from ancient store) are
 	// not indexed yet, index the chain according to the configured limit.
@@ -91,7 +95,7 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 		if indexer.limit != 0 && head >= indexer.limit {
 			from = head - indexer.limit + 1
 		}
-		rawdb.IndexTransactions(indexer.db, from, head+1, stop, true)
+		rawdb.IndexTransactions(indexer.db, max(from, expired), head+1, stop, true)
 		return
 	}
 	// The tail flag is existent (which means indexes in [tail, head] should be
@@ -105,7 +109,7 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(indexer.db, 0, end, stop, true)
+			rawdb.IndexTransactions(indexer.db, expired, end, stop, true)
 		}
 		return
 	}
@@ -113,7 +117,7 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 	// limit and the latest chain head.
 	if head-indexer.limit+1 < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(indexer.db, head-indexer.limit+1, *tail, stop, true)
+		rawdb.IndexTransactions(indexer.db, max(head-indexer.limit+1, expired), *tail, stop, true)
 	} else {
 		// Unindex a part of stale indices and forward index tail to HEAD-limit
 		rawdb.UnindexTransactions(indexer.db, *tail, head-indexer.limit+1, stop, false)
@@ -176,9 +180,16 @@ func (indexer *txIndexer) loop(chain *BlockChain) {
 
 // report returns the tx indexing progress.
 func (indexer *txIndexer) report(head uint64, tail *uint64) TxIndexProgress {
+	// Blocks below the history expiry tail can't be indexed.
+	available := head + 1 // genesis included
+	if expired := rawdb.ReadHistoryExpiryTail(indexer.db); expired < available {
+		available -= expired
+	} else {
+		available = 0
+	}
 	total := indexer.limit
-	if indexer.limit == 0 || total > head {
-		total = head + 1 // genesis included
+	if indexer.limit == 0 || total > available {
+		total = available
 	}
 	var indexed uint64
 	if tail != nil {
@@ -42,6 +42,11 @@ type Prefetcher interface {
 	// the transaction messages using the statedb, but any changes are discarded. The
 	// only goal is to pre-cache transaction signatures and state trie nodes.
 	Prefetch(block *types.Block, statedb *state.StateDB, cfg vm.Config, interrupt *atomic.Bool)
+
+	// PrefetchAccessList reads all the state accessed in a block-level access list
+	// from the statedb and applies its changes, any changes are discarded. The only
+	// goal is to pre-cache the accessed state and the trie nodes needed to hash it.
+	PrefetchAccessList(list types.BlockAccessList, statedb *state.StateDB, interrupt *atomic.Bool)
 }
 
 // Processor is an interface for processing blocks using a given initial state.
@@ -197,6 +197,10 @@ type Block struct {
 	transactions Transactions
 	withdrawals  Withdrawals
 
+	// accessList is the optional block-level access list delivered along with
+	// the block, it is not part of the block hash.
+	accessList *BlockAccessList
+
 	// caches
 	hash atomic.Pointer[common.Hash]
 	size atomic.Uint64
@@ -340,6 +344,10 @@ func (b *Block) Uncles() []*Header          { return b.uncles }
 func (b *Block) Transactions() Transactions { return b.transactions }
 func (b *Block) Withdrawals() Withdrawals   { return b.withdrawals }
 
+// AccessList returns the block-level access list delivered along with the block,
+// or nil if there is none.
+func (b *Block) AccessList() *BlockAccessList { return b.accessList }
+
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
 		if transaction.Hash() == hash {
@@ -448,6 +456,7 @@ func (b *Block) WithSeal(header *Header) *Block {
 		transactions: b.transactions,
 		uncles:       b.uncles,
 		withdrawals:  b.withdrawals,
+		accessList:   b.accessList,
 	}
 }
 
@@ -466,6 +475,18 @@ func (b *Block) WithBody(body Body) *Block {
 	return block
 }
 
+// WithAccessList returns a copy of the block with the given block-level access
+// list attached.
+func (b *Block) WithAccessList(list *BlockAccessList) *Block {
+	return &Block{
+		header:       b.header,
+		transactions: b.transactions,
+		uncles:       b.uncles,
+		withdrawals:  b.withdrawals,
+		accessList:   list,
+	}
+}
+
 // Hash returns the keccak256 hash of b's header.
 // The hash is computed on the first call and cached thereafter.
 func (b *Block) Hash() common.Hash {
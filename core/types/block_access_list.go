@@ -0,0 +1,106 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+//go:generate go run github.com/fjl/gencodec -type StorageChange -field-override storageChangeMarshaling -out gen_storage_change_json.go
+//go:generate go run github.com/fjl/gencodec -type BalanceChange -field-override balanceChangeMarshaling -out gen_balance_change_json.go
+//go:generate go run github.com/fjl/gencodec -type NonceChange -field-override nonceChangeMarshaling -out gen_nonce_change_json.go
+//go:generate go run github.com/fjl/gencodec -type CodeChange -field-override codeChangeMarshaling -out gen_code_change_json.go
+
+// BlockAccessList is the list of all the accounts and storage slots accessed
+// during the execution of a block, along with the post-values of the changes
+// made to them. The changes are keyed by block access index: zero for the system
+// calls preceding the transactions, i+1 for the i-th transaction, and one past
+// the last transaction for the withdrawals and block rewards.
+//
+// The accounts are sorted by address, the storage slots by key and the changes
+// by block access index, making the encoding of a list canonical.
+type BlockAccessList []*AccountAccess
+
+// Hash returns the keccak256 hash of the RLP encoding of the access list.
+func (l BlockAccessList) Hash() common.Hash {
+	return rlpHash(l)
+}
+
+// AccountAccess is the access of an account in a block.
+type AccountAccess struct {
+	Address        common.Address   `json:"address"`
+	StorageChanges []*SlotChanges   `json:"storageChanges,omitempty"` // Storage slots written
+	StorageReads   []common.Hash    `json:"storageReads,omitempty"`   // Storage slots only read
+	BalanceChanges []*BalanceChange `json:"balanceChanges,omitempty"`
+	NonceChanges   []*NonceChange   `json:"nonceChanges,omitempty"`
+	CodeChanges    []*CodeChange    `json:"codeChanges,omitempty"`
+}
+
+// SlotChanges is the list of changes of a storage slot.
+type SlotChanges struct {
+	Slot    common.Hash      `json:"slot"`
+	Changes []*StorageChange `json:"changes"`
+}
+
+// StorageChange is the post-value of a storage slot changed at a block access index.
+type StorageChange struct {
+	Index uint64      `json:"blockAccessIndex"`
+	Value common.Hash `json:"postValue"`
+}
+
+// field type overrides for gencodec
+type storageChangeMarshaling struct {
+	Index hexutil.Uint64
+}
+
+// BalanceChange is the post-balance of an account changed at a block access index.
+type BalanceChange struct {
+	Index   uint64       `json:"blockAccessIndex"`
+	Balance *uint256.Int `json:"postBalance"`
+}
+
+// field type overrides for gencodec
+type balanceChangeMarshaling struct {
+	Index   hexutil.Uint64
+	Balance *hexutil.U256
+}
+
+// NonceChange is the post-nonce of an account changed at a block access index.
+type NonceChange struct {
+	Index uint64 `json:"blockAccessIndex"`
+	Nonce uint64 `json:"postNonce"`
+}
+
+// field type overrides for gencodec
+type nonceChangeMarshaling struct {
+	Index hexutil.Uint64
+	Nonce hexutil.Uint64
+}
+
+// CodeChange is the new code of an account changed at a block access index.
+type CodeChange struct {
+	Index uint64 `json:"blockAccessIndex"`
+	Code  []byte `json:"newCode"`
+}
+
+// field type overrides for gencodec
+type codeChangeMarshaling struct {
+	Index hexutil.Uint64
+	Code  hexutil.Bytes
+}
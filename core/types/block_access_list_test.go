@@ -0,0 +1,73 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+func TestBlockAccessListEncoding(t *testing.T) {
+	list := BlockAccessList{
+		{
+			Address: common.Address{0x01},
+			StorageChanges: []*SlotChanges{{
+				Slot:    common.Hash{0x01},
+				Changes: []*StorageChange{{Index: 1, Value: common.Hash{0x02}}, {Index: 3, Value: common.Hash{0x03}}},
+			}},
+			StorageReads:   []common.Hash{{0x02}},
+			BalanceChanges: []*BalanceChange{{Index: 1, Balance: uint256.NewInt(100)}},
+			NonceChanges:   []*NonceChange{{Index: 2, Nonce: 1}},
+			CodeChanges:    []*CodeChange{{Index: 2, Code: []byte{0x60, 0x00}}},
+		},
+		{
+			Address:        common.Address{0x02},
+			BalanceChanges: []*BalanceChange{{Index: 4, Balance: uint256.NewInt(0)}},
+		},
+	}
+	blob, err := json.Marshal(list)
+	if err != nil {
+		t.Fatalf("failed to marshal access list: %v", err)
+	}
+	var dec BlockAccessList
+	if err := json.Unmarshal(blob, &dec); err != nil {
+		t.Fatalf("failed to unmarshal access list: %v", err)
+	}
+	if dec.Hash() != list.Hash() {
+		t.Fatalf("JSON round trip hash mismatch: have %x, want %x", dec.Hash(), list.Hash())
+	}
+	enc, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		t.Fatalf("failed to encode access list: %v", err)
+	}
+	dec = nil
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatalf("failed to decode access list: %v", err)
+	}
+	if dec.Hash() != list.Hash() {
+		t.Fatalf("RLP round trip hash mismatch: have %x, want %x", dec.Hash(), list.Hash())
+	}
+	// Any change to the list alters its hash
+	list[1].BalanceChanges[0].Index = 3
+	if dec.Hash() == list.Hash() {
+		t.Fatal("hash unchanged by access list change")
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -235,10 +236,44 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	if receipts == nil {
+		// The receipts might have been skipped during sync, regenerate them
+		header := b.eth.blockchain.GetHeaderByHash(hash)
+		if header != nil && header.Number.Uint64() < b.eth.blockchain.ReceiptsTail() {
+			return b.eth.receiptRegen.receipts(ctx, hash, header.Number.Uint64())
+		}
+	}
+	return receipts, nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	if number < b.eth.blockchain.ReceiptsTail() {
+		// The receipts might have been skipped during sync, in which case
+		// they need to be regenerated.
+		receipts, err := b.GetReceipts(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		logs := make([][]*types.Log, len(receipts))
+		for i, receipt := range receipts {
+			logs[i] = receipt.Logs
+		}
+		return logs, nil
+	}
+	if number < b.eth.blockchain.HistoryPruningCutoff() {
+		// The receipts have expired, they might still be available from
+		// the era1 archive.
+		receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+		if receipts == nil {
+			return nil, ethapi.NewPrunedHistoryError()
+		}
+		logs := make([][]*types.Log, len(receipts))
+		for i, receipt := range receipts {
+			logs[i] = receipt.Logs
+		}
+		return logs, nil
+	}
 	return rawdb.ReadLogs(b.eth.chainDb, hash, number), nil
 }
 
@@ -279,6 +314,10 @@ func (b *EthAPIBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) e
 	return b.eth.BlockChain().SubscribeChainSideEvent(ch)
 }
 
+func (b *EthAPIBackend) HistoryPruningCutoff() uint64 {
+	return b.eth.blockchain.HistoryPruningCutoff()
+}
+
 func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.eth.BlockChain().SubscribeLogsEvent(ch)
 }
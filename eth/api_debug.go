@@ -26,6 +26,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -443,3 +445,234 @@ func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	}
 	return api.eth.blockchain.GetTrieFlushInterval().String(), nil
 }
+
+// ExecutionWitness re-executes a block on top of its parent state and returns
+// the witness required to execute it statelessly.
+func (api *DebugAPI) ExecutionWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateless.Witness, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash.String())
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not executable")
+	}
+	return api.eth.blockchain.ExecutionWitness(block)
+}
+
+// SyncProgressResult is the result of a debug_syncProgress call.
+type SyncProgressResult struct {
+	Mode    string `json:"mode"`
+	Syncing bool   `json:"syncing"`
+
+	StartingBlock uint64  `json:"startingBlock"`
+	CurrentBlock  uint64  `json:"currentBlock"`
+	HighestBlock  uint64  `json:"highestBlock"`
+	CurrentHeader uint64  `json:"currentHeader"`
+	ETA           float64 `json:"eta"` // seconds
+
+	Queue SyncQueueResult  `json:"queue"`
+	State *SyncStateResult `json:"state"`
+}
+
+// SyncQueueResult reports the retrieval tasks of the chain downloader.
+type SyncQueueResult struct {
+	PendingHeaders   int    `json:"pendingHeaders"`
+	PendingBodies    int    `json:"pendingBodies"`
+	PendingReceipts  int    `json:"pendingReceipts"`
+	InFlightHeaders  int    `json:"inFlightHeaders"`
+	InFlightBodies   int    `json:"inFlightBodies"`
+	InFlightReceipts int    `json:"inFlightReceipts"`
+	ItemSize         uint64 `json:"itemSize"`
+}
+
+// SyncStateResult reports the progress of the snap state sync.
+type SyncStateResult struct {
+	Phase   string      `json:"phase"`
+	Root    common.Hash `json:"root"`
+	Started int64       `json:"started"` // unix timestamp
+
+	AccountSynced  uint64  `json:"accountSynced"`
+	AccountBytes   uint64  `json:"accountBytes"`
+	BytecodeSynced uint64  `json:"bytecodeSynced"`
+	BytecodeBytes  uint64  `json:"bytecodeBytes"`
+	StorageSynced  uint64  `json:"storageSynced"`
+	StorageBytes   uint64  `json:"storageBytes"`
+	DownloadRate   float64 `json:"downloadRate"` // bytes per second
+	Progress       float64 `json:"progress"`
+	ETA            float64 `json:"eta"` // seconds
+
+	HealStarted        int64   `json:"healStarted"` // unix timestamp
+	TrienodeHealSynced uint64  `json:"trienodeHealSynced"`
+	TrienodeHealBytes  uint64  `json:"trienodeHealBytes"`
+	BytecodeHealSynced uint64  `json:"bytecodeHealSynced"`
+	BytecodeHealBytes  uint64  `json:"bytecodeHealBytes"`
+	AccountHealed      uint64  `json:"accountHealed"`
+	StorageHealed      uint64  `json:"storageHealed"`
+	HealRate           float64 `json:"healRate"` // trie nodes per second
+	PendingTrienodes   uint64  `json:"pendingTrienodes"`
+	PendingBytecodes   uint64  `json:"pendingBytecodes"`
+	PendingHealTasks   uint64  `json:"pendingHealTasks"`
+
+	Peers []SyncPeerResult `json:"peers"`
+}
+
+// SyncPeerResult reports the state data delivered by a single peer.
+type SyncPeerResult struct {
+	Peer          string `json:"peer"`
+	Responses     uint64 `json:"responses"`
+	AccountBytes  uint64 `json:"accountBytes"`
+	StorageBytes  uint64 `json:"storageBytes"`
+	BytecodeBytes uint64 `json:"bytecodeBytes"`
+	TrienodeBytes uint64 `json:"trienodeBytes"`
+	LastDelivery  int64  `json:"lastDelivery"` // unix timestamp
+}
+
+// SyncProgress returns a detailed report of the sync progress, including the
+// chain download queues, the snap sync phases, per-peer contributions and the
+// estimated completion times.
+func (api *DebugAPI) SyncProgress() *SyncProgressResult {
+	status := api.eth.handler.downloader.Status()
+	result := &SyncProgressResult{
+		Mode:          status.Mode.String(),
+		Syncing:       status.Syncing,
+		StartingBlock: status.StartingBlock,
+		CurrentBlock:  status.CurrentBlock,
+		HighestBlock:  status.HighestBlock,
+		CurrentHeader: status.CurrentHeader,
+		ETA:           status.ETA.Seconds(),
+		Queue: SyncQueueResult{
+			PendingHeaders:   status.PendingHeaders,
+			PendingBodies:    status.PendingBodies,
+			PendingReceipts:  status.PendingReceipts,
+			InFlightHeaders:  status.InFlightHeaders,
+			InFlightBodies:   status.InFlightBodies,
+			InFlightReceipts: status.InFlightReceipts,
+			ItemSize:         uint64(status.ItemSize),
+		},
+	}
+	if state := status.State; state != nil {
+		result.State = &SyncStateResult{
+			Phase:              string(state.Phase),
+			Root:               state.Root,
+			Started:            unixTime(state.Started),
+			AccountSynced:      state.AccountSynced,
+			AccountBytes:       uint64(state.AccountBytes),
+			BytecodeSynced:     state.BytecodeSynced,
+			BytecodeBytes:      uint64(state.BytecodeBytes),
+			StorageSynced:      state.StorageSynced,
+			StorageBytes:       uint64(state.StorageBytes),
+			DownloadRate:       state.DownloadRate,
+			Progress:           state.Progress,
+			ETA:                state.ETA.Seconds(),
+			HealStarted:        unixTime(state.HealStarted),
+			TrienodeHealSynced: state.TrienodeHealSynced,
+			TrienodeHealBytes:  uint64(state.TrienodeHealBytes),
+			BytecodeHealSynced: state.BytecodeHealSynced,
+			BytecodeHealBytes:  uint64(state.BytecodeHealBytes),
+			AccountHealed:      state.AccountHealed,
+			StorageHealed:      state.StorageHealed,
+			HealRate:           state.HealRate,
+			PendingTrienodes:   state.PendingTrienodes,
+			PendingBytecodes:   state.PendingBytecodes,
+			PendingHealTasks:   state.PendingHealTasks,
+			Peers:              make([]SyncPeerResult, len(state.Peers)),
+		}
+		for i, p := range state.Peers {
+			result.State.Peers[i] = SyncPeerResult{
+				Peer:          p.Peer,
+				Responses:     p.Responses,
+				AccountBytes:  uint64(p.AccountBytes),
+				StorageBytes:  uint64(p.StorageBytes),
+				BytecodeBytes: uint64(p.BytecodeBytes),
+				TrienodeBytes: uint64(p.TrienodeBytes),
+				LastDelivery:  unixTime(p.LastDelivery),
+			}
+		}
+	}
+	return result
+}
+
+// unixTime converts t into a unix timestamp, mapping the zero time to zero.
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// StatePruningArgs are the optional settings of a debug_startStatePruning call.
+type StatePruningArgs struct {
+	BloomSize uint64 `json:"bloomSize"` // megabytes
+	BatchSize int    `json:"batchSize"`
+	Throttle  string `json:"throttle"`
+}
+
+// StartStatePruning starts removing the stale trie nodes of the hash-based
+// state database in the background, while the chain keeps importing blocks.
+func (api *DebugAPI) StartStatePruning(args *StatePruningArgs) error {
+	config := pruner.DefaultOnlineConfig
+	if args != nil {
+		if args.BloomSize != 0 {
+			config.BloomSize = args.BloomSize
+		}
+		if args.BatchSize != 0 {
+			config.BatchSize = args.BatchSize
+		}
+		if args.Throttle != "" {
+			throttle, err := time.ParseDuration(args.Throttle)
+			if err != nil {
+				return err
+			}
+			config.Throttle = throttle
+		}
+	}
+	return api.eth.blockchain.StartStatePruning(config)
+}
+
+// StatePruningResult is the result of a debug_statePruningProgress call.
+type StatePruningResult struct {
+	Phase   string      `json:"phase"`
+	Root    common.Hash `json:"root"`
+	Roots   int         `json:"roots"`
+	Started int64       `json:"started"` // unix timestamp
+	Elapsed float64     `json:"elapsed"` // seconds
+
+	Marked  uint64 `json:"marked"`
+	Flushed uint64 `json:"flushed"`
+	Swept   uint64 `json:"swept"`
+	Deleted uint64 `json:"deleted"`
+	Size    uint64 `json:"size"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// StatePruningProgress returns the status of the running or the last finished
+// state pruning, nil if none was started yet.
+func (api *DebugAPI) StatePruningProgress() *StatePruningResult {
+	progress := api.eth.blockchain.StatePruningProgress()
+	if progress == nil {
+		return nil
+	}
+	return &StatePruningResult{
+		Phase:   progress.Phase,
+		Root:    progress.Root,
+		Roots:   progress.Roots,
+		Started: unixTime(progress.Started),
+		Elapsed: progress.Elapsed.Seconds(),
+		Marked:  progress.Marked,
+		Flushed: progress.Flushed,
+		Swept:   progress.Swept,
+		Deleted: progress.Deleted,
+		Size:    uint64(progress.Size),
+		Error:   progress.Err,
+	}
+}
+
+// AbortStatePruning interrupts the running state pruning. The trie nodes
+// deleted so far are all stale, so the database is left consistent.
+func (api *DebugAPI) AbortStatePruning() error {
+	return api.eth.blockchain.AbortStatePruning()
+}
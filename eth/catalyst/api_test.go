@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	crand "crypto/rand"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -35,6 +36,7 @@ import (
 	beaconConsensus "github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
@@ -1658,6 +1660,74 @@ func TestParentBeaconBlockRoot(t *testing.T) {
 	}
 }
 
+// TestNewPayloadWithAccessList verifies that payloads carrying a block-level
+// access list are accepted, whether the access list matches or not.
+func TestNewPayloadWithAccessList(t *testing.T) {
+	genesis, preMergeBlocks := generateMergeChain(10, false)
+	n, ethservice := startEthService(t, genesis, preMergeBlocks)
+	defer n.Close()
+
+	var (
+		api    = NewConsensusAPI(ethservice)
+		parent = preMergeBlocks[len(preMergeBlocks)-1]
+		signer = types.LatestSigner(ethservice.BlockChain().Config())
+	)
+	statedb, _ := ethservice.BlockChain().StateAt(parent.Root())
+	tx, _ := types.SignTx(types.NewTransaction(statedb.GetNonce(testAddr), common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(2*params.InitialBaseFee), nil), signer, testKey)
+	ethservice.TxPool().Add([]*types.Transaction{tx}, true, true)
+
+	execData, err := assembleWithTransactions(api, parent.Hash(), &engine.PayloadAttributes{
+		Timestamp: parent.Time() + 5,
+	}, 1)
+	if err != nil {
+		t.Fatalf("Failed to create the executable data: %v", err)
+	}
+	block, err := engine.ExecutableDataToBlock(*execData, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to convert executable data to block: %v", err)
+	}
+	list, err := ethservice.BlockChain().AccessList(block)
+	if err != nil {
+		t.Fatalf("Failed to generate access list: %v", err)
+	}
+	// A mismatching access list is discarded, without invalidating the block
+	tampered := (*list)[1:]
+	execData.BlockAccessList = &tampered
+
+	status, err := api.NewPayloadV1(*execData)
+	if err != nil {
+		t.Fatalf("Failed to insert block: %v", err)
+	}
+	if status.Status != engine.VALID {
+		t.Fatalf("Unexpected status for mismatching access list: %v", status.Status)
+	}
+	if bad := rawdb.ReadAllBadBlocks(ethservice.ChainDb()); len(bad) != 0 {
+		t.Fatal("Block with mismatching access list reported as bad")
+	}
+	if res := api.checkInvalidAncestor(block.Hash(), block.Hash()); res != nil {
+		t.Fatalf("Block with mismatching access list marked invalid: %v", res.Status)
+	}
+	// The access list survives the engine API encoding
+	execData.BlockAccessList = list
+	blob, err := json.Marshal(execData)
+	if err != nil {
+		t.Fatalf("Failed to encode executable data: %v", err)
+	}
+	var decoded engine.ExecutableData
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("Failed to decode executable data: %v", err)
+	}
+	if decoded.BlockAccessList == nil || decoded.BlockAccessList.Hash() != list.Hash() {
+		t.Fatal("Access list lost in the executable data encoding")
+	}
+	if status, err = api.NewPayloadV1(decoded); err != nil {
+		t.Fatalf("Failed to insert block: %v", err)
+	}
+	if status.Status != engine.VALID {
+		t.Fatalf("Unexpected status for matching access list: %v", status.Status)
+	}
+}
+
 // TestGetClientVersion verifies the expected version info is returned.
 func TestGetClientVersion(t *testing.T) {
 	genesis, preMergeBlocks := generateMergeChain(10, false)
@@ -33,12 +33,17 @@ import (
 //
 // This tester can be applied to different networks, no matter it's pre-merge or
 // post-merge, but only for full-sync.
+//
+// In checkpoint mode, the target is a trusted checkpoint synced to with the
+// configured sync mode, and the node keeps running once it has been reached,
+// serving the pinned chain and state until a consensus client drives it further.
 type FullSyncTester struct {
-	stack   *node.Node
-	backend *eth.Ethereum
-	target  common.Hash
-	closed  chan struct{}
-	wg      sync.WaitGroup
+	stack      *node.Node
+	backend    *eth.Ethereum
+	target     common.Hash
+	checkpoint bool
+	closed     chan struct{}
+	wg         sync.WaitGroup
 }
 
 // RegisterFullSyncTester registers the full-sync tester service into the node
@@ -54,15 +59,40 @@ func RegisterFullSyncTester(stack *node.Node, backend *eth.Ethereum, target comm
 	return cl, nil
 }
 
+// RegisterCheckpointSyncer registers a full-sync tester in checkpoint mode into
+// the node stack, syncing to the given trusted checkpoint.
+func RegisterCheckpointSyncer(stack *node.Node, backend *eth.Ethereum, checkpoint common.Hash) (*FullSyncTester, error) {
+	cl := &FullSyncTester{
+		stack:      stack,
+		backend:    backend,
+		target:     checkpoint,
+		checkpoint: true,
+		closed:     make(chan struct{}),
+	}
+	stack.RegisterLifecycle(cl)
+	return cl, nil
+}
+
 // Start launches the beacon sync with provided sync target.
 func (tester *FullSyncTester) Start() error {
+	if tester.checkpoint {
+		if block := tester.backend.BlockChain().GetBlockByHash(tester.target); block != nil {
+			log.Info("Sync checkpoint already present", "number", block.NumberU64(), "hash", block.Hash())
+			return nil
+		}
+	}
 	tester.wg.Add(1)
 	go func() {
 		defer tester.wg.Done()
 
 		// Trigger beacon sync with the provided block hash as trusted
 		// chain head.
-		err := tester.backend.Downloader().BeaconDevSync(downloader.FullSync, tester.target, tester.closed)
+		var err error
+		if tester.checkpoint {
+			err = tester.backend.Downloader().CheckpointSync(tester.backend.SyncMode(), tester.target, tester.closed)
+		} else {
+			err = tester.backend.Downloader().BeaconDevSync(downloader.FullSync, tester.target, tester.closed)
+		}
 		if err != nil {
 			log.Info("Failed to trigger beacon sync", "err", err)
 		}
@@ -75,6 +105,10 @@ func (tester *FullSyncTester) Start() error {
 			case <-ticker.C:
 				// Stop in case the target block is already stored locally.
 				if block := tester.backend.BlockChain().GetBlockByHash(tester.target); block != nil {
+					if tester.checkpoint {
+						log.Info("Sync checkpoint reached", "number", block.NumberU64(), "hash", block.Hash())
+						return
+					}
 					log.Info("Full-sync target reached", "number", block.NumberU64(), "hash", block.Hash())
 					go tester.stack.Close() // async since we need to close ourselves
 					return
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -40,16 +41,45 @@ func (d *Downloader) BeaconDevSync(mode SyncMode, hash common.Hash, stop chan st
 	log.Warn("This is unhealthy for a live node!")
 	log.Warn("----------------------------------")
 
+	header, err := d.fetchTrustedHeader(hash, stop)
+	if err != nil {
+		return err
+	}
+	return d.BeaconSync(mode, header, header)
+}
+
+// CheckpointSync starts a sync towards a trusted checkpoint block without a
+// consensus client attached. The header of the checkpoint is retrieved from the
+// network and verified against the hash, then used to seed the skeleton chain
+// as both head and finalized block.
+//
+// Unlike BeaconDevSync, this is meant for bootstrapping nodes from a trusted
+// source, such as a light client or a pinned state of a replica. The chain is
+// not progressed beyond the checkpoint until a consensus client takes over.
+func (d *Downloader) CheckpointSync(mode SyncMode, hash common.Hash, stop chan struct{}) error {
+	log.Info("Starting checkpoint sync", "hash", hash, "mode", mode)
+	header, err := d.fetchTrustedHeader(hash, stop)
+	if err != nil {
+		return err
+	}
+	log.Info("Retrieved sync checkpoint", "number", header.Number, "hash", hash)
+	return d.BeaconSync(mode, header, header)
+}
+
+// fetchTrustedHeader retrieves the header with the given hash from the network,
+// blocking until a peer delivers it or stop is closed. Peers are picked at
+// random, so that a single faulty peer can't stall the retrieval.
+func (d *Downloader) fetchTrustedHeader(hash common.Hash, stop chan struct{}) (*types.Header, error) {
 	log.Info("Waiting for peers to retrieve sync target")
 	for {
 		// If the node is going down, unblock
 		select {
 		case <-stop:
-			return errors.New("stop requested")
+			return nil, errors.New("stop requested")
 		default:
 		}
 		// Pick a random peer to sync from and keep retrying if none are yet
-		// available due to fresh startup
+		// available due to fresh startup. Map iteration order is random.
 		d.peers.lock.RLock()
 		var peer *peerConnection
 		for _, peer = range d.peers.peers {
@@ -76,6 +106,6 @@ func (d *Downloader) BeaconDevSync(mode SyncMode, hash common.Hash, stop chan st
 			time.Sleep(time.Second)
 			continue
 		}
-		return d.BeaconSync(mode, headers[0], headers[0])
+		return headers[0], nil
 	}
 }
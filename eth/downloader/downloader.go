@@ -95,6 +95,7 @@ type Downloader struct {
 	// Statistics
 	syncStatsChainOrigin uint64       // Origin block number where syncing started at
 	syncStatsChainHeight uint64       // Highest block number known when syncing started
+	syncStatsStartTime   time.Time    // Time instance when syncing from the origin started
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
 	blockchain BlockChain
@@ -108,6 +109,7 @@ type Downloader struct {
 	notified      atomic.Bool
 	committed     atomic.Bool
 	ancientLimit  uint64 // The maximum block number which can be regarded as ancient data.
+	receiptWindow uint64 // Number of recent blocks whose receipts are downloaded in snap sync (0 = all)
 
 	// Channels
 	headerProcCh chan *headerTask // Channel to feed the header processor new tasks
@@ -179,6 +181,12 @@ type BlockChain interface {
 	// SnapSyncCommitHead directly commits the head block to a certain entity.
 	SnapSyncCommitHead(common.Hash) error
 
+	// ReceiptsTail returns the oldest block whose receipts were not skipped.
+	ReceiptsTail() uint64
+
+	// SetReceiptsTail marks the receipts of all older blocks as skipped.
+	SetReceiptsTail(uint64)
+
 	// InsertChain inserts a batch of blocks into the local chain.
 	InsertChain(types.Blocks) (int, error)
 
@@ -194,10 +202,15 @@ type BlockChain interface {
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
-func New(stateDb ethdb.Database, mux *event.TypeMux, chain BlockChain, dropPeer peerDropFn, success func()) *Downloader {
+//
+// If receiptWindow is non-zero, snap sync only downloads the receipts of the
+// given number of recent blocks when syncing a fresh node. Older receipts are
+// skipped and need to be regenerated by re-executing the blocks.
+func New(stateDb ethdb.Database, mux *event.TypeMux, chain BlockChain, receiptWindow uint64, dropPeer peerDropFn, success func()) *Downloader {
 	dl := &Downloader{
 		stateDB:        stateDb,
 		mux:            mux,
+		receiptWindow:  receiptWindow,
 		queue:          newQueue(blockCacheMaxItems, blockCacheInitialItems),
 		peers:          newPeerSet(),
 		blockchain:     chain,
@@ -258,6 +271,67 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 	}
 }
 
+// SyncStatus is a detailed snapshot of the sync progress, covering the chain
+// download queues and the state sync.
+type SyncStatus struct {
+	Mode    SyncMode
+	Syncing bool // Whether a sync cycle is currently running
+
+	StartingBlock uint64        // Block number where sync began
+	CurrentBlock  uint64        // Current block number where sync is at
+	HighestBlock  uint64        // Highest alleged block number in the chain
+	CurrentHeader uint64        // Current header number where sync is at
+	ETA           time.Duration // Estimated time until the chain download completes
+
+	PendingHeaders   int                // Header batches queued for retrieval
+	PendingBodies    int                // Block bodies queued for retrieval
+	PendingReceipts  int                // Block receipts queued for retrieval
+	InFlightHeaders  int                // Header requests currently in flight
+	InFlightBodies   int                // Body requests currently in flight
+	InFlightReceipts int                // Receipt requests currently in flight
+	ItemSize         common.StorageSize // Approximate size of a downloaded block
+
+	State *snap.SyncStatus // State sync status, only meaningful in snap sync
+}
+
+// Status retrieves a detailed snapshot of the sync progress. Compared to
+// Progress, it also reports the download queues, the state of the snap sync
+// phases, per-peer state contributions and completion time estimates.
+func (d *Downloader) Status() *SyncStatus {
+	progress := d.Progress()
+
+	d.syncStatsLock.RLock()
+	started := d.syncStatsStartTime
+	d.syncStatsLock.RUnlock()
+
+	queue := d.queue.Status()
+	status := &SyncStatus{
+		Mode:             d.getMode(),
+		Syncing:          d.synchronising.Load(),
+		StartingBlock:    progress.StartingBlock,
+		CurrentBlock:     progress.CurrentBlock,
+		HighestBlock:     progress.HighestBlock,
+		CurrentHeader:    d.blockchain.CurrentHeader().Number.Uint64(),
+		PendingHeaders:   queue.PendingHeaders,
+		PendingBodies:    queue.PendingBodies,
+		PendingReceipts:  queue.PendingReceipts,
+		InFlightHeaders:  queue.InFlightHeaders,
+		InFlightBodies:   queue.InFlightBodies,
+		InFlightReceipts: queue.InFlightReceipts,
+		ItemSize:         queue.ItemSize,
+		State:            d.SnapSyncer.Status(),
+	}
+	// Extrapolate the remaining time from the pace since the sync origin
+	if !started.IsZero() && status.CurrentBlock > status.StartingBlock && status.HighestBlock > status.CurrentBlock {
+		var (
+			synced = status.CurrentBlock - status.StartingBlock
+			left   = status.HighestBlock - status.CurrentBlock
+		)
+		status.ETA = time.Since(started) / time.Duration(synced) * time.Duration(left)
+	}
+	return status
+}
+
 // RegisterPeer injects a new download peer into the set of block source to be
 // used for fetching hashes and blocks from.
 func (d *Downloader) RegisterPeer(id string, version uint, peer Peer) error {
@@ -447,6 +521,7 @@ func (d *Downloader) syncToHead() (err error) {
 	d.syncStatsLock.Lock()
 	if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
 		d.syncStatsChainOrigin = origin
+		d.syncStatsStartTime = time.Now()
 	}
 	d.syncStatsChainHeight = height
 	d.syncStatsLock.Unlock()
@@ -513,8 +588,17 @@ func (d *Downloader) syncToHead() (err error) {
 			log.Info("Truncated excess ancient chain segment", "oldhead", frozen-1, "newhead", origin)
 		}
 	}
+	// Skip the receipts of old blocks if only a recent window is requested. The
+	// boundary is decided by the first sync cycle of a fresh node and persisted,
+	// so resumed syncs don't leave gaps in the receipts above it.
+	receiptsFrom := d.blockchain.ReceiptsTail()
+	if mode == SnapSync && d.receiptWindow != 0 && receiptsFrom == 0 && origin == 0 && height > d.receiptWindow {
+		receiptsFrom = height - d.receiptWindow
+		d.blockchain.SetReceiptsTail(receiptsFrom)
+		log.Info("Skipping receipts of old blocks", "below", receiptsFrom, "window", d.receiptWindow)
+	}
 	// Initiate the sync using a concurrent header and content retrieval algorithm
-	d.queue.Prepare(origin+1, mode)
+	d.queue.Prepare(origin+1, mode, receiptsFrom)
 
 	// In beacon mode, headers are served by the skeleton syncer
 	fetchers := []func() error{
@@ -1103,6 +1187,7 @@ func (d *Downloader) reportSnapSyncProgress(force bool) {
 		bodies   = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(block.Number.Uint64()), common.StorageSize(bodyBytes).TerminalString())
 		receipts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(block.Number.Uint64()), common.StorageSize(receiptBytes).TerminalString())
 	)
+	chainETAGauge.Update(int64(eta / time.Second))
 	log.Info("Syncing: chain download in progress", "synced", progress, "chain", syncedBytes, "headers", headers, "bodies", bodies, "receipts", receipts, "eta", common.PrettyDuration(eta))
 	d.syncLogTime = time.Now()
 }
@@ -76,7 +76,7 @@ func newTesterWithNotification(t *testing.T, success func()) *downloadTester {
 		chain: chain,
 		peers: make(map[string]*downloadTesterPeer),
 	}
-	tester.downloader = New(db, new(event.TypeMux), tester.chain, tester.dropPeer, success)
+	tester.downloader = New(db, new(event.TypeMux), tester.chain, 0, tester.dropPeer, success)
 	return tester
 }
 
@@ -124,6 +124,7 @@ type downloadTesterPeer struct {
 	withholdBodies map[common.Hash]struct{}
 	id             string
 	chain          *core.BlockChain
+	hashRequests   atomic.Int32 // Number of header requests by hash served
 }
 
 // Head constructs a function to retrieve a peer's current head hash
@@ -149,6 +150,8 @@ func unmarshalRlpHeaders(rlpdata []rlp.RawValue) []*types.Header {
 // origin; associated with a particular peer in the download tester. The returned
 // function can be used to retrieve batches of headers from the particular peer.
 func (dlp *downloadTesterPeer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse bool, sink chan *eth.Response) (*eth.Request, error) {
+	dlp.hashRequests.Add(1)
+
 	// Service the header query via the live handler code
 	rlpHeaders := eth.ServiceGetBlockHeadersQuery(dlp.chain, &eth.GetBlockHeadersRequest{
 		Origin: eth.HashOrNumber{
@@ -685,6 +688,80 @@ func testBeaconSync(t *testing.T, protocol uint, mode SyncMode) {
 	}
 }
 
+// Tests that checkpoint sync retrieves the trusted header from the network and
+// synchronises up to it.
+func TestCheckpointSync68Full(t *testing.T) { testCheckpointSync(t, eth.ETH68, FullSync) }
+func TestCheckpointSync68Snap(t *testing.T) { testCheckpointSync(t, eth.ETH68, SnapSync) }
+
+func testCheckpointSync(t *testing.T, protocol uint, mode SyncMode) {
+	success := make(chan struct{})
+	tester := newTesterWithNotification(t, func() {
+		close(success)
+	})
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", protocol, chain.blocks[1:])
+
+	// In full sync, use a checkpoint in the middle of the chain to ensure the
+	// blocks after it are not retrieved. Snap sync needs the state of the pivot,
+	// which test peers only serve near their head.
+	checkpoint := chain.blocks[len(chain.blocks)-1]
+	if mode == FullSync {
+		checkpoint = chain.blocks[len(chain.blocks)/2]
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := tester.downloader.CheckpointSync(mode, checkpoint.Hash(), stop); err != nil {
+		t.Fatalf("Failed to start checkpoint sync: %v", err)
+	}
+	select {
+	case <-success:
+		if head := tester.chain.CurrentBlock(); head.Hash() != checkpoint.Hash() {
+			t.Fatalf("synchronised head mismatch: have %d, want %d", head.Number, checkpoint.Number())
+		}
+	case <-time.NewTimer(time.Second * 3).C:
+		t.Fatalf("Failed to sync chain in three seconds")
+	}
+}
+
+// Tests that checkpoint sync keeps waiting for the checkpoint if no peer can
+// deliver it, until it is stopped.
+func TestCheckpointSyncUnknown(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	peer := tester.newPeer("peer", eth.ETH68, chain.blocks[1:])
+
+	stop := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tester.downloader.CheckpointSync(SnapSync, common.Hash{0xff}, stop)
+	}()
+	// Wait for the checkpoint to be requested from the peer before stopping.
+	deadline := time.Now().Add(5 * time.Second)
+	for peer.hashRequests.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("checkpoint never requested")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("checkpoint sync started with unknown checkpoint")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("checkpoint sync not stopped")
+	}
+	if head := tester.chain.CurrentHeader(); head.Number.Uint64() != 0 {
+		t.Fatalf("chain progressed to %d", head.Number)
+	}
+}
+
 // Tests that synchronisation progress (origin block number, current block number
 // and highest block number) is tracked and updated correctly.
 func TestSyncProgress68Full(t *testing.T) { testSyncProgress(t, eth.ETH68, FullSync) }
@@ -740,3 +817,101 @@ func testSyncProgress(t *testing.T, protocol uint, mode SyncMode) {
 		t.Fatalf("Failed to sync chain in three seconds")
 	}
 }
+
+// Tests that the detailed sync status reports the chain progress and drains the
+// download queues once a sync cycle completes.
+func TestSyncStatus68Full(t *testing.T) { testSyncStatus(t, eth.ETH68, FullSync) }
+func TestSyncStatus68Snap(t *testing.T) { testSyncStatus(t, eth.ETH68, SnapSync) }
+
+func testSyncStatus(t *testing.T, protocol uint, mode SyncMode) {
+	success := make(chan struct{})
+	tester := newTesterWithNotification(t, func() {
+		close(success)
+	})
+	defer tester.terminate()
+
+	if status := tester.downloader.Status(); status.Syncing || status.ETA != 0 || status.State == nil {
+		t.Fatalf("unexpected pristine status: %+v", status)
+	}
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", protocol, chain.blocks[1:])
+
+	if err := tester.downloader.BeaconSync(mode, chain.blocks[len(chain.blocks)-1].Header(), nil); err != nil {
+		t.Fatalf("failed to beacon-sync chain: %v", err)
+	}
+	select {
+	case <-success:
+	case <-time.NewTimer(time.Second * 3).C:
+		t.Fatalf("Failed to sync chain in three seconds")
+	}
+	status := tester.downloader.Status()
+	if status.Mode != mode {
+		t.Errorf("wrong mode: have %v, want %v", status.Mode, mode)
+	}
+	if head := uint64(len(chain.blocks) - 1); status.CurrentBlock != head || status.HighestBlock != head || status.CurrentHeader != head {
+		t.Errorf("wrong chain progress: have %d/%d/%d, want %d", status.CurrentBlock, status.CurrentHeader, status.HighestBlock, head)
+	}
+	if status.ETA != 0 {
+		t.Errorf("non-zero ETA after sync: %v", status.ETA)
+	}
+	if status.PendingBodies != 0 || status.PendingReceipts != 0 || status.InFlightBodies != 0 || status.InFlightReceipts != 0 {
+		t.Errorf("queue not drained: %+v", status)
+	}
+}
+
+// Tests that snap sync only downloads the receipts of a recent window of blocks
+// if requested, and marks the older ones as skipped.
+func TestReceiptWindowSync68(t *testing.T) {
+	success := make(chan struct{})
+	tester := newTesterWithNotification(t, func() {
+		close(success)
+	})
+	defer tester.terminate()
+
+	const window = 200
+	tester.downloader.receiptWindow = window
+
+	var (
+		lock   sync.Mutex
+		oldest = ^uint64(0)
+	)
+	tester.downloader.receiptFetchHook = func(headers []*types.Header) {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, header := range headers {
+			oldest = min(oldest, header.Number.Uint64())
+		}
+	}
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", eth.ETH68, chain.blocks[1:])
+
+	head := chain.blocks[len(chain.blocks)-1]
+	if err := tester.downloader.BeaconSync(SnapSync, head.Header(), nil); err != nil {
+		t.Fatalf("failed to beacon-sync chain: %v", err)
+	}
+	select {
+	case <-success:
+		assertOwnChain(t, tester, len(chain.blocks))
+	case <-time.NewTimer(time.Second * 3).C:
+		t.Fatalf("Failed to sync chain in three seconds")
+	}
+	tail := head.NumberU64() - window
+	if have := tester.chain.ReceiptsTail(); have != tail {
+		t.Fatalf("wrong receipts tail: have %d, want %d", have, tail)
+	}
+	if oldest < tail {
+		t.Fatalf("receipts below the window retrieved: block %d", oldest)
+	}
+	for _, block := range chain.blocks[1:] {
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		receipts := tester.chain.GetReceiptsByHash(block.Hash())
+		if block.NumberU64() < tail && receipts != nil {
+			t.Fatalf("block %d: skipped receipts available", block.NumberU64())
+		}
+		if block.NumberU64() >= tail && len(receipts) != len(block.Transactions()) {
+			t.Fatalf("block %d: receipts missing", block.NumberU64())
+		}
+	}
+}
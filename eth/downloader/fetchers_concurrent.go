@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 // timeoutGracePeriod is the amount of time to allow for a peer to deliver a
@@ -295,6 +296,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 				log.Error("Delivery timeout from unknown peer", "peer", req.Peer)
 				continue
 			}
+			reportPeer(peer.peer, p2p.ScoreRequestTimeout)
 			if fails > 2 {
 				queue.updateCapacity(peer, 0, 0)
 			} else {
@@ -342,6 +344,16 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 				if !errors.Is(err, errStaleDelivery) {
 					queue.updateCapacity(peer, accepted, res.Time)
 				}
+				// Feed the outcome of the delivery into the peer's reputation.
+				switch {
+				case errors.Is(err, errStaleDelivery):
+				case err != nil:
+					reportPeer(peer.peer, p2p.ScoreInvalidResponse)
+				case accepted == 0:
+					reportPeer(peer.peer, p2p.ScoreEmptyResponse)
+				default:
+					reportPeer(peer.peer, p2p.ScoreUsefulResponse)
+				}
 			}
 
 		case cont := <-queue.waker():
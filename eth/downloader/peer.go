@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/msgrate"
 )
 
@@ -65,6 +66,14 @@ type Peer interface {
 	RequestReceipts([]common.Hash, chan *eth.Response) (*eth.Request, error)
 }
 
+// reportPeer feeds a score event into the reputation system of the p2p layer, if
+// the peer supports it.
+func reportPeer(peer Peer, ev p2p.ScoreEvent) {
+	if r, ok := peer.(p2p.ScoreReporter); ok {
+		r.Report(ev)
+	}
+}
+
 // newPeerConnection creates a new downloader peer.
 func newPeerConnection(id string, version uint, peer Peer, logger log.Logger) *peerConnection {
 	return &peerConnection{
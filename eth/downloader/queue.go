@@ -123,7 +123,8 @@ func (f *fetchResult) Done(kind uint) bool {
 
 // queue represents hashes that are either need fetching or are being fetched
 type queue struct {
-	mode SyncMode // Synchronisation mode to decide on the block parts to schedule for fetching
+	mode         SyncMode // Synchronisation mode to decide on the block parts to schedule for fetching
+	receiptsFrom uint64   // First block whose receipts are fetched in snap sync
 
 	// Headers are "special", they download in batches, supported by a skeleton chain
 	headerHead      common.Hash                    // Hash of the last queued header to verify order
@@ -181,6 +182,7 @@ func (q *queue) Reset(blockCacheLimit int, thresholdInitialSize int) {
 
 	q.closed = false
 	q.mode = FullSync
+	q.receiptsFrom = 0
 
 	q.headerHead = common.Hash{}
 	q.headerPendPool = make(map[string]*fetchRequest)
@@ -248,6 +250,37 @@ func (q *queue) InFlightReceipts() bool {
 	return len(q.receiptPendPool) > 0
 }
 
+// queueStatus is a snapshot of the retrieval tasks tracked by the queue.
+type queueStatus struct {
+	PendingHeaders   int                // Header batches queued for retrieval
+	PendingBodies    int                // Block bodies queued for retrieval
+	PendingReceipts  int                // Block receipts queued for retrieval
+	InFlightHeaders  int                // Header requests currently in flight
+	InFlightBodies   int                // Body requests currently in flight
+	InFlightReceipts int                // Receipt requests currently in flight
+	ItemSize         common.StorageSize // Approximate size of a downloaded block
+}
+
+// Status retrieves a snapshot of the queued and in-flight retrieval tasks.
+func (q *queue) Status() queueStatus {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	status := queueStatus{
+		PendingBodies:    q.blockTaskQueue.Size(),
+		PendingReceipts:  q.receiptTaskQueue.Size(),
+		InFlightHeaders:  len(q.headerPendPool),
+		InFlightBodies:   len(q.blockPendPool),
+		InFlightReceipts: len(q.receiptPendPool),
+		ItemSize:         q.resultSize,
+	}
+	// The header task queue only exists if a skeleton was scheduled
+	if q.headerTaskQueue != nil {
+		status.PendingHeaders = q.headerTaskQueue.Size()
+	}
+	return status
+}
+
 // Idle returns if the queue is fully idle or has some data still inside.
 func (q *queue) Idle() bool {
 	q.lock.Lock()
@@ -328,7 +361,7 @@ func (q *queue) Schedule(headers []*types.Header, hashes []common.Hash, from uin
 			q.blockTaskQueue.Push(header, -int64(header.Number.Uint64()))
 		}
 		// Queue for receipt retrieval
-		if q.mode == SnapSync && !header.EmptyReceipts() {
+		if q.fetchReceipts(header) && !header.EmptyReceipts() {
 			if _, ok := q.receiptTaskPool[hash]; ok {
 				log.Warn("Header already scheduled for receipt fetch", "number", header.Number, "hash", hash)
 			} else {
@@ -373,7 +406,8 @@ func (q *queue) Results(block bool) []*fetchResult {
 	}
 	// Regardless if closed or not, we can still deliver whatever we have
 	results := q.resultCache.GetCompleted(maxResultsProcess)
-	for _, result := range results {
+	sizes := make([]common.StorageSize, len(results))
+	for i, result := range results {
 		// Recalculate the result item weights to prevent memory exhaustion
 		size := result.Header.Size()
 		for _, uncle := range result.Uncles {
@@ -385,12 +419,19 @@ func (q *queue) Results(block bool) []*fetchResult {
 		for _, tx := range result.Transactions {
 			size += common.StorageSize(tx.Size())
 		}
+		sizes[i] = size
+	}
+	// The result size is read by Status and Stats, update it under the lock
+	q.lock.Lock()
+	for _, size := range sizes {
 		q.resultSize = common.StorageSize(blockCacheSizeWeight)*size +
 			(1-common.StorageSize(blockCacheSizeWeight))*q.resultSize
 	}
 	// Using the newly calibrated resultsize, figure out the new throttle limit
 	// on the result cache
 	throttleThreshold := uint64((common.StorageSize(blockCacheMemory) + q.resultSize - 1) / q.resultSize)
+	q.lock.Unlock()
+
 	throttleThreshold = q.resultCache.SetThrottleThreshold(throttleThreshold)
 
 	// With results removed from the cache, wake throttled fetchers
@@ -400,7 +441,12 @@ func (q *queue) Results(block bool) []*fetchResult {
 		default:
 		}
 	}
-	// Log some info at certain times
+	// Update the queue metrics and log some info at certain times
+	status := q.Status()
+	headerPendingGauge.Update(int64(status.PendingHeaders))
+	bodyPendingGauge.Update(int64(status.PendingBodies))
+	receiptPendingGauge.Update(int64(status.PendingReceipts))
+
 	if time.Since(q.logTime) >= 60*time.Second {
 		q.logTime = time.Now()
 
@@ -522,7 +568,7 @@ func (q *queue) reserveHeaders(p *peerConnection, count int, taskPool map[common
 		// we can ask the resultcache if this header is within the
 		// "prioritized" segment of blocks. If it is not, we need to throttle
 
-		stale, throttle, item, err := q.resultCache.AddFetch(header, q.mode == SnapSync)
+		stale, throttle, item, err := q.resultCache.AddFetch(header, q.fetchReceipts(header))
 		if stale {
 			// Don't put back in the task queue, this item has already been
 			// delivered upstream
@@ -956,11 +1002,21 @@ func (q *queue) deliver(id string, taskPool map[common.Hash]*types.Header,
 
 // Prepare configures the result cache to allow accepting and caching inbound
 // fetch results.
-func (q *queue) Prepare(offset uint64, mode SyncMode) {
+//
+// In snap sync mode, receipts are only retrieved for blocks starting at
+// receiptsFrom, older ones are skipped.
+func (q *queue) Prepare(offset uint64, mode SyncMode, receiptsFrom uint64) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
 	// Prepare the queue for sync results
 	q.resultCache.Prepare(offset)
 	q.mode = mode
+	q.receiptsFrom = receiptsFrom
+}
+
+// fetchReceipts reports whether the receipts of the given block need to be
+// retrieved. The caller must hold the lock.
+func (q *queue) fetchReceipts(header *types.Header) bool {
+	return q.mode == SnapSync && header.Number.Uint64() >= q.receiptsFrom
 }
@@ -101,7 +101,7 @@ func TestBasics(t *testing.T) {
 	if !q.Idle() {
 		t.Errorf("new queue should be idle")
 	}
-	q.Prepare(1, SnapSync)
+	q.Prepare(1, SnapSync, 0)
 	if res := q.Results(false); len(res) != 0 {
 		t.Fatal("new queue should have 0 results")
 	}
@@ -198,7 +198,7 @@ func TestEmptyBlocks(t *testing.T) {
 
 	q := newQueue(10, 10)
 
-	q.Prepare(1, SnapSync)
+	q.Prepare(1, SnapSync, 0)
 
 	// Schedule a batch of headers
 	headers := emptyChain.headers()
@@ -261,6 +261,37 @@ func TestEmptyBlocks(t *testing.T) {
 	}
 }
 
+// Tests that the queue status can be retrieved while results are delivered,
+// which recalibrates the approximate result size.
+func TestStatusDuringResults(t *testing.T) {
+	q := newQueue(10, 10)
+	q.Prepare(1, SnapSync, 0)
+
+	headers := emptyChain.headers()
+	hashes := make([]common.Hash, len(headers))
+	for i, header := range headers {
+		hashes[i] = header.Hash()
+	}
+	q.Schedule(headers, hashes, 1)
+	q.ReserveBodies(dummyPeer("peer-1"), 50)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			q.Status()
+			q.Stats()
+		}
+	}()
+	if res := q.Results(false); len(res) == 0 {
+		t.Fatal("no results delivered")
+	}
+	<-done
+	if size := q.Status().ItemSize; size == 0 {
+		t.Fatal("result size not calibrated")
+	}
+}
+
 // XTestDelivery does some more extensive testing of events that happen,
 // blocks that become known and peers that make reservations and deliveries.
 // disabled since it's not really a unit-test, but can be executed to test
@@ -277,7 +308,7 @@ func XTestDelivery(t *testing.T) {
 	}
 	q := newQueue(10, 10)
 	var wg sync.WaitGroup
-	q.Prepare(1, SnapSync)
+	q.Prepare(1, SnapSync, 0)
 	wg.Add(1)
 	go func() {
 		// deliver headers
@@ -90,16 +90,36 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	ParallelExecution bool `toml:",omitempty"` // Whether to execute the transactions of blocks in parallel
+	ParallelWorkers   int  `toml:",omitempty"` // Number of workers executing transactions in parallel, zero for one per CPU
+
 	// Deprecated, use 'TransactionHistory' instead.
 	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
 
+	// HistoryExpiry enables pruning of block bodies and receipts below the
+	// history cutoff (EIP-4444). A zero cutoff means the merge block. Expired
+	// history can be served from a directory of era1 files.
+	HistoryExpiry  bool   `toml:",omitempty"`
+	HistoryCutoff  uint64 `toml:",omitempty"`
+	HistoryArchive string `toml:",omitempty"`
+
+	// ReceiptHistory is the number of recent blocks whose receipts are downloaded
+	// during snap sync, zero meaning all. Older receipts are regenerated on
+	// demand by re-executing the blocks, which requires their state.
+	ReceiptHistory uint64 `toml:",omitempty"`
+
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
 	// consistent with persistent state.
 	StateScheme string `toml:",omitempty"`
 
+	// VerkleConversion is the number of state leaves converted per block into
+	// a verkle tree maintained next to the merkle state, zero to disable. The
+	// conversion requires the preimages of the state keys.
+	VerkleConversion int `toml:",omitempty"`
+
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
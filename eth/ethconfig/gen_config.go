@@ -24,10 +24,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapDiscoveryURLs       []string
 		NoPruning               bool
 		NoPrefetch              bool
+		ParallelExecution       bool                   `toml:",omitempty"`
+		ParallelWorkers         int                    `toml:",omitempty"`
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
+		HistoryExpiry           bool                   `toml:",omitempty"`
+		HistoryCutoff           uint64                 `toml:",omitempty"`
+		HistoryArchive          string                 `toml:",omitempty"`
+		ReceiptHistory          uint64                 `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
+		VerkleConversion        int                    `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -67,10 +74,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.ParallelExecution = c.ParallelExecution
+	enc.ParallelWorkers = c.ParallelWorkers
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.HistoryExpiry = c.HistoryExpiry
+	enc.HistoryCutoff = c.HistoryCutoff
+	enc.HistoryArchive = c.HistoryArchive
+	enc.ReceiptHistory = c.ReceiptHistory
 	enc.StateScheme = c.StateScheme
+	enc.VerkleConversion = c.VerkleConversion
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -114,10 +128,17 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapDiscoveryURLs       []string
 		NoPruning               *bool
 		NoPrefetch              *bool
+		ParallelExecution       *bool                  `toml:",omitempty"`
+		ParallelWorkers         *int                   `toml:",omitempty"`
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
+		HistoryExpiry           *bool                  `toml:",omitempty"`
+		HistoryCutoff           *uint64                `toml:",omitempty"`
+		HistoryArchive          *string                `toml:",omitempty"`
+		ReceiptHistory          *uint64                `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
+		VerkleConversion        *int                   `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -174,6 +195,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.ParallelExecution != nil {
+		c.ParallelExecution = *dec.ParallelExecution
+	}
+	if dec.ParallelWorkers != nil {
+		c.ParallelWorkers = *dec.ParallelWorkers
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
@@ -183,9 +210,24 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.HistoryExpiry != nil {
+		c.HistoryExpiry = *dec.HistoryExpiry
+	}
+	if dec.HistoryCutoff != nil {
+		c.HistoryCutoff = *dec.HistoryCutoff
+	}
+	if dec.HistoryArchive != nil {
+		c.HistoryArchive = *dec.HistoryArchive
+	}
+	if dec.ReceiptHistory != nil {
+		c.ReceiptHistory = *dec.ReceiptHistory
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
+	if dec.VerkleConversion != nil {
+		c.VerkleConversion = *dec.VerkleConversion
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
@@ -107,6 +107,9 @@ func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.H
 	if err := forkFilter(status.ForkID); err != nil {
 		return fmt.Errorf("%w: %v", errForkIDRejected, err)
 	}
+	if err := p.Peer.CheckForkID(status.ForkID.Hash); err != nil {
+		return fmt.Errorf("%w: %v", errForkIDRejected, err)
+	}
 	return nil
 }
 
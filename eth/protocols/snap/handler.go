@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -129,12 +130,19 @@ func Handle(backend Backend, peer *Peer) error {
 // HandleMessage is invoked whenever an inbound message is received from a
 // remote peer on the `snap` protocol. The remote connection is torn down upon
 // returning any error.
-func HandleMessage(backend Backend, peer *Peer) error {
+func HandleMessage(backend Backend, peer *Peer) (err error) {
 	// Read the next message from the remote peer, and ensure it's fully consumed
 	msg, err := peer.rw.ReadMsg()
 	if err != nil {
 		return err
 	}
+	// Any failure past this point is caused by the message contents, so make
+	// sure it's reflected in the peer's reputation.
+	defer func() {
+		if err != nil {
+			peer.Report(p2p.ScoreProtocolViolation)
+		}
+	}()
 	if msg.Size > maxMessageSize {
 		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
 	}
@@ -288,7 +296,7 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 	if err != nil {
 		return nil, nil
 	}
-	it, err := chain.Snapshots().AccountIterator(req.Root, req.Origin)
+	it, err := newAccountIterator(chain, req.Root, req.Origin)
 	if err != nil {
 		return nil, nil
 	}
@@ -372,7 +380,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			limit, req.Limit = common.BytesToHash(req.Limit), nil
 		}
 		// Retrieve the requested state and bail out if non existent
-		it, err := chain.Snapshots().StorageIterator(req.Root, account, origin)
+		it, err := newStorageIterator(chain, req.Root, account, origin)
 		if err != nil {
 			return nil, nil
 		}
@@ -495,7 +503,10 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 		return nil, nil
 	}
 	// The 'snap' might be nil, in which case we cannot serve storage slots.
-	snap := chain.Snapshots().Snapshot(req.Root)
+	var snap snapshot.Snapshot
+	if snaps := chain.Snapshots(); snaps != nil {
+		snap = snaps.Snapshot(req.Root)
+	}
 	// Retrieve trie nodes until the packet size limit is reached
 	var (
 		nodes [][]byte
@@ -569,9 +580,15 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 
 // NodeInfo represents a short summary of the `snap` sub-protocol metadata
 // known about the host peer.
-type NodeInfo struct{}
+type NodeInfo struct {
+	Backend string `json:"backend"` // State backend serving range queries (snapshot or trie)
+	Scheme  string `json:"scheme"`  // Trie node storage scheme (hash or path)
+}
 
 // nodeInfo retrieves some `snap` protocol metadata about the running host node.
 func nodeInfo(chain *core.BlockChain) *NodeInfo {
-	return &NodeInfo{}
+	return &NodeInfo{
+		Backend: servingBackend(chain),
+		Scheme:  chain.TrieDB().Scheme(),
+	}
 }
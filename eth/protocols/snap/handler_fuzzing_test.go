@@ -90,6 +90,13 @@ func doFuzz(input []byte, obj interface{}, code int) {
 var trieRoot common.Hash
 
 func getChain() *core.BlockChain {
+	return makeTestChain(100)
+}
+
+// makeTestChain creates a short chain on top of a genesis with a thousand
+// accounts, half of them with storage. A zero snapshot limit disables the
+// state snapshot.
+func makeTestChain(snapshotLimit int) *core.BlockChain {
 	ga := make(types.GenesisAlloc, 1000)
 	var a = make([]byte, 20)
 	var mkStorage = func(k, v int) (common.Hash, common.Hash) {
@@ -122,7 +129,7 @@ func getChain() *core.BlockChain {
 		TrieDirtyLimit:      0,
 		TrieTimeLimit:       5 * time.Minute,
 		TrieCleanNoPrefetch: true,
-		SnapshotLimit:       100,
+		SnapshotLimit:       snapshotLimit,
 		SnapshotWait:        true,
 	}
 	trieRoot = blocks[len(blocks)-1].Root()
@@ -67,3 +67,29 @@ var (
 	largeStorageDiscardGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/storage/chunk/discard", nil)
 	largeStorageResumedGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/storage/chunk/resume", nil)
 )
+
+var (
+	// syncProgressGauge tracks the estimated fraction of the state downloaded,
+	// syncETAGauge the estimated seconds until the download phase completes.
+	syncProgressGauge = metrics.NewRegisteredGaugeFloat64("eth/protocols/snap/sync/progress", nil)
+	syncETAGauge      = metrics.NewRegisteredGauge("eth/protocols/snap/sync/eta", nil)
+
+	// Ingress meters track the state data throughput of the sync, in bytes.
+	accountInMeter  = metrics.NewRegisteredMeter("eth/protocols/snap/sync/accounts/in", nil)
+	storageInMeter  = metrics.NewRegisteredMeter("eth/protocols/snap/sync/storage/in", nil)
+	bytecodeInMeter = metrics.NewRegisteredMeter("eth/protocols/snap/sync/bytecodes/in", nil)
+	trienodeInMeter = metrics.NewRegisteredMeter("eth/protocols/snap/sync/heal/trienodes/in", nil)
+
+	// Healing gauges track the trie node and bytecode requests queued for
+	// retrieval, and the number of nodes the scheduler is still missing.
+	healPendingTrienodeGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/heal/pending/trienodes", nil)
+	healPendingBytecodeGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/heal/pending/bytecodes", nil)
+	healPendingTaskGauge     = metrics.NewRegisteredGauge("eth/protocols/snap/sync/heal/pending/tasks", nil)
+)
+
+var (
+	// Serve meters track the range queries served from the snapshot and the
+	// ones falling back to iterating the state trie.
+	snapshotServeMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/snapshot", nil)
+	trieServeMeter     = metrics.NewRegisteredMeter("eth/protocols/snap/serve/trie", nil)
+)
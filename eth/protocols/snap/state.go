@@ -0,0 +1,187 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// The flat state of both the hash and the path scheme is maintained by the state
+// snapshot, the path database only stores trie nodes. The snapshot iterators are
+// therefore the only flat-state iterators, and range queries fall back to trie
+// iteration only if the snapshot is unavailable.
+const (
+	// BackendSnapshot means range queries are served from the state snapshot.
+	BackendSnapshot = "snapshot"
+
+	// BackendTrie means range queries are served by iterating the state trie,
+	// because the snapshot is disabled or not yet constructed.
+	BackendTrie = "trie"
+)
+
+// servingBackend reports which backend is used to serve range queries for the
+// current chain head.
+func servingBackend(chain *core.BlockChain) string {
+	snaps := chain.Snapshots()
+	if snaps == nil {
+		return BackendTrie
+	}
+	if generating, err := snaps.Generating(); err != nil || generating {
+		return BackendTrie
+	}
+	if snaps.Snapshot(chain.CurrentBlock().Root) == nil {
+		return BackendTrie
+	}
+	return BackendSnapshot
+}
+
+// newAccountIterator creates an iterator over the accounts of the given state,
+// starting at origin. The flat state of the snapshot is used if it's available,
+// otherwise the accounts are retrieved from the state trie.
+func newAccountIterator(chain *core.BlockChain, root common.Hash, origin common.Hash) (snapshot.AccountIterator, error) {
+	if snaps := chain.Snapshots(); snaps != nil {
+		if it, err := snaps.AccountIterator(root, origin); err == nil {
+			snapshotServeMeter.Mark(1)
+			return it, nil
+		}
+	}
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), chain.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	nodeIt, err := tr.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	trieServeMeter.Mark(1)
+	return &trieAccountIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+// newStorageIterator creates an iterator over the storage slots of an account
+// in the given state, starting at origin. The flat state of the snapshot is used
+// if it's available, otherwise the slots are retrieved from the storage trie.
+func newStorageIterator(chain *core.BlockChain, root common.Hash, account common.Hash, origin common.Hash) (snapshot.StorageIterator, error) {
+	if snaps := chain.Snapshots(); snaps != nil {
+		if it, err := snaps.StorageIterator(root, account, origin); err == nil {
+			snapshotServeMeter.Mark(1)
+			return it, nil
+		}
+	}
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), chain.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	acc, err := accTrie.GetAccountByHash(account)
+	if err != nil {
+		return nil, err
+	}
+	trieServeMeter.Mark(1)
+	if acc == nil || acc.Root == types.EmptyRootHash {
+		// Non-existent accounts and empty storages yield no slots, same as
+		// the snapshot iterators.
+		return new(trieStorageIterator), nil
+	}
+	tr, err := trie.NewStateTrie(trie.StorageTrieID(root, account, acc.Root), chain.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	nodeIt, err := tr.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &trieStorageIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+// trieAccountIterator is an account iterator backed by the state trie. Trie
+// leaves hold full accounts, which are converted to the slim format used by
+// the snap protocol.
+type trieAccountIterator struct {
+	it      *trie.Iterator
+	account []byte
+	err     error
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *trieAccountIterator) Next() bool {
+	if it.err != nil || !it.it.Next() {
+		return false
+	}
+	acc, err := types.FullAccount(it.it.Value)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.account = types.SlimAccountRLP(*acc)
+	return true
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *trieAccountIterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err
+}
+
+// Hash returns the hash of the account the iterator is currently at.
+func (it *trieAccountIterator) Hash() common.Hash {
+	return common.BytesToHash(it.it.Key)
+}
+
+// Account returns the RLP encoded slim account the iterator is currently at.
+func (it *trieAccountIterator) Account() []byte {
+	return it.account
+}
+
+// Release is a noop, trie iterators hold no resources.
+func (it *trieAccountIterator) Release() {}
+
+// trieStorageIterator is a storage iterator backed by a storage trie. A nil
+// inner iterator represents an empty storage.
+type trieStorageIterator struct {
+	it *trie.Iterator
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *trieStorageIterator) Next() bool {
+	return it.it != nil && it.it.Next()
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *trieStorageIterator) Error() error {
+	if it.it == nil {
+		return nil
+	}
+	return it.it.Err
+}
+
+// Hash returns the hash of the storage slot the iterator is currently at.
+func (it *trieStorageIterator) Hash() common.Hash {
+	return common.BytesToHash(it.it.Key)
+}
+
+// Slot returns the RLP encoded storage slot the iterator is currently at.
+func (it *trieStorageIterator) Slot() []byte {
+	return it.it.Value
+}
+
+// Release is a noop, trie iterators hold no resources.
+func (it *trieStorageIterator) Release() {}
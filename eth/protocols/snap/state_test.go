@@ -0,0 +1,109 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that range queries served from the state trie are identical to the
+// ones served from the snapshot, and that their proofs are valid.
+func TestServeWithoutSnapshot(t *testing.T) {
+	snapChain := makeTestChain(100)
+	defer snapChain.Stop()
+	trieChain := makeTestChain(0)
+	defer trieChain.Stop()
+
+	if backend := servingBackend(snapChain); backend != BackendSnapshot {
+		t.Fatalf("snapshot chain backend mismatch: have %s, want %s", backend, BackendSnapshot)
+	}
+	if backend := servingBackend(trieChain); backend != BackendTrie {
+		t.Fatalf("trie chain backend mismatch: have %s, want %s", backend, BackendTrie)
+	}
+	root := trieChain.CurrentBlock().Root
+
+	// Retrieve the whole account range in small chunks and verify each
+	var (
+		origin  common.Hash
+		storage []common.Hash
+	)
+	for {
+		req := &GetAccountRangePacket{Root: root, Origin: origin, Limit: common.MaxHash, Bytes: 4096}
+		snapReq := *req
+		want, wantProof := ServiceGetAccountRangeQuery(snapChain, &snapReq)
+		have, haveProof := ServiceGetAccountRangeQuery(trieChain, req)
+		if len(have) == 0 {
+			t.Fatalf("no accounts served from origin %x", origin)
+		}
+		if !reflect.DeepEqual(have, want) || !reflect.DeepEqual(haveProof, wantProof) {
+			t.Fatalf("account range mismatch from origin %x", origin)
+		}
+		keys, vals := make([][]byte, len(have)), make([][]byte, len(have))
+		for i, acc := range have {
+			full, err := types.FullAccount(acc.Body)
+			if err != nil {
+				t.Fatalf("invalid account %x: %v", acc.Hash, err)
+			}
+			keys[i] = acc.Hash[:]
+			if vals[i], err = rlp.EncodeToBytes(full); err != nil {
+				t.Fatal(err)
+			}
+			if full.Root != types.EmptyRootHash {
+				storage = append(storage, acc.Hash)
+			}
+		}
+		proof := memorydb.New()
+		for _, node := range haveProof {
+			proof.Put(crypto.Keccak256(node), node)
+		}
+		cont, err := trie.VerifyRangeProof(root, origin[:], keys, vals, proof)
+		if err != nil {
+			t.Fatalf("invalid account range proof from origin %x: %v", origin, err)
+		}
+		if !cont {
+			break
+		}
+		origin = incHash(have[len(have)-1].Hash)
+	}
+	if len(storage) == 0 {
+		t.Fatal("no accounts with storage found")
+	}
+	// Retrieve the storage of a few accounts, the missing one yielding no slots
+	req := &GetStorageRangesPacket{Root: root, Accounts: append(storage[:4:4], common.Hash{0x01}), Bytes: softResponseLimit}
+	snapReq := *req
+	want, wantProof := ServiceGetStorageRangesQuery(snapChain, &snapReq)
+	have, haveProof := ServiceGetStorageRangesQuery(trieChain, req)
+	if len(have) != 4 || len(have[0]) == 0 {
+		t.Fatalf("storage ranges not served: %d", len(have))
+	}
+	if !reflect.DeepEqual(have, want) || !reflect.DeepEqual(haveProof, wantProof) {
+		t.Fatal("storage ranges mismatch")
+	}
+	for i, slot := range have[0] {
+		if _, _, err := rlp.SplitString(slot.Body); err != nil {
+			t.Fatalf("invalid storage slot %d: %v", i, err)
+		}
+	}
+}
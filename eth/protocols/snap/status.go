@@ -0,0 +1,217 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SyncPhase is the stage a snap sync cycle is currently in.
+type SyncPhase string
+
+const (
+	PhaseIdle     SyncPhase = "idle"     // No sync cycle was started yet
+	PhaseDownload SyncPhase = "download" // Account ranges are being downloaded
+	PhaseHeal     SyncPhase = "heal"     // Trie is being healed to the current root
+	PhaseDone     SyncPhase = "done"     // State sync completed
+)
+
+// PeerContribution is the amount of state data delivered by a single peer.
+type PeerContribution struct {
+	Peer          string
+	Responses     uint64             // Number of accepted responses
+	AccountBytes  common.StorageSize // Account range bytes delivered
+	StorageBytes  common.StorageSize // Storage range bytes delivered
+	BytecodeBytes common.StorageSize // Bytecode bytes delivered (sync and heal)
+	TrienodeBytes common.StorageSize // Trie node bytes delivered for healing
+	LastDelivery  time.Time          // Time of the last accepted response
+}
+
+// SyncStatus is a detailed snapshot of the state sync progress, covering both
+// the download and the healing phases.
+type SyncStatus struct {
+	Phase   SyncPhase
+	Root    common.Hash
+	Started time.Time // Time instance when snapshot sync started
+
+	// Download phase statistics
+	AccountSynced  uint64
+	AccountBytes   common.StorageSize
+	BytecodeSynced uint64
+	BytecodeBytes  common.StorageSize
+	StorageSynced  uint64
+	StorageBytes   common.StorageSize
+	DownloadRate   float64       // Average download speed in bytes per second
+	Progress       float64       // Estimated fraction of the state downloaded
+	ETA            time.Duration // Estimated time until the download completes
+
+	// Healing phase statistics
+	HealStarted        time.Time // Time instance when healing started
+	TrienodeHealSynced uint64
+	TrienodeHealBytes  common.StorageSize
+	BytecodeHealSynced uint64
+	BytecodeHealBytes  common.StorageSize
+	AccountHealed      uint64
+	StorageHealed      uint64
+	HealRate           float64 // Average trie nodes healed per second
+	PendingTrienodes   uint64  // Trie node requests queued for retrieval
+	PendingBytecodes   uint64  // Bytecode requests queued for retrieval
+	PendingHealTasks   uint64  // Nodes still missing according to the scheduler
+
+	Peers []PeerContribution // Contributions of connected peers, ordered by total bytes
+}
+
+// Status returns a detailed snapshot of the state sync progress. The snapshot
+// is refreshed by the sync loop, so it may lag slightly behind.
+func (s *Syncer) Status() *SyncStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	status := *s.extStatus
+	status.Peers = make([]PeerContribution, 0, len(s.contributions))
+	for _, c := range s.contributions {
+		status.Peers = append(status.Peers, *c)
+	}
+	sort.Slice(status.Peers, func(i, j int) bool {
+		a, b := status.Peers[i], status.Peers[j]
+		return a.AccountBytes+a.StorageBytes+a.BytecodeBytes+a.TrienodeBytes >
+			b.AccountBytes+b.StorageBytes+b.BytecodeBytes+b.TrienodeBytes
+	})
+	return &status
+}
+
+// updateStatus refreshes the externally visible status snapshot and the sync
+// metrics. It must be called from the sync goroutine, as it accesses the task
+// set without locking.
+func (s *Syncer) updateStatus() {
+	phase := PhaseDone
+	switch {
+	case len(s.tasks) > 0:
+		phase = PhaseDownload
+	case s.healer.scheduler.Pending() > 0:
+		phase = PhaseHeal
+		if s.healStartTime.IsZero() {
+			s.healStartTime = time.Now()
+		}
+	}
+	status := &SyncStatus{
+		Phase:              phase,
+		Root:               s.root,
+		Started:            s.startTime,
+		AccountSynced:      s.accountSynced,
+		AccountBytes:       s.accountBytes,
+		BytecodeSynced:     s.bytecodeSynced,
+		BytecodeBytes:      s.bytecodeBytes,
+		StorageSynced:      s.storageSynced,
+		StorageBytes:       s.storageBytes,
+		HealStarted:        s.healStartTime,
+		TrienodeHealSynced: s.trienodeHealSynced,
+		TrienodeHealBytes:  s.trienodeHealBytes,
+		BytecodeHealSynced: s.bytecodeHealSynced,
+		BytecodeHealBytes:  s.bytecodeHealBytes,
+		AccountHealed:      s.accountHealed,
+		StorageHealed:      s.storageHealed,
+		PendingHealTasks:   uint64(s.healer.scheduler.Pending()),
+	}
+	if elapsed := time.Since(s.startTime).Seconds(); elapsed > 0 {
+		status.DownloadRate = float64(s.accountBytes+s.bytecodeBytes+s.storageBytes) / elapsed
+	}
+	if !s.healStartTime.IsZero() {
+		if elapsed := time.Since(s.healStartTime).Seconds(); elapsed > 0 {
+			status.HealRate = float64(s.trienodeHealSynced) / elapsed
+		}
+	}
+	switch phase {
+	case PhaseDownload:
+		status.Progress, status.ETA, _ = s.estimateSyncProgress()
+	default:
+		// The amount of healing needed is unknown upfront, so there's no
+		// meaningful estimate once the download phase is over.
+		status.Progress = 1
+	}
+	s.lock.Lock()
+	status.PendingTrienodes = uint64(len(s.healer.trieTasks))
+	status.PendingBytecodes = uint64(len(s.healer.codeTasks))
+	s.extStatus = status
+	s.lock.Unlock()
+
+	syncProgressGauge.Update(status.Progress)
+	syncETAGauge.Update(int64(status.ETA / time.Second))
+	healPendingTrienodeGauge.Update(int64(status.PendingTrienodes))
+	healPendingBytecodeGauge.Update(int64(status.PendingBytecodes))
+	healPendingTaskGauge.Update(int64(status.PendingHealTasks))
+}
+
+// estimateSyncProgress estimates the fraction of the state downloaded so far,
+// based on the portion of the account hash space already covered, along with
+// the remaining time until the download phase completes at the current pace.
+func (s *Syncer) estimateSyncProgress() (float64, time.Duration, bool) {
+	synced := s.accountBytes + s.bytecodeBytes + s.storageBytes
+	if synced == 0 {
+		return 0, 0, false
+	}
+	accountGaps := new(big.Int)
+	for _, task := range s.tasks {
+		accountGaps.Add(accountGaps, new(big.Int).Sub(task.Last.Big(), task.Next.Big()))
+	}
+	accountFills := new(big.Int).Sub(hashSpace, accountGaps)
+	if accountFills.BitLen() == 0 {
+		return 0, 0, false
+	}
+	estBytes := float64(new(big.Int).Div(
+		new(big.Int).Mul(new(big.Int).SetUint64(uint64(synced)), hashSpace),
+		accountFills,
+	).Uint64())
+	if estBytes < 1.0 {
+		return 0, 0, false
+	}
+	elapsed := time.Since(s.startTime)
+	estTime := elapsed / time.Duration(synced) * time.Duration(estBytes)
+
+	return float64(synced) / estBytes, estTime - elapsed, true
+}
+
+// trackDelivery accounts an accepted response to the delivering peer and marks
+// the ingress meters. The caller must hold the syncer lock.
+func (s *Syncer) trackDelivery(peer string, kind uint64, size common.StorageSize) {
+	c := s.contributions[peer]
+	if c == nil {
+		c = &PeerContribution{Peer: peer}
+		s.contributions[peer] = c
+	}
+	c.Responses++
+	c.LastDelivery = time.Now()
+
+	switch kind {
+	case AccountRangeMsg:
+		c.AccountBytes += size
+		accountInMeter.Mark(int64(size))
+	case StorageRangesMsg:
+		c.StorageBytes += size
+		storageInMeter.Mark(int64(size))
+	case ByteCodesMsg:
+		c.BytecodeBytes += size
+		bytecodeInMeter.Mark(int64(size))
+	case TrieNodesMsg:
+		c.TrienodeBytes += size
+		trienodeInMeter.Mark(int64(size))
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/msgrate"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
@@ -475,6 +476,9 @@ type Syncer struct {
 	storageBytes   common.StorageSize // Number of storage trie bytes persisted to disk
 
 	extProgress *SyncProgress // progress that can be exposed to external caller.
+	extStatus   *SyncStatus   // detailed status that can be exposed to external caller.
+
+	contributions map[string]*PeerContribution // State data delivered by each peer
 
 	// Request tracking during healing phase
 	trienodeHealIdlers map[string]struct{} // Peers that aren't serving trie node requests
@@ -503,8 +507,9 @@ type Syncer struct {
 	storageHealed      uint64             // Number of storage slots downloaded during the healing stage
 	storageHealedBytes common.StorageSize // Number of raw storage bytes persisted to disk during the healing stage
 
-	startTime time.Time // Time instance when snapshot sync started
-	logTime   time.Time // Time instance when status was last reported
+	startTime     time.Time // Time instance when snapshot sync started
+	healStartTime time.Time // Time instance when state healing started
+	logTime       time.Time // Time instance when status was last reported
 
 	pend sync.WaitGroup // Tracks network request goroutines for graceful shutdown
 	lock sync.RWMutex   // Protects fields that can change outside of sync (peers, reqs, root)
@@ -539,7 +544,9 @@ func NewSyncer(db ethdb.KeyValueStore, scheme string) *Syncer {
 		trienodeHealThrottle: maxTrienodeHealThrottle, // Tune downward instead of insta-filling with junk
 		stateWriter:          db.NewBatch(),
 
-		extProgress: new(SyncProgress),
+		extProgress:   new(SyncProgress),
+		extStatus:     &SyncStatus{Phase: PhaseIdle},
+		contributions: make(map[string]*PeerContribution),
 	}
 }
 
@@ -592,6 +599,7 @@ func (s *Syncer) Unregister(id string) error {
 	delete(s.bytecodeIdlers, id)
 	delete(s.trienodeHealIdlers, id)
 	delete(s.bytecodeHealIdlers, id)
+	delete(s.contributions, id)
 	s.lock.Unlock()
 
 	// Notify any active syncs that pending requests need to be reverted
@@ -621,6 +629,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 	}
 	// Retrieve the previous sync status from LevelDB and abort if already synced
 	s.loadSyncStatus()
+	s.updateStatus()
 	if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
 		log.Debug("Snapshot sync already completed")
 		return nil
@@ -685,6 +694,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		// Remove all completed tasks and terminate sync if everything's done
 		s.cleanStorageTasks()
 		s.cleanAccountTasks()
+		s.updateStatus()
 		if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
 			return nil
 		}
@@ -1070,6 +1080,7 @@ func (s *Syncer) assignAccountTasks(success chan *accountResponse, fail chan *ac
 		}
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Account range request timed out", "reqid", reqid)
+			reportPeer(peer, p2p.ScoreRequestTimeout)
 			s.rates.Update(idle, AccountRangeMsg, 0, 0)
 			s.scheduleRevertAccountRequest(req)
 		})
@@ -1181,6 +1192,7 @@ func (s *Syncer) assignBytecodeTasks(success chan *bytecodeResponse, fail chan *
 		}
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Bytecode request timed out", "reqid", reqid)
+			reportPeer(peer, p2p.ScoreRequestTimeout)
 			s.rates.Update(idle, ByteCodesMsg, 0, 0)
 			s.scheduleRevertBytecodeRequest(req)
 		})
@@ -1329,6 +1341,7 @@ func (s *Syncer) assignStorageTasks(success chan *storageResponse, fail chan *st
 		}
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Storage request timed out", "reqid", reqid)
+			reportPeer(peer, p2p.ScoreRequestTimeout)
 			s.rates.Update(idle, StorageRangesMsg, 0, 0)
 			s.scheduleRevertStorageRequest(req)
 		})
@@ -1466,6 +1479,7 @@ func (s *Syncer) assignTrienodeHealTasks(success chan *trienodeHealResponse, fai
 		}
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Trienode heal request timed out", "reqid", reqid)
+			reportPeer(peer, p2p.ScoreRequestTimeout)
 			s.rates.Update(idle, TrieNodesMsg, 0, 0)
 			s.scheduleRevertTrienodeHealRequest(req)
 		})
@@ -1582,6 +1596,7 @@ func (s *Syncer) assignBytecodeHealTasks(success chan *bytecodeHealResponse, fai
 		}
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Bytecode heal request timed out", "reqid", reqid)
+			reportPeer(peer, p2p.ScoreRequestTimeout)
 			s.rates.Update(idle, ByteCodesMsg, 0, 0)
 			s.scheduleRevertBytecodeHealRequest(req)
 		})
@@ -2479,6 +2494,24 @@ func (s *Syncer) forwardAccountTask(task *accountTask) {
 	log.Debug("Persisted range of accounts", "accounts", len(res.accounts), "bytes", s.accountBytes-oldAccountBytes)
 }
 
+// reportPeer feeds a score event into the reputation system of the p2p layer, if
+// the peer supports it.
+func reportPeer(peer SyncPeer, ev p2p.ScoreEvent) {
+	if r, ok := peer.(p2p.ScoreReporter); ok {
+		r.Report(ev)
+	}
+}
+
+// reportDelivery rates a non-empty response based on its round trip time, compared
+// to the target round trip time derived from all peers' measured rates.
+func (s *Syncer) reportDelivery(peer SyncPeer, elapsed time.Duration) {
+	if elapsed > s.rates.TargetRoundTrip() {
+		reportPeer(peer, p2p.ScoreSlowResponse)
+	} else {
+		reportPeer(peer, p2p.ScoreUsefulResponse)
+	}
+}
+
 // OnAccounts is a callback method to invoke when a range of accounts are
 // received from a remote peer.
 func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, accounts [][]byte, proof [][]byte) error {
@@ -2516,7 +2549,9 @@ func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, acco
 		return nil
 	}
 	delete(s.accountReqs, id)
-	s.rates.Update(peer.ID(), AccountRangeMsg, time.Since(req.time), int(size))
+	elapsed := time.Since(req.time)
+	s.rates.Update(peer.ID(), AccountRangeMsg, elapsed, int(size))
+	s.trackDelivery(peer.ID(), AccountRangeMsg, size)
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2532,6 +2567,7 @@ func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, acco
 	if len(hashes) == 0 && len(accounts) == 0 && len(proof) == 0 {
 		logger.Debug("Peer rejected account range request", "root", s.root)
 		s.statelessPeers[peer.ID()] = struct{}{}
+		reportPeer(peer, p2p.ScoreEmptyResponse)
 		s.lock.Unlock()
 
 		// Signal this request as failed, and ready for rescheduling
@@ -2540,6 +2576,7 @@ func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, acco
 	}
 	root := s.root
 	s.lock.Unlock()
+	s.reportDelivery(peer, elapsed)
 
 	// Reconstruct a partial trie from the response and verify it
 	keys := make([][]byte, len(hashes))
@@ -2626,7 +2663,9 @@ func (s *Syncer) onByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error
 		return nil
 	}
 	delete(s.bytecodeReqs, id)
-	s.rates.Update(peer.ID(), ByteCodesMsg, time.Since(req.time), len(bytecodes))
+	elapsed := time.Since(req.time)
+	s.rates.Update(peer.ID(), ByteCodesMsg, elapsed, len(bytecodes))
+	s.trackDelivery(peer.ID(), ByteCodesMsg, size)
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2642,6 +2681,7 @@ func (s *Syncer) onByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error
 	if len(bytecodes) == 0 {
 		logger.Debug("Peer rejected bytecode request")
 		s.statelessPeers[peer.ID()] = struct{}{}
+		reportPeer(peer, p2p.ScoreEmptyResponse)
 		s.lock.Unlock()
 
 		// Signal this request as failed, and ready for rescheduling
@@ -2649,6 +2689,7 @@ func (s *Syncer) onByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error
 		return nil
 	}
 	s.lock.Unlock()
+	s.reportDelivery(peer, elapsed)
 
 	// Cross reference the requested bytecodes with the response to find gaps
 	// that the serving node is missing
@@ -2739,7 +2780,9 @@ func (s *Syncer) OnStorage(peer SyncPeer, id uint64, hashes [][]common.Hash, slo
 		return nil
 	}
 	delete(s.storageReqs, id)
-	s.rates.Update(peer.ID(), StorageRangesMsg, time.Since(req.time), int(size))
+	elapsed := time.Since(req.time)
+	s.rates.Update(peer.ID(), StorageRangesMsg, elapsed, int(size))
+	s.trackDelivery(peer.ID(), StorageRangesMsg, size)
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2770,11 +2813,13 @@ func (s *Syncer) OnStorage(peer SyncPeer, id uint64, hashes [][]common.Hash, slo
 	if len(hashes) == 0 && len(proof) == 0 {
 		logger.Debug("Peer rejected storage request")
 		s.statelessPeers[peer.ID()] = struct{}{}
+		reportPeer(peer, p2p.ScoreEmptyResponse)
 		s.lock.Unlock()
 		s.scheduleRevertStorageRequest(req) // reschedule request
 		return nil
 	}
 	s.lock.Unlock()
+	s.reportDelivery(peer, elapsed)
 
 	// Reconstruct the partial tries from the response and verify them
 	var cont bool
@@ -2873,7 +2918,9 @@ func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error
 		return nil
 	}
 	delete(s.trienodeHealReqs, id)
-	s.rates.Update(peer.ID(), TrieNodesMsg, time.Since(req.time), len(trienodes))
+	elapsed := time.Since(req.time)
+	s.rates.Update(peer.ID(), TrieNodesMsg, elapsed, len(trienodes))
+	s.trackDelivery(peer.ID(), TrieNodesMsg, size)
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2889,6 +2936,7 @@ func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error
 	if len(trienodes) == 0 {
 		logger.Debug("Peer rejected trienode heal request")
 		s.statelessPeers[peer.ID()] = struct{}{}
+		reportPeer(peer, p2p.ScoreEmptyResponse)
 		s.lock.Unlock()
 
 		// Signal this request as failed, and ready for rescheduling
@@ -2896,6 +2944,7 @@ func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error
 		return nil
 	}
 	s.lock.Unlock()
+	s.reportDelivery(peer, elapsed)
 
 	// Cross reference the requested trienodes with the response to find gaps
 	// that the serving node is missing
@@ -2980,7 +3029,9 @@ func (s *Syncer) onHealByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) e
 		return nil
 	}
 	delete(s.bytecodeHealReqs, id)
-	s.rates.Update(peer.ID(), ByteCodesMsg, time.Since(req.time), len(bytecodes))
+	elapsed := time.Since(req.time)
+	s.rates.Update(peer.ID(), ByteCodesMsg, elapsed, len(bytecodes))
+	s.trackDelivery(peer.ID(), ByteCodesMsg, size)
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2996,6 +3047,7 @@ func (s *Syncer) onHealByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) e
 	if len(bytecodes) == 0 {
 		logger.Debug("Peer rejected bytecode heal request")
 		s.statelessPeers[peer.ID()] = struct{}{}
+		reportPeer(peer, p2p.ScoreEmptyResponse)
 		s.lock.Unlock()
 
 		// Signal this request as failed, and ready for rescheduling
@@ -3003,6 +3055,7 @@ func (s *Syncer) onHealByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) e
 		return nil
 	}
 	s.lock.Unlock()
+	s.reportDelivery(peer, elapsed)
 
 	// Cross reference the requested bytecodes with the response to find gaps
 	// that the serving node is missing
@@ -3091,38 +3144,21 @@ func (s *Syncer) reportSyncProgress(force bool) {
 	}
 	// Don't report anything until we have a meaningful progress
 	synced := s.accountBytes + s.bytecodeBytes + s.storageBytes
-	if synced == 0 {
-		return
-	}
-	accountGaps := new(big.Int)
-	for _, task := range s.tasks {
-		accountGaps.Add(accountGaps, new(big.Int).Sub(task.Last.Big(), task.Next.Big()))
-	}
-	accountFills := new(big.Int).Sub(hashSpace, accountGaps)
-	if accountFills.BitLen() == 0 {
+	progress, eta, ok := s.estimateSyncProgress()
+	if !ok {
 		return
 	}
 	s.logTime = time.Now()
-	estBytes := float64(new(big.Int).Div(
-		new(big.Int).Mul(new(big.Int).SetUint64(uint64(synced)), hashSpace),
-		accountFills,
-	).Uint64())
-	// Don't report anything until we have a meaningful progress
-	if estBytes < 1.0 {
-		return
-	}
-	elapsed := time.Since(s.startTime)
-	estTime := elapsed / time.Duration(synced) * time.Duration(estBytes)
 
 	// Create a mega progress report
 	var (
-		progress = fmt.Sprintf("%.2f%%", float64(synced)*100/estBytes)
+		percent  = fmt.Sprintf("%.2f%%", progress*100)
 		accounts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.accountSynced), s.accountBytes.TerminalString())
 		storage  = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.storageSynced), s.storageBytes.TerminalString())
 		bytecode = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.bytecodeSynced), s.bytecodeBytes.TerminalString())
 	)
-	log.Info("Syncing: state download in progress", "synced", progress, "state", synced,
-		"accounts", accounts, "slots", storage, "codes", bytecode, "eta", common.PrettyDuration(estTime-elapsed))
+	log.Info("Syncing: state download in progress", "synced", percent, "state", synced,
+		"accounts", accounts, "slots", storage, "codes", bytecode, "eta", common.PrettyDuration(eta))
 }
 
 // reportHealProgress calculates various status reports and provides it to the user.
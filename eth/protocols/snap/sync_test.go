@@ -1975,3 +1975,78 @@ func newDbConfig(scheme string) *triedb.Config {
 	}
 	return &triedb.Config{PathDB: pathdb.Defaults}
 }
+
+// TestSyncStatus tests that the detailed sync status tracks the download phase
+// and the contribution of each peer.
+func TestSyncStatus(t *testing.T) {
+	t.Parallel()
+
+	testSyncStatus(t, rawdb.HashScheme)
+	testSyncStatus(t, rawdb.PathScheme)
+}
+
+func testSyncStatus(t *testing.T, scheme string) {
+	var (
+		once   sync.Once
+		cancel = make(chan struct{})
+		term   = func() {
+			once.Do(func() {
+				close(cancel)
+			})
+		}
+	)
+	sourceAccountTrie, elems, storageTries, storageElems := makeAccountTrieWithStorage(scheme, 10, 100, true, false, false)
+
+	mkSource := func(name string) *testPeer {
+		source := newTestPeer(name, t, term)
+		source.accountTrie = sourceAccountTrie.Copy()
+		source.accountValues = elems
+		source.setStorageTries(storageTries)
+		source.storageValues = storageElems
+		return source
+	}
+	syncer := setupSyncer(scheme, mkSource("sourceA"), mkSource("sourceB"))
+	if status := syncer.Status(); status.Phase != PhaseIdle {
+		t.Fatalf("wrong phase before sync: have %s, want %s", status.Phase, PhaseIdle)
+	}
+	done := checkStall(t, term)
+	if err := syncer.Sync(sourceAccountTrie.Hash(), cancel); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	close(done)
+
+	status := syncer.Status()
+	if status.Phase != PhaseDone {
+		t.Fatalf("wrong phase after sync: have %s, want %s", status.Phase, PhaseDone)
+	}
+	if status.Root != sourceAccountTrie.Hash() {
+		t.Fatalf("wrong root: have %x, want %x", status.Root, sourceAccountTrie.Hash())
+	}
+	if status.AccountSynced != uint64(len(elems)) {
+		t.Fatalf("wrong accounts synced: have %d, want %d", status.AccountSynced, len(elems))
+	}
+	if status.StorageSynced == 0 || status.BytecodeSynced == 0 {
+		t.Fatalf("storage or bytecodes not reported: %d slots, %d codes", status.StorageSynced, status.BytecodeSynced)
+	}
+	if status.Progress != 1 || status.PendingTrienodes != 0 || status.PendingHealTasks != 0 {
+		t.Fatalf("unexpected completion status: progress %f, pending %d/%d", status.Progress, status.PendingTrienodes, status.PendingHealTasks)
+	}
+	var delivered common.StorageSize
+	for i, p := range status.Peers {
+		if p.Responses == 0 {
+			t.Errorf("peer %s: no responses tracked", p.Peer)
+		}
+		total := p.AccountBytes + p.StorageBytes + p.BytecodeBytes + p.TrienodeBytes
+		if i > 0 {
+			prev := status.Peers[i-1]
+			if total > prev.AccountBytes+prev.StorageBytes+prev.BytecodeBytes+prev.TrienodeBytes {
+				t.Errorf("peers not ordered by contribution")
+			}
+		}
+		delivered += p.AccountBytes
+	}
+	if len(status.Peers) == 0 || delivered == 0 {
+		t.Fatalf("no account data contributions tracked: %v", status.Peers)
+	}
+	verifyTrie(scheme, syncer.db, sourceAccountTrie.Hash(), t)
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// receiptCacheLimit is the number of blocks whose regenerated receipts are
+	// kept in memory.
+	receiptCacheLimit = 256
+
+	// receiptRegenReexec is the number of blocks the regenerator is willing to
+	// re-execute to produce the missing state of a block's parent.
+	receiptRegenReexec = uint64(128)
+)
+
+// errReceiptsUnavailable is returned if the receipts of a block were skipped
+// during sync and can't be regenerated, because the state is not available.
+var errReceiptsUnavailable = errors.New("receipts not stored and state unavailable for re-execution")
+
+// receiptRegenerator recreates the receipts of blocks whose receipts were not
+// downloaded during snap sync, by re-executing them on top of their parent's
+// state.
+type receiptRegenerator struct {
+	eth   *Ethereum
+	cache *lru.Cache[common.Hash, types.Receipts]
+}
+
+// newReceiptRegenerator creates a receipt regenerator for the given node.
+func newReceiptRegenerator(eth *Ethereum) *receiptRegenerator {
+	return &receiptRegenerator{
+		eth:   eth,
+		cache: lru.NewCache[common.Hash, types.Receipts](receiptCacheLimit),
+	}
+}
+
+// receipts returns the receipts of the given block, re-executing it if they
+// are not cached yet.
+func (r *receiptRegenerator) receipts(ctx context.Context, hash common.Hash, number uint64) (types.Receipts, error) {
+	if receipts, ok := r.cache.Get(hash); ok {
+		return receipts, nil
+	}
+	block := r.eth.blockchain.GetBlock(hash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d %x not found", number, hash)
+	}
+	parent := r.eth.blockchain.GetBlock(block.ParentHash(), number-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent block #%d %x not found", number-1, block.ParentHash())
+	}
+	statedb, release, err := r.eth.stateAtBlock(ctx, parent, receiptRegenReexec, nil, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errReceiptsUnavailable, err)
+	}
+	defer release()
+
+	receipts, _, _, err := r.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-execute block #%d %x: %w", number, hash, err)
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != block.ReceiptHash() {
+		return nil, fmt.Errorf("regenerated receipts of block #%d %x mismatch: have %x, want %x", number, hash, root, block.ReceiptHash())
+	}
+	log.Debug("Regenerated block receipts", "number", number, "hash", hash, "receipts", len(receipts))
+	r.cache.Add(hash, receipts)
+	return receipts, nil
+}
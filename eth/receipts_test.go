@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestReceiptRegeneration(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSigner(params.TestChainConfig)
+		genesis = &core.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		// A contract emitting a log with the caller as topic
+		code = common.Hex2Bytes("33600052600160206000a1")
+	)
+	const blocks = 300
+	_, chain, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), blocks, func(i int, gen *core.BlockGen) {
+		var tx *types.Transaction
+		if i == 0 {
+			tx = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: gen.TxNonce(addr), Gas: 100000, GasPrice: gen.BaseFee(), Data: append(common.Hex2Bytes("600b600c600039600b6000f3"), code...)})
+		} else {
+			contract := crypto.CreateAddress(addr, 0)
+			tx = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: gen.TxNonce(addr), To: &contract, Gas: 100000, GasPrice: gen.BaseFee()})
+		}
+		gen.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	blockchain, err := core.NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blockchain.Stop()
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatal(err)
+	}
+	// Pretend all receipts were skipped during sync
+	blockchain.SetReceiptsTail(blocks + 1)
+
+	eth := &Ethereum{blockchain: blockchain, chainDb: db}
+	eth.receiptRegen = newReceiptRegenerator(eth)
+	backend := &EthAPIBackend{eth: eth}
+
+	// Receipts near the head can be regenerated from the available state
+	for _, block := range []*types.Block{chain[0], chain[1], chain[blocks-1]} {
+		if receipts := blockchain.GetReceiptsByHash(block.Hash()); receipts != nil {
+			t.Fatalf("block %d: skipped receipts returned by the chain", block.NumberU64())
+		}
+		want := rawdb.ReadReceipts(db, block.Hash(), block.NumberU64(), block.Time(), params.TestChainConfig)
+		have, err := backend.GetReceipts(context.Background(), block.Hash())
+		if err != nil {
+			t.Fatalf("block %d: failed to regenerate receipts: %v", block.NumberU64(), err)
+		}
+		if len(have) != len(want) || have[0].TxHash != want[0].TxHash || have[0].GasUsed != want[0].GasUsed || len(have[0].Logs) != len(want[0].Logs) {
+			t.Fatalf("block %d: regenerated receipts mismatch", block.NumberU64())
+		}
+		logs, err := backend.GetLogs(context.Background(), block.Hash(), block.NumberU64())
+		if err != nil {
+			t.Fatalf("block %d: failed to regenerate logs: %v", block.NumberU64(), err)
+		}
+		if block.NumberU64() > 1 && (len(logs) != 1 || len(logs[0]) != 1 || logs[0][0].BlockHash != block.Hash()) {
+			t.Fatalf("block %d: wrong regenerated logs: %v", block.NumberU64(), logs)
+		}
+	}
+	if eth.receiptRegen.cache.Len() != 3 {
+		t.Fatalf("regenerated receipts not cached: %d", eth.receiptRegen.cache.Len())
+	}
+	// Deep in the chain, the state has been garbage collected
+	block := chain[blocks/2]
+	if _, err := backend.GetReceipts(context.Background(), block.Hash()); !errors.Is(err, errReceiptsUnavailable) {
+		t.Fatalf("wrong error for pruned state: %v", err)
+	}
+}
@@ -0,0 +1,489 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package netdb implements the key-value database layer based on a networked
+// key-value service. Contrary to remotedb, which offers read-only access to the
+// database of a remote geth node, netdb is a full-featured backend, supporting
+// writes, batches, iterators and snapshots.
+package netdb
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// maxIdleConns is the maximum number of idle connections kept open to the
+	// server for reuse.
+	maxIdleConns = 16
+
+	// dialTimeout is the maximum time allowed for establishing a connection.
+	dialTimeout = 5 * time.Second
+)
+
+// conn is a single connection to the key-value server.
+type conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	broken bool // Whether the connection failed and can't be reused
+}
+
+// roundTrip sends a request and waits for its response.
+func (c *conn) roundTrip(req *request, res *response) error {
+	var deadline time.Time
+	if req.Op != opCompact {
+		deadline = time.Now().Add(requestTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+
+	if err := writeMsg(c.writer, req); err != nil {
+		return err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	return readMsg(c.reader, res)
+}
+
+// call executes a request on the connection. If the request fails in transit,
+// the connection is closed, as it's in an unknown state.
+func (c *conn) call(req *request) (*response, error) {
+	res := new(response)
+	if err := c.roundTrip(req, res); err != nil {
+		c.broken = true
+		c.conn.Close()
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+	return res, nil
+}
+
+// Database is a key-value store backed by a remote key-value server. Requests
+// are spread over a pool of connections, so the database is safe for concurrent
+// use.
+type Database struct {
+	addr string // Network address of the key-value server
+
+	lock   sync.Mutex
+	idle   []*conn // Connections available for reuse
+	closed bool
+}
+
+// New creates a database backed by the key-value server listening on the given
+// TCP address.
+func New(addr string) (*Database, error) {
+	db := &Database{addr: addr}
+
+	// Ensure the server is reachable
+	c, err := db.dial()
+	if err != nil {
+		return nil, err
+	}
+	db.idle = append(db.idle, c)
+	return db, nil
+}
+
+// dial establishes a new connection to the server.
+func (db *Database) dial() (*conn, error) {
+	c, err := net.DialTimeout("tcp", db.addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{
+		conn:   c,
+		reader: bufio.NewReader(c),
+		writer: bufio.NewWriter(c),
+	}, nil
+}
+
+// acquire retrieves an idle connection or establishes a new one.
+func (db *Database) acquire() (*conn, error) {
+	db.lock.Lock()
+	if db.closed {
+		db.lock.Unlock()
+		return nil, errClosed
+	}
+	if n := len(db.idle); n > 0 {
+		c := db.idle[n-1]
+		db.idle = db.idle[:n-1]
+		db.lock.Unlock()
+		return c, nil
+	}
+	db.lock.Unlock()
+	return db.dial()
+}
+
+// release returns a connection to the pool, or closes it if the pool is full.
+func (db *Database) release(c *conn) {
+	if c.broken {
+		return
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.closed || len(db.idle) >= maxIdleConns {
+		c.conn.Close()
+		return
+	}
+	db.idle = append(db.idle, c)
+}
+
+// call executes a request on the server.
+func (db *Database) call(req *request) (*response, error) {
+	c, err := db.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer db.release(c)
+	return c.call(req)
+}
+
+// Close closes the connections to the server. The remote store is not affected.
+func (db *Database) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	for _, c := range db.idle {
+		c.conn.Close()
+	}
+	db.idle = nil
+	return nil
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	res, err := db.call(&request{Op: opHas, Key: key})
+	if err != nil {
+		return false, err
+	}
+	return res.Found, nil
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	res, err := db.call(&request{Op: opGet, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if !res.Found {
+		return nil, errNotFound
+	}
+	return res.Value, nil
+}
+
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	_, err := db.call(&request{Op: opPut, Key: key, Value: value})
+	return err
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	_, err := db.call(&request{Op: opDelete, Key: key})
+	return err
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+//
+// The server side iterator belongs to the connection it was created on, so the
+// iterator holds on to a connection until released.
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	c, err := db.acquire()
+	if err != nil {
+		return &iterator{err: err, exhausted: true, released: true}
+	}
+	res, err := c.call(&request{Op: opIteratorNew, Key: prefix, Value: start})
+	if err != nil {
+		db.release(c)
+		return &iterator{err: err, exhausted: true, released: true}
+	}
+	return &iterator{db: db, conn: c, id: res.ID, pos: -1}
+}
+
+// NewSnapshot creates a database snapshot based on the current state. The
+// snapshot is held by the server until released, along with the connection it
+// was created on.
+func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	c, err := db.acquire()
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.call(&request{Op: opSnapshotNew})
+	if err != nil {
+		db.release(c)
+		return nil, err
+	}
+	return &snapshot{db: db, conn: c, id: res.ID}, nil
+}
+
+// Stat returns a particular internal stat of the remote database.
+func (db *Database) Stat(property string) (string, error) {
+	res, err := db.call(&request{Op: opStat, Key: []byte(property)})
+	if err != nil {
+		return "", err
+	}
+	return string(res.Value), nil
+}
+
+// Compact flattens the underlying remote data store for the given key range.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	_, err := db.call(&request{Op: opCompact, Key: start, Value: limit})
+	return err
+}
+
+// batch is a write-only batch that commits changes to the remote store when
+// Write is called. A batch cannot be used concurrently.
+type batch struct {
+	db   *Database
+	ops  []batchOp
+	size int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.ops = append(b.ops, batchOp{Key: common.CopyBytes(key), Value: common.CopyBytes(value)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts the key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.ops = append(b.ops, batchOp{Delete: true, Key: common.CopyBytes(key)})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to the remote store atomically. Batches
+// too large for a single message are staged on the server in chunks over the
+// same connection, and written out with the last chunk.
+func (b *batch) Write() error {
+	c, err := b.db.acquire()
+	if err != nil {
+		return err
+	}
+	defer b.db.release(c)
+
+	ops := b.ops
+	for {
+		var (
+			n    int
+			size int
+		)
+		for n < len(ops) && (n == 0 || size < batchChunkBytes) {
+			size += len(ops[n].Key) + len(ops[n].Value)
+			n++
+		}
+		req := &request{Op: opBatch, Batch: ops[:n]}
+		if n < len(ops) {
+			req.Op = opBatchStage
+		}
+		if _, err := c.call(req); err != nil {
+			return err
+		}
+		if ops = ops[n:]; len(ops) == 0 {
+			return nil
+		}
+	}
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, op := range b.ops {
+		if op.Delete {
+			if err := w.Delete(op.Key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(op.Key, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterator is an iterator over a remote key-value store, retrieving the entries
+// in pages from the server.
+type iterator struct {
+	db        *Database
+	conn      *conn
+	id        uint64
+	keys      [][]byte
+	values    [][]byte
+	pos       int
+	exhausted bool // Whether the server has no more entries to deliver
+	released  bool // Whether the server side iterator was released
+	err       error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil || it.released {
+		return false
+	}
+	if it.pos+1 < len(it.keys) {
+		it.pos++
+		return true
+	}
+	if it.exhausted {
+		it.keys, it.values, it.pos = nil, nil, -1
+		return false
+	}
+	res, err := it.conn.call(&request{Op: opIteratorNext, ID: it.id})
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.keys, it.values, it.pos = res.Keys, res.Values, 0
+	it.exhausted = res.Found
+	if len(it.keys) == 0 {
+		it.pos = -1
+		return false
+	}
+	return true
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done. The caller
+// should not modify the contents of the returned slice, and its contents may
+// change on the next call to Next.
+func (it *iterator) Key() []byte {
+	if it.pos < 0 || it.pos >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.pos]
+}
+
+// Value returns the value of the current key/value pair, or nil if done. The
+// caller should not modify the contents of the returned slice, and its contents
+// may change on the next call to Next.
+func (it *iterator) Value() []byte {
+	if it.pos < 0 || it.pos >= len(it.values) {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	if it.released {
+		return
+	}
+	it.released = true
+	it.keys, it.values, it.pos = nil, nil, -1
+	it.conn.call(&request{Op: opIteratorRelease, ID: it.id})
+	it.db.release(it.conn)
+}
+
+// snapshot is a read-only view of the remote key-value store, held by the
+// server until released.
+type snapshot struct {
+	db       *Database
+	conn     *conn
+	id       uint64
+	lock     sync.Mutex // Serialises the requests over the connection
+	released bool
+}
+
+// call executes a request of the snapshot on its connection.
+func (snap *snapshot) call(req *request) (*response, error) {
+	snap.lock.Lock()
+	defer snap.lock.Unlock()
+
+	if snap.released {
+		return nil, errSnapshotReleased
+	}
+	return snap.conn.call(req)
+}
+
+// Has retrieves if a key is present in the snapshot.
+func (snap *snapshot) Has(key []byte) (bool, error) {
+	res, err := snap.call(&request{Op: opSnapshotHas, ID: snap.id, Key: key})
+	if err != nil {
+		return false, err
+	}
+	return res.Found, nil
+}
+
+// Get retrieves the given key if it's present in the snapshot.
+func (snap *snapshot) Get(key []byte) ([]byte, error) {
+	res, err := snap.call(&request{Op: opSnapshotGet, ID: snap.id, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if !res.Found {
+		return nil, errNotFound
+	}
+	return res.Value, nil
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (snap *snapshot) Release() {
+	snap.lock.Lock()
+	defer snap.lock.Unlock()
+
+	if snap.released {
+		return
+	}
+	snap.released = true
+	snap.conn.call(&request{Op: opSnapshotRelease, ID: snap.id})
+	snap.db.release(snap.conn)
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// newTestDatabase starts a server backed by an in-memory store and connects a
+// database to it.
+func newTestDatabase(t testing.TB) (*Database, *Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(memorydb.New())
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	db, err := New(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, server
+}
+
+func TestNetDB(t *testing.T) {
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+			db, _ := newTestDatabase(t)
+			return db
+		})
+	})
+}
+
+// Tests that iterators spanning multiple pages deliver all entries, and that
+// the server side resources are released.
+func TestIteratorPaging(t *testing.T) {
+	db, server := newTestDatabase(t)
+	defer db.Close()
+
+	var (
+		items = 3*iteratorPageItems + 7
+		batch = db.NewBatch()
+		key   = make([]byte, 8)
+	)
+	for i := 0; i < items; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		batch.Put(key, bytes.Repeat(key, 4))
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := db.NewIterator(nil, nil)
+	for i := 0; i < items; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		if !it.Next() {
+			t.Fatalf("iterator exhausted at %d: %v", i, it.Error())
+		}
+		if !bytes.Equal(it.Key(), key) || !bytes.Equal(it.Value(), bytes.Repeat(key, 4)) {
+			t.Fatalf("entry %d mismatch: have %x", i, it.Key())
+		}
+	}
+	if it.Next() {
+		t.Fatalf("iterator not exhausted: %x", it.Key())
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	// Snapshots must be isolated from later writes
+	binary.BigEndian.PutUint64(key, 0)
+	db.Delete(key)
+	if has, err := snap.Has(key); err != nil || !has {
+		t.Fatalf("snapshot affected by delete: %v, %v", has, err)
+	}
+	it.Release()
+	snap.Release()
+
+	if iterators, snapshots := server.handles(); iterators != 0 || snapshots != 0 {
+		t.Fatalf("server resources leaked: %d iterators, %d snapshots", iterators, snapshots)
+	}
+}
+
+// handles returns the number of iterators and snapshots held by the server.
+func (s *Server) handles() (iterators int, snapshots int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, sess := range s.conns {
+		sess.lock.Lock()
+		iterators += len(sess.iterators)
+		snapshots += len(sess.snapshots)
+		sess.lock.Unlock()
+	}
+	return iterators, snapshots
+}
+
+// Tests that the iterators and snapshots of a client are released by the server
+// when the client disconnects without releasing them.
+func TestDisconnectRelease(t *testing.T) {
+	db, server := newTestDatabase(t)
+
+	db.Put([]byte("key"), []byte("value"))
+	it := db.NewIterator(nil, nil)
+	if !it.Next() {
+		t.Fatal("iterator exhausted:", it.Error())
+	}
+	snap, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iterators, snapshots := server.handles(); iterators != 1 || snapshots != 1 {
+		t.Fatalf("wrong server resources: %d iterators, %d snapshots", iterators, snapshots)
+	}
+	// Drop the connections without releasing anything.
+	db.Close()
+	it.(*iterator).conn.conn.Close()
+	snap.(*snapshot).conn.conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		iterators, snapshots := server.handles()
+		if iterators == 0 && snapshots == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server resources leaked: %d iterators, %d snapshots", iterators, snapshots)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests that batches exceeding the chunk size are written completely.
+func TestLargeBatch(t *testing.T) {
+	db, _ := newTestDatabase(t)
+	defer db.Close()
+
+	var (
+		items = 3*batchChunkBytes/(1024*1024) + 1
+		batch = db.NewBatch()
+		value = make([]byte, 1024*1024)
+	)
+	for i := 0; i < items; i++ {
+		batch.Put([]byte{byte(i)}, value)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < items; i++ {
+		if v, err := db.Get([]byte{byte(i)}); err != nil || len(v) != len(value) {
+			t.Fatalf("item %d not written: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The wire protocol is a sequence of length-prefixed RLP messages. Every request
+// sent by the client is answered by exactly one response, in order.
+//
+// Iterators, snapshots and staged batch writes belong to the connection they
+// were created on, and are released by the server when the connection closes.
+
+// Request operation codes.
+const (
+	opHas uint8 = iota
+	opGet
+	opPut
+	opDelete
+	opBatch
+	opIteratorNew
+	opIteratorNext
+	opIteratorRelease
+	opSnapshotNew
+	opSnapshotHas
+	opSnapshotGet
+	opSnapshotRelease
+	opStat
+	opCompact
+	opBatchStage
+)
+
+const (
+	// maxMessageSize is the maximum size of a single protocol message.
+	maxMessageSize = 256 * 1024 * 1024
+
+	// batchChunkBytes is the soft limit of the key and value bytes sent in a
+	// single batch message. Larger batches are staged on the server in chunks
+	// and written atomically with the last one.
+	batchChunkBytes = 16 * 1024 * 1024
+
+	// iteratorPageItems is the maximum number of entries returned in a single
+	// iterator page.
+	iteratorPageItems = 1024
+
+	// iteratorPageBytes is the soft limit of the key and value bytes returned
+	// in a single iterator page.
+	iteratorPageBytes = 1024 * 1024
+
+	// requestTimeout is the maximum time allowed for a client request to be
+	// answered. Compactions are exempt as they may run for hours.
+	requestTimeout = time.Minute
+
+	// readTimeout is the maximum time allowed for receiving the rest of a
+	// message once it started arriving.
+	readTimeout = 30 * time.Second
+
+	// writeTimeout is the maximum time allowed for sending a message.
+	writeTimeout = 30 * time.Second
+)
+
+var (
+	// errNotFound is returned if a key is not present in the remote store.
+	errNotFound = errors.New("not found")
+
+	// errClosed is returned if the database was already closed.
+	errClosed = errors.New("database closed")
+
+	// errSnapshotReleased is returned if a snapshot was already released.
+	errSnapshotReleased = errors.New("snapshot released")
+
+	// errMessageTooLarge is returned if a message exceeds the size limit.
+	errMessageTooLarge = errors.New("message too large")
+)
+
+// batchOp is a single write operation of a batch.
+type batchOp struct {
+	Delete bool
+	Key    []byte
+	Value  []byte
+}
+
+// request is a message sent from the client to the server. The meaning of the
+// fields depends on the operation.
+type request struct {
+	Op    uint8
+	ID    uint64    // Iterator or snapshot identifier
+	Key   []byte    // Key, iterator prefix, stat property or compaction start
+	Value []byte    // Value, iterator start or compaction limit
+	Batch []batchOp // Write operations of a batch
+}
+
+// response is a message sent from the server to the client.
+type response struct {
+	Error  string   // Failure reason, empty on success
+	Found  bool     // Whether the requested key exists, or the iterator is exhausted
+	ID     uint64   // Identifier of a newly created iterator or snapshot
+	Value  []byte   // Value or stat of the request
+	Keys   [][]byte // Keys of an iterator page
+	Values [][]byte // Values of an iterator page
+}
+
+// writeMsg encodes and sends a message to the connection.
+func writeMsg(w io.Writer, msg interface{}) error {
+	blob, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return err
+	}
+	if len(blob) > maxMessageSize {
+		return errMessageTooLarge
+	}
+	frame := make([]byte, 4+len(blob))
+	binary.BigEndian.PutUint32(frame, uint32(len(blob)))
+	copy(frame[4:], blob)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// readMsg reads and decodes a message from the connection.
+func readMsg(r io.Reader, msg interface{}) error {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxMessageSize {
+		return errMessageTooLarge
+	}
+	blob := make([]byte, size)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return err
+	}
+	if err := rlp.DecodeBytes(blob, msg); err != nil {
+		return fmt.Errorf("invalid message: %v", err)
+	}
+	return nil
+}
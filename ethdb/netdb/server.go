@@ -0,0 +1,357 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netdb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Server exposes a key-value store to remote clients over the network.
+type Server struct {
+	db ethdb.KeyValueStore
+
+	lock      sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]*session
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// session holds the iterators, snapshots and staged batch writes created on a
+// single connection.
+type session struct {
+	lock      sync.Mutex
+	nextID    uint64
+	iterators map[uint64]ethdb.Iterator
+	snapshots map[uint64]ethdb.Snapshot
+	batch     ethdb.Batch // Batch writes staged for an atomic commit
+}
+
+// release releases all resources held by the session.
+func (sess *session) release() {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	for id, it := range sess.iterators {
+		it.Release()
+		delete(sess.iterators, id)
+	}
+	for id, snap := range sess.snapshots {
+		snap.Release()
+		delete(sess.snapshots, id)
+	}
+	sess.batch = nil
+}
+
+// NewServer creates a server exposing the given key-value store.
+func NewServer(db ethdb.KeyValueStore) *Server {
+	return &Server{
+		db:        db,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]*session),
+	}
+}
+
+// Serve accepts connections on the listener and serves the requests of each of
+// them, until the listener fails or the server is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return errClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.lock.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			delete(s.listeners, listener)
+			s.lock.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return nil
+		}
+		sess := &session{
+			iterators: make(map[uint64]ethdb.Iterator),
+			snapshots: make(map[uint64]ethdb.Snapshot),
+		}
+		s.conns[conn] = sess
+		s.wg.Add(1)
+		s.lock.Unlock()
+
+		go s.serveConn(conn, sess)
+	}
+}
+
+// Close stops accepting new connections, terminates the existing ones and
+// releases all open iterators and snapshots. The key-value store itself is not
+// closed.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	for listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+
+	// The connections release their iterators and snapshots on exit
+	s.wg.Wait()
+	return nil
+}
+
+// serveConn processes the requests of a single connection until it's closed,
+// then releases the resources held by the connection.
+func (s *Server) serveConn(conn net.Conn, sess *session) {
+	defer s.wg.Done()
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+		sess.release()
+	}()
+	var (
+		reader = bufio.NewReader(conn)
+		writer = bufio.NewWriter(conn)
+	)
+	for {
+		// Connections may idle between requests, but once a request starts
+		// arriving, it must be received in time.
+		conn.SetReadDeadline(time.Time{})
+		if _, err := reader.Peek(1); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Debug("Failed to read database request", "remote", conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		var req request
+		if err := readMsg(reader, &req); err != nil {
+			log.Debug("Failed to read database request", "remote", conn.RemoteAddr(), "err", err)
+			return
+		}
+		res := s.handle(sess, &req)
+
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := writeMsg(writer, res); err != nil {
+			log.Debug("Failed to send database response", "remote", conn.RemoteAddr(), "err", err)
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// handle executes a single request of a connection against the key-value store.
+func (s *Server) handle(sess *session, req *request) *response {
+	res := new(response)
+	fail := func(err error) *response {
+		res.Error = err.Error()
+		return res
+	}
+	switch req.Op {
+	case opHas:
+		has, err := s.db.Has(req.Key)
+		if err != nil {
+			return fail(err)
+		}
+		res.Found = has
+
+	case opGet:
+		// Key-value stores report missing keys as errors of their own, so tell
+		// them apart from real failures to be able to report them uniformly.
+		value, err := s.db.Get(req.Key)
+		if err != nil {
+			if has, herr := s.db.Has(req.Key); herr != nil || has {
+				return fail(err)
+			}
+			break
+		}
+		res.Value, res.Found = value, true
+
+	case opPut:
+		if err := s.db.Put(req.Key, req.Value); err != nil {
+			return fail(err)
+		}
+
+	case opDelete:
+		if err := s.db.Delete(req.Key); err != nil {
+			return fail(err)
+		}
+
+	case opBatchStage, opBatch:
+		// Stage the operations in the pending batch of the connection, and
+		// write it out once the last chunk arrives.
+		sess.lock.Lock()
+		batch := sess.batch
+		sess.batch = nil
+		sess.lock.Unlock()
+
+		if batch == nil {
+			batch = s.db.NewBatch()
+		}
+		for _, op := range req.Batch {
+			var err error
+			if op.Delete {
+				err = batch.Delete(op.Key)
+			} else {
+				err = batch.Put(op.Key, op.Value)
+			}
+			if err != nil {
+				return fail(err)
+			}
+		}
+		if req.Op == opBatchStage {
+			sess.lock.Lock()
+			sess.batch = batch
+			sess.lock.Unlock()
+			break
+		}
+		if err := batch.Write(); err != nil {
+			return fail(err)
+		}
+
+	case opIteratorNew:
+		it := s.db.NewIterator(req.Key, req.Value)
+
+		sess.lock.Lock()
+		sess.nextID++
+		res.ID = sess.nextID
+		sess.iterators[res.ID] = it
+		sess.lock.Unlock()
+
+	case opIteratorNext:
+		sess.lock.Lock()
+		it := sess.iterators[req.ID]
+		sess.lock.Unlock()
+		if it == nil {
+			return fail(fmt.Errorf("unknown iterator %d", req.ID))
+		}
+		var size int
+		for len(res.Keys) < iteratorPageItems && size < iteratorPageBytes {
+			if !it.Next() {
+				res.Found = true // exhausted
+				break
+			}
+			// Iterators may reuse the key and value buffers, copy them out
+			res.Keys = append(res.Keys, common.CopyBytes(it.Key()))
+			res.Values = append(res.Values, common.CopyBytes(it.Value()))
+			size += len(it.Key()) + len(it.Value())
+		}
+		if err := it.Error(); err != nil {
+			return fail(err)
+		}
+
+	case opIteratorRelease:
+		sess.lock.Lock()
+		if it := sess.iterators[req.ID]; it != nil {
+			it.Release()
+			delete(sess.iterators, req.ID)
+		}
+		sess.lock.Unlock()
+
+	case opSnapshotNew:
+		snap, err := s.db.NewSnapshot()
+		if err != nil {
+			return fail(err)
+		}
+		sess.lock.Lock()
+		sess.nextID++
+		res.ID = sess.nextID
+		sess.snapshots[res.ID] = snap
+		sess.lock.Unlock()
+
+	case opSnapshotHas, opSnapshotGet:
+		sess.lock.Lock()
+		snap := sess.snapshots[req.ID]
+		sess.lock.Unlock()
+		if snap == nil {
+			return fail(fmt.Errorf("unknown snapshot %d", req.ID))
+		}
+		has, err := snap.Has(req.Key)
+		if err != nil {
+			return fail(err)
+		}
+		res.Found = has
+		if has && req.Op == opSnapshotGet {
+			if res.Value, err = snap.Get(req.Key); err != nil {
+				return fail(err)
+			}
+		}
+
+	case opSnapshotRelease:
+		sess.lock.Lock()
+		if snap := sess.snapshots[req.ID]; snap != nil {
+			snap.Release()
+			delete(sess.snapshots, req.ID)
+		}
+		sess.lock.Unlock()
+
+	case opStat:
+		stat, err := s.db.Stat(string(req.Key))
+		if err != nil {
+			return fail(err)
+		}
+		res.Value = []byte(stat)
+
+	case opCompact:
+		var start, limit []byte
+		if len(req.Key) > 0 {
+			start = req.Key
+		}
+		if len(req.Value) > 0 {
+			limit = req.Value
+		}
+		if err := s.db.Compact(start, limit); err != nil {
+			return fail(err)
+		}
+
+	default:
+		return fail(fmt.Errorf("unknown operation %d", req.Op))
+	}
+	return res
+}
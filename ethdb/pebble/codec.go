@@ -0,0 +1,215 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pebble
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// valueCodecFile is the file in the database directory marking that the values
+// are stored with a codec tag, i.e. that the database supports key compression.
+const valueCodecFile = "GETH_VALUE_CODEC"
+
+// Codec tags prepended to the stored values.
+const (
+	valueRaw    byte = 0
+	valueSnappy byte = 1
+	valueZstd   byte = 2
+)
+
+var errInvalidValue = errors.New("invalid value encoding")
+
+// valueCodec compresses the values of a database according to the key compression
+// settings. Every value is prefixed with a tag identifying its codec, so values
+// remain readable when the settings change.
+//
+// A nil codec stores the values as they are, which is the case for databases
+// created without key compression.
+type valueCodec struct {
+	keys    []KeyCompression // Compression by key prefix, longest prefixes first
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// openValueCodec returns the value codec of the database in the given directory.
+// Databases are only switched to tagged values if key compression is requested
+// when they are created.
+func openValueCodec(dir string, db *pebble.DB, settings *Settings, readonly bool) (*valueCodec, error) {
+	marker := filepath.Join(dir, valueCodecFile)
+	if _, err := os.Stat(marker); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(settings.KeyCompression) == 0 {
+			return nil, nil
+		}
+		if readonly {
+			// Nothing is written, the values are read as they are
+			return nil, nil
+		}
+		empty, err := isEmpty(db)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, errors.New("key compression can only be enabled on a new database")
+		}
+		if err := writeMarker(marker); err != nil {
+			return nil, err
+		}
+	}
+	return newValueCodec(settings.KeyCompression)
+}
+
+// writeMarker durably creates the value codec marker, before any value is written
+// with a codec tag.
+func writeMarker(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte{1}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// HasKeyCompression reports whether the database in the given directory was
+// created with key compression.
+func HasKeyCompression(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, valueCodecFile))
+	return err == nil
+}
+
+func isEmpty(db *pebble.DB) (bool, error) {
+	iter, err := db.NewIter(nil)
+	if err != nil {
+		return false, err
+	}
+	defer iter.Close()
+	return !iter.First(), iter.Error()
+}
+
+func newValueCodec(keys []KeyCompression) (*valueCodec, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	return &valueCodec{keys: keys, encoder: encoder, decoder: decoder}, nil
+}
+
+// close releases the resources of the zstd codec.
+func (c *valueCodec) close() {
+	if c != nil {
+		c.encoder.Close()
+		c.decoder.Close()
+	}
+}
+
+// compression returns the compression of the values stored under the key.
+func (c *valueCodec) compression(key []byte) string {
+	for _, k := range c.keys {
+		if bytes.HasPrefix(key, k.Prefix) {
+			return k.Compression
+		}
+	}
+	return CompressionNone
+}
+
+// encode appends the encoded value to dst. Values are stored raw if compression
+// doesn't make them smaller.
+func (c *valueCodec) encode(dst []byte, key, value []byte) []byte {
+	if c == nil {
+		return append(dst, value...)
+	}
+	start := len(dst)
+	switch c.compression(key) {
+	case CompressionSnappy:
+		dst = append(dst, valueSnappy)
+		n := len(dst)
+		dst = append(dst, make([]byte, snappy.MaxEncodedLen(len(value)))...)
+		dst = dst[:n+len(snappy.Encode(dst[n:], value))]
+	case CompressionZstd:
+		dst = c.encoder.EncodeAll(value, append(dst, valueZstd))
+	}
+	if len(dst) > start && len(dst)-start <= len(value) {
+		return dst
+	}
+	dst = append(dst[:start], valueRaw)
+	return append(dst, value...)
+}
+
+// decode returns the value stored in enc. The result never aliases enc.
+func (c *valueCodec) decode(enc []byte) ([]byte, error) {
+	if c == nil {
+		ret := make([]byte, len(enc))
+		copy(ret, enc)
+		return ret, nil
+	}
+	if len(enc) == 0 {
+		return nil, errInvalidValue
+	}
+	switch enc[0] {
+	case valueRaw:
+		ret := make([]byte, len(enc)-1)
+		copy(ret, enc[1:])
+		return ret, nil
+	case valueSnappy:
+		return snappy.Decode(nil, enc[1:])
+	case valueZstd:
+		return c.decoder.DecodeAll(enc[1:], []byte{})
+	default:
+		return nil, fmt.Errorf("%w: unknown codec %d", errInvalidValue, enc[0])
+	}
+}
+
+// view returns the value stored in enc, aliasing enc if it's not compressed.
+func (c *valueCodec) view(enc []byte) ([]byte, error) {
+	if c == nil {
+		return enc, nil
+	}
+	if len(enc) > 0 && enc[0] == valueRaw {
+		return enc[1:], nil
+	}
+	return c.decode(enc)
+}
@@ -0,0 +1,250 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Supported tuning profiles.
+const (
+	ProfileDefault = "default" // Balanced settings, identical to the historical ones
+	ProfileSync    = "sync"    // Write-heavy settings for initial sync and block import
+	ProfileRPC     = "rpc"     // Read-heavy settings for RPC serving nodes
+)
+
+// Supported block compression algorithms.
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// numLevels is the number of levels in the pebble LSM tree.
+const numLevels = 7
+
+// Config contains the user-tunable options of a pebble database. Zero fields
+// are filled in from the selected profile.
+//
+// Pebble picks the compression of a table by the level it is written to, not by
+// the keys it contains. Data is written to the upper (hot) levels first and is
+// moved to the bottom levels as it ages, so frequently rewritten entries like
+// recent trie nodes mostly live in the hot levels, while the bulk of the state
+// and the chain data settles in the cold ones.
+//
+// The level compression applies to all keys in a level alike. To choose the
+// compression by key, e.g. to compress the flat state with zstd while storing
+// the trie nodes uncompressed, the values under the given key prefixes are
+// compressed individually before they are written. The level compression is
+// applied to the blocks on top, set it to none to only compress by key. Key
+// compression can only be enabled when the database is created.
+type Config struct {
+	Profile        string           `toml:",omitempty"` // Tuning profile to start from
+	Compression    string           `toml:",omitempty"` // Compression of the cold levels
+	HotCompression string           `toml:",omitempty"` // Compression of the hot levels
+	KeyCompression []KeyCompression `toml:",omitempty"` // Compression of the values by key prefix
+	BloomBits      int              `toml:",omitempty"` // Bloom filter bits per key (negative disables filters)
+	BlockSize      int              `toml:",omitempty"` // Uncompressed size of the data blocks in bytes
+}
+
+// KeyCompression is the compression of the values stored under a key prefix.
+type KeyCompression struct {
+	Prefix      hexutil.Bytes
+	Compression string
+}
+
+// Settings are the effective tuning options of a pebble database, after
+// applying the user overrides on top of the selected profile.
+type Settings struct {
+	Profile               string
+	Compression           string           // Compression of the cold levels
+	HotCompression        string           // Compression of the hot levels
+	HotLevels             int              // Number of upper levels considered hot
+	KeyCompression        []KeyCompression // Compression by key prefix, longest prefixes first
+	BloomBits             int              // Bloom filter bits per key, zero if disabled
+	BlockSize             int              // Uncompressed size of the data blocks in bytes
+	TargetFileSize        int64            // Target size of the tables in the first level
+	MemTables             int              // Number of memory tables, including the frozen ones
+	L0CompactionThreshold int              // Level-zero read amplification triggering compactions
+	L0StopWritesThreshold int              // Level-zero read amplification stalling writes
+}
+
+// KeyCompressionFor returns the key compression of the values under the given
+// prefix, if all of them are compressed alike.
+func (s *Settings) KeyCompressionFor(prefix []byte) (string, bool) {
+	for _, k := range s.KeyCompression {
+		if bytes.HasPrefix(prefix, k.Prefix) {
+			return k.Compression, true
+		}
+	}
+	return "", false
+}
+
+// profiles are the predefined tuning presets.
+var profiles = map[string]Settings{
+	ProfileDefault: {
+		Profile:               ProfileDefault,
+		Compression:           CompressionSnappy,
+		HotCompression:        CompressionSnappy,
+		HotLevels:             2,
+		BloomBits:             10,
+		BlockSize:             4 * 1024,
+		TargetFileSize:        2 * 1024 * 1024,
+		MemTables:             2,
+		L0CompactionThreshold: 4,
+		L0StopWritesThreshold: 12,
+	},
+	// The sync profile trades read amplification for write throughput: bigger
+	// tables and a deeper level-zero mean fewer compactions, and skipping the
+	// compression of the hot levels saves CPU on data that is rewritten soon.
+	ProfileSync: {
+		Profile:               ProfileSync,
+		Compression:           CompressionSnappy,
+		HotCompression:        CompressionNone,
+		HotLevels:             3,
+		BloomBits:             10,
+		BlockSize:             4 * 1024,
+		TargetFileSize:        8 * 1024 * 1024,
+		MemTables:             4,
+		L0CompactionThreshold: 8,
+		L0StopWritesThreshold: 24,
+	},
+	// The rpc profile keeps level-zero shallow and the bloom filters precise to
+	// make point lookups cheap, and compresses the rarely rewritten bottom of
+	// the tree harder so that more of it fits into the page cache.
+	ProfileRPC: {
+		Profile:               ProfileRPC,
+		Compression:           CompressionZstd,
+		HotCompression:        CompressionSnappy,
+		HotLevels:             2,
+		BloomBits:             16,
+		BlockSize:             4 * 1024,
+		TargetFileSize:        2 * 1024 * 1024,
+		MemTables:             2,
+		L0CompactionThreshold: 2,
+		L0StopWritesThreshold: 12,
+	},
+}
+
+// Profiles returns the names of the supported tuning profiles.
+func Profiles() []string {
+	return []string{ProfileDefault, ProfileSync, ProfileRPC}
+}
+
+// Settings validates the config and returns the effective tuning options.
+func (c *Config) Settings() (*Settings, error) {
+	name := ProfileDefault
+	if c != nil && c.Profile != "" {
+		name = strings.ToLower(c.Profile)
+	}
+	settings, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pebble profile %q, allowed %s", name, strings.Join(Profiles(), ", "))
+	}
+	if c == nil {
+		return &settings, nil
+	}
+	if c.Compression != "" {
+		if _, err := parseCompression(c.Compression); err != nil {
+			return nil, err
+		}
+		settings.Compression = strings.ToLower(c.Compression)
+	}
+	if c.HotCompression != "" {
+		if _, err := parseCompression(c.HotCompression); err != nil {
+			return nil, err
+		}
+		settings.HotCompression = strings.ToLower(c.HotCompression)
+	}
+	for _, k := range c.KeyCompression {
+		if len(k.Prefix) == 0 {
+			return nil, fmt.Errorf("empty key compression prefix")
+		}
+		if _, err := parseCompression(k.Compression); err != nil {
+			return nil, err
+		}
+		for _, other := range settings.KeyCompression {
+			if bytes.Equal(k.Prefix, other.Prefix) {
+				return nil, fmt.Errorf("duplicate key compression prefix %v", k.Prefix)
+			}
+		}
+		settings.KeyCompression = append(settings.KeyCompression, KeyCompression{
+			Prefix:      bytes.Clone(k.Prefix),
+			Compression: strings.ToLower(k.Compression),
+		})
+	}
+	// Match the longest prefixes first
+	slices.SortStableFunc(settings.KeyCompression, func(a, b KeyCompression) int {
+		return len(b.Prefix) - len(a.Prefix)
+	})
+	switch {
+	case c.BloomBits < 0:
+		settings.BloomBits = 0
+	case c.BloomBits > 0:
+		settings.BloomBits = c.BloomBits
+	}
+	if c.BlockSize != 0 {
+		if c.BlockSize < 1024 || c.BlockSize > 1024*1024 {
+			return nil, fmt.Errorf("invalid pebble block size %d, must be between 1KiB and 1MiB", c.BlockSize)
+		}
+		settings.BlockSize = c.BlockSize
+	}
+	return &settings, nil
+}
+
+// levels assembles the per-level options of the LSM tree.
+func (s *Settings) levels() []pebble.LevelOptions {
+	hot, _ := parseCompression(s.HotCompression)
+	cold, _ := parseCompression(s.Compression)
+
+	levels := make([]pebble.LevelOptions, numLevels)
+	for i := range levels {
+		levels[i] = pebble.LevelOptions{
+			BlockSize:      s.BlockSize,
+			Compression:    cold,
+			TargetFileSize: s.TargetFileSize,
+		}
+		if i < s.HotLevels {
+			levels[i].Compression = hot
+		}
+		if s.BloomBits > 0 {
+			levels[i].FilterPolicy = bloom.FilterPolicy(s.BloomBits)
+		}
+	}
+	return levels
+}
+
+// parseCompression converts a compression name into the pebble option.
+func parseCompression(name string) (pebble.Compression, error) {
+	switch strings.ToLower(name) {
+	case CompressionNone:
+		return pebble.NoCompression, nil
+	case CompressionSnappy:
+		return pebble.SnappyCompression, nil
+	case CompressionZstd:
+		return pebble.ZstdCompression, nil
+	default:
+		return pebble.DefaultCompression, fmt.Errorf("unknown compression %q, allowed %s, %s or %s", name, CompressionNone, CompressionSnappy, CompressionZstd)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func TestConfigSettings(t *testing.T) {
+	// A nil config must yield the historical defaults
+	settings, err := (*Config)(nil).Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*settings, profiles[ProfileDefault]) {
+		t.Fatalf("nil config settings mismatch: have %+v", settings)
+	}
+	// Overrides must be applied on top of the profile
+	settings, err = (&Config{Profile: "RPC", HotCompression: "none", BloomBits: -1, BlockSize: 16384}).Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Profile != ProfileRPC || settings.Compression != CompressionZstd || settings.HotCompression != CompressionNone {
+		t.Fatalf("profile overrides mismatch: have %+v", settings)
+	}
+	if settings.BloomBits != 0 || settings.BlockSize != 16384 {
+		t.Fatalf("filter and block overrides mismatch: have %+v", settings)
+	}
+	levels := settings.levels()
+	for i, level := range levels {
+		want := pebble.ZstdCompression
+		if i < settings.HotLevels {
+			want = pebble.NoCompression
+		}
+		if level.Compression != want || level.FilterPolicy != nil || level.BlockSize != 16384 {
+			t.Fatalf("level %d options mismatch: %+v", i, level)
+		}
+	}
+	// Key compression must be matched by the longest prefix
+	settings, err = (&Config{KeyCompression: []KeyCompression{
+		{Prefix: []byte{0x01}, Compression: "ZSTD"},
+		{Prefix: []byte{0x01, 0x02}, Compression: "none"},
+	}}).Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := settings.KeyCompressionFor([]byte{0x01, 0x02, 0x03}); !ok || c != CompressionNone {
+		t.Fatalf("longest prefix mismatch: have %q, %v", c, ok)
+	}
+	if c, ok := settings.KeyCompressionFor([]byte{0x01}); !ok || c != CompressionZstd {
+		t.Fatalf("prefix mismatch: have %q, %v", c, ok)
+	}
+	if _, ok := settings.KeyCompressionFor(nil); ok {
+		t.Fatal("key compression reported for all keys")
+	}
+	// Invalid options must be rejected
+	for _, config := range []*Config{
+		{Profile: "turbo"},
+		{Compression: "lz4"},
+		{HotCompression: "gzip"},
+		{BlockSize: 100},
+		{KeyCompression: []KeyCompression{{Compression: CompressionZstd}}},
+		{KeyCompression: []KeyCompression{{Prefix: []byte{0x01}, Compression: "lz4"}}},
+		{KeyCompression: []KeyCompression{{Prefix: []byte{0x01}, Compression: "zstd"}, {Prefix: []byte{0x01}, Compression: "none"}}},
+	} {
+		if _, err := config.Settings(); err == nil {
+			t.Errorf("invalid config %+v accepted", config)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	for _, profile := range Profiles() {
+		t.Run(profile, func(t *testing.T) {
+			db, err := NewWithConfig(t.TempDir(), 16, 16, "", false, true, &Config{Profile: profile})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			for i := 0; i < 1000; i++ {
+				key := []byte(fmt.Sprintf("key-%04d", i))
+				if err := db.Put(key, bytes.Repeat(key, 16)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Push the data through all levels to exercise every compression
+			if err := db.Compact(nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 1000; i++ {
+				key := []byte(fmt.Sprintf("key-%04d", i))
+				if val, err := db.Get(key); err != nil || !bytes.Equal(val, bytes.Repeat(key, 16)) {
+					t.Fatalf("key %s: value mismatch: %x, %v", key, val, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEstimateCompression(t *testing.T) {
+	db := memorydb.New()
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("a-%04d", i))
+		db.Put(key, bytes.Repeat([]byte{byte(i)}, 100))
+	}
+	db.Put([]byte("b-0000"), []byte{0x01})
+
+	est, err := EstimateCompression(db, []byte("a-"), 500, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Entries != 500 || est.None != 500*106 {
+		t.Fatalf("sample mismatch: have %d entries, %v bytes", est.Entries, est.None)
+	}
+	if est.Snappy >= est.None || est.Zstd >= est.None {
+		t.Fatalf("repetitive data not compressed: snappy %v, zstd %v, raw %v", est.Snappy, est.Zstd, est.None)
+	}
+	if est.Size(CompressionNone) != est.None || est.Size(CompressionZstd) != est.Zstd {
+		t.Fatal("size accessor mismatch")
+	}
+	// Estimate the compression of the individual values
+	est, err = EstimateCompression(db, []byte("a-"), 500, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Entries != 500 || est.None != 500*106 {
+		t.Fatalf("sample mismatch: have %d entries, %v bytes", est.Entries, est.None)
+	}
+	if est.Snappy >= est.None || est.Zstd >= est.None {
+		t.Fatalf("repetitive values not compressed: snappy %v, zstd %v, raw %v", est.Snappy, est.Zstd, est.None)
+	}
+}
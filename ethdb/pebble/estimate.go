@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pebble
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionEstimate is the size of a sample of database entries under each
+// of the supported compression algorithms.
+type CompressionEstimate struct {
+	Entries int                // Number of entries sampled
+	None    common.StorageSize // Uncompressed size of the sampled entries
+	Snappy  common.StorageSize // Size of the sample with snappy compression
+	Zstd    common.StorageSize // Size of the sample with zstd compression
+}
+
+// Size returns the estimated size of the sample with the given compression.
+func (e *CompressionEstimate) Size(compression string) common.StorageSize {
+	switch compression {
+	case CompressionSnappy:
+		return e.Snappy
+	case CompressionZstd:
+		return e.Zstd
+	default:
+		return e.None
+	}
+}
+
+// EstimateCompression samples up to limit entries with the given key prefix and
+// measures how well they compress. Like in pebble tables, the entries are packed
+// into blocks of the given size, which are compressed individually. A zero block
+// size estimates the key compression instead, which compresses every value on
+// its own and stores the keys as they are.
+func EstimateCompression(db ethdb.Iteratee, prefix []byte, limit int, blockSize int) (*CompressionEstimate, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+
+	var (
+		estimate = new(CompressionEstimate)
+		block    = make([]byte, 0, blockSize)
+		scratch  []byte
+	)
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		estimate.None += common.StorageSize(len(block))
+		scratch = snappy.Encode(scratch[:cap(scratch)], block)
+		estimate.Snappy += common.StorageSize(len(scratch))
+		scratch = encoder.EncodeAll(block, scratch[:0])
+		estimate.Zstd += common.StorageSize(len(scratch))
+		block = block[:0]
+	}
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	for estimate.Entries < limit && it.Next() {
+		if blockSize <= 0 {
+			var (
+				key   = common.StorageSize(len(it.Key()))
+				value = it.Value()
+				size  = common.StorageSize(len(value))
+			)
+			// Compressed values carry a codec tag, and are stored raw if
+			// that's smaller.
+			scratch = snappy.Encode(scratch[:cap(scratch)], value)
+			estimate.Snappy += key + 1 + min(common.StorageSize(len(scratch)), size)
+			scratch = encoder.EncodeAll(value, scratch[:0])
+			estimate.Zstd += key + 1 + min(common.StorageSize(len(scratch)), size)
+			estimate.None += key + size
+			estimate.Entries++
+			continue
+		}
+		block = append(block, it.Key()...)
+		block = append(block, it.Value()...)
+		if len(block) >= blockSize {
+			flush()
+		}
+		estimate.Entries++
+	}
+	flush()
+	return estimate, it.Error()
+}
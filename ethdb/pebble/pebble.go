@@ -26,7 +26,6 @@ import (
 	"time"
 
 	"github.com/cockroachdb/pebble"
-	"github.com/cockroachdb/pebble/bloom"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -55,8 +54,9 @@ const (
 // Apart from basic data storage functionality it also supports batch writes and
 // iterating over the keyspace in binary-alphabetical order.
 type Database struct {
-	fn string     // filename for reporting
-	db *pebble.DB // Underlying pebble storage engine
+	fn    string      // filename for reporting
+	db    *pebble.DB  // Underlying pebble storage engine
+	codec *valueCodec // Compression of the values by key, nil if disabled
 
 	compTimeMeter       metrics.Meter // Meter for measuring the total time spent in database compaction
 	compReadMeter       metrics.Meter // Meter for measuring the data read during compaction
@@ -145,6 +145,16 @@ func (l panicLogger) Fatalf(format string, args ...interface{}) {
 // New returns a wrapped pebble DB object. The namespace is the prefix that the
 // metrics reporting should use for surfacing internal stats.
 func New(file string, cache int, handles int, namespace string, readonly bool, ephemeral bool) (*Database, error) {
+	return NewWithConfig(file, cache, handles, namespace, readonly, ephemeral, nil)
+}
+
+// NewWithConfig returns a wrapped pebble DB object, tuned according to the given
+// config. A nil config selects the default profile.
+func NewWithConfig(file string, cache int, handles int, namespace string, readonly bool, ephemeral bool, config *Config) (*Database, error) {
+	settings, err := config.Settings()
+	if err != nil {
+		return nil, err
+	}
 	// Ensure we have some minimal caching and file guarantees
 	if cache < minCache {
 		cache = minCache
@@ -154,6 +164,9 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 	}
 	logger := log.New("database", file)
 	logger.Info("Allocated cache and file handles", "cache", common.StorageSize(cache*1024*1024), "handles", handles)
+	logger.Info("Configured pebble tuning", "profile", settings.Profile, "compression", settings.Compression,
+		"hotcompression", settings.HotCompression, "keycompression", len(settings.KeyCompression),
+		"bloombits", settings.BloomBits, "blocksize", settings.BlockSize)
 
 	// The max memtable size is limited by the uint32 offsets stored in
 	// internal/arenaskl.node, DeferredBatchOp, and flushableBatchEntry.
@@ -167,9 +180,9 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 	// Taken from https://github.com/cockroachdb/pebble/blob/master/internal/constants/constants.go
 	maxMemTableSize := (1<<31)<<(^uint(0)>>63) - 1
 
-	// Two memory tables is configured which is identical to leveldb,
-	// including a frozen memory table and another live one.
-	memTableLimit := 2
+	// By default two memory tables are configured which is identical to
+	// leveldb, including a frozen memory table and another live one.
+	memTableLimit := settings.MemTables
 	memTableSize := cache * 1024 * 1024 / 2 / memTableLimit
 
 	// The memory table size is currently capped at maxMemTableSize-1 due to a
@@ -209,17 +222,14 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 		// Here use all available CPUs for faster compaction.
 		MaxConcurrentCompactions: runtime.NumCPU,
 
+		// The level-zero read amplification at which compactions are started
+		// and at which writes are stalled until they catch up.
+		L0CompactionThreshold: settings.L0CompactionThreshold,
+		L0StopWritesThreshold: settings.L0StopWritesThreshold,
+
 		// Per-level options. Options for at least one level must be specified. The
 		// options for the last level are used for all subsequent levels.
-		Levels: []pebble.LevelOptions{
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-		},
+		Levels:   settings.levels(),
 		ReadOnly: readonly,
 		EventListener: &pebble.EventListener{
 			CompactionBegin: db.onCompactionBegin,
@@ -240,6 +250,16 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 	}
 	db.db = innerDB
 
+	// Set up the compression of the values by key
+	db.codec, err = openValueCodec(file, innerDB, settings, readonly)
+	if err != nil {
+		innerDB.Close()
+		return nil, err
+	}
+	if len(settings.KeyCompression) > 0 && db.codec == nil {
+		logger.Warn("Key compression only applies to new databases")
+	}
+
 	db.compTimeMeter = metrics.GetOrRegisterMeter(namespace+"compact/time", nil)
 	db.compReadMeter = metrics.GetOrRegisterMeter(namespace+"compact/input", nil)
 	db.compWriteMeter = metrics.GetOrRegisterMeter(namespace+"compact/output", nil)
@@ -277,6 +297,7 @@ func (d *Database) Close() error {
 		}
 		d.quitChan = nil
 	}
+	defer d.codec.close()
 	return d.db.Close()
 }
 
@@ -308,10 +329,8 @@ func (d *Database) Get(key []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	ret := make([]byte, len(dat))
-	copy(ret, dat)
-	closer.Close()
-	return ret, nil
+	defer closer.Close()
+	return d.codec.decode(dat)
 }
 
 // Put inserts the given value into the key-value store.
@@ -321,6 +340,9 @@ func (d *Database) Put(key []byte, value []byte) error {
 	if d.closed {
 		return pebble.ErrClosed
 	}
+	if d.codec != nil {
+		value = d.codec.encode(nil, key, value)
+	}
 	return d.db.Set(key, value, d.writeOptions)
 }
 
@@ -353,7 +375,8 @@ func (d *Database) NewBatchWithSize(size int) ethdb.Batch {
 
 // snapshot wraps a pebble snapshot for implementing the Snapshot interface.
 type snapshot struct {
-	db *pebble.Snapshot
+	db    *pebble.Snapshot
+	codec *valueCodec
 }
 
 // NewSnapshot creates a database snapshot based on the current state.
@@ -363,7 +386,7 @@ type snapshot struct {
 // the stale data will never be cleaned up by the underlying compactor.
 func (d *Database) NewSnapshot() (ethdb.Snapshot, error) {
 	snap := d.db.NewSnapshot()
-	return &snapshot{db: snap}, nil
+	return &snapshot{db: snap, codec: d.codec}, nil
 }
 
 // Has retrieves if a key is present in the snapshot backing by a key-value
@@ -388,10 +411,8 @@ func (snap *snapshot) Get(key []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	ret := make([]byte, len(dat))
-	copy(ret, dat)
-	closer.Close()
-	return ret, nil
+	defer closer.Close()
+	return snap.codec.decode(dat)
 }
 
 // Release releases associated resources. Release should always succeed and can
@@ -566,12 +587,17 @@ type batch struct {
 	b    *pebble.Batch
 	db   *Database
 	size int
+	buf  []byte // Scratch space for encoding the values
 }
 
 // Put inserts the given value into the batch for later committing.
 func (b *batch) Put(key, value []byte) error {
-	b.b.Set(key, value, nil)
 	b.size += len(key) + len(value)
+	if b.db.codec != nil {
+		b.buf = b.db.codec.encode(b.buf[:0], key, value)
+		value = b.buf
+	}
+	b.b.Set(key, value, nil)
 	return nil
 }
 
@@ -614,6 +640,10 @@ func (b *batch) Replay(w ethdb.KeyValueWriter) error {
 		// The (k,v) slices might be overwritten if the batch is reset/reused,
 		// and the receiver should copy them if they are to be retained long-term.
 		if kind == pebble.InternalKeyKindSet {
+			v, err := b.db.codec.view(v)
+			if err != nil {
+				return err
+			}
 			w.Put(k, v)
 		} else if kind == pebble.InternalKeyKindDelete {
 			w.Delete(k)
@@ -630,8 +660,10 @@ func (b *batch) Replay(w ethdb.KeyValueWriter) error {
 // The pebble iterator is not thread-safe.
 type pebbleIterator struct {
 	iter     *pebble.Iterator
+	codec    *valueCodec
 	moved    bool
 	released bool
+	err      error // Error decoding a value
 }
 
 // NewIterator creates a binary-alphabetical iterator over a subset
@@ -643,7 +675,7 @@ func (d *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
 		UpperBound: upperBound(prefix),
 	})
 	iter.First()
-	return &pebbleIterator{iter: iter, moved: true, released: false}
+	return &pebbleIterator{iter: iter, codec: d.codec, moved: true, released: false}
 }
 
 // Next moves the iterator to the next key/value pair. It returns whether the
@@ -659,6 +691,9 @@ func (iter *pebbleIterator) Next() bool {
 // Error returns any accumulated error. Exhausting all the key/value pairs
 // is not considered to be an error.
 func (iter *pebbleIterator) Error() error {
+	if iter.err != nil {
+		return iter.err
+	}
 	return iter.iter.Error()
 }
 
@@ -673,7 +708,16 @@ func (iter *pebbleIterator) Key() []byte {
 // caller should not modify the contents of the returned slice, and its contents
 // may change on the next call to Next.
 func (iter *pebbleIterator) Value() []byte {
-	return iter.iter.Value()
+	value := iter.iter.Value()
+	if value == nil {
+		return nil
+	}
+	value, err := iter.codec.view(value)
+	if err != nil {
+		iter.err = err
+		return nil
+	}
+	return value
 }
 
 // Release releases associated resources. Release should always succeed and can
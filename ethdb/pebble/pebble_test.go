@@ -17,12 +17,14 @@
 package pebble
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 )
 
 func TestPebbleDB(t *testing.T) {
@@ -41,6 +43,152 @@ func TestPebbleDB(t *testing.T) {
 	})
 }
 
+func TestPebbleDBKeyCompression(t *testing.T) {
+	// Compress the values of all keys, alternating the codecs
+	var keys []KeyCompression
+	for i := 0; i < 256; i++ {
+		compression := []string{CompressionNone, CompressionSnappy, CompressionZstd}[i%3]
+		keys = append(keys, KeyCompression{Prefix: []byte{byte(i)}, Compression: compression})
+	}
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+			db, err := NewWithConfig(t.TempDir(), 16, 16, "", false, true, &Config{KeyCompression: keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		})
+	})
+}
+
+func TestKeyCompression(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		config = &Config{
+			Compression:    CompressionNone,
+			HotCompression: CompressionNone,
+			KeyCompression: []KeyCompression{
+				{Prefix: []byte("a"), Compression: CompressionZstd},
+				{Prefix: []byte("ab"), Compression: CompressionNone},
+				{Prefix: []byte("s"), Compression: CompressionSnappy},
+			},
+		}
+		entries = map[string][]byte{
+			"a-1":  bytes.Repeat([]byte{0x01}, 100),
+			"ab-1": bytes.Repeat([]byte{0x02}, 100),
+			"s-1":  bytes.Repeat([]byte{0x03}, 100),
+			"x-1":  bytes.Repeat([]byte{0x04}, 100),
+			"a-2":  {0x05}, // not compressible
+			"s-2":  {},
+		}
+		codecs = map[string]byte{
+			"a-1": valueZstd, "ab-1": valueRaw, "s-1": valueSnappy, "x-1": valueRaw, "a-2": valueRaw, "s-2": valueRaw,
+		}
+	)
+	db, err := NewWithConfig(dir, 16, 16, "", false, true, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasKeyCompression(dir) {
+		t.Fatal("new database not marked for key compression")
+	}
+	batch := db.NewBatch()
+	for key, value := range entries {
+		if key == "x-1" {
+			db.Put([]byte(key), value)
+		} else {
+			batch.Put([]byte(key), value)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	// Check the stored encodings
+	for key, codec := range codecs {
+		enc, closer, err := db.db.Get([]byte(key))
+		if err != nil {
+			t.Fatalf("key %s: %v", key, err)
+		}
+		if enc[0] != codec {
+			t.Errorf("key %s: codec mismatch: have %d, want %d", key, enc[0], codec)
+		}
+		if codec != valueRaw && len(enc) >= len(entries[key]) {
+			t.Errorf("key %s: value not compressed", key)
+		}
+		closer.Close()
+	}
+	// Check the values through every accessor
+	check := func(db *Database) {
+		t.Helper()
+		for key, value := range entries {
+			if have, err := db.Get([]byte(key)); err != nil || !bytes.Equal(have, value) {
+				t.Errorf("key %s: value mismatch: have %x, %v", key, have, err)
+			}
+		}
+		it := db.NewIterator(nil, nil)
+		defer it.Release()
+		var n int
+		for ; it.Next(); n++ {
+			if !bytes.Equal(it.Value(), entries[string(it.Key())]) {
+				t.Errorf("key %s: iterated value mismatch: have %x", it.Key(), it.Value())
+			}
+		}
+		if err := it.Error(); err != nil || n != len(entries) {
+			t.Errorf("iteration failed: %d entries, %v", n, err)
+		}
+		snap, _ := db.NewSnapshot()
+		defer snap.Release()
+		if have, err := snap.Get([]byte("a-1")); err != nil || !bytes.Equal(have, entries["a-1"]) {
+			t.Errorf("snapshot value mismatch: have %x, %v", have, err)
+		}
+	}
+	check(db)
+
+	mem := memorydb.New()
+	if err := batch.Replay(mem); err != nil {
+		t.Fatal(err)
+	}
+	if have, _ := mem.Get([]byte("a-1")); !bytes.Equal(have, entries["a-1"]) {
+		t.Errorf("replayed value mismatch: have %x", have)
+	}
+	db.Close()
+
+	// Values remain readable without the key compression settings
+	db, err = NewWithConfig(dir, 16, 16, "", false, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(db)
+	db.Close()
+}
+
+func TestKeyCompressionExisting(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewWithConfig(dir, 16, 16, "", false, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put([]byte("a"), []byte{0x01})
+	db.Close()
+
+	// Existing databases can't switch to key compression
+	config := &Config{KeyCompression: []KeyCompression{{Prefix: []byte("a"), Compression: CompressionZstd}}}
+	if _, err := NewWithConfig(dir, 16, 16, "", false, true, config); err == nil {
+		t.Fatal("key compression enabled on existing database")
+	}
+	db, err = NewWithConfig(dir, 16, 16, "", true, true, config)
+	if err != nil {
+		t.Fatal("failed to open read-only database:", err)
+	}
+	if have, err := db.Get([]byte("a")); err != nil || !bytes.Equal(have, []byte{0x01}) {
+		t.Fatalf("value mismatch: have %x, %v", have, err)
+	}
+	db.Close()
+	if HasKeyCompression(dir) {
+		t.Fatal("existing database marked for key compression")
+	}
+}
+
 func BenchmarkPebbleDB(b *testing.B) {
 	dbtest.BenchDatabaseSuite(b, func() ethdb.KeyValueStore {
 		db, err := pebble.Open("", &pebble.Options{
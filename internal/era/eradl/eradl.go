@@ -0,0 +1,408 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package eradl implements a downloader for Era1 archives served by an HTTP
+// mirror.
+package eradl
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// ChecksumsFile is the name of the file listing the SHA256 checksums of all
+	// Era1 files of the mirror, in epoch order.
+	ChecksumsFile = "checksums.txt"
+
+	defaultParallel = 4
+	partialSuffix   = ".partial"
+)
+
+// File is an Era1 file served by the mirror.
+type File struct {
+	Name     string
+	Epoch    uint64
+	Checksum common.Hash // SHA256 of the file content
+	Root     common.Hash // Trusted accumulator root
+}
+
+// Loader downloads Era1 files from an HTTP mirror. The mirror is expected to
+// serve the directory layout created by 'geth export-history': the Era1 files
+// of a network, a checksums.txt file, and a listing of the directory at the
+// base URL.
+//
+// Everything served by the mirror is untrusted: the files are verified against
+// accumulator roots obtained from a trusted source, and only the epochs these
+// cover are downloaded.
+type Loader struct {
+	base     *url.URL
+	network  string
+	roots    []common.Hash
+	parallel int
+	client   *http.Client
+}
+
+// New creates a loader for the mirror at baseURL, accepting the Era1 files
+// with the given trusted accumulator roots, in epoch order. At most parallel
+// files are downloaded at the same time.
+func New(baseURL string, network string, roots []common.Hash, parallel int) (*Loader, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("no trusted accumulator roots")
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mirror URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported mirror URL scheme %q", base.Scheme)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	if parallel <= 0 {
+		parallel = defaultParallel
+	}
+	return &Loader{base: base, network: network, roots: roots, parallel: parallel, client: new(http.Client)}, nil
+}
+
+// ReadRoots reads a file of trusted accumulator roots, one hex-encoded root per
+// line in epoch order.
+func ReadRoots(file string) ([]common.Hash, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := parseHashes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accumulator roots file %s: %w", file, err)
+	}
+	return roots, nil
+}
+
+// Files retrieves the list of Era1 files available on the mirror, in epoch
+// order, along with their checksums. The files of the epochs without a trusted
+// accumulator root are left out.
+func (l *Loader) Files(ctx context.Context) ([]File, error) {
+	listing, err := l.get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("can't read mirror index: %w", err)
+	}
+	names, err := parseListing(listing, l.network)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := l.get(ctx, ChecksumsFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %s: %w", ChecksumsFile, err)
+	}
+	checksums, err := parseHashes(sums)
+	if err != nil {
+		return nil, err
+	}
+	if len(checksums) != len(names) {
+		return nil, fmt.Errorf("mirror lists %d era1 files, but %d checksums", len(names), len(checksums))
+	}
+	if len(names) > len(l.roots) {
+		log.Warn("Ignoring era1 files without trusted accumulator root", "mirror", len(names), "trusted", len(l.roots))
+		names = names[:len(l.roots)]
+	}
+	files := make([]File, len(names))
+	for i, name := range names {
+		files[i] = File{Name: name, Epoch: uint64(i), Checksum: checksums[i], Root: l.roots[i]}
+	}
+	return files, nil
+}
+
+// Download fetches all Era1 files from the mirror into dir, along with the
+// checksums file. Files which are already present are kept if their checksum
+// is correct and its accumulator root trusted, and interrupted downloads are
+// resumed. Every file is verified against its checksum and trusted accumulator
+// root before it is moved into place.
+func (l *Loader) Download(ctx context.Context, dir string) error {
+	files, err := l.Files(ctx)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no era1 files for network %s on mirror", l.network)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		start    = time.Now()
+		done     atomic.Uint64
+		reported atomic.Int64
+		firstErr error
+		errOnce  sync.Once
+		wg       sync.WaitGroup
+		queue    = make(chan File)
+	)
+	reported.Store(start.UnixNano())
+	for i := 0; i < l.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				if err := l.fetch(ctx, dir, f); err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("%s: %w", f.Name, err)
+						cancel()
+					})
+					return
+				}
+				n := done.Add(1)
+				// Give the user some feedback that something is happening.
+				if last := reported.Load(); time.Since(time.Unix(0, last)) >= 8*time.Second && reported.CompareAndSwap(last, time.Now().UnixNano()) {
+					log.Info("Downloading era1 files", "done", n, "total", len(files), "elapsed", common.PrettyDuration(time.Since(start)))
+				}
+			}
+		}()
+	}
+loop:
+	for _, f := range files {
+		select {
+		case queue <- f:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Store the checksums last, so the directory is only importable once all
+	// files are complete.
+	sums := make([]string, len(files))
+	for i, f := range files {
+		sums[i] = f.Checksum.Hex()
+	}
+	if err := os.WriteFile(filepath.Join(dir, ChecksumsFile), []byte(strings.Join(sums, "\n")), 0644); err != nil {
+		return err
+	}
+	log.Info("Downloaded era1 files", "count", len(files), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// fetch downloads a single file into dir, unless it is already present.
+func (l *Loader) fetch(ctx context.Context, dir string, f File) error {
+	path := filepath.Join(dir, f.Name)
+	if sum, err := checksum(path); err == nil && sum == f.Checksum {
+		if err := verify(path, f); err == nil {
+			return nil
+		}
+	}
+	partial := path + partialSuffix
+	if err := l.download(ctx, f.Name, partial); err != nil {
+		return err
+	}
+	if err := verify(partial, f); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// download fetches the named file into path, resuming from the current size of
+// the file if the server supports range requests.
+func (l *Loader) download(ctx context.Context, name string, path string) error {
+	out, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url(name), nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		log.Debug("Resuming era1 download", "file", name, "offset", offset)
+	case http.StatusOK:
+		// Range not supported or no partial file, start from scratch.
+		if err := out.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil // partial file is already complete
+	default:
+		return fmt.Errorf("HTTP status %s", resp.Status)
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+// get retrieves a small file from the mirror.
+func (l *Loader) get(ctx context.Context, name string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 16*1024*1024))
+}
+
+// url returns the URL of the named file on the mirror.
+func (l *Loader) url(name string) string {
+	return l.base.ResolveReference(&url.URL{Path: name}).String()
+}
+
+// verify checks the checksum of a downloaded file, and that its content matches
+// the trusted accumulator root.
+func verify(path string, f File) error {
+	sum, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	if sum != f.Checksum {
+		return fmt.Errorf("checksum mismatch: have %s, want %s", sum, f.Checksum)
+	}
+	e, err := era.Open(path)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+	root, err := e.Accumulator()
+	if err != nil {
+		return err
+	}
+	if root != f.Root {
+		return fmt.Errorf("accumulator root mismatch: have %s, want %s", root, f.Root)
+	}
+	if want := era.Filename(filenameNetwork(f.Name), int(f.Epoch), root); want != f.Name {
+		return fmt.Errorf("accumulator root %s doesn't match file name", root)
+	}
+	return era.CheckAccumulator(e)
+}
+
+// checksum computes the SHA256 hash of a file.
+func checksum(path string) (common.Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(h.Sum(nil)), nil
+}
+
+// filenameNetwork returns the network part of an Era1 file name.
+func filenameNetwork(name string) string {
+	return name[:strings.IndexByte(name, '-')]
+}
+
+// parseListing extracts the names of the Era1 files of a network from a
+// directory listing, which is usually an HTML page. The files must cover a
+// contiguous range of epochs starting at zero.
+func parseListing(listing []byte, network string) ([]string, error) {
+	var (
+		re     = regexp.MustCompile(regexp.QuoteMeta(network) + `-(\d{5,})-[0-9a-f]{8}\.era1`)
+		epochs = make(map[uint64]string)
+	)
+	for _, match := range re.FindAllSubmatch(listing, -1) {
+		epoch, err := strconv.ParseUint(string(match[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", match[0])
+		}
+		if name, ok := epochs[epoch]; ok && name != string(match[0]) {
+			return nil, fmt.Errorf("duplicate files for epoch %d", epoch)
+		}
+		epochs[epoch] = string(match[0])
+	}
+	names := make([]string, 0, len(epochs))
+	for epoch := uint64(0); epoch < uint64(len(epochs)); epoch++ {
+		name, ok := epochs[epoch]
+		if !ok {
+			return nil, fmt.Errorf("missing epoch %d", epoch)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseHashes parses a list of newline-separated hex hashes.
+func parseHashes(b []byte) ([]common.Hash, error) {
+	var (
+		sums []common.Hash
+		s    = bufio.NewScanner(strings.NewReader(string(b)))
+	)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		if len(strings.TrimPrefix(line, "0x")) != 2*common.HashLength {
+			return nil, errors.New("invalid hash " + line)
+		}
+		sums = append(sums, common.HexToHash(line))
+	}
+	return sums, s.Err()
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package eradl
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeMirror creates a directory of Era1 files with the given number of epochs,
+// as written by 'geth export-history', and returns it along with the accumulator
+// roots of the files.
+func makeMirror(t *testing.T, epochs int, size int) (string, []common.Hash) {
+	var (
+		dir    = t.TempDir()
+		parent common.Hash
+		sums   []string
+		roots  []common.Hash
+	)
+	for epoch := 0; epoch < epochs; epoch++ {
+		var buf bytes.Buffer
+		builder := era.NewBuilder(&buf)
+		for i := 0; i < size; i++ {
+			number := epoch*size + i
+			header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(number)), Difficulty: big.NewInt(1)}
+			txs := []*types.Transaction{types.NewTransaction(uint64(number), common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)}
+			receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000}}
+			block := types.NewBlock(header, &types.Body{Transactions: txs}, receipts, trie.NewStackTrie(nil))
+			if err := builder.Add(block, receipts, big.NewInt(int64(number+1))); err != nil {
+				t.Fatal(err)
+			}
+			parent = block.Hash()
+		}
+		root, err := builder.Finalize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, era.Filename("testnet", epoch, root))
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum, _ := checksum(path)
+		sums = append(sums, sum.Hex())
+		roots = append(roots, root)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ChecksumsFile), []byte(strings.Join(sums, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, roots
+}
+
+func TestDownload(t *testing.T) {
+	var (
+		mirror, roots = makeMirror(t, 5, 16)
+		ranges        atomic.Int32
+		fs            = http.FileServer(http.Dir(mirror))
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			ranges.Add(1)
+		}
+		fs.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	l, err := New(srv.URL, "testnet", roots, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := l.Files(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("wrong number of files: %d", len(files))
+	}
+
+	// Simulate an interrupted download of the second file.
+	dir := t.TempDir()
+	content, _ := os.ReadFile(filepath.Join(mirror, files[1].Name))
+	if err := os.WriteFile(filepath.Join(dir, files[1].Name+partialSuffix), content[:len(content)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Download(context.Background(), dir); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if ranges.Load() != 1 {
+		t.Fatalf("download not resumed, %d range requests", ranges.Load())
+	}
+	entries, err := era.ReadDir(dir, "testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("wrong number of downloaded files: %d", len(entries))
+	}
+	for _, f := range files {
+		if sum, err := checksum(filepath.Join(dir, f.Name)); err != nil || sum != f.Checksum {
+			t.Fatalf("file %s not downloaded correctly", f.Name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, f.Name+partialSuffix)); !os.IsNotExist(err) {
+			t.Fatalf("partial file %s not removed", f.Name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ChecksumsFile)); err != nil {
+		t.Fatal("checksums file not written")
+	}
+}
+
+func TestDownloadCorrupted(t *testing.T) {
+	mirror, roots := makeMirror(t, 2, 8)
+	srv := httptest.NewServer(http.FileServer(http.Dir(mirror)))
+	defer srv.Close()
+
+	l, err := New(srv.URL, "testnet", roots, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := l.Files(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the second file on the mirror.
+	path := filepath.Join(mirror, files[1].Name)
+	content, _ := os.ReadFile(path)
+	content[len(content)/2] ^= 0xff
+	os.WriteFile(path, content, 0644)
+
+	dir := t.TempDir()
+	if err := l.Download(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("wrong error for corrupted file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, files[1].Name)); !os.IsNotExist(err) {
+		t.Fatal("corrupted file was stored")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ChecksumsFile)); !os.IsNotExist(err) {
+		t.Fatal("checksums written for incomplete download")
+	}
+}
+
+func TestDownloadUntrusted(t *testing.T) {
+	mirror, roots := makeMirror(t, 3, 8)
+	srv := httptest.NewServer(http.FileServer(http.Dir(mirror)))
+	defer srv.Close()
+
+	if _, err := New(srv.URL, "testnet", nil, 1); err == nil {
+		t.Fatal("loader created without trusted roots")
+	}
+
+	// Files whose accumulator root isn't trusted are rejected, even though the
+	// checksums published by the mirror match.
+	untrusted := slices.Clone(roots)
+	untrusted[1] = common.Hash{0x01}
+	l, err := New(srv.URL, "testnet", untrusted, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := l.Download(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "accumulator root mismatch") {
+		t.Fatalf("wrong error for untrusted file: %v", err)
+	}
+
+	// Epochs without a trusted root are not downloaded.
+	l, err = New(srv.URL, "testnet", roots[:2], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir = t.TempDir()
+	if err := l.Download(context.Background(), dir); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	entries, err := era.ReadDir(dir, "testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("wrong number of downloaded files: %d", len(entries))
+	}
+}
+
+func TestParseListing(t *testing.T) {
+	listing := []byte(`<a href="testnet-00001-aabbccdd.era1">testnet-00001-aabbccdd.era1</a>
+<a href="testnet-00000-11223344.era1">testnet-00000-11223344.era1</a>
+<a href="other-00000-11223344.era1">other-00000-11223344.era1</a>`)
+	names, err := parseListing(listing, "testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "testnet-00000-11223344.era1" {
+		t.Fatalf("wrong names: %v", names)
+	}
+	if _, err := parseListing([]byte("testnet-00001-aabbccdd.era1"), "testnet"); err == nil {
+		t.Fatal("missing epoch not detected")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package execdb
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	ssz "github.com/ferranbt/fastssz"
+)
+
+// proofDepth is the depth of the hash list tree, log2(MaxSize).
+const proofDepth = 13
+
+// zeroHashes are the roots of empty subtrees of each depth.
+var zeroHashes [proofDepth]common.Hash
+
+func init() {
+	for i := 1; i < proofDepth; i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+// ComputeAccumulator calculates the SSZ hash tree root of the list of block
+// hashes in an EraE archive.
+func ComputeAccumulator(hashes []common.Hash) (common.Hash, error) {
+	if len(hashes) > MaxSize {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxSize)
+	}
+	hh := ssz.NewHasher()
+	for _, hash := range hashes {
+		hh.Append(hash[:])
+	}
+	hh.MerkleizeWithMixin(0, uint64(len(hashes)), uint64(MaxSize))
+	return hh.HashRoot()
+}
+
+// ComputeProof returns the Merkle branch proving the inclusion of the block
+// hash at the given index in the hash list.
+func ComputeProof(hashes []common.Hash, index int) ([]common.Hash, error) {
+	if len(hashes) > MaxSize {
+		return nil, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxSize)
+	}
+	if index < 0 || index >= len(hashes) {
+		return nil, fmt.Errorf("index %d out of range", index)
+	}
+	var (
+		proof = make([]common.Hash, 0, proofDepth)
+		layer = hashes
+	)
+	for depth := 0; depth < proofDepth; depth++ {
+		sibling := index ^ 1
+		if sibling < len(layer) {
+			proof = append(proof, layer[sibling])
+		} else {
+			proof = append(proof, zeroHashes[depth])
+		}
+		next := make([]common.Hash, (len(layer)+1)/2)
+		for i := range next {
+			right := zeroHashes[depth]
+			if 2*i+1 < len(layer) {
+				right = layer[2*i+1]
+			}
+			next[i] = hashPair(layer[2*i], right)
+		}
+		layer, index = next, index/2
+	}
+	return proof, nil
+}
+
+// VerifyProof checks that hash is the block hash at the given index of a hash
+// list with the given root and number of entries.
+func VerifyProof(root common.Hash, count uint64, index uint64, hash common.Hash, proof []common.Hash) error {
+	if len(proof) != proofDepth {
+		return fmt.Errorf("invalid proof length %d", len(proof))
+	}
+	if index >= count {
+		return fmt.Errorf("index %d out of range", index)
+	}
+	node := hash
+	for i, sibling := range proof {
+		if index>>i&1 == 0 {
+			node = hashPair(node, sibling)
+		} else {
+			node = hashPair(sibling, node)
+		}
+	}
+	var length common.Hash
+	binary.LittleEndian.PutUint64(length[:], count)
+	if hashPair(node, length) != root {
+		return errors.New("invalid proof")
+	}
+	return nil
+}
+
+// hashPair returns the SHA256 hash of two concatenated tree nodes.
+func hashPair(a, b common.Hash) (h common.Hash) {
+	hasher := sha256.New()
+	hasher.Write(a[:])
+	hasher.Write(b[:])
+	hasher.Sum(h[:0])
+	return h
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package execdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/internal/era/e2store"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// Builder is used to create EraE archives of post-merge block data.
+//
+// Era1 files record the total difficulty of every block and commit to it in
+// their accumulator, which is meaningless after the merge. EraE files are
+// e2store files with the same layout as Era1, minus the total difficulty:
+//
+//	erae := Version | block-tuple* | other-entries* | BlockHashesRoot | BlockIndex
+//	block-tuple :=  CompressedHeader | CompressedBody | CompressedReceipts
+//
+// Each basic element is its own entry:
+//
+//	Version            = { type: [0x65, 0x32], data: nil }
+//	CompressedHeader   = { type: [0x03, 0x00], data: snappyFramed(rlp(header)) }
+//	CompressedBody     = { type: [0x04, 0x00], data: snappyFramed(rlp(body)) }
+//	CompressedReceipts = { type: [0x05, 0x00], data: snappyFramed(rlp(receipts)) }
+//	BlockHashesRoot    = { type: [0x08, 0x00], data: hash-list-root }
+//	BlockIndex         = { type: [0x32, 0x66], data: block-index }
+//
+// Bodies contain the withdrawals of the block, if any. The hash list root is
+// the SSZ hash_tree_root of the list of block hashes in the archive:
+//
+//	hash-list-root := hash_tree_root(List[Bytes32, 8192])
+//
+// Since every header commits to its body and receipts, the root is sufficient
+// to verify the complete content of the archive, and single blocks can be
+// proven against it with a Merkle branch.
+//
+// BlockIndex is identical to Era1. It stores the number of the first block,
+// followed by relative offsets to each block tuple and the block count:
+//
+//	block-index := starting-number | index | index | index ... | count
+//
+// The maximum number of blocks in an EraE file is 8192.
+type Builder struct {
+	w        *e2store.Writer
+	startNum *uint64
+	indexes  []uint64
+	hashes   []common.Hash
+	written  int
+
+	buf    *bytes.Buffer
+	snappy *snappy.Writer
+}
+
+// NewBuilder returns a new Builder instance.
+func NewBuilder(w io.Writer) *Builder {
+	buf := bytes.NewBuffer(nil)
+	return &Builder{
+		w:      e2store.NewWriter(w),
+		buf:    buf,
+		snappy: snappy.NewBufferedWriter(buf),
+	}
+}
+
+// Add writes a compressed block entry and compressed receipts entry to the
+// underlying e2store file.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts) error {
+	eh, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		return err
+	}
+	eb, err := rlp.EncodeToBytes(block.Body())
+	if err != nil {
+		return err
+	}
+	er, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		return err
+	}
+	return b.AddRLP(eh, eb, er, block.NumberU64(), block.Hash())
+}
+
+// AddRLP writes a compressed block entry and compressed receipts entry to the
+// underlying e2store file. Blocks must be added in ascending order.
+func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash common.Hash) error {
+	// Write version entry before first block.
+	if b.startNum == nil {
+		n, err := b.w.Write(era.TypeVersion, nil)
+		if err != nil {
+			return err
+		}
+		startNum := number
+		b.startNum = &startNum
+		b.written += n
+	}
+	if len(b.indexes) >= MaxSize {
+		return fmt.Errorf("exceeds maximum batch size of %d", MaxSize)
+	}
+	if want := *b.startNum + uint64(len(b.indexes)); number != want {
+		return fmt.Errorf("non-contiguous block %d, want %d", number, want)
+	}
+	b.indexes = append(b.indexes, uint64(b.written))
+	b.hashes = append(b.hashes, hash)
+
+	// Write block data.
+	if err := b.snappyWrite(era.TypeCompressedHeader, header); err != nil {
+		return err
+	}
+	if err := b.snappyWrite(era.TypeCompressedBody, body); err != nil {
+		return err
+	}
+	return b.snappyWrite(era.TypeCompressedReceipts, receipts)
+}
+
+// Finalize computes the hash list root and block index values, then writes the
+// corresponding e2store entries.
+func (b *Builder) Finalize() (common.Hash, error) {
+	if b.startNum == nil {
+		return common.Hash{}, errors.New("finalize called on empty builder")
+	}
+	root, err := ComputeAccumulator(b.hashes)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error calculating hash list root: %w", err)
+	}
+	n, err := b.w.Write(TypeBlockHashesRoot, root[:])
+	b.written += n
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error writing hash list root: %w", err)
+	}
+	// Construct the block index, with offsets relative to the beginning of
+	// the index entry.
+	var (
+		base  = int64(b.written)
+		count = len(b.indexes)
+		index = make([]byte, 16+count*8)
+	)
+	binary.LittleEndian.PutUint64(index, *b.startNum)
+	for i, offset := range b.indexes {
+		relative := int64(offset) - base
+		binary.LittleEndian.PutUint64(index[8+i*8:], uint64(relative))
+	}
+	binary.LittleEndian.PutUint64(index[8+count*8:], uint64(count))
+
+	if _, err := b.w.Write(era.TypeBlockIndex, index); err != nil {
+		return common.Hash{}, fmt.Errorf("unable to write block index: %w", err)
+	}
+	return root, nil
+}
+
+// snappyWrite is a small helper to take care snappy encoding and writing an e2store entry.
+func (b *Builder) snappyWrite(typ uint16, in []byte) error {
+	b.buf.Reset()
+	b.snappy.Reset(b.buf)
+	if _, err := b.snappy.Write(in); err != nil {
+		return fmt.Errorf("error snappy encoding: %w", err)
+	}
+	if err := b.snappy.Flush(); err != nil {
+		return fmt.Errorf("error flushing snappy encoding: %w", err)
+	}
+	n, err := b.w.Write(typ, b.buf.Bytes())
+	b.written += n
+	if err != nil {
+		return fmt.Errorf("error writing e2store entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package execdb
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeChain creates a chain segment of post-merge blocks with transactions,
+// receipts and withdrawals.
+func makeChain(start uint64, n int) ([]*types.Block, []types.Receipts) {
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		parent   common.Hash
+	)
+	for i := 0; i < n; i++ {
+		var (
+			txs []*types.Transaction
+			rs  types.Receipts
+			ws  = []*types.Withdrawal{{Index: uint64(i), Validator: 1, Address: common.Address{byte(i)}, Amount: 100}}
+		)
+		for j := 0; j < i%3; j++ {
+			txs = append(txs, types.NewTransaction(uint64(j), common.Address{byte(i)}, big.NewInt(1), 21000, big.NewInt(1), nil))
+			r := &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: uint64(j+1) * 21000,
+				Logs:              []*types.Log{{Address: common.Address{byte(j)}, Data: []byte{byte(i)}}},
+			}
+			r.Bloom = types.CreateBloom(types.Receipts{r})
+			rs = append(rs, r)
+		}
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(start + uint64(i)),
+			Difficulty: new(big.Int),
+			BaseFee:    big.NewInt(7),
+		}
+		block := types.NewBlock(header, &types.Body{Transactions: txs, Withdrawals: ws}, rs, trie.NewStackTrie(nil))
+		blocks = append(blocks, block)
+		receipts = append(receipts, rs)
+		parent = block.Hash()
+	}
+	return blocks, receipts
+}
+
+func TestBuilder(t *testing.T) {
+	var (
+		dir              = t.TempDir()
+		blocks, receipts = makeChain(100, 64)
+		hashes           []common.Hash
+	)
+	f, err := os.Create(filepath.Join(dir, "test.erae"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	builder := NewBuilder(f)
+	for i, block := range blocks {
+		if err := builder.Add(block, receipts[i]); err != nil {
+			t.Fatalf("error adding block %d: %v", i, err)
+		}
+		hashes = append(hashes, block.Hash())
+	}
+	if err := builder.Add(blocks[0], receipts[0]); err == nil {
+		t.Fatal("non-contiguous block accepted")
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("error finalizing: %v", err)
+	}
+	if want, _ := ComputeAccumulator(hashes); root != want {
+		t.Fatalf("wrong root: have %x, want %x", root, want)
+	}
+
+	e, err := From(f)
+	if err != nil {
+		t.Fatalf("error opening erae: %v", err)
+	}
+	if e.Start() != 100 || e.Count() != 64 {
+		t.Fatalf("wrong metadata: start %d, count %d", e.Start(), e.Count())
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("wrong accumulator: %x, %v", have, err)
+	}
+	// Check random access.
+	for _, i := range []int{0, 17, 63} {
+		block, err := e.GetBlockByNumber(blocks[i].NumberU64())
+		if err != nil {
+			t.Fatalf("error reading block %d: %v", i, err)
+		}
+		if block.Hash() != blocks[i].Hash() || len(block.Withdrawals()) != 1 {
+			t.Fatalf("block %d mismatch", i)
+		}
+		rs, err := e.GetReceiptsByNumber(blocks[i].NumberU64())
+		if err != nil {
+			t.Fatalf("error reading receipts %d: %v", i, err)
+		}
+		if types.DeriveSha(rs, trie.NewStackTrie(nil)) != blocks[i].ReceiptHash() {
+			t.Fatalf("receipts %d mismatch", i)
+		}
+	}
+	if _, err := e.GetBlockByNumber(99); err == nil {
+		t.Fatal("out-of-bounds block returned")
+	}
+	// Check iteration.
+	it, err := NewIterator(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for ; it.Next(); n++ {
+		if it.Error() != nil {
+			t.Fatalf("error iterating: %v", it.Error())
+		}
+		block, rs, err := it.BlockAndReceipts()
+		if err != nil {
+			t.Fatalf("error reading block %d: %v", it.Number(), err)
+		}
+		if block.Hash() != blocks[n].Hash() || len(rs) != len(receipts[n]) {
+			t.Fatalf("block %d mismatch", it.Number())
+		}
+		if *block.Header().WithdrawalsHash != types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)) {
+			t.Fatalf("block %d: withdrawals mismatch", it.Number())
+		}
+	}
+	if n != len(blocks) {
+		t.Fatalf("iterated %d blocks, want %d", n, len(blocks))
+	}
+}
+
+func TestRejectEra1(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.era1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	builder := era.NewBuilder(f)
+	for i := 0; i < 4; i++ {
+		if err := builder.AddRLP([]byte{1}, []byte{2}, []byte{3}, uint64(i), common.Hash{byte(i)}, big.NewInt(int64(i+1)), big.NewInt(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := builder.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := From(f); err == nil {
+		t.Fatal("era1 file accepted")
+	}
+}
+
+func TestProof(t *testing.T) {
+	for _, count := range []int{1, 2, 7, 100, MaxSize} {
+		hashes := make([]common.Hash, count)
+		for i := range hashes {
+			hashes[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+		}
+		root, err := ComputeAccumulator(hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range []int{0, count / 2, count - 1} {
+			proof, err := ComputeProof(hashes, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyProof(root, uint64(count), uint64(i), hashes[i], proof); err != nil {
+				t.Fatalf("count %d, index %d: %v", count, i, err)
+			}
+			if err := VerifyProof(root, uint64(count), uint64(i), common.Hash{0xff}, proof); err == nil {
+				t.Fatalf("count %d, index %d: invalid proof accepted", count, i)
+			}
+		}
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		Filename("mainnet", 5, common.Hash{1}),
+		Filename("mainnet", 6, common.Hash{2}),
+		Filename("sepolia", 0, common.Hash{3}),
+		"mainnet-00000-01000000.era1",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != "mainnet-00005-01000000.erae" {
+		t.Fatalf("wrong entries: %v", entries)
+	}
+	os.WriteFile(filepath.Join(dir, Filename("mainnet", 8, common.Hash{})), nil, 0644)
+	if _, err := ReadDir(dir, "mainnet"); err == nil {
+		t.Fatal("missing epoch not detected")
+	}
+}
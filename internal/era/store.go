@@ -0,0 +1,141 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ErrNotFound is returned by Store when the requested block is not available.
+var ErrNotFound = errors.New("block not found in era1 files")
+
+// Store provides random access to blocks and receipts in a directory of Era1
+// files. Blocks are looked up by number and hash, so the directory may contain
+// files of multiple networks. All returned data is verified against the block
+// header.
+type Store struct {
+	dir   string
+	files map[uint64][]string // epoch -> file names
+}
+
+// NewStore creates a store backed by the Era1 files in dir.
+func NewStore(dir string) (*Store, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	s := &Store{dir: dir, files: make(map[uint64][]string)}
+	for _, entry := range entries {
+		if path.Ext(entry.Name()) != ".era1" {
+			continue
+		}
+		parts := strings.Split(entry.Name(), "-")
+		if len(parts) != 3 {
+			continue
+		}
+		epoch, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", entry.Name())
+		}
+		s.files[epoch] = append(s.files[epoch], entry.Name())
+	}
+	return s, nil
+}
+
+// Files returns the number of Era1 files in the store.
+func (s *Store) Files() int {
+	var n int
+	for _, names := range s.files {
+		n += len(names)
+	}
+	return n
+}
+
+// GetBlock returns the block with the given number and hash.
+func (s *Store) GetBlock(number uint64, hash common.Hash) (*types.Block, error) {
+	var block *types.Block
+	err := s.find(number, hash, func(e *Era, header *types.Header) error {
+		b, err := e.GetBlockByNumber(number)
+		if err != nil {
+			return err
+		}
+		if err := verifyBody(header, b.Body()); err != nil {
+			return err
+		}
+		block = b
+		return nil
+	})
+	return block, err
+}
+
+// GetReceipts returns the receipts of the block with the given number and hash.
+// Only the consensus fields of the receipts are set.
+func (s *Store) GetReceipts(number uint64, hash common.Hash) (types.Receipts, error) {
+	var receipts types.Receipts
+	err := s.find(number, hash, func(e *Era, header *types.Header) error {
+		r, err := e.GetReceiptsByNumber(number)
+		if err != nil {
+			return err
+		}
+		if root := types.DeriveSha(r, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+			return fmt.Errorf("receipt root mismatch in block %d: have %x, want %x", number, root, header.ReceiptHash)
+		}
+		receipts = r
+		return nil
+	})
+	return receipts, err
+}
+
+// find opens the Era1 file containing the given block and runs fn on it.
+func (s *Store) find(number uint64, hash common.Hash, fn func(*Era, *types.Header) error) error {
+	for _, name := range s.files[number/uint64(MaxEra1Size)] {
+		e, err := Open(filepath.Join(s.dir, name))
+		if err != nil {
+			return err
+		}
+		header, err := e.GetHeaderByNumber(number)
+		if err != nil || header.Hash() != hash {
+			e.Close()
+			continue
+		}
+		err = fn(e, header)
+		e.Close()
+		return err
+	}
+	return ErrNotFound
+}
+
+// verifyBody checks that the body belongs to the given header.
+func verifyBody(header *types.Header, body *types.Body) error {
+	if hash := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return fmt.Errorf("transaction root mismatch in block %d: have %x, want %x", header.Number, hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(body.Uncles); hash != header.UncleHash {
+		return fmt.Errorf("uncle root mismatch in block %d: have %x, want %x", header.Number, hash, header.UncleHash)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestStore(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		blocks   []*types.Block
+		receipts []types.Receipts
+	)
+	// Create a chain segment with transactions and logs.
+	parent := common.Hash{}
+	for i := 0; i < 16; i++ {
+		var (
+			txs []*types.Transaction
+			rs  types.Receipts
+		)
+		for j := 0; j < i%4; j++ {
+			tx := types.NewTransaction(uint64(j), common.Address{byte(i)}, big.NewInt(1), 21000, big.NewInt(1), nil)
+			r := &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: uint64(j+1) * 21000,
+				Logs:              []*types.Log{{Address: common.Address{byte(j)}, Data: []byte{byte(i)}}},
+			}
+			r.Bloom = types.CreateBloom(types.Receipts{r})
+			txs = append(txs, tx)
+			rs = append(rs, r)
+		}
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)}
+		block := types.NewBlock(header, &types.Body{Transactions: txs}, rs, trie.NewStackTrie(nil))
+		blocks = append(blocks, block)
+		receipts = append(receipts, rs)
+		parent = block.Hash()
+	}
+	f, err := os.Create(filepath.Join(dir, "test-00000-00000000.era1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := NewBuilder(f)
+	for i, block := range blocks {
+		if err := builder.Add(block, receipts[i], big.NewInt(int64(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := builder.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Files() != 1 {
+		t.Fatalf("wrong number of files: %d", store.Files())
+	}
+	for i, want := range blocks {
+		block, err := store.GetBlock(want.NumberU64(), want.Hash())
+		if err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		if block.Hash() != want.Hash() || len(block.Transactions()) != len(want.Transactions()) {
+			t.Fatalf("block %d: wrong block returned", i)
+		}
+		rs, err := store.GetReceipts(want.NumberU64(), want.Hash())
+		if err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		if len(rs) != len(receipts[i]) {
+			t.Fatalf("block %d: wrong number of receipts %d, want %d", i, len(rs), len(receipts[i]))
+		}
+		for j, r := range rs {
+			if r.CumulativeGasUsed != receipts[i][j].CumulativeGasUsed || len(r.Logs) != 1 {
+				t.Fatalf("block %d: wrong receipt %d", i, j)
+			}
+		}
+	}
+	// Blocks of other chains aren't returned.
+	if _, err := store.GetBlock(1, common.Hash{1}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("wrong error for unknown hash: %v", err)
+	}
+	if _, err := store.GetBlock(uint64(MaxEra1Size), common.Hash{1}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("wrong error for missing epoch: %v", err)
+	}
+}
@@ -51,6 +51,7 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
 )
 
 // estimateGasErrorRatio is the amount of overestimation eth_estimateGas is
@@ -737,16 +738,7 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 		}
 		// Create the proofs for the storageKeys.
 		for i, key := range keys {
-			// Output key encoding is a bit special: if the input was a 32-byte hash, it is
-			// returned as such. Otherwise, we apply the QUANTITY encoding mandated by the
-			// JSON-RPC spec for getProof. This behavior exists to preserve backwards
-			// compatibility with older client versions.
-			var outputKey string
-			if keyLengths[i] != 32 {
-				outputKey = hexutil.EncodeBig(key.Big())
-			} else {
-				outputKey = hexutil.Encode(key[:])
-			}
+			outputKey := encodeProofKey(key, keyLengths[i])
 			if storageTrie == nil {
 				storageProof[i] = StorageResult{outputKey, &hexutil.Big{}, []string{}}
 				continue
@@ -780,6 +772,142 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 	}, statedb.Error()
 }
 
+// maxMultiProofKeys is the maximum number of accounts and storage slots proven
+// by a single GetMultiProof call.
+const maxMultiProofKeys = 1024
+
+// MultiProofRequest selects an account and optionally some of its storage
+// slots to prove in GetMultiProof.
+type MultiProofRequest struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []string       `json:"storageKeys"`
+}
+
+// MultiProofResult is the result of GetMultiProof. The proof nodes of all the
+// accounts and storage slots are deduplicated into a single list: the accounts
+// are proven against the state root, the storage slots against the storage hash
+// of their account.
+type MultiProofResult struct {
+	StateRoot common.Hash          `json:"stateRoot"`
+	Accounts  []MultiAccountResult `json:"accounts"`
+	Nodes     []string             `json:"nodes"`
+}
+
+// MultiAccountResult is an account proven by GetMultiProof.
+type MultiAccountResult struct {
+	Address     common.Address       `json:"address"`
+	Balance     *hexutil.Big         `json:"balance"`
+	CodeHash    common.Hash          `json:"codeHash"`
+	Nonce       hexutil.Uint64       `json:"nonce"`
+	StorageHash common.Hash          `json:"storageHash"`
+	Storage     []MultiStorageResult `json:"storage"`
+}
+
+// MultiStorageResult is a storage slot proven by GetMultiProof.
+type MultiStorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+}
+
+// GetMultiProof returns a batched Merkle-proof of several accounts and
+// optionally some of their storage keys. Unlike GetProof, the trie nodes shared
+// by the proofs are included only once.
+func (s *BlockChainAPI) GetMultiProof(ctx context.Context, requests []MultiProofRequest, blockNrOrHash rpc.BlockNumberOrHash) (*MultiProofResult, error) {
+	var (
+		keys       = make([][]common.Hash, len(requests))
+		keyLengths = make([][]int, len(requests))
+		total      = len(requests)
+	)
+	for _, req := range requests {
+		total += len(req.StorageKeys)
+	}
+	if total > maxMultiProofKeys {
+		return nil, fmt.Errorf("too many keys requested: %d, want at most %d", total, maxMultiProofKeys)
+	}
+	// Deserialize all keys. This prevents state access on invalid input.
+	for i, req := range requests {
+		keys[i] = make([]common.Hash, len(req.StorageKeys))
+		keyLengths[i] = make([]int, len(req.StorageKeys))
+		for j, hexKey := range req.StorageKeys {
+			var err error
+			keys[i][j], keyLengths[i][j], err = decodeHash(hexKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	var (
+		nodes       = trienode.NewProofSet()
+		accounts    = make([]MultiAccountResult, len(requests))
+		accountKeys = make([][]byte, len(requests))
+	)
+	for i, req := range requests {
+		storageRoot := statedb.GetStorageRoot(req.Address)
+		accounts[i] = MultiAccountResult{
+			Address:     req.Address,
+			Balance:     (*hexutil.Big)(statedb.GetBalance(req.Address).ToBig()),
+			CodeHash:    statedb.GetCodeHash(req.Address),
+			Nonce:       hexutil.Uint64(statedb.GetNonce(req.Address)),
+			StorageHash: storageRoot,
+			Storage:     make([]MultiStorageResult, len(keys[i])),
+		}
+		accountKeys[i] = crypto.Keccak256(req.Address.Bytes())
+
+		slotKeys := make([][]byte, len(keys[i]))
+		for j, key := range keys[i] {
+			accounts[i].Storage[j] = MultiStorageResult{
+				Key:   encodeProofKey(key, keyLengths[i][j]),
+				Value: (*hexutil.Big)(statedb.GetState(req.Address, key).Big()),
+			}
+			slotKeys[j] = crypto.Keccak256(key.Bytes())
+		}
+		if len(slotKeys) == 0 || storageRoot == types.EmptyRootHash || storageRoot == (common.Hash{}) {
+			continue
+		}
+		id := trie.StorageTrieID(header.Root, common.BytesToHash(accountKeys[i]), storageRoot)
+		st, err := trie.NewStateTrie(id, statedb.Database().TrieDB())
+		if err != nil {
+			return nil, err
+		}
+		if err := st.ProveMulti(slotKeys, nodes); err != nil {
+			return nil, err
+		}
+	}
+	tr, err := trie.NewStateTrie(trie.StateTrieID(header.Root), statedb.Database().TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	if err := tr.ProveMulti(accountKeys, nodes); err != nil {
+		return nil, err
+	}
+	list := nodes.List()
+	result := &MultiProofResult{
+		StateRoot: header.Root,
+		Accounts:  accounts,
+		Nodes:     make([]string, len(list)),
+	}
+	for i, node := range list {
+		result.Nodes[i] = hexutil.Encode(node)
+	}
+	return result, statedb.Error()
+}
+
+// encodeProofKey encodes a storage key for the output of the proof methods.
+// Output key encoding is a bit special: if the input was a 32-byte hash, it is
+// returned as such. Otherwise, we apply the QUANTITY encoding mandated by the
+// JSON-RPC spec for getProof. This behavior exists to preserve backwards
+// compatibility with older client versions.
+func encodeProofKey(key common.Hash, inputLength int) string {
+	if inputLength != 32 {
+		return hexutil.EncodeBig(key.Big())
+	}
+	return hexutil.Encode(key[:])
+}
+
 // decodeHash parses a hex-encoded 32-byte hash. The input may optionally
 // be prefixed by 0x and can have a byte length up to 32.
 func decodeHash(s string) (h common.Hash, inputLength int, err error) {
@@ -847,6 +975,9 @@ func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNu
 		}
 		return response, err
 	}
+	if err == nil && number >= 0 && uint64(number) < s.b.HistoryPruningCutoff() {
+		return nil, NewPrunedHistoryError()
+	}
 	return nil, err
 }
 
@@ -857,6 +988,11 @@ func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fu
 	if block != nil {
 		return s.rpcMarshalBlock(ctx, block, true, fullTx)
 	}
+	if err == nil {
+		if header, _ := s.b.HeaderByHash(ctx, hash); header != nil && header.Number.Uint64() < s.b.HistoryPruningCutoff() {
+			return nil, NewPrunedHistoryError()
+		}
+	}
 	return nil, err
 }
 
@@ -52,10 +52,13 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/blocktest"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 func testTransactionMarshal(t *testing.T, tests []txData, config *params.ChainConfig) {
@@ -584,6 +587,7 @@ func (b testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) even
 func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) HistoryPruningCutoff() uint64 { return b.chain.HistoryPruningCutoff() }
 func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	panic("implement me")
 }
@@ -2053,3 +2057,104 @@ func testRPCResponseWithFile(t *testing.T, testid int, result interface{}, rpc s
 	}
 	require.JSONEqf(t, string(want), string(data), "test %d: json not match, want: %s, have: %s", testid, string(want), string(data))
 }
+
+func TestGetMultiProof(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(3)
+		contract = common.HexToAddress("0xc0de")
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				accounts[1].addr: {Balance: big.NewInt(params.Ether), Nonce: 5},
+				contract: {
+					Balance: big.NewInt(1),
+					Code:    []byte{byte(vm.STOP)},
+					Storage: map[common.Hash]common.Hash{
+						common.HexToHash("0x01"): common.HexToHash("0xaa"),
+						common.HexToHash("0x02"): common.HexToHash("0xbb"),
+					},
+				},
+			},
+		}
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	requests := []MultiProofRequest{
+		{Address: accounts[0].addr},
+		{Address: accounts[1].addr, StorageKeys: []string{"0x1"}},
+		{Address: contract, StorageKeys: []string{"0x1", "0x0000000000000000000000000000000000000000000000000000000000000002", "0x3"}},
+		{Address: accounts[2].addr}, // non-existent
+	}
+	result, err := api.GetMultiProof(context.Background(), requests, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		t.Fatalf("failed to get multiproof: %v", err)
+	}
+	proof := memorydb.New()
+	for _, node := range result.Nodes {
+		blob := hexutil.MustDecode(node)
+		if ok, _ := proof.Has(crypto.Keccak256(blob)); ok {
+			t.Fatalf("duplicate proof node %s", node)
+		}
+		proof.Put(crypto.Keccak256(blob), blob)
+	}
+	var keys [][]byte
+	for _, req := range requests {
+		keys = append(keys, crypto.Keccak256(req.Address.Bytes()))
+	}
+	values, err := trie.VerifyMultiProof(result.StateRoot, keys, proof)
+	if err != nil {
+		t.Fatalf("failed to verify account proofs: %v", err)
+	}
+	for i, account := range result.Accounts {
+		if values[i] == nil {
+			if account.Balance.ToInt().Sign() != 0 || account.Nonce != 0 {
+				t.Fatalf("account %d: absent account reported non-empty", i)
+			}
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(values[i], &acc); err != nil {
+			t.Fatalf("account %d: failed to decode: %v", i, err)
+		}
+		if acc.Balance.ToBig().Cmp(account.Balance.ToInt()) != 0 || acc.Nonce != uint64(account.Nonce) || acc.Root != account.StorageHash || common.BytesToHash(acc.CodeHash) != account.CodeHash {
+			t.Fatalf("account %d: proven account mismatch", i)
+		}
+	}
+	// Verify the storage slots of the contract
+	storage := result.Accounts[2].Storage
+	want := []struct {
+		key   string
+		value int64
+	}{{"0x1", 0xaa}, {"0x0000000000000000000000000000000000000000000000000000000000000002", 0xbb}, {"0x3", 0}}
+	keys = keys[:0]
+	for i, slot := range storage {
+		if slot.Key != want[i].key || slot.Value.ToInt().Int64() != want[i].value {
+			t.Fatalf("slot %d mismatch: have %s=%v, want %s=%d", i, slot.Key, slot.Value, want[i].key, want[i].value)
+		}
+		keys = append(keys, crypto.Keccak256(common.HexToHash(slot.Key).Bytes()))
+	}
+	values, err = trie.VerifyMultiProof(result.Accounts[2].StorageHash, keys, proof)
+	if err != nil {
+		t.Fatalf("failed to verify storage proofs: %v", err)
+	}
+	for i, value := range values {
+		var content []byte
+		if value != nil {
+			if _, content, _, err = rlp.Split(value); err != nil {
+				t.Fatalf("slot %d: failed to decode: %v", i, err)
+			}
+		}
+		if new(big.Int).SetBytes(content).Int64() != want[i].value {
+			t.Fatalf("slot %d: proven value mismatch: have %x", i, content)
+		}
+	}
+	// Oversized requests must be rejected
+	requests = []MultiProofRequest{{Address: contract, StorageKeys: make([]string, maxMultiProofKeys)}}
+	if _, err := api.GetMultiProof(context.Background(), requests, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)); err == nil {
+		t.Fatal("oversized request not rejected")
+	}
+}
@@ -73,6 +73,7 @@ type Backend interface {
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
+	HistoryPruningCutoff() uint64 // oldest block whose body and receipts are retained
 
 	// Transaction pool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
@@ -142,6 +142,16 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setFirewall',
+			call: 'admin_setFirewall',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reloadFirewall',
+			call: 'admin_reloadFirewall',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -192,6 +202,10 @@ web3._extend({
 		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'firewall',
+			getter: 'admin_firewall'
+		}),
 		new web3._extend.Property({
 			name: 'nodeInfo',
 			getter: 'admin_nodeInfo'
@@ -501,6 +515,33 @@ web3._extend({
 			call: 'debug_getTrieFlushInterval',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'syncProgress',
+			call: 'debug_syncProgress',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'executionWitness',
+			call: 'debug_executionWitness',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'startStatePruning',
+			call: 'debug_startStatePruning',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'statePruningProgress',
+			call: 'debug_statePruningProgress',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'abortStatePruning',
+			call: 'debug_abortStatePruning',
+			params: 0
+		}),
 	],
 	properties: []
 });
@@ -594,6 +635,12 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getMultiProof',
+			call: 'eth_getMultiProof',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'createAccessList',
 			call: 'eth_createAccessList',
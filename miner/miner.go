@@ -48,6 +48,7 @@ type Config struct {
 	GasCeil             uint64         // Target gas ceiling for mined blocks.
 	GasPrice            *big.Int       // Minimum gas price for mining a transaction
 	Recommit            time.Duration  // The time interval for miner to re-create mining work.
+	AccessList          bool           // Whether to embed the block-level access list into the built payloads
 }
 
 // DefaultConfig contains default settings for miner.
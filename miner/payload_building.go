@@ -189,6 +189,7 @@ func (miner *Miner) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 		withdrawals: args.Withdrawals,
 		beaconRoot:  args.BeaconRoot,
 		noTxs:       true,
+		accessList:  miner.config.AccessList,
 	}
 	empty := miner.generateWork(emptyParams)
 	if empty.err != nil {
@@ -220,6 +221,7 @@ func (miner *Miner) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 			withdrawals: args.Withdrawals,
 			beaconRoot:  args.BeaconRoot,
 			noTxs:       false,
+			accessList:  miner.config.AccessList,
 		}
 
 		for {
@@ -197,6 +197,44 @@ func TestBuildPayload(t *testing.T) {
 	}
 }
 
+func TestBuildPayloadWithAccessList(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		recipient = common.HexToAddress("0xdeadbeef")
+	)
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), db, 0)
+	w.config.AccessList = true
+
+	args := &BuildPayloadArgs{
+		Parent:       b.chain.CurrentBlock().Hash(),
+		Timestamp:    uint64(time.Now().Unix()),
+		Random:       common.Hash{},
+		FeeRecipient: recipient,
+	}
+	payload, err := w.buildPayload(args)
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	verify := func(outer *engine.ExecutionPayloadEnvelope, block *types.Block, txs int) {
+		data := outer.ExecutionPayload
+		if len(data.Transactions) != txs {
+			t.Fatal("Unexpected transaction set")
+		}
+		if data.BlockAccessList == nil || data.BlockAccessList != block.AccessList() {
+			t.Fatal("Missing block access list")
+		}
+		list, err := b.chain.AccessList(block)
+		if err != nil {
+			t.Fatalf("Failed to generate access list: %v", err)
+		}
+		if have, want := data.BlockAccessList.Hash(), list.Hash(); have != want {
+			t.Fatalf("Block access list mismatch: have %x, want %x", have, want)
+		}
+	}
+	verify(payload.ResolveEmpty(), payload.empty, 0)
+	verify(payload.ResolveFull(), payload.full, len(pendingTxs))
+}
+
 func TestPayloadId(t *testing.T) {
 	t.Parallel()
 	ids := make(map[string]int)
@@ -85,6 +85,7 @@ type generateParams struct {
 	withdrawals types.Withdrawals // List of withdrawals to include in block (shanghai field)
 	beaconRoot  *common.Hash      // The beacon root (cancun field).
 	noTxs       bool              // Flag whether an empty block without any transaction is expected
+	accessList  bool              // Flag whether the block-level access list is embedded into the block
 }
 
 // generateWork generates a sealing block based on the given parameters.
@@ -110,6 +111,13 @@ func (miner *Miner) generateWork(params *generateParams) *newPayloadResult {
 	if err != nil {
 		return &newPayloadResult{err: err}
 	}
+	if params.accessList {
+		list, err := miner.chain.AccessList(block)
+		if err != nil {
+			return &newPayloadResult{err: err}
+		}
+		block = block.WithAccessList(list)
+	}
 	return &newPayloadResult{
 		block:    block,
 		fees:     totalFees(block, work.receipts),
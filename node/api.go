@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/firewall"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -120,6 +121,47 @@ func (api *adminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// Firewall returns the active peer firewall policy, or nil if no firewall is set.
+func (api *adminAPI) Firewall() (*firewall.Policy, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	fw := server.Firewall()
+	if fw == nil {
+		return nil, nil
+	}
+	policy := fw.Policy()
+	return &policy, nil
+}
+
+// SetFirewall replaces the peer firewall policy. Connected peers which are rejected
+// by the new policy are disconnected.
+func (api *adminAPI) SetFirewall(policy firewall.Policy) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	fw, err := firewall.New(policy)
+	if err != nil {
+		return false, err
+	}
+	server.SetFirewall(fw)
+	return true, nil
+}
+
+// ReloadFirewall reads the peer firewall policy file again and applies it.
+func (api *adminAPI) ReloadFirewall() (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.ReloadFirewall(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *adminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
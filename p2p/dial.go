@@ -77,6 +77,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errLowReputation    = errors.New("reputation too low")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -132,6 +133,9 @@ type dialConfig struct {
 	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
 	resolver       nodeResolver
 	dialer         NodeDialer
+	quic           bool                    // dialer supports QUIC endpoints
+	reputation     func(enode.ID) float64  // peer reputation lookup, may be nil
+	firewall       func(*enode.Node) error // firewall policy check, may be nil
 	log            log.Logger
 	clock          mclock.Clock
 	rand           *mrand.Rand
@@ -239,7 +243,7 @@ loop:
 
 		select {
 		case node := <-nodesCh:
-			if err := d.checkDial(node); err != nil {
+			if err := d.checkDynamicDial(node); err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IPAddr(), "reason", err)
 			} else {
 				d.startDial(newDialTask(node, dynDialedConn))
@@ -377,7 +381,9 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 		// This check can trigger if a non-TCP node is found
 		// by discovery. If there is no IP, the node is a static
 		// node and the actual endpoint will be resolved later in dialTask.
-		return errNoPort
+		if _, hasQUIC := n.QUICEndpoint(); !hasQUIC || !d.quic {
+			return errNoPort
+		}
 	}
 	if _, ok := d.dialing[n.ID()]; ok {
 		return errAlreadyDialing
@@ -388,16 +394,33 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if d.netRestrict != nil && !d.netRestrict.ContainsAddr(n.IPAddr()) {
 		return errNetRestrict
 	}
+	if d.firewall != nil {
+		if err := d.firewall(n); err != nil {
+			return err
+		}
+	}
 	if d.history.contains(string(n.ID().Bytes())) {
 		return errRecentlyDialed
 	}
 	return nil
 }
 
+// checkDynamicDial returns an error if the dynamic dial candidate n should not be
+// dialed. In addition to checkDial, this rejects nodes with a bad reputation.
+func (d *dialScheduler) checkDynamicDial(n *enode.Node) error {
+	if err := d.checkDial(n); err != nil {
+		return err
+	}
+	if d.reputation != nil && d.reputation(n.ID()) < minDialReputation {
+		return errLowReputation
+	}
+	return nil
+}
+
 // startStaticDials starts n static dial tasks.
 func (d *dialScheduler) startStaticDials(n int) (started int) {
 	for started = 0; started < n && len(d.staticPool) > 0; started++ {
-		idx := d.rand.Intn(len(d.staticPool))
+		idx := d.pickStatic()
 		task := d.staticPool[idx]
 		d.startDial(task)
 		d.removeFromStaticPool(idx)
@@ -405,6 +428,20 @@ func (d *dialScheduler) startStaticDials(n int) (started int) {
 	return started
 }
 
+// pickStatic selects a random task from staticPool, preferring nodes with a better
+// reputation. Two tasks are sampled and the better scored one is chosen.
+func (d *dialScheduler) pickStatic() int {
+	idx := d.rand.Intn(len(d.staticPool))
+	if d.reputation == nil || len(d.staticPool) == 1 {
+		return idx
+	}
+	other := d.rand.Intn(len(d.staticPool))
+	if d.reputation(d.staticPool[other].dest().ID()) > d.reputation(d.staticPool[idx].dest().ID()) {
+		return other
+	}
+	return idx
+}
+
 // updateStaticPool attempts to move the given static dial back into staticPool.
 func (d *dialScheduler) updateStaticPool(id enode.ID) {
 	task, ok := d.static[id]
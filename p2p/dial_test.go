@@ -152,6 +152,36 @@ func TestDialSchedNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that dynamic dial candidates with a bad reputation are discarded.
+func TestDialSchedLowReputation(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.0.1:30303"),
+		newNode(uintID(0x02), "127.0.0.2:30303"),
+		newNode(uintID(0x03), "127.0.0.3:30303"),
+		newNode(uintID(0x04), "127.0.0.4:30303"),
+	}
+	scores := map[enode.ID]float64{
+		nodes[1].ID(): minDialReputation - 1,
+		nodes[3].ID(): minDialReputation,
+	}
+	config := dialConfig{
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+		reputation:     func(id enode.ID) float64 { return scores[id] },
+	}
+	runDialTest(t, config, []dialTestRound{
+		{
+			discovered:   nodes,
+			wantNewDials: []*enode.Node{nodes[0], nodes[2], nodes[3]},
+		},
+		{
+			succeeded: []enode.ID{nodes[0].ID(), nodes[2].ID(), nodes[3].ID()},
+		},
+	})
+}
+
 // This test checks that static dials work and obey the limits.
 func TestDialSchedStaticDial(t *testing.T) {
 	t.Parallel()
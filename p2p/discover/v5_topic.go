@@ -0,0 +1,629 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// This file implements topic advertisement. Nodes advertise themselves under a topic
+// by placing ads on 'registrar' nodes close to the topic hash. Registrars hand out
+// tickets when their ad space is exhausted, and the advertiser must come back with the
+// ticket after the wait time has passed. Searchers find advertisers by sending
+// TOPICQUERY to nodes close to the topic hash.
+
+const (
+	topicAdLifetime       = 15 * time.Minute // how long an ad stays in the topic table
+	topicQueueLimit       = 100              // max number of ads per topic
+	topicTableLimit       = 10000            // max number of ads in the topic table
+	topicRegWindow        = 10 * time.Second // time in which a ticket can be used after its wait time
+	topicRenewMargin      = 5 * time.Second  // advertisers renew their ads this long before they expire
+	topicMaxWaitTime      = topicAdLifetime  // advertisers give up when asked to wait longer
+	topicQueryResultLimit = 16               // max number of ads returned by TOPICQUERY
+	topicRegistrarLimit   = 8                // number of registrars an advertiser places ads on
+	topicLookupInterval   = time.Minute      // interval of registrar/searcher lookups
+)
+
+var (
+	errInvalidTicket  = errors.New("invalid ticket")
+	errTicketWaitTime = errors.New("ticket wait time too long")
+)
+
+// Topic identifies a topic for advertisement.
+type Topic [32]byte
+
+// NewTopic creates the topic identifier for the given name.
+func NewTopic(name string) Topic {
+	return Topic(crypto.Keccak256Hash([]byte(name)))
+}
+
+// String returns the topic in hex.
+func (t Topic) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// topicTable stores the topic ads placed on the local node. It is accessed by the
+// dispatch loop only.
+type topicTable struct {
+	queues map[Topic][]topicAd
+	count  int
+}
+
+type topicAd struct {
+	node    *enode.Node
+	expires mclock.AbsTime
+	cumWait time.Duration // total wait time of the node before the ad was placed
+}
+
+func newTopicTable() *topicTable {
+	return &topicTable{queues: make(map[Topic][]topicAd)}
+}
+
+// expire removes all expired ads.
+func (tab *topicTable) expire(now mclock.AbsTime) {
+	for topic, queue := range tab.queues {
+		i := 0
+		for i < len(queue) && queue[i].expires <= now {
+			i++
+		}
+		tab.count -= i
+		if i == len(queue) {
+			delete(tab.queues, topic)
+		} else {
+			tab.queues[topic] = queue[i:]
+		}
+	}
+}
+
+// waitTime returns the time until node id can place an ad for topic. Nodes which
+// have waited for cumWait in total take precedence over the ads of nodes which
+// waited less, so there is no wait time if such an ad can be replaced. A node may
+// renew its own ad within the registration window before it expires.
+func (tab *topicTable) waitTime(topic Topic, id enode.ID, cumWait time.Duration, now mclock.AbsTime) time.Duration {
+	tab.expire(now)
+	queue := tab.queues[topic]
+	for _, ad := range queue {
+		if ad.node.ID() == id {
+			return max(time.Duration(ad.expires-now)-topicRegWindow, 0)
+		}
+	}
+	if _, _, ok := tab.victim(topic, cumWait); ok {
+		return 0
+	}
+	if len(queue) >= topicQueueLimit {
+		return time.Duration(queue[0].expires - now)
+	}
+	if tab.count >= topicTableLimit {
+		// The table is full, wait for the oldest ad to expire. Queues are ordered by
+		// expiration time, so it's the head of one of the queues.
+		var next mclock.AbsTime
+		for _, q := range tab.queues {
+			if next == 0 || q[0].expires < next {
+				next = q[0].expires
+			}
+		}
+		return time.Duration(next - now)
+	}
+	return 0
+}
+
+// victim returns the ad to be replaced when a node which waited for cumWait in
+// total places an ad for topic while the queue or the table is full. It's the
+// most recent of the ads with the least total wait time, if that is less than
+// cumWait.
+func (tab *topicTable) victim(topic Topic, cumWait time.Duration) (Topic, int, bool) {
+	var queues map[Topic][]topicAd
+	switch {
+	case len(tab.queues[topic]) >= topicQueueLimit:
+		queues = map[Topic][]topicAd{topic: tab.queues[topic]}
+	case tab.count >= topicTableLimit:
+		queues = tab.queues
+	default:
+		return Topic{}, 0, false
+	}
+	var (
+		vtopic Topic
+		vindex = -1
+		vwait  = cumWait
+	)
+	for t, queue := range queues {
+		for i, ad := range queue {
+			if ad.cumWait < vwait || (ad.cumWait == vwait && vindex >= 0 && ad.expires >= tab.queues[vtopic][vindex].expires) {
+				vtopic, vindex, vwait = t, i, ad.cumWait
+			}
+		}
+	}
+	return vtopic, vindex, vindex >= 0
+}
+
+// add places an ad, replacing the victim if the queue or the table is full. A
+// previous ad of the node is renewed, keeping its total wait time. The caller
+// must check that waitTime is zero.
+func (tab *topicTable) add(topic Topic, n *enode.Node, cumWait time.Duration, now mclock.AbsTime) {
+	for i, ad := range tab.queues[topic] {
+		if ad.node.ID() == n.ID() {
+			cumWait = max(cumWait, ad.cumWait)
+			tab.remove(topic, i)
+			break
+		}
+	}
+	if vtopic, vindex, ok := tab.victim(topic, cumWait); ok {
+		tab.remove(vtopic, vindex)
+	}
+	tab.queues[topic] = append(tab.queues[topic], topicAd{node: n, expires: now.Add(topicAdLifetime), cumWait: cumWait})
+	tab.count++
+}
+
+// remove drops the ad at index i of the topic queue.
+func (tab *topicTable) remove(topic Topic, i int) {
+	queue := append(tab.queues[topic][:i:i], tab.queues[topic][i+1:]...)
+	if len(queue) == 0 {
+		delete(tab.queues, topic)
+	} else {
+		tab.queues[topic] = queue
+	}
+	tab.count--
+}
+
+// nodes returns up to limit random advertisers of topic.
+func (tab *topicTable) nodes(topic Topic, limit int, now mclock.AbsTime) []*enode.Node {
+	tab.expire(now)
+	queue := tab.queues[topic]
+	nodes := make([]*enode.Node, 0, min(len(queue), limit))
+	for _, i := range rand.Perm(len(queue)) {
+		if len(nodes) == limit {
+			break
+		}
+		nodes = append(nodes, queue[i].node)
+	}
+	return nodes
+}
+
+// topicTicket is the content of a ticket. Tickets are encrypted and authenticated
+// with a local key, so they can only be used with the registrar which issued them.
+type topicTicket struct {
+	Topic   Topic
+	Node    enode.ID
+	IP      []byte
+	Issued  uint64 // mclock.AbsTime
+	Wait    uint64 // time.Duration
+	CumWait uint64 // time.Duration, total wait time of all tickets issued to the node
+}
+
+// ticketCipher encrypts and decrypts tickets.
+type ticketCipher struct {
+	aead cipher.AEAD
+}
+
+func newTicketCipher() *ticketCipher {
+	key := make([]byte, 16)
+	crand.Read(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &ticketCipher{aead: aead}
+}
+
+func (c *ticketCipher) encode(tk *topicTicket) []byte {
+	enc, _ := rlp.EncodeToBytes(tk)
+	nonce := make([]byte, c.aead.NonceSize())
+	crand.Read(nonce)
+	return c.aead.Seal(nonce, nonce, enc, nil)
+}
+
+func (c *ticketCipher) decode(ticket []byte) (*topicTicket, error) {
+	if len(ticket) < c.aead.NonceSize() {
+		return nil, errInvalidTicket
+	}
+	nonce, ct := ticket[:c.aead.NonceSize()], ticket[c.aead.NonceSize():]
+	enc, err := c.aead.Open(nil, nonce, ct, nil)
+	if err != nil {
+		return nil, errInvalidTicket
+	}
+	var tk topicTicket
+	if err := rlp.DecodeBytes(enc, &tk); err != nil {
+		return nil, errInvalidTicket
+	}
+	return &tk, nil
+}
+
+// handleRegtopic places an ad or issues a ticket.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr netip.AddrPort) {
+	node, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil || node.ID() != fromID {
+		t.log.Debug("Invalid record in "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+	var (
+		topic   = Topic(p.Topic)
+		now     = t.clock.Now()
+		cumWait time.Duration
+	)
+	if len(p.Ticket) > 0 {
+		tk, err := t.tickets.decode(p.Ticket)
+		if err == nil && (tk.Topic != topic || tk.Node != fromID || netutil.IPToAddr(tk.IP) != fromAddr.Addr()) {
+			err = errInvalidTicket
+		}
+		if err != nil {
+			t.log.Debug("Invalid ticket in "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		} else {
+			usable := mclock.AbsTime(tk.Issued).Add(time.Duration(tk.Wait))
+			switch {
+			case now < usable:
+				// Too early, the node has to wait for the remaining time.
+				resp := &v5wire.Ticket{ReqID: p.ReqID, Ticket: p.Ticket, WaitTime: waitSeconds(time.Duration(usable - now))}
+				t.sendResponse(fromID, fromAddr, resp)
+				return
+			case now <= usable.Add(topicRegWindow):
+				cumWait = time.Duration(tk.CumWait)
+			}
+		}
+	}
+
+	wait := t.topics.waitTime(topic, fromID, cumWait, now)
+	if wait == 0 {
+		t.topics.add(topic, node, cumWait, now)
+		t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Topic: p.Topic})
+		return
+	}
+	tk := &topicTicket{Topic: topic, Node: fromID, IP: fromAddr.Addr().AsSlice(), CumWait: uint64(cumWait)}
+	t.sendTicket(p.ReqID, tk, now, wait, fromID, fromAddr)
+}
+
+// sendTicket issues a ticket with the given wait time.
+func (t *UDPv5) sendTicket(reqid []byte, tk *topicTicket, now mclock.AbsTime, wait time.Duration, toID enode.ID, toAddr netip.AddrPort) {
+	// The wait time is rounded up to whole seconds because that's the unit
+	// used in the TICKET message.
+	secs := waitSeconds(wait)
+	wait = time.Duration(secs) * time.Second
+	tk.Issued = uint64(now)
+	tk.Wait = uint64(wait)
+	tk.CumWait += uint64(wait)
+	resp := &v5wire.Ticket{ReqID: reqid, Ticket: t.tickets.encode(tk), WaitTime: secs}
+	t.sendResponse(toID, toAddr, resp)
+}
+
+// waitSeconds converts a wait time to seconds, rounding up.
+func waitSeconds(d time.Duration) uint64 {
+	return uint64((d + time.Second - 1) / time.Second)
+}
+
+// handleTopicQuery returns the advertisers of a topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr netip.AddrPort) {
+	var nodes []*enode.Node
+	for _, n := range t.topics.nodes(Topic(p.Topic), topicQueryResultLimit, t.clock.Now()) {
+		if netutil.CheckRelayAddr(fromAddr.Addr(), n.IPAddr()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// regtopic sends REGTOPIC to a registrar and waits for the response. It returns
+// a non-nil ticket if the ad was not placed.
+func (t *UDPv5) regtopic(n *enode.Node, topic Topic, ticket []byte) (*v5wire.Ticket, error) {
+	req := &v5wire.Regtopic{Topic: topic, ENR: t.Self().Record(), Ticket: ticket}
+	resp := t.callToNode(n, v5wire.TicketMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case respMsg := <-resp.ch:
+		switch respMsg := respMsg.(type) {
+		case *v5wire.Ticket:
+			return respMsg, nil
+		case *v5wire.Regconfirmation:
+			if respMsg.Topic != topic {
+				return nil, errors.New("wrong topic in " + respMsg.Name())
+			}
+			return nil, nil
+		}
+		return nil, errors.New("unexpected response")
+	case err := <-resp.err:
+		return nil, err
+	}
+}
+
+// topicQuery sends TOPICQUERY to a node and waits for responses.
+func (t *UDPv5) topicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.callToNode(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic})
+	return t.waitForNodes(resp, nil)
+}
+
+// RegisterTopic starts advertising the local node under the given topic. The
+// advertisement is maintained until Stop is called on the returned registration
+// or the transport is closed.
+func (t *UDPv5) RegisterTopic(topic Topic) *TopicRegistration {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	r := &TopicRegistration{
+		t:          t,
+		topic:      topic,
+		ctx:        ctx,
+		cancel:     cancel,
+		registered: make(map[enode.ID]topicRegistrar),
+	}
+	r.wg.Add(1)
+	go r.loop()
+	return r
+}
+
+// TopicRegistration is an active advertisement of the local node.
+type TopicRegistration struct {
+	t      *UDPv5
+	topic  Topic
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	registered map[enode.ID]topicRegistrar
+}
+
+type topicRegistrar struct {
+	node    *enode.Node
+	expires mclock.AbsTime
+}
+
+// Topic returns the advertised topic.
+func (r *TopicRegistration) Topic() Topic {
+	return r.topic
+}
+
+// Registrars returns the nodes which currently hold an ad of the local node.
+func (r *TopicRegistration) Registrars() []*enode.Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.t.clock.Now()
+	nodes := make([]*enode.Node, 0, len(r.registered))
+	for _, reg := range r.registered {
+		if reg.expires > now {
+			nodes = append(nodes, reg.node)
+		}
+	}
+	return nodes
+}
+
+// Stop ends the advertisement. Ads which have already been placed remain on the
+// registrars until they expire.
+func (r *TopicRegistration) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *TopicRegistration) loop() {
+	defer r.wg.Done()
+
+	var (
+		target = enode.ID(r.topic)
+		active = make(map[enode.ID]struct{})
+		done   = make(chan enode.ID)
+		timer  = r.t.clock.NewTimer(0)
+	)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+			if len(active) < topicRegistrarLimit {
+				for _, n := range r.t.newLookup(r.ctx, target).run() {
+					if len(active) >= topicRegistrarLimit {
+						break
+					}
+					if _, ok := active[n.ID()]; ok {
+						continue
+					}
+					active[n.ID()] = struct{}{}
+					go r.registerAt(n, done)
+				}
+			}
+			timer.Reset(topicLookupInterval)
+		case id := <-done:
+			delete(active, id)
+		case <-r.ctx.Done():
+			for len(active) > 0 {
+				delete(active, <-done)
+			}
+			return
+		}
+	}
+}
+
+// registerAt maintains an ad on a single registrar.
+func (r *TopicRegistration) registerAt(n *enode.Node, done chan<- enode.ID) {
+	defer func() { done <- n.ID() }()
+
+	var ticket []byte
+	for {
+		resp, err := r.t.regtopic(n, r.topic, ticket)
+		if err != nil {
+			r.t.log.Debug("Topic registration failed", "topic", r.topic, "id", n.ID(), "err", err)
+			r.setRegistrar(n, 0)
+			return
+		}
+		// Renew placed ads shortly before they expire, so they are replaced without
+		// a gap.
+		wait := topicAdLifetime - topicRenewMargin
+		if resp == nil {
+			r.t.log.Trace("Topic ad placed", "topic", r.topic, "id", n.ID())
+			r.setRegistrar(n, r.t.clock.Now().Add(topicAdLifetime))
+			ticket = nil
+		} else {
+			wait = time.Duration(resp.WaitTime) * time.Second
+			if wait > topicMaxWaitTime {
+				r.t.log.Debug("Topic registration failed", "topic", r.topic, "id", n.ID(), "err", errTicketWaitTime)
+				r.setRegistrar(n, 0)
+				return
+			}
+			ticket = resp.Ticket
+		}
+
+		timer := r.t.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-r.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (r *TopicRegistration) setRegistrar(n *enode.Node, expires mclock.AbsTime) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expires == 0 {
+		delete(r.registered, n.ID())
+	} else {
+		r.registered[n.ID()] = topicRegistrar{node: n, expires: expires}
+	}
+}
+
+// TopicSearch returns an iterator over nodes advertising the given topic.
+func (t *UDPv5) TopicSearch(topic Topic) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	it := &topicSearchIterator{
+		t:       t,
+		topic:   topic,
+		ctx:     ctx,
+		cancel:  cancel,
+		results: make(chan *enode.Node),
+		seen:    make(map[enode.ID]struct{}),
+	}
+	it.wg.Add(1)
+	go it.loop()
+	return it
+}
+
+// topicSearchIterator finds advertisers of a topic. It performs lookups toward the
+// topic hash and sends TOPICQUERY to every node encountered during the lookup.
+type topicSearchIterator struct {
+	t       *UDPv5
+	topic   Topic
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	results chan *enode.Node
+	node    *enode.Node
+
+	mu   sync.Mutex
+	seen map[enode.ID]struct{}
+}
+
+// Node returns the current node.
+func (it *topicSearchIterator) Node() *enode.Node {
+	return it.node
+}
+
+// Next moves to the next node.
+func (it *topicSearchIterator) Next() bool {
+	if it.ctx.Err() != nil {
+		it.node = nil
+		return false
+	}
+	select {
+	case it.node = <-it.results:
+		return true
+	case <-it.ctx.Done():
+		it.node = nil
+		return false
+	}
+}
+
+// Close ends the iterator.
+func (it *topicSearchIterator) Close() {
+	it.cancel()
+	it.wg.Wait()
+}
+
+func (it *topicSearchIterator) loop() {
+	defer it.wg.Done()
+
+	if it.t.tab.len() == 0 {
+		<-it.t.tab.refresh()
+	}
+	target := enode.ID(it.topic)
+	for {
+		l := newLookup(it.ctx, it.t.tab, target, func(n *enode.Node) ([]*enode.Node, error) {
+			nodes, err := it.t.lookupWorker(n, target)
+			if err == nil {
+				it.query(n)
+			}
+			return nodes, err
+		})
+		l.run()
+
+		timer := it.t.clock.NewTimer(topicLookupInterval)
+		select {
+		case <-timer.C():
+		case <-it.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// query sends TOPICQUERY to n and delivers the results.
+func (it *topicSearchIterator) query(n *enode.Node) {
+	nodes, err := it.t.topicQuery(n, it.topic)
+	if err != nil {
+		it.t.log.Trace("TOPICQUERY failed", "id", n.ID(), "err", err)
+	}
+	for _, ad := range nodes {
+		if ad.ID() == it.t.Self().ID() || !it.markSeen(ad.ID()) {
+			continue
+		}
+		select {
+		case it.results <- ad:
+		case <-it.ctx.Done():
+			return
+		}
+	}
+}
+
+func (it *topicSearchIterator) markSeen(id enode.ID) bool {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if _, ok := it.seen[id]; ok {
+		return false
+	}
+	it.seen[id] = struct{}{}
+	return true
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestTopicTable(t *testing.T) {
+	var (
+		tab   = newTopicTable()
+		topic = NewTopic("test")
+		now   = mclock.AbsTime(1000)
+		nodes = nodesAtDistance(enode.ID{}, 256, topicQueueLimit+1)
+	)
+	for i, n := range nodes[:topicQueueLimit] {
+		if w := tab.waitTime(topic, n.ID(), 0, now); w != 0 {
+			t.Fatalf("node %d: non-zero wait time %v", i, w)
+		}
+		tab.add(topic, n, 0, now)
+		now += mclock.AbsTime(time.Second)
+	}
+	// Registered nodes must wait until their ad can be renewed.
+	if w := tab.waitTime(topic, nodes[1].ID(), 0, now); w != topicAdLifetime-(topicQueueLimit-1)*time.Second-topicRegWindow {
+		t.Fatal("wrong wait time for registered node:", w)
+	}
+	// The queue is full, new nodes must wait for the oldest ad to expire.
+	if w := tab.waitTime(topic, nodes[topicQueueLimit].ID(), 0, now); w != topicAdLifetime-topicQueueLimit*time.Second {
+		t.Fatal("wrong wait time for new node:", w)
+	}
+	// Other topics are not affected.
+	if w := tab.waitTime(NewTopic("other"), nodes[topicQueueLimit].ID(), 0, now); w != 0 {
+		t.Fatal("wrong wait time for other topic:", w)
+	}
+	if n := tab.nodes(topic, topicQueryResultLimit, now); len(n) != topicQueryResultLimit {
+		t.Fatal("wrong number of nodes returned:", len(n))
+	}
+
+	// Check expiry.
+	now = mclock.AbsTime(1000).Add(topicAdLifetime)
+	if n := tab.nodes(topic, topicQueueLimit, now); len(n) != topicQueueLimit-1 {
+		t.Fatal("wrong number of nodes after expiry:", len(n))
+	}
+	if w := tab.waitTime(topic, nodes[topicQueueLimit].ID(), 0, now); w != 0 {
+		t.Fatal("non-zero wait time after expiry:", w)
+	}
+	now = now.Add(topicAdLifetime)
+	tab.expire(now)
+	if tab.count != 0 || len(tab.queues) != 0 {
+		t.Fatalf("table not empty after expiry: count %d, queues %d", tab.count, len(tab.queues))
+	}
+}
+
+// This test checks that nodes which waited for a ticket take precedence over the
+// ads of nodes which waited less, and that ads are renewed before they expire.
+func TestTopicTablePriority(t *testing.T) {
+	var (
+		tab   = newTopicTable()
+		topic = NewTopic("test")
+		now   = mclock.AbsTime(1000)
+		nodes = nodesAtDistance(enode.ID{}, 256, topicQueueLimit+2)
+		last  = nodes[topicQueueLimit-1]
+	)
+	for _, n := range nodes[:topicQueueLimit] {
+		tab.add(topic, n, 0, now)
+	}
+	// Without a ticket, the node has to wait for the oldest ad to expire.
+	if w := tab.waitTime(topic, nodes[topicQueueLimit].ID(), 0, now); w != topicAdLifetime {
+		t.Fatal("wrong wait time without ticket:", w)
+	}
+	// With a ticket, the most recent ad of a node without one is replaced.
+	if w := tab.waitTime(topic, nodes[topicQueueLimit].ID(), time.Minute, now); w != 0 {
+		t.Fatal("non-zero wait time with ticket:", w)
+	}
+	tab.add(topic, nodes[topicQueueLimit], time.Minute, now)
+	if tab.count != topicQueueLimit {
+		t.Fatal("wrong ad count after replacement:", tab.count)
+	}
+	for _, ad := range tab.queues[topic] {
+		if ad.node.ID() == last.ID() {
+			t.Fatal("most recent ad not replaced")
+		}
+	}
+	// Ads of nodes without a ticket are replaced first, the ads of the ticket
+	// holders are kept.
+	if w := tab.waitTime(topic, nodes[topicQueueLimit+1].ID(), 30*time.Second, now); w != 0 {
+		t.Fatal("non-zero wait time with ticket:", w)
+	}
+	tab.add(topic, nodes[topicQueueLimit+1], 30*time.Second, now)
+	held := make(map[enode.ID]time.Duration)
+	for _, ad := range tab.queues[topic] {
+		if ad.cumWait > 0 {
+			held[ad.node.ID()] = ad.cumWait
+		}
+	}
+	if len(held) != 2 || held[nodes[topicQueueLimit].ID()] != time.Minute || held[nodes[topicQueueLimit+1].ID()] != 30*time.Second {
+		t.Fatal("wrong ads of ticket holders:", held)
+	}
+
+	// Ads are renewed without a gap within the registration window before they
+	// expire, keeping their total wait time.
+	renew := now.Add(topicAdLifetime - topicRegWindow)
+	if w := tab.waitTime(topic, nodes[topicQueueLimit].ID(), 0, renew); w != 0 {
+		t.Fatal("non-zero wait time for renewal:", w)
+	}
+	tab.add(topic, nodes[topicQueueLimit], 0, renew)
+	if tab.count != topicQueueLimit {
+		t.Fatal("wrong ad count after renewal:", tab.count)
+	}
+	queue := tab.queues[topic]
+	if ad := queue[len(queue)-1]; ad.node.ID() != nodes[topicQueueLimit].ID() || ad.expires != renew.Add(topicAdLifetime) || ad.cumWait != time.Minute {
+		t.Fatalf("wrong renewed ad: %v, expires %v, cumulative wait %v", ad.node.ID(), ad.expires, ad.cumWait)
+	}
+}
+
+func TestTicketCipher(t *testing.T) {
+	c := newTicketCipher()
+	tk := &topicTicket{Topic: NewTopic("test"), IP: []byte{10, 0, 0, 1}, Issued: 1, Wait: 2, CumWait: 3}
+	enc := c.encode(tk)
+	dec, err := c.decode(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Topic != tk.Topic || !bytes.Equal(dec.IP, tk.IP) || dec.Issued != 1 || dec.Wait != 2 || dec.CumWait != 3 {
+		t.Fatalf("wrong decoded ticket %+v", dec)
+	}
+	enc[len(enc)-1]++
+	if _, err := c.decode(enc); err != errInvalidTicket {
+		t.Fatal("tampered ticket accepted")
+	}
+	if _, err := newTicketCipher().decode(c.encode(tk)); err != errInvalidTicket {
+		t.Fatal("ticket accepted by other registrar")
+	}
+}
+
+// This test checks that REGTOPIC and TOPICQUERY are handled correctly.
+func TestUDPv5_regtopicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = NewTopic("test")
+		remote = test.getNode(test.remotekey, test.remoteaddr).Node()
+	)
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{1}, Topic: topic, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr netip.AddrPort, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte{1}) {
+			t.Errorf("wrong request ID %x", p.ReqID)
+		}
+		if p.Topic != topic {
+			t.Errorf("wrong topic %x", p.Topic)
+		}
+	})
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{2}, Topic: topic})
+	test.expectNodes([]byte{2}, 1, []*enode.Node{remote})
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{3}, Topic: NewTopic("other")})
+	test.expectNodes([]byte{3}, 1, nil)
+
+	// Registering again gets a ticket because the ad is already placed.
+	var ticket []byte
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{4}, Topic: topic, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr netip.AddrPort, _ v5wire.Nonce) {
+		if wait := time.Duration(p.WaitTime) * time.Second; wait < topicAdLifetime-time.Minute || wait > topicAdLifetime {
+			t.Errorf("wrong wait time %v", wait)
+		}
+		ticket = p.Ticket
+	})
+	tk, err := test.udp.tickets.decode(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Topic != topic || tk.Node != remote.ID() {
+		t.Fatalf("wrong ticket content %+v", tk)
+	}
+
+	// Using the ticket too early returns the same ticket.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{5}, Topic: topic, ENR: remote.Record(), Ticket: ticket})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr netip.AddrPort, _ v5wire.Nonce) {
+		if !bytes.Equal(p.Ticket, ticket) {
+			t.Error("ticket changed")
+		}
+	})
+
+	// REGTOPIC with a mismatching record is ignored.
+	other := test.getNode(newkey(), netip.MustParseAddrPort("10.0.1.100:30303")).Node()
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{6}, Topic: NewTopic("other"), ENR: other.Record()})
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{7}, Topic: NewTopic("other")})
+	test.expectNodes([]byte{7}, 1, nil)
+}
+
+// This test checks that REGTOPIC calls handle both response types.
+func TestUDPv5_regtopicCall(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = NewTopic("test")
+		remote = test.getNode(test.remotekey, test.remoteaddr).Node()
+		done   = make(chan error, 1)
+		result *v5wire.Ticket
+	)
+	go func() {
+		var err error
+		result, err = test.udp.regtopic(remote, topic, nil)
+		done <- err
+	}()
+	test.waitPacketOut(func(p *v5wire.Regtopic, addr netip.AddrPort, _ v5wire.Nonce) {
+		test.packetIn(&v5wire.Ticket{ReqID: p.ReqID, Ticket: []byte{1, 2, 3}, WaitTime: 10})
+	})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.WaitTime != 10 || !bytes.Equal(result.Ticket, []byte{1, 2, 3}) {
+		t.Fatalf("wrong result %+v", result)
+	}
+
+	go func() {
+		var err error
+		result, err = test.udp.regtopic(remote, topic, []byte{1, 2, 3})
+		done <- err
+	}()
+	test.waitPacketOut(func(p *v5wire.Regtopic, addr netip.AddrPort, _ v5wire.Nonce) {
+		if !bytes.Equal(p.Ticket, []byte{1, 2, 3}) {
+			t.Error("wrong ticket in REGTOPIC")
+		}
+		test.packetIn(&v5wire.Regconfirmation{ReqID: p.ReqID, Topic: topic})
+	})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if result != nil {
+		t.Fatalf("got ticket %+v for confirmed registration", result)
+	}
+}
+
+// Real sockets, real crypto: this test checks that a node registered under a topic
+// can be found through topic search.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 5
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			cfg.Bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	topic := NewTopic("test")
+	reg := nodes[1].RegisterTopic(topic)
+	defer reg.Stop()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(reg.Registrars()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("topic not registered")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	it := nodes[N-1].TopicSearch(topic)
+	defer it.Close()
+	found := make(chan *enode.Node, 1)
+	go func() {
+		if it.Next() {
+			found <- it.Node()
+		}
+	}()
+	select {
+	case n := <-found:
+		if n.ID() != nodes[1].Self().ID() {
+			t.Fatalf("wrong node found: %v", n.ID())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("topic search found nothing")
+	}
+}
@@ -51,6 +51,11 @@ const (
 	dbNodePong      = "lastpong"
 	dbNodeSeq       = "seq"
 
+	// Reputation fields are not tied to an IP address and are stored using the
+	// unspecified address, i.e. "n:<ID>:v4:<::>:score".
+	dbNodeScore     = "score"
+	dbNodeScoreTime = "scoretime"
+
 	// Local information is keyed by ID only, the full key is "local:<ID>:seq".
 	// Use localItemKey to create those keys.
 	dbLocalSeq = "seq"
@@ -333,10 +338,14 @@ func (db *DB) expireNodes() {
 	var (
 		threshold    = time.Now().Add(-dbNodeExpiration).Unix()
 		youngestPong int64
+		scoreTime    int64
 		atEnd        = false
 	)
 	for !atEnd {
 		id, ip, field := splitNodeItemKey(it.Key())
+		if field == dbNodeScoreTime {
+			scoreTime, _ = binary.Varint(it.Value())
+		}
 		if field == dbNodePong {
 			time, _ := binary.Varint(it.Value())
 			if time > youngestPong {
@@ -354,8 +363,13 @@ func (db *DB) expireNodes() {
 			// Remove everything if there was no recent enough pong.
 			if youngestPong > 0 && youngestPong < threshold {
 				deleteRange(db.lvl, nodeKey(id))
+			} else if youngestPong == 0 && scoreTime > 0 && scoreTime < threshold {
+				// Nodes never seen by discovery only hold information which isn't
+				// tied to an address, such as the reputation. Remove it once the
+				// score has decayed.
+				deleteRange(db.lvl, nodeItemKey(id, zeroIP, ""))
 			}
-			youngestPong = 0
+			youngestPong, scoreTime = 0, 0
 		}
 	}
 }
@@ -411,6 +425,22 @@ func (db *DB) UpdateFindFails(id ID, ip netip.Addr, fails int) error {
 	return db.storeInt64(nodeItemKey(id, ip, dbNodeFindFails), int64(fails))
 }
 
+// Reputation retrieves the persisted reputation score of a node and the time
+// at which it was last updated.
+func (db *DB) Reputation(id ID) (score int64, updated time.Time) {
+	score = db.fetchInt64(nodeItemKey(id, zeroIP, dbNodeScore))
+	updated = time.Unix(db.fetchInt64(nodeItemKey(id, zeroIP, dbNodeScoreTime)), 0)
+	return score, updated
+}
+
+// UpdateReputation stores the reputation score of a node.
+func (db *DB) UpdateReputation(id ID, score int64, updated time.Time) error {
+	if err := db.storeInt64(nodeItemKey(id, zeroIP, dbNodeScore), score); err != nil {
+		return err
+	}
+	return db.storeInt64(nodeItemKey(id, zeroIP, dbNodeScoreTime), updated.Unix())
+}
+
 // FindFailsV5 retrieves the discv5 findnode failure counter.
 func (db *DB) FindFailsV5(id ID, ip netip.Addr) int {
 	if !ip.IsValid() {
@@ -462,6 +462,35 @@ func TestDBExpiration(t *testing.T) {
 
 // This test checks that expiration works when discovery v5 data is present
 // in the database.
+// This test checks that the reputation of nodes never seen by discovery expires.
+func TestDBExpireReputation(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		stale = ID{1}
+		fresh = ID{2}
+		seen  = ID{3}
+		ip    = netip.MustParseAddr("127.0.0.1")
+	)
+	db.UpdateReputation(stale, -1000, time.Now().Add(-dbNodeExpiration-time.Minute))
+	db.UpdateReputation(fresh, -1000, time.Now())
+	db.UpdateReputation(seen, -1000, time.Now().Add(-dbNodeExpiration-time.Minute))
+	db.UpdateLastPongReceived(seen, ip, time.Now())
+
+	db.expireNodes()
+
+	if score, _ := db.Reputation(stale); score != 0 {
+		t.Error("stale reputation not expired")
+	}
+	if score, _ := db.Reputation(fresh); score != -1000 {
+		t.Error("fresh reputation expired")
+	}
+	if score, _ := db.Reputation(seen); score != -1000 {
+		t.Error("reputation of live node expired")
+	}
+}
+
 func TestDBExpireV5(t *testing.T) {
 	db, _ := OpenDB("")
 	defer db.Close()
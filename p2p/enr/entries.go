@@ -77,6 +77,18 @@ type UDP6 uint16
 
 func (v UDP6) ENRKey() string { return "udp6" }
 
+// QUIC is the "rlpx-quic" key, which holds the UDP port of RLPx over QUIC. It is
+// distinct from the "quic" key used by consensus layer clients for libp2p.
+type QUIC uint16
+
+func (v QUIC) ENRKey() string { return "rlpx-quic" }
+
+// QUIC6 is the "rlpx-quic6" key, which holds the IPv6-specific UDP port of RLPx
+// over QUIC.
+type QUIC6 uint16
+
+func (v QUIC6) ENRKey() string { return "rlpx-quic6" }
+
 // ID is the "id" key, which holds the name of the identity scheme.
 type ID string
 
@@ -0,0 +1,410 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package firewall implements connection policies for the p2p server.
+//
+// A policy is an ordered list of rules. Each rule has an action (allow or deny) and a
+// set of conditions on the connection: its direction, the remote IP address, the client
+// name and capabilities announced in the devp2p handshake, the fork ID of the eth
+// protocol and the entries of the node record. The first rule whose conditions all match
+// decides whether the connection is allowed. If no rule matches, the default action of the
+// policy applies.
+//
+// Not all information about a connection is available at all times. For example, the
+// client name is unknown when dialing, and the fork ID is only known once the eth
+// protocol handshake has completed. If the first applicable rule depends on information
+// that isn't available yet, the connection is allowed provisionally and the policy is
+// checked again when more information becomes available. Once no more information can
+// become available, rules depending on missing information don't match: the node record
+// of an inbound peer, for example, is never known.
+package firewall
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Action is the action of a rule.
+type Action string
+
+const (
+	Allow Action = "allow"
+	Deny  Action = "deny"
+)
+
+// Direction restricts a rule to inbound or outbound connections.
+type Direction string
+
+const (
+	Any      Direction = ""
+	Inbound  Direction = "inbound"
+	Outbound Direction = "outbound"
+)
+
+// Policy is the configuration of a firewall.
+type Policy struct {
+	// Groups are named lists of CIDR ranges, which can be referenced by rules.
+	Groups map[string][]string `json:"groups,omitempty"`
+
+	// Rules are checked in order. The first matching rule applies.
+	Rules []Rule `json:"rules"`
+
+	// Default is the action applied when no rule matches. It defaults to allow.
+	Default Action `json:"default,omitempty"`
+}
+
+// Rule is a firewall rule. All conditions of a rule must match for it to apply.
+// Empty conditions match any connection.
+type Rule struct {
+	Action    Action    `json:"action"`
+	Direction Direction `json:"direction,omitempty"`
+
+	// Net contains CIDR ranges or group names. It matches if the remote IP is
+	// contained in any of the ranges.
+	Net []string `json:"net,omitempty"`
+
+	// Client is a regular expression matched against the client name.
+	Client string `json:"client,omitempty"`
+
+	// Caps contains capabilities like "snap" or "eth/68". It matches if the remote
+	// node supports any of them.
+	Caps []string `json:"caps,omitempty"`
+
+	// ForkID contains hex-encoded fork hashes. It matches if the remote node is on
+	// any of the forks.
+	ForkID []string `json:"forkid,omitempty"`
+
+	// ENR contains node record keys. It matches if the node record contains all of them.
+	ENR []string `json:"enr,omitempty"`
+}
+
+// Conn contains the known information about a connection.
+type Conn struct {
+	Inbound bool
+	IP      netip.Addr
+
+	// Record is the node record of the remote node. It is nil when unknown.
+	Record *enr.Record
+
+	// HasHandshake is set when Name and Caps are known.
+	HasHandshake bool
+	Name         string
+	Caps         []Cap
+
+	// ForkHash is the fork hash of the remote node. It is nil when unknown.
+	ForkHash *[4]byte
+
+	// Final is set when no more information about the connection will become
+	// available. Conditions on unknown information don't match then.
+	Final bool
+}
+
+// Cap is a capability announced in the devp2p handshake.
+type Cap struct {
+	Name    string
+	Version uint
+}
+
+// DeniedError is returned by Check when a connection is rejected.
+type DeniedError struct {
+	Rule int // index of the rule, -1 for the default action
+}
+
+func (err *DeniedError) Error() string {
+	if err.Rule < 0 {
+		return "denied by firewall default policy"
+	}
+	return fmt.Sprintf("denied by firewall rule %d", err.Rule)
+}
+
+// Firewall checks connections against a policy.
+type Firewall struct {
+	policy Policy
+	rules  []*rule
+	def    Action
+}
+
+type rule struct {
+	action    Action
+	direction Direction
+	net       *netutil.Netlist
+	client    *regexp.Regexp
+	caps      []Cap // version zero means any version
+	forks     [][4]byte
+	enr       []string
+}
+
+// result of matching a condition
+type match int
+
+const (
+	matchNo match = iota
+	matchYes
+	matchUnknown
+)
+
+// New creates a firewall from a policy.
+func New(p Policy) (*Firewall, error) {
+	fw := &Firewall{policy: p, def: p.Default}
+	switch fw.def {
+	case "":
+		fw.def = Allow
+	case Allow, Deny:
+	default:
+		return nil, fmt.Errorf("invalid default action %q", p.Default)
+	}
+	for name, cidrs := range p.Groups {
+		if _, err := netutil.ParseNetlist(strings.Join(cidrs, ",")); err != nil {
+			return nil, fmt.Errorf("group %q: %v", name, err)
+		}
+	}
+	for i, r := range p.Rules {
+		cr, err := compileRule(r, p.Groups)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		fw.rules = append(fw.rules, cr)
+	}
+	return fw, nil
+}
+
+// Load reads a JSON policy file and creates a firewall from it.
+func Load(file string) (*Firewall, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid firewall policy %s: %v", file, err)
+	}
+	return New(p)
+}
+
+func compileRule(r Rule, groups map[string][]string) (*rule, error) {
+	cr := &rule{action: r.Action, direction: r.Direction, enr: r.ENR}
+	switch r.Action {
+	case Allow, Deny:
+	default:
+		return nil, fmt.Errorf("invalid action %q", r.Action)
+	}
+	switch r.Direction {
+	case Any, Inbound, Outbound:
+	default:
+		return nil, fmt.Errorf("invalid direction %q", r.Direction)
+	}
+	if len(r.Net) > 0 {
+		var cidrs []string
+		for _, n := range r.Net {
+			if g, ok := groups[n]; ok {
+				cidrs = append(cidrs, g...)
+			} else {
+				cidrs = append(cidrs, n)
+			}
+		}
+		list, err := netutil.ParseNetlist(strings.Join(cidrs, ","))
+		if err != nil {
+			return nil, err
+		}
+		cr.net = list
+	}
+	if r.Client != "" {
+		re, err := regexp.Compile(r.Client)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client pattern: %v", err)
+		}
+		cr.client = re
+	}
+	for _, c := range r.Caps {
+		name, version, _ := strings.Cut(c, "/")
+		cap := Cap{Name: name}
+		if version != "" {
+			v, err := strconv.ParseUint(version, 10, 32)
+			if err != nil || v == 0 {
+				return nil, fmt.Errorf("invalid capability %q", c)
+			}
+			cap.Version = uint(v)
+		}
+		cr.caps = append(cr.caps, cap)
+	}
+	for _, f := range r.ForkID {
+		b, err := hex.DecodeString(strings.TrimPrefix(f, "0x"))
+		if err != nil || len(b) != 4 {
+			return nil, fmt.Errorf("invalid fork hash %q", f)
+		}
+		cr.forks = append(cr.forks, [4]byte(b))
+	}
+	return cr, nil
+}
+
+// Policy returns the policy of the firewall.
+func (fw *Firewall) Policy() Policy {
+	return fw.policy
+}
+
+// Check returns a *DeniedError if the connection is rejected by the policy.
+func (fw *Firewall) Check(c *Conn) error {
+	for i, r := range fw.rules {
+		switch r.match(c) {
+		case matchNo:
+			continue
+		case matchUnknown:
+			if c.Final {
+				continue
+			}
+			// The decision depends on information which isn't available yet.
+			return nil
+		}
+		if r.action == Deny {
+			return &DeniedError{Rule: i}
+		}
+		return nil
+	}
+	if fw.def == Deny {
+		return &DeniedError{Rule: -1}
+	}
+	return nil
+}
+
+func (r *rule) match(c *Conn) match {
+	switch {
+	case r.direction == Inbound && !c.Inbound:
+		return matchNo
+	case r.direction == Outbound && c.Inbound:
+		return matchNo
+	}
+	result := matchYes
+	for _, m := range []func(*Conn) match{r.matchNet, r.matchClient, r.matchCaps, r.matchFork, r.matchENR} {
+		switch m(c) {
+		case matchNo:
+			return matchNo
+		case matchUnknown:
+			result = matchUnknown
+		}
+	}
+	return result
+}
+
+func (r *rule) matchNet(c *Conn) match {
+	switch {
+	case r.net == nil:
+		return matchYes
+	case !c.IP.IsValid():
+		return matchUnknown
+	case r.net.ContainsAddr(c.IP):
+		return matchYes
+	default:
+		return matchNo
+	}
+}
+
+func (r *rule) matchClient(c *Conn) match {
+	switch {
+	case r.client == nil:
+		return matchYes
+	case !c.HasHandshake:
+		return matchUnknown
+	case r.client.MatchString(c.Name):
+		return matchYes
+	default:
+		return matchNo
+	}
+}
+
+func (r *rule) matchCaps(c *Conn) match {
+	if len(r.caps) == 0 {
+		return matchYes
+	}
+	if !c.HasHandshake {
+		return matchUnknown
+	}
+	for _, want := range r.caps {
+		for _, have := range c.Caps {
+			if have.Name == want.Name && (want.Version == 0 || have.Version == want.Version) {
+				return matchYes
+			}
+		}
+	}
+	return matchNo
+}
+
+func (r *rule) matchFork(c *Conn) match {
+	if len(r.forks) == 0 {
+		return matchYes
+	}
+	hash := c.ForkHash
+	if hash == nil {
+		hash = recordForkHash(c.Record)
+	}
+	if hash == nil {
+		return matchUnknown
+	}
+	for _, f := range r.forks {
+		if f == *hash {
+			return matchYes
+		}
+	}
+	return matchNo
+}
+
+func (r *rule) matchENR(c *Conn) match {
+	if len(r.enr) == 0 {
+		return matchYes
+	}
+	if c.Record == nil {
+		return matchUnknown
+	}
+	for _, key := range r.enr {
+		var v rlp.RawValue
+		if err := c.Record.Load(enr.WithEntry(key, &v)); err != nil {
+			return matchNo
+		}
+	}
+	return matchYes
+}
+
+// ethEntry is the "eth" entry of the node record, as defined by the eth protocol.
+type ethEntry struct {
+	ForkID struct {
+		Hash [4]byte
+		Next uint64
+	}
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+func (ethEntry) ENRKey() string { return "eth" }
+
+// recordForkHash returns the fork hash announced in the node record.
+func recordForkHash(r *enr.Record) *[4]byte {
+	if r == nil {
+		return nil
+	}
+	var entry ethEntry
+	if err := r.Load(&entry); err != nil {
+		return nil
+	}
+	return &entry.ForkID.Hash
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package firewall
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestFirewall(t *testing.T) {
+	policy := Policy{
+		Groups: map[string][]string{
+			"lan": {"10.0.0.0/8", "192.168.0.0/16"},
+		},
+		Rules: []Rule{
+			{Action: Allow, Net: []string{"lan"}},                          // 0
+			{Action: Deny, Direction: Inbound, Net: []string{"1.0.0.0/8"}}, // 1
+			{Action: Deny, Client: "^BadClient/"},                          // 2
+			{Action: Deny, ForkID: []string{"0xdeadbeef"}},                 // 3
+			{Action: Allow, Direction: Outbound, ENR: []string{"snap"}},    // 4
+			{Action: Allow, Caps: []string{"eth/68", "snap"}},              // 5
+		},
+		Default: Deny,
+	}
+	fw, err := New(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ip       = netip.MustParseAddr("2.2.2.2")
+		badFork  = [4]byte{0xde, 0xad, 0xbe, 0xef}
+		goodFork = [4]byte{1, 2, 3, 4}
+		snapENR  = new(enr.Record)
+	)
+	snapENR.Set(enr.WithEntry("snap", []interface{}{}))
+
+	tests := []struct {
+		name string
+		conn Conn
+		rule int // -2 means allowed
+	}{
+		{
+			name: "lan",
+			conn: Conn{Inbound: true, IP: netip.MustParseAddr("10.1.2.3")},
+			rule: -2,
+		},
+		{
+			name: "denied network inbound",
+			conn: Conn{Inbound: true, IP: netip.MustParseAddr("1.2.3.4")},
+			rule: 1,
+		},
+		{
+			name: "undecided before handshake",
+			conn: Conn{IP: netip.MustParseAddr("1.2.3.4")},
+			rule: -2,
+		},
+		{
+			name: "bad client",
+			conn: Conn{IP: ip, HasHandshake: true, Name: "BadClient/v1.0"},
+			rule: 2,
+		},
+		{
+			name: "fork unknown",
+			conn: Conn{IP: ip, HasHandshake: true, Name: "Geth/v1.14", Caps: []Cap{{"eth", 68}}},
+			rule: -2,
+		},
+		{
+			name: "bad fork",
+			conn: Conn{IP: ip, HasHandshake: true, Name: "Geth/v1.14", Caps: []Cap{{"eth", 68}}, ForkHash: &badFork},
+			rule: 3,
+		},
+		{
+			name: "good fork",
+			conn: Conn{IP: ip, HasHandshake: true, Name: "Geth/v1.14", Caps: []Cap{{"eth", 68}}, ForkHash: &goodFork},
+			rule: -2,
+		},
+		{
+			name: "record with snap",
+			conn: Conn{IP: ip, Record: snapENR, HasHandshake: true, ForkHash: &goodFork},
+			rule: -2,
+		},
+		{
+			name: "no matching capability",
+			conn: Conn{IP: ip, Record: new(enr.Record), HasHandshake: true, Caps: []Cap{{"eth", 67}}, ForkHash: &goodFork},
+			rule: -1,
+		},
+		{
+			name: "any capability version",
+			conn: Conn{Inbound: true, IP: ip, HasHandshake: true, Caps: []Cap{{"snap", 2}}, ForkHash: &goodFork},
+			rule: -2,
+		},
+	}
+	for _, test := range tests {
+		err := fw.Check(&test.conn)
+		if test.rule == -2 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		var denied *DeniedError
+		if !errors.As(err, &denied) {
+			t.Errorf("%s: connection not denied", test.name)
+		} else if denied.Rule != test.rule {
+			t.Errorf("%s: denied by rule %d, want %d", test.name, denied.Rule, test.rule)
+		}
+	}
+}
+
+func TestFirewallENRForkID(t *testing.T) {
+	fw, err := New(Policy{Rules: []Rule{{Action: Deny, ForkID: []string{"deadbeef"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r enr.Record
+	var entry ethEntry
+	entry.ForkID.Hash = [4]byte{0xde, 0xad, 0xbe, 0xef}
+	r.Set(&entry)
+	if err := fw.Check(&Conn{Record: &r}); err == nil {
+		t.Fatal("fork ID in node record not checked")
+	}
+}
+
+// This test checks that rules depending on information which never becomes
+// available don't bypass the default action.
+func TestFirewallInboundDefaultDeny(t *testing.T) {
+	fw, err := New(Policy{
+		Rules: []Rule{
+			{Action: Allow, ENR: []string{"snap"}},
+			{Action: Allow, ForkID: []string{"01020304"}},
+		},
+		Default: Deny,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		ip       = netip.MustParseAddr("2.2.2.2")
+		goodFork = [4]byte{1, 2, 3, 4}
+		badFork  = [4]byte{0xde, 0xad, 0xbe, 0xef}
+	)
+	tests := []struct {
+		name    string
+		conn    Conn
+		allowed bool
+	}{
+		{"accepted", Conn{Inbound: true, IP: ip}, true},
+		{"pending eth handshake", Conn{Inbound: true, IP: ip, HasHandshake: true}, true},
+		{"no eth handshake", Conn{Inbound: true, IP: ip, HasHandshake: true, Final: true}, false},
+		{"bad fork", Conn{Inbound: true, IP: ip, HasHandshake: true, ForkHash: &badFork, Final: true}, false},
+		{"good fork", Conn{Inbound: true, IP: ip, HasHandshake: true, ForkHash: &goodFork, Final: true}, true},
+	}
+	for _, test := range tests {
+		err := fw.Check(&test.conn)
+		if test.allowed && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.allowed && err == nil {
+			t.Errorf("%s: connection not denied", test.name)
+		}
+	}
+}
+
+func TestPolicyErrors(t *testing.T) {
+	bad := []Policy{
+		{Default: "reject"},
+		{Rules: []Rule{{Action: "drop"}}},
+		{Rules: []Rule{{Action: Allow, Direction: "sideways"}}},
+		{Rules: []Rule{{Action: Allow, Net: []string{"nogroup"}}}},
+		{Rules: []Rule{{Action: Allow, Client: "("}}},
+		{Rules: []Rule{{Action: Allow, Caps: []string{"eth/x"}}}},
+		{Rules: []Rule{{Action: Allow, ForkID: []string{"0x1234"}}}},
+		{Groups: map[string][]string{"g": {"300.0.0.0/8"}}},
+	}
+	for i, p := range bad {
+		if _, err := New(p); err == nil {
+			t.Errorf("policy %d: expected error", i)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "firewall.json")
+	policy := `{
+		"groups": {"office": ["203.0.113.0/24"]},
+		"rules": [{"action": "allow", "direction": "inbound", "net": ["office"]}],
+		"default": "deny"
+	}`
+	if err := os.WriteFile(file, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Check(&Conn{Inbound: true, IP: netip.MustParseAddr("203.0.113.5")}); err != nil {
+		t.Fatal("office connection denied:", err)
+	}
+	if err := fw.Check(&Conn{Inbound: true, IP: netip.MustParseAddr("198.51.100.1")}); err == nil {
+		t.Fatal("connection not denied by default policy")
+	}
+}
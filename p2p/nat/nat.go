@@ -37,8 +37,10 @@ type Interface interface {
 	//
 	// protocol is "UDP" or "TCP". Some implementations allow setting
 	// a display name for the mapping. The mapping may be removed by
-	// the gateway when its lifetime ends.
-	AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error)
+	// the gateway when its lifetime ends. AddMapping returns the mapped
+	// external port and the lifetime granted by the gateway, which may
+	// be shorter than the requested one.
+	AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, time.Duration, error)
 	DeleteMapping(protocol string, extport, intport int) error
 
 	// ExternalIP should return the external (Internet-facing)
@@ -49,6 +51,15 @@ type Interface interface {
 	String() string
 }
 
+// Pinholer is implemented by NAT interfaces which can open IPv6 firewall pinholes.
+// IPv6 hosts usually have a globally routable address, but the gateway may still
+// filter inbound traffic unless a pinhole is requested for the local address.
+// AddPinhole returns the lifetime granted by the gateway.
+type Pinholer interface {
+	AddPinhole(protocol string, ip net.IP, port int, lifetime time.Duration) (time.Duration, error)
+	DeletePinhole(protocol string, ip net.IP, port int) error
+}
+
 // Parse parses a NAT interface description.
 // The following formats are currently accepted.
 // Note that mechanism names are not case-sensitive.
@@ -59,6 +70,8 @@ type Interface interface {
 //	"upnp"               uses the Universal Plug and Play protocol
 //	"pmp"                uses NAT-PMP with an auto-detected gateway address
 //	"pmp:192.168.0.1"    uses NAT-PMP with the given gateway address
+//	"pcp"                uses PCP with an auto-detected gateway address
+//	"pcp:fe80::1"        uses PCP with the given gateway address
 func Parse(spec string) (Interface, error) {
 	var (
 		before, after, found = strings.Cut(spec, ":")
@@ -85,6 +98,8 @@ func Parse(spec string) (Interface, error) {
 		return UPnP(), nil
 	case "pmp", "natpmp", "nat-pmp":
 		return PMP(ip), nil
+	case "pcp":
+		return PCP(ip), nil
 	default:
 		return nil, fmt.Errorf("unknown mechanism %q", before)
 	}
@@ -107,7 +122,7 @@ func Map(m Interface, c <-chan struct{}, protocol string, extport, intport int,
 		log.Debug("Deleting port mapping")
 		m.DeleteMapping(protocol, extport, intport)
 	}()
-	if _, err := m.AddMapping(protocol, extport, intport, name, DefaultMapTimeout); err != nil {
+	if _, _, err := m.AddMapping(protocol, extport, intport, name, DefaultMapTimeout); err != nil {
 		log.Debug("Couldn't add port mapping", "err", err)
 	} else {
 		log.Info("Mapped network port")
@@ -120,7 +135,7 @@ func Map(m Interface, c <-chan struct{}, protocol string, extport, intport int,
 			}
 		case <-refresh.C:
 			log.Trace("Refreshing port mapping")
-			if _, err := m.AddMapping(protocol, extport, intport, name, DefaultMapTimeout); err != nil {
+			if _, _, err := m.AddMapping(protocol, extport, intport, name, DefaultMapTimeout); err != nil {
 				log.Debug("Couldn't add port mapping", "err", err)
 			}
 			refresh.Reset(DefaultMapTimeout)
@@ -138,18 +153,21 @@ func (n ExtIP) String() string              { return fmt.Sprintf("ExtIP(%v)", ne
 
 // These do nothing.
 
-func (ExtIP) AddMapping(string, int, int, string, time.Duration) (uint16, error) { return 0, nil }
-func (ExtIP) DeleteMapping(string, int, int) error                               { return nil }
+func (ExtIP) AddMapping(string, int, int, string, time.Duration) (uint16, time.Duration, error) {
+	return 0, 0, nil
+}
+func (ExtIP) DeleteMapping(string, int, int) error { return nil }
 
 // Any returns a port mapper that tries to discover any supported
 // mechanism on the local network.
 func Any() Interface {
 	// TODO: attempt to discover whether the local machine has an
 	// Internet-class address. Return ExtIP in this case.
-	return startautodisc("UPnP or NAT-PMP", func() Interface {
-		found := make(chan Interface, 2)
+	return startautodisc("UPnP, NAT-PMP or PCP", func() Interface {
+		found := make(chan Interface, 3)
 		go func() { found <- discoverUPnP() }()
 		go func() { found <- discoverPMP() }()
+		go func() { found <- discoverPCP() }()
 		for i := 0; i < cap(found); i++ {
 			if c := <-found; c != nil {
 				return c
@@ -175,6 +193,17 @@ func PMP(gateway net.IP) Interface {
 	return startautodisc("NAT-PMP", discoverPMP)
 }
 
+// PCP returns a port mapper that uses the Port Control Protocol. The provided
+// gateway address should be the IP of your router. If the given gateway address is
+// nil, PCP will attempt to auto-discover the router. IPv6 firewall pinholes can only
+// be requested if an IPv6 gateway address is given.
+func PCP(gateway net.IP) Interface {
+	if gateway != nil {
+		return newPCP(gateway)
+	}
+	return startautodisc("PCP", discoverPCP)
+}
+
 // autodisc represents a port mapping mechanism that is still being
 // auto-discovered. Calls to the Interface methods on this type will
 // wait until the discovery is done and then call the method on the
@@ -196,9 +225,9 @@ func startautodisc(what string, doit func() Interface) Interface {
 	return &autodisc{what: what, doit: doit}
 }
 
-func (n *autodisc) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+func (n *autodisc) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, time.Duration, error) {
 	if err := n.wait(); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	return n.found.AddMapping(protocol, extport, intport, name, lifetime)
 }
@@ -210,6 +239,28 @@ func (n *autodisc) DeleteMapping(protocol string, extport, intport int) error {
 	return n.found.DeleteMapping(protocol, extport, intport)
 }
 
+func (n *autodisc) AddPinhole(protocol string, ip net.IP, port int, lifetime time.Duration) (time.Duration, error) {
+	if err := n.wait(); err != nil {
+		return 0, err
+	}
+	p, ok := n.found.(Pinholer)
+	if !ok {
+		return 0, fmt.Errorf("%v does not support pinholes", n.found)
+	}
+	return p.AddPinhole(protocol, ip, port, lifetime)
+}
+
+func (n *autodisc) DeletePinhole(protocol string, ip net.IP, port int) error {
+	if err := n.wait(); err != nil {
+		return err
+	}
+	p, ok := n.found.(Pinholer)
+	if !ok {
+		return fmt.Errorf("%v does not support pinholes", n.found)
+	}
+	return p.DeletePinhole(protocol, ip, port)
+}
+
 func (n *autodisc) ExternalIP() (net.IP, error) {
 	if err := n.wait(); err != nil {
 		return nil, err
@@ -0,0 +1,348 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the Port Control Protocol (PCP) as specified in RFC 6887.
+// Only the ANNOUNCE and MAP opcodes are supported. MAP requests made for an IPv6
+// internal address are used to open firewall pinholes on IPv6 gateways.
+
+const (
+	pcpServerPort   = 5351
+	pcpVersion      = 2
+	pcpOpAnnounce   = 0
+	pcpOpMap        = 1
+	pcpResponseBit  = 0x80
+	pcpHeaderSize   = 24
+	pcpMapSize      = 36
+	pcpMaxPacket    = 1100
+	pcpRetries      = 4
+	pcpFirstTimeout = 250 * time.Millisecond
+)
+
+// PCP result codes, see RFC 6887 section 7.4.
+const (
+	pcpSuccess           = 0
+	pcpUnsuppVersion     = 1
+	pcpNotAuthorized     = 2
+	pcpMalformedRequest  = 3
+	pcpUnsuppOpcode      = 4
+	pcpUnsuppOption      = 5
+	pcpMalformedOption   = 6
+	pcpNetworkFailure    = 7
+	pcpNoResources       = 8
+	pcpUnsuppProtocol    = 9
+	pcpUserExQuota       = 10
+	pcpCannotProvideExt  = 11
+	pcpAddressMismatch   = 12
+	pcpExcessiveRemotePs = 13
+)
+
+var pcpResultNames = map[byte]string{
+	pcpUnsuppVersion:     "unsupported version",
+	pcpNotAuthorized:     "not authorized",
+	pcpMalformedRequest:  "malformed request",
+	pcpUnsuppOpcode:      "unsupported opcode",
+	pcpUnsuppOption:      "unsupported option",
+	pcpMalformedOption:   "malformed option",
+	pcpNetworkFailure:    "network failure",
+	pcpNoResources:       "no resources",
+	pcpUnsuppProtocol:    "unsupported protocol",
+	pcpUserExQuota:       "user exceeded quota",
+	pcpCannotProvideExt:  "cannot provide external",
+	pcpAddressMismatch:   "address mismatch",
+	pcpExcessiveRemotePs: "excessive remote peers",
+}
+
+var errPCPTimeout = errors.New("PCP request timed out")
+
+// pcpError is returned when the gateway responds with a non-success result code.
+type pcpError byte
+
+func (e pcpError) Error() string {
+	if name, ok := pcpResultNames[byte(e)]; ok {
+		return "PCP error: " + name
+	}
+	return fmt.Sprintf("PCP error: result code %d", byte(e))
+}
+
+// pcpMappingKey identifies a mapping for the purpose of nonce reuse. PCP servers
+// require that refresh and delete requests carry the nonce of the original request.
+type pcpMappingKey struct {
+	proto   byte
+	intport uint16
+	client  string
+}
+
+// pcp implements the Port Control Protocol.
+type pcp struct {
+	gw   net.IP
+	port int // server port, always pcpServerPort except in tests
+
+	mu     sync.Mutex
+	nonces map[pcpMappingKey][12]byte
+	extIP  net.IP // external address reported by the most recent mapping
+}
+
+func newPCP(gw net.IP) *pcp {
+	return &pcp{gw: gw, port: pcpServerPort, nonces: make(map[pcpMappingKey][12]byte)}
+}
+
+func (n *pcp) String() string {
+	return fmt.Sprintf("PCP(%v)", n.gw)
+}
+
+// ExternalIP returns the external address assigned by the gateway for the most
+// recently created mapping. PCP has no dedicated opcode for querying the address, so
+// this returns an error until a mapping has been established.
+func (n *pcp) ExternalIP() (net.IP, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.extIP == nil {
+		return nil, errors.New("PCP external address not known yet")
+	}
+	return n.extIP, nil
+}
+
+func (n *pcp) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, time.Duration, error) {
+	if lifetime <= 0 {
+		return 0, 0, errors.New("lifetime must not be <= 0")
+	}
+	res, err := n.requestMap(nil, protocol, extport, intport, lifetime)
+	if err != nil {
+		return 0, 0, err
+	}
+	n.mu.Lock()
+	n.extIP = res.extIP
+	n.mu.Unlock()
+	return res.extPort, res.lifetime, nil
+}
+
+func (n *pcp) DeleteMapping(protocol string, extport, intport int) error {
+	_, err := n.requestMap(nil, protocol, 0, intport, 0)
+	return err
+}
+
+// AddPinhole opens an IPv6 firewall pinhole for the given local address and port.
+// This only works if the gateway was configured with an IPv6 address.
+func (n *pcp) AddPinhole(protocol string, ip net.IP, port int, lifetime time.Duration) (time.Duration, error) {
+	if lifetime <= 0 {
+		return 0, errors.New("lifetime must not be <= 0")
+	}
+	if err := n.checkPinhole(ip); err != nil {
+		return 0, err
+	}
+	res, err := n.requestMap(ip, protocol, port, port, lifetime)
+	if err != nil {
+		return 0, err
+	}
+	return res.lifetime, nil
+}
+
+// DeletePinhole closes a pinhole created by AddPinhole.
+func (n *pcp) DeletePinhole(protocol string, ip net.IP, port int) error {
+	if err := n.checkPinhole(ip); err != nil {
+		return err
+	}
+	_, err := n.requestMap(ip, protocol, 0, port, 0)
+	return err
+}
+
+func (n *pcp) checkPinhole(ip net.IP) error {
+	if n.gw.To4() != nil {
+		return errors.New("PCP gateway is not reachable over IPv6")
+	}
+	if ip.To4() != nil {
+		return errors.New("pinholes require an IPv6 address")
+	}
+	return nil
+}
+
+// pcpMapResult is the decoded payload of a MAP response.
+type pcpMapResult struct {
+	lifetime time.Duration
+	extPort  uint16
+	extIP    net.IP
+}
+
+// requestMap sends a MAP request. If client is nil, the request is sent from the
+// default local address and the mapping is created for that address.
+func (n *pcp) requestMap(client net.IP, protocol string, extport, intport int, lifetime time.Duration) (*pcpMapResult, error) {
+	var proto byte
+	switch strings.ToUpper(protocol) {
+	case "TCP":
+		proto = 6
+	case "UDP":
+		proto = 17
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+	conn, err := n.dial(client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client = conn.LocalAddr().(*net.UDPAddr).IP
+
+	key := pcpMappingKey{proto, uint16(intport), client.String()}
+	nonce := n.nonce(key, lifetime == 0)
+
+	req := make([]byte, pcpHeaderSize+pcpMapSize)
+	req[0] = pcpVersion
+	req[1] = pcpOpMap
+	binary.BigEndian.PutUint32(req[4:], uint32(lifetime/time.Second))
+	copy(req[8:24], client.To16())
+	copy(req[24:36], nonce[:])
+	req[36] = proto
+	binary.BigEndian.PutUint16(req[40:], uint16(intport))
+	binary.BigEndian.PutUint16(req[42:], uint16(extport))
+	if client.To4() != nil {
+		// Suggest the IPv4 'any' address, mapped into IPv6.
+		copy(req[44:60], net.IPv4zero.To16())
+	}
+
+	resp, err := n.roundTrip(conn, req, pcpOpMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < pcpHeaderSize+pcpMapSize {
+		return nil, errors.New("PCP MAP response too short")
+	}
+	if !bytes.Equal(resp[24:36], nonce[:]) {
+		return nil, errors.New("PCP MAP response nonce mismatch")
+	}
+	return &pcpMapResult{
+		lifetime: time.Duration(binary.BigEndian.Uint32(resp[4:])) * time.Second,
+		extPort:  binary.BigEndian.Uint16(resp[42:]),
+		extIP:    net.IP(bytes.Clone(resp[44:60])),
+	}, nil
+}
+
+// nonce returns the mapping nonce for the given key. A new nonce is created for
+// unknown mappings. If remove is true, the nonce is dropped after the call.
+func (n *pcp) nonce(key pcpMappingKey, remove bool) [12]byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nonce, ok := n.nonces[key]
+	if !ok {
+		crand.Read(nonce[:])
+		n.nonces[key] = nonce
+	}
+	if remove {
+		delete(n.nonces, key)
+	}
+	return nonce
+}
+
+// announce sends an ANNOUNCE request. This is used to check whether the gateway
+// supports PCP.
+func (n *pcp) announce() error {
+	conn, err := n.dial(nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	req := make([]byte, pcpHeaderSize)
+	req[0] = pcpVersion
+	req[1] = pcpOpAnnounce
+	copy(req[8:24], conn.LocalAddr().(*net.UDPAddr).IP.To16())
+	_, err = n.roundTrip(conn, req, pcpOpAnnounce)
+	return err
+}
+
+func (n *pcp) dial(local net.IP) (*net.UDPConn, error) {
+	var laddr *net.UDPAddr
+	if local != nil {
+		laddr = &net.UDPAddr{IP: local}
+	}
+	return net.DialUDP("udp", laddr, &net.UDPAddr{IP: n.gw, Port: n.port})
+}
+
+// roundTrip sends a request and waits for the matching response, retransmitting
+// with exponential backoff.
+func (n *pcp) roundTrip(conn *net.UDPConn, req []byte, op byte) ([]byte, error) {
+	buf := make([]byte, pcpMaxPacket)
+	timeout := pcpFirstTimeout
+	for i := 0; i < pcpRetries; i++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		for {
+			nbytes, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			resp := buf[:nbytes]
+			if nbytes < pcpHeaderSize || resp[0] != pcpVersion || resp[1] != op|pcpResponseBit {
+				continue // not a response to our request
+			}
+			if resp[3] != pcpSuccess {
+				return nil, pcpError(resp[3])
+			}
+			return bytes.Clone(resp), nil
+		}
+		timeout *= 2
+	}
+	return nil, errPCPTimeout
+}
+
+func discoverPCP() Interface {
+	// Send announcements to all potential gateways.
+	gws := potentialGateways()
+	found := make(chan *pcp, len(gws))
+	for i := range gws {
+		gw := gws[i]
+		go func() {
+			c := newPCP(gw)
+			if err := c.announce(); err != nil {
+				found <- nil
+			} else {
+				found <- c
+			}
+		}()
+	}
+	// Return the one that responds first.
+	timeout := time.NewTimer(1 * time.Second)
+	defer timeout.Stop()
+	for range gws {
+		select {
+		case c := <-found:
+			if c != nil {
+				return c
+			}
+		case <-timeout.C:
+			return nil
+		}
+	}
+	return nil
+}
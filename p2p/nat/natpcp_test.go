@@ -0,0 +1,232 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePCPGateway is a minimal PCP server for testing.
+type fakePCPGateway struct {
+	conn  *net.UDPConn
+	extIP net.IP
+
+	mu          sync.Mutex
+	mappings    map[uint16]fakePCPMapping // keyed by internal port
+	result      byte                      // result code to return
+	maxLifetime uint32                    // max granted lifetime in seconds, if non-zero
+}
+
+type fakePCPMapping struct {
+	client   net.IP
+	nonce    [12]byte
+	extPort  uint16
+	lifetime uint32
+}
+
+func newFakePCPGateway(t *testing.T, ip net.IP) *fakePCPGateway {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	if err != nil {
+		t.Skip("can't listen:", err)
+	}
+	gw := &fakePCPGateway{
+		conn:     conn,
+		extIP:    net.IP{192, 0, 2, 1},
+		mappings: make(map[uint16]fakePCPMapping),
+	}
+	go gw.serve()
+	t.Cleanup(func() { conn.Close() })
+	return gw
+}
+
+func (gw *fakePCPGateway) client() *pcp {
+	addr := gw.conn.LocalAddr().(*net.UDPAddr)
+	c := newPCP(addr.IP)
+	c.port = addr.Port
+	return c
+}
+
+func (gw *fakePCPGateway) mapping(intport uint16) (fakePCPMapping, bool) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	m, ok := gw.mappings[intport]
+	return m, ok
+}
+
+func (gw *fakePCPGateway) serve() {
+	buf := make([]byte, pcpMaxPacket)
+	for {
+		n, from, err := gw.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		req := buf[:n]
+		if n < pcpHeaderSize || req[0] != pcpVersion {
+			continue
+		}
+		resp := make([]byte, n)
+		copy(resp, req)
+		resp[1] |= pcpResponseBit
+		resp[2] = 0
+		copy(resp[8:24], make([]byte, 16))
+
+		gw.mu.Lock()
+		resp[3] = gw.result
+		if req[1] == pcpOpMap && gw.result == pcpSuccess {
+			var (
+				lifetime = binary.BigEndian.Uint32(req[4:])
+				client   = net.IP(req[8:24])
+				intport  = binary.BigEndian.Uint16(req[40:])
+				extport  = binary.BigEndian.Uint16(req[42:])
+				m        = fakePCPMapping{client: append(net.IP{}, client...), lifetime: lifetime}
+			)
+			copy(m.nonce[:], req[24:36])
+			if !client.Equal(from.IP) {
+				resp[3] = pcpAddressMismatch
+			} else if lifetime == 0 {
+				delete(gw.mappings, intport)
+			} else {
+				if gw.maxLifetime != 0 && lifetime > gw.maxLifetime {
+					m.lifetime = gw.maxLifetime
+					binary.BigEndian.PutUint32(resp[4:], m.lifetime)
+				}
+				if extport == 0 {
+					extport = intport
+				}
+				m.extPort = extport + 1 // always assign a different port
+				gw.mappings[intport] = m
+				binary.BigEndian.PutUint16(resp[42:], m.extPort)
+				if client.To4() != nil {
+					copy(resp[44:60], gw.extIP.To16())
+				} else {
+					copy(resp[44:60], client)
+				}
+			}
+		}
+		gw.mu.Unlock()
+		gw.conn.WriteToUDP(resp, from)
+	}
+}
+
+func TestPCPMapping(t *testing.T) {
+	gw := newFakePCPGateway(t, net.IP{127, 0, 0, 1})
+	c := gw.client()
+
+	if err := c.announce(); err != nil {
+		t.Fatal("announce failed:", err)
+	}
+	if _, err := c.ExternalIP(); err == nil {
+		t.Fatal("expected error for unknown external IP")
+	}
+
+	port, lifetime, err := c.AddMapping("TCP", 30303, 30303, "test", 10*time.Minute)
+	if err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+	if port != 30304 {
+		t.Fatal("wrong mapped port", port)
+	}
+	if lifetime != 10*time.Minute {
+		t.Fatal("wrong granted lifetime", lifetime)
+	}
+	m, ok := gw.mapping(30303)
+	if !ok {
+		t.Fatal("mapping not created")
+	}
+	if m.lifetime != 600 {
+		t.Fatal("wrong lifetime", m.lifetime)
+	}
+	ip, err := c.ExternalIP()
+	if err != nil {
+		t.Fatal("ExternalIP failed:", err)
+	}
+	if !ip.Equal(gw.extIP) {
+		t.Fatalf("wrong external IP %v, want %v", ip, gw.extIP)
+	}
+
+	// Refresh must reuse the nonce. The gateway may grant a shorter lifetime.
+	gw.mu.Lock()
+	gw.maxLifetime = 120
+	gw.mu.Unlock()
+	if _, lifetime, err = c.AddMapping("TCP", 30304, 30303, "test", 10*time.Minute); err != nil {
+		t.Fatal("refresh failed:", err)
+	}
+	if lifetime != 2*time.Minute {
+		t.Fatal("wrong granted lifetime on refresh", lifetime)
+	}
+	m2, _ := gw.mapping(30303)
+	if m2.nonce != m.nonce {
+		t.Fatal("nonce changed on refresh")
+	}
+
+	if err := c.DeleteMapping("TCP", 30304, 30303); err != nil {
+		t.Fatal("DeleteMapping failed:", err)
+	}
+	if _, ok := gw.mapping(30303); ok {
+		t.Fatal("mapping not deleted")
+	}
+}
+
+func TestPCPError(t *testing.T) {
+	gw := newFakePCPGateway(t, net.IP{127, 0, 0, 1})
+	gw.mu.Lock()
+	gw.result = pcpNotAuthorized
+	gw.mu.Unlock()
+	c := gw.client()
+
+	_, _, err := c.AddMapping("UDP", 30303, 30303, "test", 10*time.Minute)
+	var pcpErr pcpError
+	if !errors.As(err, &pcpErr) || pcpErr != pcpNotAuthorized {
+		t.Fatal("wrong error:", err)
+	}
+}
+
+func TestPCPPinhole(t *testing.T) {
+	gw := newFakePCPGateway(t, net.IPv6loopback)
+	c := gw.client()
+
+	lifetime, err := c.AddPinhole("UDP", net.IPv6loopback, 30303, 10*time.Minute)
+	if err != nil {
+		t.Fatal("AddPinhole failed:", err)
+	}
+	if lifetime != 10*time.Minute {
+		t.Fatal("wrong granted lifetime", lifetime)
+	}
+	m, ok := gw.mapping(30303)
+	if !ok {
+		t.Fatal("pinhole not created")
+	}
+	if !m.client.Equal(net.IPv6loopback) {
+		t.Fatal("wrong client address", m.client)
+	}
+	if err := c.DeletePinhole("UDP", net.IPv6loopback, 30303); err != nil {
+		t.Fatal("DeletePinhole failed:", err)
+	}
+	if _, ok := gw.mapping(30303); ok {
+		t.Fatal("pinhole not deleted")
+	}
+
+	// Pinholes can't be requested for IPv4 addresses.
+	if _, err := c.AddPinhole("UDP", net.IP{127, 0, 0, 1}, 30303, 10*time.Minute); err == nil {
+		t.Fatal("expected error for IPv4 pinhole")
+	}
+}
@@ -45,21 +45,21 @@ func (n *pmp) ExternalIP() (net.IP, error) {
 	return response.ExternalIPAddress[:], nil
 }
 
-func (n *pmp) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+func (n *pmp) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, time.Duration, error) {
 	if lifetime <= 0 {
-		return 0, errors.New("lifetime must not be <= 0")
+		return 0, 0, errors.New("lifetime must not be <= 0")
 	}
 	// Note order of port arguments is switched between our
 	// AddMapping and the client's AddPortMapping.
 	res, err := n.c.AddPortMapping(strings.ToLower(protocol), intport, extport, int(lifetime/time.Second))
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
 	// NAT-PMP maps an alternative available port number if the requested port
 	// is already mapped to another address and returns success. Handling of
 	// alternate port numbers is done by the caller.
-	return res.MappedExternalPort, nil
+	return res.MappedExternalPort, time.Duration(res.PortMappingLifetimeInSeconds) * time.Second, nil
 }
 
 func (n *pmp) DeleteMapping(protocol string, extport, intport int) (err error) {
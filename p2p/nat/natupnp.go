@@ -79,10 +79,12 @@ func (n *upnp) ExternalIP() (addr net.IP, err error) {
 	return ip, nil
 }
 
-func (n *upnp) AddMapping(protocol string, extport, intport int, desc string, lifetime time.Duration) (uint16, error) {
+// AddMapping creates a port mapping. UPnP gateways don't report the lifetime of
+// the mapping, the requested one is returned.
+func (n *upnp) AddMapping(protocol string, extport, intport int, desc string, lifetime time.Duration) (uint16, time.Duration, error) {
 	ip, err := n.internalAddress()
 	if err != nil {
-		return 0, nil // TODO: Shouldn't we return the error?
+		return 0, 0, nil // TODO: Shouldn't we return the error?
 	}
 	protocol = strings.ToUpper(protocol)
 	lifetimeS := uint32(lifetime / time.Second)
@@ -92,16 +94,17 @@ func (n *upnp) AddMapping(protocol string, extport, intport int, desc string, li
 		return n.client.AddPortMapping("", uint16(extport), protocol, uint16(intport), ip.String(), true, desc, lifetimeS)
 	})
 	if err == nil {
-		return uint16(extport), nil
+		return uint16(extport), lifetime, nil
 	}
 
-	return uint16(extport), n.withRateLimit(func() error {
+	err = n.withRateLimit(func() error {
 		p, err := n.addAnyPortMapping(protocol, extport, intport, ip, desc, lifetimeS)
 		if err == nil {
 			extport = int(p)
 		}
 		return err
 	})
+	return uint16(extport), lifetime, err
 }
 
 func (n *upnp) addAnyPortMapping(protocol string, extport, intport int, ip net.IP, desc string, lifetimeS uint32) (uint16, error) {
@@ -23,6 +23,7 @@ import (
 	"net"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -31,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/firewall"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -115,6 +117,13 @@ type Peer struct {
 	pingRecv chan struct{}
 	disc     chan DiscReason
 
+	// reputation receives score events if set
+	reputation *reputation
+
+	// firewall is the connection policy of the server, if set
+	firewall *atomic.Pointer[firewall.Firewall]
+	forkHash atomic.Pointer[[4]byte]
+
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
@@ -499,11 +508,12 @@ type PeerInfo struct {
 	Name    string   `json:"name"`          // Name of the node, including client type, version, OS, custom data
 	Caps    []string `json:"caps"`          // Protocols advertised by this peer
 	Network struct {
-		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
-		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
-		Inbound       bool   `json:"inbound"`
-		Trusted       bool   `json:"trusted"`
-		Static        bool   `json:"static"`
+		LocalAddress  string  `json:"localAddress"`  // Local endpoint of the TCP data connection
+		RemoteAddress string  `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+		Inbound       bool    `json:"inbound"`
+		Trusted       bool    `json:"trusted"`
+		Static        bool    `json:"static"`
+		Reputation    float64 `json:"reputation"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -531,6 +541,7 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	info.Network.Reputation = p.Reputation()
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
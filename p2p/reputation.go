@@ -0,0 +1,244 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// reputationHalfLife is the time after which a reputation score decays to half
+	// its value. Scores decay towards zero, so peers can recover from past
+	// misbehaviour and cannot bank good behaviour forever.
+	reputationHalfLife = time.Hour
+
+	// maxReputation bounds the absolute value of a reputation score.
+	maxReputation = 100
+
+	// minDialReputation is the reputation below which dynamic dial candidates are
+	// discarded.
+	minDialReputation = -20
+
+	// evictReputation is the reputation below which a connected peer may be evicted
+	// in favour of a better scored node when the server is at capacity.
+	evictReputation = -10
+
+	// reputationDBScale converts scores to the integer representation stored in
+	// the node database.
+	reputationDBScale = 1000
+)
+
+// ScoreEvent describes peer behaviour which affects its reputation.
+type ScoreEvent int
+
+const (
+	ScoreUsefulResponse    ScoreEvent = iota // peer delivered requested data
+	ScoreEmptyResponse                       // peer responded without useful data
+	ScoreSlowResponse                        // peer responded much slower than others
+	ScoreRequestTimeout                      // peer failed to respond in time
+	ScoreInvalidResponse                     // peer delivered data which failed validation
+	ScoreProtocolViolation                   // peer violated the protocol rules
+)
+
+var scoreEventWeights = [...]float64{
+	ScoreUsefulResponse:    1,
+	ScoreEmptyResponse:     -1,
+	ScoreSlowResponse:      -0.5,
+	ScoreRequestTimeout:    -3,
+	ScoreInvalidResponse:   -20,
+	ScoreProtocolViolation: -30,
+}
+
+func (ev ScoreEvent) String() string {
+	switch ev {
+	case ScoreUsefulResponse:
+		return "useful response"
+	case ScoreEmptyResponse:
+		return "empty response"
+	case ScoreSlowResponse:
+		return "slow response"
+	case ScoreRequestTimeout:
+		return "request timeout"
+	case ScoreInvalidResponse:
+		return "invalid response"
+	case ScoreProtocolViolation:
+		return "protocol violation"
+	default:
+		return "unknown score event"
+	}
+}
+
+// ScoreReporter is implemented by peers which accept reputation feedback. Protocol
+// peer types which embed *Peer implement it, which allows packages that only know
+// about their own peer abstraction to feed the reputation system.
+type ScoreReporter interface {
+	Report(ScoreEvent)
+}
+
+// reputation tracks the reputation scores of peers. Scores of connected peers are
+// kept in memory and persisted to the node database when the peer disconnects.
+// Events reported for other nodes, e.g. by protocol handlers which are still
+// shutting down, are applied to the database directly.
+type reputation struct {
+	db  *enode.DB
+	now func() time.Time
+
+	mu     sync.Mutex
+	scores map[enode.ID]*reputationScore
+}
+
+type reputationScore struct {
+	value   float64
+	updated time.Time
+}
+
+func newReputation(db *enode.DB) *reputation {
+	return &reputation{db: db, now: time.Now, scores: make(map[enode.ID]*reputationScore)}
+}
+
+// decayed returns the score value at time now.
+func (s *reputationScore) decayed(now time.Time) float64 {
+	elapsed := now.Sub(s.updated)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(elapsed)/float64(reputationHalfLife))
+}
+
+// track loads the score of a connected peer into memory.
+func (r *reputation) track(id enode.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.scores[id] == nil {
+		r.scores[id] = r.stored(id)
+	}
+}
+
+// stored reads the persisted score of a node.
+func (r *reputation) stored(id enode.ID) *reputationScore {
+	value, updated := r.db.Reputation(id)
+	return &reputationScore{value: float64(value) / reputationDBScale, updated: updated}
+}
+
+// score returns the current reputation of a node.
+func (r *reputation) score(id enode.ID) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.scores[id]
+	if s == nil {
+		s = r.stored(id)
+	}
+	return s.decayed(r.now())
+}
+
+// report applies a score event to the reputation of a node.
+func (r *reputation) report(id enode.ID, ev ScoreEvent) {
+	if int(ev) < 0 || int(ev) >= len(scoreEventWeights) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	s, tracked := r.scores[id]
+	if !tracked {
+		s = r.stored(id)
+	}
+	s.value = s.decayed(now) + scoreEventWeights[ev]
+	s.value = math.Max(-maxReputation, math.Min(maxReputation, s.value))
+	s.updated = now
+	if !tracked {
+		r.db.UpdateReputation(id, int64(s.value*reputationDBScale), s.updated)
+	}
+}
+
+// flush persists the score of a node and removes it from memory.
+func (r *reputation) flush(id enode.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.scores[id]; s != nil {
+		r.db.UpdateReputation(id, int64(s.value*reputationDBScale), s.updated)
+		delete(r.scores, id)
+	}
+}
+
+// flushAll persists all scores held in memory.
+func (r *reputation) flushAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, s := range r.scores {
+		r.db.UpdateReputation(id, int64(s.value*reputationDBScale), s.updated)
+	}
+	clear(r.scores)
+}
+
+// Report applies a score event to the reputation of the peer. It does nothing for
+// peers which are not managed by a Server.
+func (p *Peer) Report(ev ScoreEvent) {
+	if p == nil || p.reputation == nil {
+		return
+	}
+	p.log.Trace("Adjusting peer reputation", "event", ev)
+	p.reputation.report(p.ID(), ev)
+}
+
+// Reputation returns the current reputation score of the peer.
+func (p *Peer) Reputation() float64 {
+	if p == nil || p.reputation == nil {
+		return 0
+	}
+	return p.reputation.score(p.ID())
+}
+
+// evictionCandidate returns the connected peer with the lowest reputation if it can
+// be evicted to make room for the connection c. Trusted and static peers are never
+// evicted, and inbound connections can only replace inbound peers.
+func (srv *Server) evictionCandidate(peers map[enode.ID]*Peer, c *conn) *Peer {
+	if c.is(trustedConn) {
+		return nil
+	}
+	var (
+		worst      *Peer
+		worstScore float64
+	)
+	for _, p := range peers {
+		if p.rw.is(trustedConn) || p.rw.is(staticDialedConn) {
+			continue
+		}
+		if c.is(inboundConn) && !p.rw.is(inboundConn) {
+			continue
+		}
+		if score := srv.reputation.score(p.ID()); worst == nil || score < worstScore {
+			worst, worstScore = p, score
+		}
+	}
+	if worst == nil || worstScore >= evictReputation {
+		return nil
+	}
+	if srv.reputation.score(c.node.ID()) <= worstScore {
+		return nil
+	}
+	return worst
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func newTestReputation(t *testing.T) (*reputation, *time.Time) {
+	db, err := enode.OpenDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	now := time.Unix(1700000000, 0)
+	r := newReputation(db)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestReputationScore(t *testing.T) {
+	r, now := newTestReputation(t)
+	id := randomID()
+
+	if s := r.score(id); s != 0 {
+		t.Fatal("unknown node has non-zero score", s)
+	}
+	for i := 0; i < 10; i++ {
+		r.report(id, ScoreUsefulResponse)
+	}
+	r.report(id, ScoreRequestTimeout)
+	if s := r.score(id); s != 7 {
+		t.Fatal("wrong score", s)
+	}
+
+	// Scores decay towards zero.
+	*now = now.Add(reputationHalfLife)
+	if s := r.score(id); math.Abs(s-3.5) > 1e-9 {
+		t.Fatal("wrong score after one half-life", s)
+	}
+
+	// Scores are bounded.
+	for i := 0; i < 10; i++ {
+		r.report(id, ScoreProtocolViolation)
+	}
+	if s := r.score(id); s != -maxReputation {
+		t.Fatal("score not clamped", s)
+	}
+}
+
+func TestReputationPersist(t *testing.T) {
+	r, now := newTestReputation(t)
+	id1, id2 := randomID(), randomID()
+
+	r.track(id1)
+	r.track(id2)
+	r.report(id1, ScoreInvalidResponse)
+	r.report(id2, ScoreUsefulResponse)
+	r.flush(id1)
+	if _, ok := r.scores[id1]; ok {
+		t.Fatal("flushed score still held in memory")
+	}
+	r.flushAll()
+	if len(r.scores) != 0 {
+		t.Fatal("scores held in memory after flushAll")
+	}
+
+	// The scores are loaded from the database by a new instance.
+	r2 := newReputation(r.db)
+	r2.now = r.now
+	if s := r2.score(id1); s != -20 {
+		t.Fatal("wrong persisted score for id1:", s)
+	}
+	if s := r2.score(id2); s != 1 {
+		t.Fatal("wrong persisted score for id2:", s)
+	}
+	*now = now.Add(2 * reputationHalfLife)
+	if s := r2.score(id1); s != -5 {
+		t.Fatal("persisted score did not decay:", s)
+	}
+}
+
+// This test checks that events reported after a peer disconnected are persisted
+// instead of being held in memory.
+func TestReputationLateReport(t *testing.T) {
+	r, _ := newTestReputation(t)
+	id := randomID()
+
+	r.track(id)
+	r.report(id, ScoreUsefulResponse)
+	r.flush(id)
+
+	r.report(id, ScoreRequestTimeout)
+	if len(r.scores) != 0 {
+		t.Fatal("late report held in memory")
+	}
+	if s := r.score(id); s != -2 {
+		t.Fatal("wrong score after late report:", s)
+	}
+}
+
+// This test checks that a peer with low reputation is evicted when the server is
+// at capacity and a better peer connects.
+func TestServerEvictLowReputation(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    2,
+			NoDial:      true,
+			NoDiscovery: true,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&newkey().PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+
+	var (
+		good, bad = randomID(), randomID()
+		newID     = randomID()
+	)
+	srv.reputation.report(bad, ScoreProtocolViolation)
+	for _, id := range []enode.ID{good, bad} {
+		if err := srv.checkpoint(newconn(id), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add conn %v: %v", id, err)
+		}
+	}
+
+	// A node with a worse score than the worst peer is rejected.
+	worse := randomID()
+	srv.reputation.report(worse, ScoreProtocolViolation)
+	srv.reputation.report(worse, ScoreProtocolViolation)
+	if err := srv.checkpoint(newconn(worse), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Fatal("wrong error for worse node:", err)
+	}
+
+	// A new node replaces the badly scored peer.
+	if err := srv.checkpoint(newconn(newID), srv.checkpointPostHandshake); err != nil {
+		t.Fatal("unexpected error at posthandshake:", err)
+	}
+	if err := srv.checkpoint(newconn(newID), srv.checkpointAddPeer); err != nil {
+		t.Fatal("unexpected error at addpeer:", err)
+	}
+	peers := make(map[enode.ID]bool)
+	for _, p := range srv.Peers() {
+		peers[p.ID()] = true
+	}
+	if len(peers) != 2 || !peers[good] || !peers[newID] {
+		t.Fatalf("wrong peer set after eviction: %v", peers)
+	}
+}
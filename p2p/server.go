@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"cmp"
 	"crypto/ecdsa"
+	"crypto/tls"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -39,8 +40,10 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/firewall"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/quic-go/quic-go"
 )
 
 const (
@@ -127,6 +130,11 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// FirewallFile is the path of a JSON firewall policy file. The policy decides
+	// which peers are allowed based on their address, client name, capabilities,
+	// fork ID and node record. See package p2p/firewall for the format.
+	FirewallFile string `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -148,6 +156,11 @@ type Config struct {
 	// for TCP and DiscAddr for the UDP discovery protocol.
 	DiscAddr string
 
+	// If QUICListenAddr is set to a non-empty address, the server will also accept
+	// connections over the experimental QUIC transport on the given UDP address,
+	// and prefer QUIC when dialing nodes which announce a QUIC port.
+	QUICListenAddr string `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -184,21 +197,28 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
-	listener     net.Listener
-	ourHandshake *protoHandshake
-	loopWG       sync.WaitGroup // loop, listenLoop
-	peerFeed     event.Feed
-	log          log.Logger
-
-	nodedb    *enode.DB
-	localnode *enode.LocalNode
-	discv4    *discover.UDPv4
-	discv5    *discover.UDPv5
-	discmix   *enode.FairMix
-	dialsched *dialScheduler
+	listener      net.Listener
+	quicListener  *quicListener
+	quicTransport *quic.Transport
+	quicTLS       *tls.Config
+	ourHandshake  *protoHandshake
+	loopWG        sync.WaitGroup // loop, listenLoop, portMappingLoop
+	peerFeed      event.Feed
+	log           log.Logger
+
+	nodedb     *enode.DB
+	reputation *reputation
+	firewall   atomic.Pointer[firewall.Firewall]
+	localnode  *enode.LocalNode
+	discv4     *discover.UDPv4
+	discv5     *discover.UDPv5
+	discmix    *enode.FairMix
+	dialsched  *dialScheduler
 
 	// This is read by the NAT port mapping loop.
 	portMappingRegister chan *portMapping
+	natInfoLock         sync.Mutex
+	natInfo             *NATInfo // published by the NAT port mapping loop
 
 	// Channels into the run loop.
 	quit                    chan struct{}
@@ -425,9 +445,15 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quicListener != nil {
+		srv.quicListener.Close()
+	}
 	close(srv.quit)
 	srv.lock.Unlock()
 	srv.loopWG.Wait()
+	if srv.quicTransport != nil {
+		srv.quicTransport.Close()
+	}
 }
 
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
@@ -495,6 +521,9 @@ func (srv *Server) Start() (err error) {
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
+	if err := srv.setupFirewall(); err != nil {
+		return err
+	}
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
@@ -505,6 +534,11 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 	}
+	if srv.QUICListenAddr != "" {
+		if err := srv.setupQUIC(); err != nil {
+			return err
+		}
+	}
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
@@ -530,6 +564,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.reputation = newReputation(db)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -612,6 +647,8 @@ func (srv *Server) setupDialScheduler() {
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
 		dialer:         srv.Dialer,
+		reputation:     srv.reputation.score,
+		firewall:       srv.checkFirewallDial,
 		clock:          srv.clock,
 	}
 	if srv.discv4 != nil {
@@ -619,6 +656,10 @@ func (srv *Server) setupDialScheduler() {
 	}
 	if config.dialer == nil {
 		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
+		if srv.quicTransport != nil {
+			config.dialer = quicDialer{srv.quicTransport, srv.quicTLS, config.dialer}
+			config.quic = true
+		}
 	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
 	for _, n := range srv.StaticNodes {
@@ -668,7 +709,7 @@ func (srv *Server) setupListening() error {
 	}
 
 	srv.loopWG.Add(1)
-	go srv.listenLoop()
+	go srv.listenLoop(srv.listener)
 	return nil
 }
 
@@ -721,6 +762,7 @@ func (srv *Server) run() {
 
 	var (
 		peers        = make(map[enode.ID]*Peer)
+		evicted      = make(map[*Peer]struct{}) // peers removed from 'peers' which are still shutting down
 		inboundCount = 0
 		trusted      = make(map[enode.ID]bool, len(srv.TrustedNodes))
 	)
@@ -768,12 +810,31 @@ running:
 				c.flags |= trustedConn
 			}
 			// TODO: track in-progress inbound node IDs (pre-Peer) to avoid dialing them.
-			c.cont <- srv.postHandshakeChecks(peers, inboundCount, c)
+			err := srv.postHandshakeChecks(peers, inboundCount, c)
+			if err == DiscTooManyPeers && srv.evictionCandidate(peers, c) != nil {
+				// Let the connection proceed, the eviction happens when it is added.
+				err = nil
+			}
+			c.cont <- err
 
 		case c := <-srv.checkpointAddPeer:
 			// At this point the connection is past the protocol handshake.
 			// Its capabilities are known and the remote identity is verified.
 			err := srv.addPeerChecks(peers, inboundCount, c)
+			if err == DiscTooManyPeers {
+				// Make room by evicting the peer with the lowest reputation, if its
+				// score is worse than the reputation of the new node.
+				if victim := srv.evictionCandidate(peers, c); victim != nil {
+					srv.log.Debug("Evicting p2p peer with low reputation", "id", victim.ID(), "reputation", victim.Reputation(), "for", c.node.ID())
+					delete(peers, victim.ID())
+					evicted[victim] = struct{}{}
+					if victim.Inbound() {
+						inboundCount--
+					}
+					go victim.Disconnect(DiscTooManyPeers)
+					err = srv.addPeerChecks(peers, inboundCount, c)
+				}
+			}
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := srv.launchPeer(c)
@@ -795,11 +856,20 @@ running:
 		case pd := <-srv.delpeer:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
-			delete(peers, pd.ID())
+			_, wasEvicted := evicted[pd.Peer]
+			if wasEvicted {
+				// The peer was already removed from the peer set.
+				delete(evicted, pd.Peer)
+			} else {
+				delete(peers, pd.ID())
+			}
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
+			srv.reputation.flush(pd.ID())
 			if pd.Inbound() {
-				inboundCount--
+				if !wasEvicted {
+					inboundCount--
+				}
 				activeInboundPeerGauge.Dec(1)
 			} else {
 				activeOutboundPeerGauge.Dec(1)
@@ -824,11 +894,16 @@ running:
 	// Wait for peers to shut down. Pending connections and tasks are
 	// not handled here and will terminate soon-ish because srv.quit
 	// is closed.
-	for len(peers) > 0 {
+	for len(peers)+len(evicted) > 0 {
 		p := <-srv.delpeer
 		p.log.Trace("<-delpeer (spindown)")
-		delete(peers, p.ID())
+		if _, ok := evicted[p.Peer]; ok {
+			delete(evicted, p.Peer)
+		} else {
+			delete(peers, p.ID())
+		}
 	}
+	srv.reputation.flushAll()
 }
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
@@ -851,6 +926,10 @@ func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
 		return DiscUselessPeer
 	}
+	if err := srv.checkFirewall(firewallConn(c, nil, !announcesForkID(srv.Protocols, c.caps))); err != nil {
+		srv.log.Debug("Peer rejected by firewall", "id", c.node.ID(), "addr", c.fd.RemoteAddr(), "conn", c.flags, "err", err)
+		return DiscUselessPeer
+	}
 	// Repeat the post-handshake checks because the
 	// peer set might have changed since those checks were performed.
 	return srv.postHandshakeChecks(peers, inboundCount, c)
@@ -858,8 +937,12 @@ func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *
 
 // listenLoop runs in its own goroutine and accepts
 // inbound connections.
-func (srv *Server) listenLoop() {
-	srv.log.Debug("TCP listener up", "addr", srv.listener.Addr())
+func (srv *Server) listenLoop(listener net.Listener) {
+	if _, ok := listener.(*quicListener); ok {
+		srv.log.Debug("QUIC listener up", "addr", listener.Addr())
+	} else {
+		srv.log.Debug("TCP listener up", "addr", listener.Addr())
+	}
 
 	// The slots channel limits accepts of new connections.
 	tokens := defaultMaxPendingPeers
@@ -890,7 +973,7 @@ func (srv *Server) listenLoop() {
 			lastLog time.Time
 		)
 		for {
-			fd, err = srv.listener.Accept()
+			fd, err = listener.Accept()
 			if netutil.IsTemporaryError(err) {
 				if time.Since(lastLog) > 1*time.Second {
 					srv.log.Debug("Temporary read error", "err", err)
@@ -934,6 +1017,9 @@ func (srv *Server) checkInboundConn(remoteIP netip.Addr) error {
 	if srv.NetRestrict != nil && !srv.NetRestrict.ContainsAddr(remoteIP) {
 		return errors.New("not in netrestrict list")
 	}
+	if err := srv.checkFirewall(&firewall.Conn{Inbound: true, IP: remoteIP}); err != nil {
+		return err
+	}
 	// Reject Internet peers that try too often.
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
@@ -1025,9 +1111,14 @@ func (srv *Server) setupConn(c *conn, dialDest *enode.Node) error {
 func nodeFromConn(pubkey *ecdsa.PublicKey, conn net.Conn) *enode.Node {
 	var ip net.IP
 	var port int
-	if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
-		ip = tcp.IP
-		port = tcp.Port
+	switch addr := conn.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+		port = addr.Port
+	case *net.UDPAddr:
+		// QUIC connection.
+		ip = addr.IP
+		port = addr.Port
 	}
 	return enode.NewV4(pubkey, ip, port, port)
 }
@@ -1045,6 +1136,9 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.reputation = srv.reputation
+	srv.reputation.track(c.node.ID())
+	p.firewall = &srv.firewall
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
@@ -1095,10 +1189,12 @@ type NodeInfo struct {
 	ENR   string `json:"enr"`   // Ethereum Node Record
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
-		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
-		Listener  int `json:"listener"`  // TCP listening port for RLPx
+		Discovery int `json:"discovery"`      // UDP listening port for discovery protocol
+		Listener  int `json:"listener"`       // TCP listening port for RLPx
+		QUIC      int `json:"quic,omitempty"` // UDP listening port for RLPx over QUIC
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
+	NAT        *NATInfo               `json:"nat,omitempty"` // Port mapping status
 	Protocols  map[string]interface{} `json:"protocols"`
 }
 
@@ -1112,10 +1208,14 @@ func (srv *Server) NodeInfo() *NodeInfo {
 		ID:         node.ID().String(),
 		IP:         node.IPAddr().String(),
 		ListenAddr: srv.ListenAddr,
+		NAT:        srv.natStatus(),
 		Protocols:  make(map[string]interface{}),
 	}
 	info.Ports.Discovery = node.UDP()
 	info.Ports.Listener = node.TCP()
+	if quic, ok := node.QUICEndpoint(); ok {
+		info.Ports.QUIC = int(quic.Port())
+	}
 	info.ENR = node.String()
 
 	// Gather all the running protocol infos (only once per protocol type)
@@ -0,0 +1,136 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/firewall"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// setupFirewall loads the firewall policy file.
+func (srv *Server) setupFirewall() error {
+	if srv.FirewallFile == "" {
+		return nil
+	}
+	fw, err := firewall.Load(srv.FirewallFile)
+	if err != nil {
+		return err
+	}
+	srv.firewall.Store(fw)
+	return nil
+}
+
+// Firewall returns the active firewall, or nil if there is none.
+func (srv *Server) Firewall() *firewall.Firewall {
+	return srv.firewall.Load()
+}
+
+// SetFirewall replaces the firewall. Passing nil removes the firewall. Connected
+// peers which are rejected by the new firewall are disconnected.
+func (srv *Server) SetFirewall(fw *firewall.Firewall) {
+	srv.firewall.Store(fw)
+	if fw == nil {
+		return
+	}
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		for _, p := range peers {
+			hash := p.forkHash.Load()
+			_, eth := p.running["eth"]
+			if err := fw.Check(firewallConn(p.rw, hash, hash != nil || !eth)); err != nil {
+				p.log.Debug("Disconnecting peer rejected by firewall", "err", err)
+				p.Disconnect(DiscUselessPeer)
+			}
+		}
+	})
+}
+
+// ReloadFirewall reads the firewall policy file again and applies it.
+func (srv *Server) ReloadFirewall() error {
+	if srv.FirewallFile == "" {
+		return errors.New("no firewall policy file configured")
+	}
+	fw, err := firewall.Load(srv.FirewallFile)
+	if err != nil {
+		return err
+	}
+	srv.SetFirewall(fw)
+	srv.log.Info("Reloaded firewall policy", "file", srv.FirewallFile)
+	return nil
+}
+
+// checkFirewall checks a connection against the firewall.
+func (srv *Server) checkFirewall(c *firewall.Conn) error {
+	if fw := srv.firewall.Load(); fw != nil {
+		return fw.Check(c)
+	}
+	return nil
+}
+
+// checkFirewallDial checks a dial candidate against the firewall.
+func (srv *Server) checkFirewallDial(n *enode.Node) error {
+	return srv.checkFirewall(&firewall.Conn{IP: n.IPAddr(), Record: n.Record()})
+}
+
+// firewallConn returns the firewall view of a connection which has completed
+// the protocol handshake. The view is final if the fork hash of the peer is
+// known or won't be announced, as nothing else is learnt about the peer later.
+func firewallConn(c *conn, forkHash *[4]byte, final bool) *firewall.Conn {
+	fc := &firewall.Conn{
+		Inbound:      c.is(inboundConn),
+		IP:           netutil.AddrAddr(c.fd.RemoteAddr()),
+		HasHandshake: true,
+		Name:         c.name,
+		ForkHash:     forkHash,
+		Final:        final,
+	}
+	if !fc.Inbound {
+		// Node records are only known for dialed nodes. For inbound connections,
+		// c.node is created from the remote address.
+		fc.Record = c.node.Record()
+	}
+	for _, cap := range c.caps {
+		fc.Caps = append(fc.Caps, firewall.Cap{Name: cap.Name, Version: cap.Version})
+	}
+	return fc
+}
+
+// announcesForkID reports whether the peer of a connection will announce its fork
+// hash, which happens in the handshake of the eth protocol.
+func announcesForkID(protocols []Protocol, caps []Cap) bool {
+	for _, proto := range protocols {
+		if proto.Name == "eth" && countMatchingProtocols([]Protocol{proto}, caps) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckForkID checks the fork hash announced by the peer in the eth protocol
+// handshake against the firewall of the server.
+func (p *Peer) CheckForkID(hash [4]byte) error {
+	if p == nil || p.firewall == nil {
+		return nil
+	}
+	p.forkHash.Store(&hash)
+	if fw := p.firewall.Load(); fw != nil {
+		return fw.Check(firewallConn(p.rw, &hash, true))
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/firewall"
+)
+
+func TestServerFirewall(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "firewall.json")
+	policy := `{"rules": [{"action": "deny", "client": "^bad"}]}`
+	if err := os.WriteFile(file, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{
+		Config: Config{
+			PrivateKey:   newkey(),
+			MaxPeers:     10,
+			NoDial:       true,
+			NoDiscovery:  true,
+			FirewallFile: file,
+			Logger:       testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID, name string) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&newkey().PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, name: name, cont: make(chan error)}
+	}
+
+	// Connections are checked after the protocol handshake.
+	if err := srv.checkpoint(newconn(randomID(), "bad/v1"), srv.checkpointAddPeer); err != DiscUselessPeer {
+		t.Fatal("wrong error for denied client:", err)
+	}
+	good, other := randomID(), randomID()
+	if err := srv.checkpoint(newconn(good, "good/v1"), srv.checkpointAddPeer); err != nil {
+		t.Fatal("unexpected error for allowed client:", err)
+	}
+	if err := srv.checkpoint(newconn(other, "other/v1"), srv.checkpointAddPeer); err != nil {
+		t.Fatal("unexpected error for allowed client:", err)
+	}
+
+	// Replacing the firewall disconnects peers which are no longer allowed.
+	fw, err := firewall.New(firewall.Policy{Rules: []firewall.Rule{{Action: firewall.Deny, Client: "^good"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetFirewall(fw)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		peers := srv.Peers()
+		if len(peers) == 1 && peers[0].ID() == other {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("denied peer not disconnected, have %d peers", len(peers))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Reloading restores the policy from the file.
+	if err := srv.ReloadFirewall(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Firewall().Policy().Rules[0].Client != "^bad" {
+		t.Fatal("policy not reloaded")
+	}
+}
+
+// This test checks that inbound peers are not allowed by rules depending on
+// information they never provide.
+func TestServerFirewallInboundDefaultDeny(t *testing.T) {
+	run := func(p *Peer, rw MsgReadWriter) error {
+		_, err := rw.ReadMsg()
+		return err
+	}
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			Protocols:   []Protocol{{Name: "eth", Version: 68, Run: run}, {Name: "snap", Version: 1, Run: run}},
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	fw, err := firewall.New(firewall.Policy{
+		Rules: []firewall.Rule{
+			{Action: firewall.Allow, ENR: []string{"snap"}},
+			{Action: firewall.Allow, ForkID: []string{"01020304"}},
+		},
+		Default: firewall.Deny,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetFirewall(fw)
+
+	newconn := func(caps []Cap) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&newkey().PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), randomID())
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, caps: caps, cont: make(chan error)}
+	}
+	// Peers not running eth never announce their fork ID.
+	if err := srv.checkpoint(newconn([]Cap{{"snap", 1}}), srv.checkpointAddPeer); err != DiscUselessPeer {
+		t.Fatal("wrong error for peer without fork ID:", err)
+	}
+	// Peers running eth are checked again in the eth handshake.
+	c := newconn([]Cap{{"eth", 68}})
+	if err := srv.checkpoint(c, srv.checkpointAddPeer); err != nil {
+		t.Fatal("unexpected error for peer running eth:", err)
+	}
+	p := newPeer(log.Root(), c, nil)
+	p.firewall = &srv.firewall
+	if err := p.CheckForkID([4]byte{0xde, 0xad, 0xbe, 0xef}); err == nil {
+		t.Fatal("unlisted fork ID accepted")
+	}
+	if err := p.CheckForkID([4]byte{1, 2, 3, 4}); err != nil {
+		t.Fatal("listed fork ID rejected:", err)
+	}
+}
+
+func TestServerFirewallDial(t *testing.T) {
+	srv := &Server{Config: Config{Logger: testlog.Logger(t, log.LvlTrace)}}
+	fw, err := firewall.New(firewall.Policy{
+		Rules: []firewall.Rule{{Action: firewall.Deny, Direction: firewall.Outbound, Net: []string{"10.0.0.0/8"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.firewall.Store(fw)
+
+	if err := srv.checkFirewallDial(newNode(randomID(), "10.0.0.1:30303")); err == nil {
+		t.Fatal("dial to denied network allowed")
+	}
+	if err := srv.checkFirewallDial(newNode(randomID(), "127.0.0.1:30303")); err != nil {
+		t.Fatal("dial to allowed network denied:", err)
+	}
+	// Inbound connections aren't affected by the outbound rule.
+	if err := srv.checkFirewall(&firewall.Conn{Inbound: true, IP: netip.MustParseAddr("10.0.0.1")}); err != nil {
+		t.Fatal("inbound connection denied:", err)
+	}
+}
+
+func TestPeerCheckForkID(t *testing.T) {
+	fw, err := firewall.New(firewall.Policy{Rules: []firewall.Rule{{Action: firewall.Deny, ForkID: []string{"0xdeadbeef"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ptr atomic.Pointer[firewall.Firewall]
+	ptr.Store(fw)
+
+	fd, _ := net.Pipe()
+	defer fd.Close()
+	c := &conn{fd: fd, flags: inboundConn, node: enode.SignNull(new(enr.Record), randomID())}
+	p := newPeer(log.Root(), c, nil)
+	p.firewall = &ptr
+	if err := p.CheckForkID([4]byte{0xde, 0xad, 0xbe, 0xef}); err == nil {
+		t.Fatal("denied fork ID accepted")
+	}
+	if err := p.CheckForkID([4]byte{1, 2, 3, 4}); err != nil {
+		t.Fatal("allowed fork ID rejected:", err)
+	}
+	// Peers without a server are not checked.
+	if err := NewPeer(randomID(), "test", nil).CheckForkID([4]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
@@ -18,6 +18,8 @@ package p2p
 
 import (
 	"net"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -41,16 +43,47 @@ type portMapping struct {
 	// for use by the portMappingLoop goroutine:
 	extPort  int // the mapped port returned by the NAT interface
 	nextTime mclock.AbsTime
+	expires  mclock.AbsTime // expiry of the current mapping
+	err      error          // error of the last mapping attempt
+	pinhole  net.IP         // IPv6 address of the open firewall pinhole
+}
+
+// portMapRefreshTime returns the time after which a mapping with the given granted
+// lifetime is refreshed. Gateways may grant shorter lifetimes than requested, the
+// mapping is refreshed early enough to not expire in that case. A zero lifetime
+// means the gateway didn't report it.
+func portMapRefreshTime(lifetime time.Duration) time.Duration {
+	if lifetime <= 0 {
+		lifetime = portMapDuration
+	}
+	return min(portMapRefreshInterval, lifetime*portMapRefreshInterval/portMapDuration)
+}
+
+// NATInfo describes the state of the NAT port mapper.
+type NATInfo struct {
+	Mechanism  string            `json:"mechanism"`
+	ExternalIP string            `json:"externalIP,omitempty"`
+	Mappings   []*NATMappingInfo `json:"mappings"`
+}
+
+// NATMappingInfo describes a single port mapping.
+type NATMappingInfo struct {
+	Protocol     string     `json:"protocol"`
+	InternalPort int        `json:"internalPort"`
+	ExternalPort int        `json:"externalPort,omitempty"` // zero if the port is not mapped
+	Pinhole      string     `json:"pinhole,omitempty"`      // IPv6 address of the firewall pinhole
+	Expires      *time.Time `json:"expires,omitempty"`
+	Error        string     `json:"error,omitempty"`
 }
 
 // setupPortMapping starts the port mapping loop if necessary.
 // Note: this needs to be called after the LocalNode instance has been set on the server.
 func (srv *Server) setupPortMapping() {
-	// portMappingRegister will receive up to two values: one for the TCP port if
-	// listening is enabled, and one more for enabling UDP port mapping if discovery is
-	// enabled. We make it buffered to avoid blocking setup while a mapping request is in
-	// progress.
-	srv.portMappingRegister = make(chan *portMapping, 2)
+	// portMappingRegister will receive up to three values: one for the TCP port if
+	// listening is enabled, one for enabling UDP port mapping if discovery is
+	// enabled, and one more for the QUIC port if QUIC is enabled. We make it
+	// buffered to avoid blocking setup while a mapping request is in progress.
+	srv.portMappingRegister = make(chan *portMapping, 3)
 
 	switch srv.NAT.(type) {
 	case nil:
@@ -62,6 +95,7 @@ func (srv *Server) setupPortMapping() {
 		// ExtIP doesn't block, set the IP right away.
 		ip, _ := srv.NAT.ExternalIP()
 		srv.localnode.SetStaticIP(ip)
+		srv.setNATInfo(&NATInfo{Mechanism: srv.NAT.String(), ExternalIP: ip.String(), Mappings: []*NATMappingInfo{}})
 		srv.loopWG.Add(1)
 		go srv.consumePortMappingRequests()
 
@@ -91,7 +125,7 @@ func (srv *Server) portMappingLoop() {
 	}
 
 	var (
-		mappings  = make(map[string]*portMapping, 2)
+		mappings  = make(map[string]*portMapping, 3)
 		refresh   = mclock.NewAlarm(srv.clock)
 		extip     = mclock.NewAlarm(srv.clock)
 		lastExtIP net.IP
@@ -106,6 +140,10 @@ func (srv *Server) portMappingLoop() {
 				log.Debug("Deleting port mapping")
 				srv.NAT.DeleteMapping(m.protocol, m.extPort, m.port)
 			}
+			if m.pinhole != nil {
+				log.Debug("Deleting firewall pinhole", "proto", m.protocol, "ip", m.pinhole, "port", m.port)
+				srv.NAT.(nat.Pinholer).DeletePinhole(m.protocol, m.pinhole, m.port)
+			}
 		}
 	}()
 
@@ -114,6 +152,7 @@ func (srv *Server) portMappingLoop() {
 		for _, m := range mappings {
 			refresh.Schedule(m.nextTime)
 		}
+		srv.publishNATInfo(mappings, lastExtIP)
 
 		select {
 		case <-srv.quit:
@@ -141,7 +180,7 @@ func (srv *Server) portMappingLoop() {
 			if m.protocol != "TCP" && m.protocol != "UDP" {
 				panic("unknown NAT protocol name: " + m.protocol)
 			}
-			mappings[m.protocol] = m
+			mappings[m.name] = m
 			m.nextTime = srv.clock.Now()
 
 		case <-refresh.C():
@@ -156,17 +195,38 @@ func (srv *Server) portMappingLoop() {
 				}
 				log := newLogger(m.protocol, external, m.port)
 
+				pinholeLifetime := srv.refreshPinhole(m)
+
 				log.Trace("Attempting port mapping")
-				p, err := srv.NAT.AddMapping(m.protocol, external, m.port, m.name, portMapDuration)
+				p, lifetime, err := srv.NAT.AddMapping(m.protocol, external, m.port, m.name, portMapDuration)
 				if err != nil {
 					log.Debug("Couldn't add port mapping", "err", err)
 					m.extPort = 0
+					m.err = err
+					m.expires = 0
 					m.nextTime = srv.clock.Now().Add(portMapRetryInterval)
+					if m.pinhole != nil {
+						m.nextTime = min(m.nextTime, srv.clock.Now().Add(portMapRefreshTime(pinholeLifetime)))
+					}
 					continue
 				}
-				// It was mapped!
+				// It was mapped! The refresh is based on the lifetime granted by
+				// the gateway.
+				if lifetime <= 0 {
+					lifetime = portMapDuration
+				}
 				m.extPort = int(p)
-				m.nextTime = srv.clock.Now().Add(portMapRefreshInterval)
+				m.err = nil
+				m.expires = srv.clock.Now().Add(lifetime)
+				m.nextTime = srv.clock.Now().Add(portMapRefreshTime(lifetime))
+				if m.pinhole != nil {
+					m.nextTime = min(m.nextTime, srv.clock.Now().Add(portMapRefreshTime(pinholeLifetime)))
+				}
+				if lastExtIP == nil {
+					// Some mechanisms only learn the external IP by creating a
+					// mapping, so query it again right away.
+					extip.Schedule(srv.clock.Now())
+				}
 				if external != m.extPort {
 					log = newLogger(m.protocol, m.extPort, m.port)
 					log.Info("NAT mapped alternative port")
@@ -175,13 +235,92 @@ func (srv *Server) portMappingLoop() {
 				}
 
 				// Update port in local ENR.
-				switch m.protocol {
-				case "TCP":
+				switch {
+				case m.protocol == "TCP":
 					srv.localnode.Set(enr.TCP(m.extPort))
-				case "UDP":
+				case m.name == quicPortMappingName:
+					srv.localnode.Set(enr.QUIC(m.extPort))
+				default:
 					srv.localnode.SetFallbackUDP(m.extPort)
 				}
 			}
 		}
 	}
 }
+
+// refreshPinhole opens or refreshes an IPv6 firewall pinhole for the mapped port if
+// the NAT interface supports it and the local node has an IPv6 address. It returns
+// the lifetime of the pinhole granted by the gateway.
+func (srv *Server) refreshPinhole(m *portMapping) time.Duration {
+	p, ok := srv.NAT.(nat.Pinholer)
+	if !ok {
+		return 0
+	}
+	var ip6 enr.IPv6
+	if srv.localnode.Node().Load(&ip6) != nil || !net.IP(ip6).IsGlobalUnicast() {
+		return 0
+	}
+	ip := net.IP(ip6)
+	if m.pinhole != nil && !m.pinhole.Equal(ip) {
+		p.DeletePinhole(m.protocol, m.pinhole, m.port)
+		m.pinhole = nil
+	}
+	lifetime, err := p.AddPinhole(m.protocol, ip, m.port, portMapDuration)
+	if err != nil {
+		log.Debug("Couldn't open firewall pinhole", "proto", m.protocol, "ip", ip, "port", m.port, "err", err)
+		m.pinhole = nil
+		return 0
+	}
+	if m.pinhole == nil {
+		log.Info("Opened firewall pinhole", "proto", m.protocol, "ip", ip, "port", m.port)
+	}
+	m.pinhole = ip
+	return lifetime
+}
+
+// publishNATInfo updates the port mapping status reported by NodeInfo.
+func (srv *Server) publishNATInfo(mappings map[string]*portMapping, extip net.IP) {
+	info := &NATInfo{Mechanism: srv.NAT.String(), Mappings: []*NATMappingInfo{}}
+	if extip != nil {
+		info.ExternalIP = extip.String()
+	}
+	for _, m := range mappings {
+		mi := &NATMappingInfo{
+			Protocol:     m.protocol,
+			InternalPort: m.port,
+			ExternalPort: m.extPort,
+		}
+		if m.expires != 0 {
+			expires := time.Now().Add(time.Duration(m.expires - srv.clock.Now()))
+			mi.Expires = &expires
+		}
+		if m.pinhole != nil {
+			mi.Pinhole = m.pinhole.String()
+		}
+		if m.err != nil {
+			mi.Error = m.err.Error()
+		}
+		info.Mappings = append(info.Mappings, mi)
+	}
+	slices.SortFunc(info.Mappings, func(a, b *NATMappingInfo) int {
+		if c := strings.Compare(a.Protocol, b.Protocol); c != 0 {
+			return c
+		}
+		return a.InternalPort - b.InternalPort
+	})
+	srv.setNATInfo(info)
+}
+
+func (srv *Server) setNATInfo(info *NATInfo) {
+	srv.natInfoLock.Lock()
+	defer srv.natInfoLock.Unlock()
+	srv.natInfo = info
+}
+
+// natStatus returns the current port mapping status, or nil if no NAT
+// mechanism is configured.
+func (srv *Server) natStatus() *NATInfo {
+	srv.natInfoLock.Lock()
+	defer srv.natInfoLock.Unlock()
+	return srv.natInfo
+}
@@ -48,12 +48,13 @@ func TestServerPortMapping(t *testing.T) {
 	defer srv.Stop()
 
 	// Wait for the port mapping to be registered. Synchronization with the port mapping
-	// goroutine works like this: For each iteration, we allow other goroutines to run and
-	// also advance the virtual clock by 1 second. Waiting stops when the NAT interface
-	// has received some requests, or when the clock reaches a timeout.
+	// goroutine works like this: For each iteration, we give the goroutine some time to
+	// send its requests and then advance the virtual clock by 1 second. Waiting stops
+	// when the NAT interface has received some requests, or when the clock reaches a
+	// timeout.
 	deadline := clock.Now().Add(portMapRefreshInterval)
-	for clock.Now() < deadline && mockNAT.mapRequests.Load() < 2 {
-		time.Sleep(10 * time.Millisecond)
+	mapped := func() bool { return mockNAT.mapRequests.Load() >= 2 }
+	for clock.Now() < deadline && !waitFor(100*time.Millisecond, mapped) {
 		clock.Run(1 * time.Second)
 	}
 
@@ -74,18 +75,112 @@ func TestServerPortMapping(t *testing.T) {
 	if enr.UDP() != 30000 {
 		t.Error("wrong UDP port in ENR:", enr.UDP())
 	}
+
+	// Check the mapping status reported in NodeInfo. The status is published by
+	// the port mapping goroutine after the requests, so wait for it.
+	waitFor(5*time.Second, func() bool {
+		info := srv.NodeInfo().NAT
+		return info != nil && info.ExternalIP != "" && len(info.Mappings) == 2 &&
+			info.Mappings[0].Expires != nil && info.Mappings[1].Expires != nil
+	})
+	info := srv.NodeInfo().NAT
+	if info == nil {
+		t.Fatal("no NAT info in NodeInfo")
+	}
+	if info.ExternalIP != "192.0.2.0" {
+		t.Error("wrong external IP in NodeInfo:", info.ExternalIP)
+	}
+	if len(info.Mappings) != 2 {
+		t.Fatal("wrong number of mappings in NodeInfo:", len(info.Mappings))
+	}
+	for _, m := range info.Mappings {
+		if m.ExternalPort != 30000 {
+			t.Errorf("wrong external port for %s mapping: %d", m.Protocol, m.ExternalPort)
+		}
+		if m.Expires == nil {
+			t.Errorf("no expiry for %s mapping", m.Protocol)
+		}
+	}
+}
+
+// This test checks that mappings are refreshed before the lifetime granted by the
+// gateway ends, if it is shorter than the requested one.
+func TestServerPortMappingLifetime(t *testing.T) {
+	clock := new(mclock.Simulated)
+	mockNAT := &mockNAT{mappedPort: 30000, lifetime: time.Minute}
+	srv := Server{
+		Config: Config{
+			PrivateKey: newkey(),
+			NoDial:     true,
+			ListenAddr: ":0",
+			NAT:        mockNAT,
+			Logger:     testlog.Logger(t, log.LvlTrace),
+			clock:      clock,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	deadline := clock.Now().Add(portMapRefreshInterval)
+	mapped := func() bool { return mockNAT.mapRequests.Load() >= 2 }
+	for clock.Now() < deadline && !waitFor(100*time.Millisecond, mapped) {
+		clock.Run(1 * time.Second)
+	}
+	if !mapped() {
+		t.Fatal("ports not mapped")
+	}
+	// The reported expiry is based on the granted lifetime.
+	waitFor(5*time.Second, func() bool {
+		info := srv.NodeInfo().NAT
+		return info != nil && len(info.Mappings) == 2 && info.Mappings[0].Expires != nil
+	})
+	info := srv.NodeInfo().NAT
+	if info == nil || len(info.Mappings) != 2 || info.Mappings[0].Expires == nil {
+		t.Fatal("no mapping status in NodeInfo")
+	}
+	if left := time.Until(*info.Mappings[0].Expires); left > time.Minute {
+		t.Fatal("expiry beyond the granted lifetime:", left)
+	}
+	// The mappings must be refreshed before they expire.
+	start := clock.Now()
+	refreshed := func() bool { return mockNAT.mapRequests.Load() >= 4 }
+	for clock.Now() < start.Add(time.Minute) && !waitFor(100*time.Millisecond, refreshed) {
+		clock.Run(1 * time.Second)
+	}
+	if !refreshed() {
+		t.Fatal("mappings not refreshed within the granted lifetime")
+	}
+}
+
+// waitFor polls the condition until it holds or the timeout expires, and reports
+// whether it holds.
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
 }
 
 type mockNAT struct {
 	mappedPort    uint16
+	lifetime      time.Duration // granted lifetime, the requested one if zero
 	mapRequests   atomic.Int32
 	unmapRequests atomic.Int32
 	ipRequests    atomic.Int32
 }
 
-func (m *mockNAT) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+func (m *mockNAT) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, time.Duration, error) {
 	m.mapRequests.Add(1)
-	return m.mappedPort, nil
+	if m.lifetime != 0 {
+		lifetime = m.lifetime
+	}
+	return m.mappedPort, lifetime, nil
 }
 
 func (m *mockNAT) DeleteMapping(protocol string, extport, intport int) error {
@@ -0,0 +1,241 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/quic-go/quic-go"
+)
+
+// This file implements the experimental QUIC transport. A devp2p session over QUIC
+// uses a single bidirectional stream, on top of which the regular RLPx handshake and
+// framing is performed. Peer authentication is provided by RLPx, so the TLS
+// certificates used by QUIC are ephemeral and not verified.
+
+const (
+	quicALPN             = "devp2p"
+	quicPortMappingName  = "ethereum p2p quic"
+	quicHandshakeTimeout = 5 * time.Second
+	quicKeepAlive        = 15 * time.Second
+	quicAcceptQueue      = 16
+)
+
+var errQUICListenerClosed = errors.New("QUIC listener closed")
+
+func quicConfig() *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout: quicHandshakeTimeout,
+		MaxIdleTimeout:       frameReadTimeout,
+		KeepAlivePeriod:      quicKeepAlive,
+	}
+}
+
+// quicTLSConfig creates a TLS configuration with an ephemeral self-signed certificate.
+func quicTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		NextProtos:         []string{quicALPN},
+		InsecureSkipVerify: true, // peers are authenticated by the RLPx handshake
+		MinVersion:         tls.VersionTLS13,
+	}, nil
+}
+
+// setupQUIC starts the QUIC listener.
+func (srv *Server) setupQUIC() error {
+	addr, err := net.ResolveUDPAddr("udp", srv.QUICListenAddr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	tlsConf, err := quicTLSConfig()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	tr := &quic.Transport{Conn: conn}
+	ql, err := tr.Listen(tlsConf, quicConfig())
+	if err != nil {
+		tr.Close()
+		return err
+	}
+	srv.quicTransport = tr
+	srv.quicTLS = tlsConf
+	srv.quicListener = newQUICListener(ql)
+	srv.QUICListenAddr = conn.LocalAddr().String()
+
+	// Update the local node record and map the QUIC port if NAT is configured.
+	laddr := conn.LocalAddr().(*net.UDPAddr)
+	srv.localnode.Set(enr.QUIC(laddr.Port))
+	if !laddr.IP.IsLoopback() && !laddr.IP.IsPrivate() {
+		srv.portMappingRegister <- &portMapping{
+			protocol: "UDP",
+			name:     quicPortMappingName,
+			port:     laddr.Port,
+		}
+	}
+
+	srv.loopWG.Add(1)
+	go srv.listenLoop(srv.quicListener)
+	return nil
+}
+
+// quicListener adapts a QUIC listener to net.Listener. Connections are returned by
+// Accept once the remote side has opened its stream.
+type quicListener struct {
+	l       *quic.Listener
+	ctx     context.Context
+	cancel  context.CancelFunc
+	conns   chan net.Conn
+	closeWG sync.WaitGroup
+}
+
+func newQUICListener(l *quic.Listener) *quicListener {
+	ctx, cancel := context.WithCancel(context.Background())
+	ql := &quicListener{l: l, ctx: ctx, cancel: cancel, conns: make(chan net.Conn, quicAcceptQueue)}
+	ql.closeWG.Add(1)
+	go ql.acceptLoop()
+	return ql
+}
+
+func (ql *quicListener) acceptLoop() {
+	defer ql.closeWG.Done()
+	for {
+		qc, err := ql.l.Accept(ql.ctx)
+		if err != nil {
+			return
+		}
+		ql.closeWG.Add(1)
+		go func() {
+			defer ql.closeWG.Done()
+			ctx, cancel := context.WithTimeout(ql.ctx, quicHandshakeTimeout)
+			defer cancel()
+			stream, err := qc.AcceptStream(ctx)
+			if err != nil {
+				qc.CloseWithError(0, "")
+				return
+			}
+			select {
+			case ql.conns <- &quicConn{Stream: stream, conn: qc}:
+			case <-ql.ctx.Done():
+				qc.CloseWithError(0, "")
+			}
+		}()
+	}
+}
+
+// Accept implements net.Listener.
+func (ql *quicListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-ql.conns:
+		return c, nil
+	case <-ql.ctx.Done():
+		return nil, errQUICListenerClosed
+	}
+}
+
+// Close implements net.Listener.
+func (ql *quicListener) Close() error {
+	ql.cancel()
+	err := ql.l.Close()
+	ql.closeWG.Wait()
+	for {
+		select {
+		case c := <-ql.conns:
+			c.Close()
+		default:
+			return err
+		}
+	}
+}
+
+// Addr implements net.Listener.
+func (ql *quicListener) Addr() net.Addr {
+	return ql.l.Addr()
+}
+
+// quicConn is a devp2p session stream over QUIC. It implements net.Conn.
+type quicConn struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// Close closes the stream and the underlying QUIC connection.
+func (c *quicConn) Close() error {
+	c.Stream.CancelRead(0)
+	c.Stream.Close()
+	return c.conn.CloseWithError(0, "")
+}
+
+// quicDialer dials nodes over QUIC when they announce a QUIC endpoint, and falls
+// back to the given dialer otherwise.
+type quicDialer struct {
+	tr       *quic.Transport
+	tls      *tls.Config
+	fallback NodeDialer
+}
+
+func (d quicDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	addr, ok := dest.QUICEndpoint()
+	if !ok {
+		return d.fallback.Dial(ctx, dest)
+	}
+	qc, err := d.tr.Dial(ctx, net.UDPAddrFromAddrPort(addr), d.tls, quicConfig())
+	if err != nil {
+		if _, hasTCP := dest.TCPEndpoint(); hasTCP {
+			return d.fallback.Dial(ctx, dest)
+		}
+		return nil, err
+	}
+	stream, err := qc.OpenStreamSync(ctx)
+	if err != nil {
+		qc.CloseWithError(0, "")
+		return nil, err
+	}
+	return &quicConn{Stream: stream, conn: qc}, nil
+}
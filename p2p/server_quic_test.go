@@ -0,0 +1,111 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// This test checks that two servers can establish a devp2p session over QUIC
+// and exchange protocol messages.
+func TestServerQUIC(t *testing.T) {
+	var (
+		received = make(chan uint64, 1)
+		proto    = Protocol{
+			Name:    "test",
+			Version: 1,
+			Length:  1,
+			Run: func(p *Peer, rw MsgReadWriter) error {
+				if err := Send(rw, 0, uint64(42)); err != nil {
+					return err
+				}
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				var v uint64
+				if err := msg.Decode(&v); err != nil {
+					return err
+				}
+				select {
+				case received <- v:
+				default:
+				}
+				<-p.closed
+				return nil
+			},
+		}
+	)
+	srv1 := &Server{Config: Config{
+		PrivateKey:     newkey(),
+		MaxPeers:       1,
+		NoDiscovery:    true,
+		QUICListenAddr: "127.0.0.1:0",
+		Protocols:      []Protocol{proto},
+		Logger:         testlog.Logger(t, log.LvlTrace).New("server", "1"),
+	}}
+	srv2 := &Server{Config: Config{
+		PrivateKey:     newkey(),
+		MaxPeers:       1,
+		NoDiscovery:    true,
+		NoDial:         true,
+		QUICListenAddr: "127.0.0.1:0",
+		Protocols:      []Protocol{proto},
+		Logger:         testlog.Logger(t, log.LvlTrace).New("server", "2"),
+	}}
+	if err := srv1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv1.Stop()
+	if err := srv2.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv2.Stop()
+
+	// srv2 only announces a QUIC port.
+	node := srv2.Self()
+	if node.TCP() != 0 {
+		t.Fatal("node has TCP port", node.TCP())
+	}
+	if _, ok := node.QUICEndpoint(); !ok {
+		t.Fatal("node has no QUIC endpoint")
+	}
+	if srv2.NodeInfo().Ports.QUIC == 0 {
+		t.Fatal("QUIC port missing in NodeInfo")
+	}
+	if !syncAddPeer(srv1, node) {
+		t.Fatal("peer not connected")
+	}
+	peer := srv1.Peers()[0]
+	if _, ok := peer.RemoteAddr().(*net.UDPAddr); !ok {
+		t.Fatalf("peer has non-QUIC remote address %v", peer.RemoteAddr())
+	}
+
+	select {
+	case v := <-received:
+		if v != 42 {
+			t.Fatal("wrong message received:", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -111,6 +112,110 @@ func (t *StateTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
 	return t.trie.Prove(key, proofDb)
 }
 
+// ProveMulti constructs a merkle multiproof for a set of keys. The result
+// contains all encoded nodes on the paths to the values at the keys, nodes shared
+// by several paths are included only once. The trie is traversed once for all
+// keys, so every node is resolved only once too.
+//
+// The nodes proving the absence of the keys not contained in the trie are
+// included the same way as in Prove.
+func (t *Trie) ProveMulti(keys [][]byte, proofDb ethdb.KeyValueWriter) error {
+	// Short circuit if the trie is already committed and not usable.
+	if t.committed {
+		return ErrCommitted
+	}
+	hexkeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		hexkeys = append(hexkeys, keybytesToHex(key))
+	}
+	slices.SortFunc(hexkeys, bytes.Compare)
+	hexkeys = slices.CompactFunc(hexkeys, bytes.Equal)
+
+	hasher := newHasher(false)
+	defer returnHasherToPool(hasher)
+
+	return t.proveMulti(t.root, nil, hexkeys, true, hasher, proofDb)
+}
+
+// proveMulti writes the given node and all nodes below it on the paths to the
+// given keys into the proof. The keys are sorted and relative to the node.
+func (t *Trie) proveMulti(tn node, prefix []byte, keys [][]byte, root bool, hasher *hasher, proofDb ethdb.KeyValueWriter) error {
+	// Drop the keys ending at this node, nothing below it is needed for them.
+	keys = slices.DeleteFunc(keys, func(key []byte) bool { return len(key) == 0 })
+	if len(keys) == 0 || tn == nil {
+		return nil
+	}
+	if hash, ok := tn.(hashNode); ok {
+		blob, err := t.reader.node(prefix, common.BytesToHash(hash))
+		if err != nil {
+			log.Error("Unhandled trie error in Trie.ProveMulti", "err", err)
+			return err
+		}
+		tn = mustDecodeNodeUnsafe(hash, blob)
+	}
+	switch n := tn.(type) {
+	case *shortNode:
+		var rest [][]byte
+		for _, key := range keys {
+			if len(key) >= len(n.Key) && bytes.Equal(n.Key, key[:len(n.Key)]) {
+				rest = append(rest, key[len(n.Key):])
+			}
+		}
+		writeProofNode(n, root, hasher, proofDb)
+		return t.proveMulti(n.Val, append(common.CopyBytes(prefix), n.Key...), rest, false, hasher, proofDb)
+
+	case *fullNode:
+		writeProofNode(n, root, hasher, proofDb)
+
+		// The keys are sorted, so the ones continuing at the same child are
+		// adjacent to each other.
+		for start := 0; start < len(keys); {
+			end, nibble := start+1, keys[start][0]
+			for end < len(keys) && keys[end][0] == nibble {
+				end++
+			}
+			rest := make([][]byte, 0, end-start)
+			for _, key := range keys[start:end] {
+				rest = append(rest, key[1:])
+			}
+			if err := t.proveMulti(n.Children[nibble], append(common.CopyBytes(prefix), nibble), rest, false, hasher, proofDb); err != nil {
+				return err
+			}
+			start = end
+		}
+		return nil
+
+	case valueNode:
+		return nil
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
+	}
+}
+
+// writeProofNode writes a node into the proof if its database encoding is a
+// hash, or if it's the root node.
+func writeProofNode(n node, root bool, hasher *hasher, proofDb ethdb.KeyValueWriter) {
+	n, hn := hasher.proofHash(n)
+	if hash, ok := hn.(hashNode); ok || root {
+		enc := nodeToBytes(n)
+		if !ok {
+			hash = hasher.hashData(enc)
+		}
+		proofDb.Put(hash, enc)
+	}
+}
+
+// ProveMulti constructs a merkle multiproof for a set of keys. The result
+// contains all encoded nodes on the paths to the values at the keys, nodes shared
+// by several paths are included only once.
+//
+// The nodes proving the absence of the keys not contained in the trie are
+// included the same way as in Prove.
+func (t *StateTrie) ProveMulti(keys [][]byte, proofDb ethdb.KeyValueWriter) error {
+	return t.trie.ProveMulti(keys, proofDb)
+}
+
 // VerifyProof checks merkle proofs. The given proof must contain the value for
 // key in a trie with the given root hash. VerifyProof returns an error if the
 // proof contains invalid trie nodes or the wrong value.
@@ -140,6 +245,59 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb ethdb.KeyValueReader)
 	}
 }
 
+// VerifyMultiProof checks a merkle multiproof of several keys, as created by
+// ProveMulti. The proof nodes must be keyed by their hashes. It returns the
+// values of the keys in a trie with the given root hash in the same order as the
+// keys, nil for the keys the proof shows to be absent. An error is returned if
+// the proof contains invalid trie nodes or lacks some of them.
+func VerifyMultiProof(rootHash common.Hash, keys [][]byte, proofDb ethdb.KeyValueReader) ([][]byte, error) {
+	var (
+		values = make([][]byte, len(keys))
+		nodes  = make(map[common.Hash]node)
+	)
+	resolve := func(hash common.Hash) (node, error) {
+		if n, ok := nodes[hash]; ok {
+			return n, nil
+		}
+		buf, _ := proofDb.Get(hash[:])
+		if buf == nil {
+			return nil, fmt.Errorf("proof node (hash %064x) missing", hash)
+		}
+		n, err := decodeNode(hash[:], buf)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %v", err)
+		}
+		nodes[hash] = n
+		return n, nil
+	}
+	for i, key := range keys {
+		var (
+			hexkey   = keybytesToHex(key)
+			wantHash = rootHash
+		)
+	walk:
+		for {
+			n, err := resolve(wantHash)
+			if err != nil {
+				return nil, fmt.Errorf("key %x: %v", key, err)
+			}
+			keyrest, cld := get(n, hexkey, true)
+			switch cld := cld.(type) {
+			case nil:
+				// The trie doesn't contain the key.
+				break walk
+			case hashNode:
+				hexkey = keyrest
+				copy(wantHash[:], cld)
+			case valueNode:
+				values[i] = cld
+				break walk
+			}
+		}
+	}
+	return values, nil
+}
+
 // proofToPath converts a merkle proof to trie node path. The main purpose of
 // this function is recovering a node path from the merkle proof stream. All
 // necessary nodes will be resolved and leave the remaining as hashnode.
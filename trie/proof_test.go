@@ -165,6 +165,99 @@ func TestMissingKeyProof(t *testing.T) {
 	}
 }
 
+// TestMultiProof tests that a multiproof contains exactly the nodes of the
+// individual proofs of its keys, and that it proves both existent and
+// non-existent keys.
+func TestMultiProof(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root := trie.Hash()
+
+	var (
+		keys   [][]byte
+		values [][]byte
+	)
+	for _, kv := range vals {
+		keys = append(keys, kv.k)
+		values = append(values, kv.v)
+		if len(keys) == 64 {
+			break
+		}
+	}
+	for i := 0; i < 16; i++ {
+		keys = append(keys, randBytes(32))
+		values = append(values, nil)
+	}
+	// Include a duplicate key, it must be proven only once
+	keys = append(keys, keys[0])
+	values = append(values, values[0])
+
+	proof := memorydb.New()
+	if err := trie.ProveMulti(keys, proof); err != nil {
+		t.Fatalf("failed to create multiproof: %v", err)
+	}
+	union := memorydb.New()
+	for _, key := range keys {
+		if err := trie.Prove(key, union); err != nil {
+			t.Fatalf("failed to prove key %x: %v", key, err)
+		}
+	}
+	if proof.Len() != union.Len() {
+		t.Fatalf("proof size mismatch: have %d nodes, want %d", proof.Len(), union.Len())
+	}
+	it := union.NewIterator(nil, nil)
+	for it.Next() {
+		if blob, err := proof.Get(it.Key()); err != nil || !bytes.Equal(blob, it.Value()) {
+			t.Fatalf("proof node %x missing from multiproof", it.Key())
+		}
+	}
+	it.Release()
+
+	have, err := VerifyMultiProof(root, keys, proof)
+	if err != nil {
+		t.Fatalf("failed to verify multiproof: %v", err)
+	}
+	for i := range keys {
+		if !bytes.Equal(have[i], values[i]) {
+			t.Fatalf("value %d mismatch for key %x: have %x, want %x", i, keys[i], have[i], values[i])
+		}
+	}
+	// Any node missing from the proof must be detected
+	it = proof.NewIterator(nil, nil)
+	for it.Next() {
+		partial := memorydb.New()
+		inner := proof.NewIterator(nil, nil)
+		for inner.Next() {
+			if !bytes.Equal(inner.Key(), it.Key()) {
+				partial.Put(inner.Key(), inner.Value())
+			}
+		}
+		inner.Release()
+		if _, err := VerifyMultiProof(root, keys, partial); err == nil {
+			t.Fatalf("missing proof node %x not detected", it.Key())
+		}
+	}
+	it.Release()
+}
+
+// TestMultiProofEmpty tests multiproofs of an empty trie, and without keys.
+func TestMultiProofEmpty(t *testing.T) {
+	trie := NewEmpty(newTestDatabase(rawdb.NewMemoryDatabase(), rawdb.HashScheme))
+	proof := memorydb.New()
+	if err := trie.ProveMulti([][]byte{{0x1}, {0x2}}, proof); err != nil {
+		t.Fatalf("failed to create multiproof: %v", err)
+	}
+	if proof.Len() != 0 {
+		t.Fatalf("proof of empty trie has %d nodes", proof.Len())
+	}
+	trie, _ = randomTrie(100)
+	if err := trie.ProveMulti(nil, proof); err != nil {
+		t.Fatalf("failed to create multiproof: %v", err)
+	}
+	if proof.Len() != 0 {
+		t.Fatalf("proof without keys has %d nodes", proof.Len())
+	}
+}
+
 // TestRangeProof tests normal range proof with both edge proofs
 // as the existent proof. The test cases are generated randomly.
 func TestRangeProof(t *testing.T) {
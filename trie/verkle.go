@@ -256,6 +256,61 @@ func (t *VerkleTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
 	panic("not implemented")
 }
 
+// Proof creates the multiproof of the given keys in the tree, serialized along
+// with the state diff of the keys. The state diff holds the values of the keys
+// in the tree, and their values in the post-state tree if given and different.
+func (t *VerkleTrie) Proof(posttrie *VerkleTrie, keys [][]byte) (*verkle.VerkleProof, verkle.StateDiff, error) {
+	var postroot verkle.VerkleNode
+	if posttrie != nil {
+		postroot = posttrie.root
+	}
+	// The keys are sorted in place, don't mess with the caller's slice.
+	keys = append([][]byte{}, keys...)
+	proof, _, _, _, err := verkle.MakeVerkleMultiProof(t.root, postroot, keys, t.nodeResolver)
+	if err != nil {
+		return nil, nil, err
+	}
+	return verkle.SerializeProof(proof)
+}
+
+// NewVerkleTrieFromProof constructs a partial verkle tree with the given root
+// from a multiproof and its state diff, as created by Proof. The proof is
+// verified against the root. The tree holds only the proven leaves, accessing
+// any other part of it results in a missing node error.
+func NewVerkleTrieFromProof(root common.Hash, vp *verkle.VerkleProof, diff verkle.StateDiff, cache *utils.PointCache) (*VerkleTrie, error) {
+	var rootC verkle.Point
+	if err := rootC.SetBytes(root[:]); err != nil {
+		return nil, fmt.Errorf("invalid root commitment: %w", err)
+	}
+	// A block without any state access has an empty proof
+	if vp == nil && len(diff) == 0 {
+		return &VerkleTrie{
+			root:   verkle.NewStatelessInternal(0, &rootC),
+			cache:  cache,
+			reader: newEmptyReader(),
+		}, nil
+	}
+	if vp == nil {
+		return nil, errors.New("state diff without proof")
+	}
+	proof, err := verkle.DeserializeProof(vp, diff)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proof: %w", err)
+	}
+	node, err := verkle.PreStateTreeFromProof(proof, &rootC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proof: %w", err)
+	}
+	if err := verkle.VerifyVerkleProofWithPreState(proof, node); err != nil {
+		return nil, err
+	}
+	return &VerkleTrie{
+		root:   node,
+		cache:  cache,
+		reader: newEmptyReader(),
+	}, nil
+}
+
 // Copy returns a deep-copied verkle tree.
 func (t *VerkleTrie) Copy() *VerkleTrie {
 	return &VerkleTrie{
@@ -265,6 +320,12 @@ func (t *VerkleTrie) Copy() *VerkleTrie {
 	}
 }
 
+// Witness is not supported by verkle trees, the accessed leaves are proven with
+// a multiproof instead, see Proof.
+func (t *VerkleTrie) Witness() map[string]struct{} {
+	return nil
+}
+
 // IsVerkle indicates if the trie is a Verkle trie.
 func (t *VerkleTrie) IsVerkle() bool {
 	return true
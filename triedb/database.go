@@ -18,6 +18,7 @@ package triedb
 
 import (
 	"errors"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -84,6 +85,8 @@ type Database struct {
 	diskdb    ethdb.Database // Persistent database to store the snapshot
 	preimages *preimageStore // The store for caching preimages
 	backend   backend        // The backend for managing trie nodes
+
+	nodeCache atomic.Pointer[NodeCache] // Node cache shared by the state readers, nil if disabled
 }
 
 // NewDatabase initializes the trie database with default settings, note
@@ -123,13 +126,38 @@ func NewDatabase(diskdb ethdb.Database, config *Config) *Database {
 // Reader returns a reader for accessing all trie nodes with provided state root.
 // An error will be returned if the requested state is not available.
 func (db *Database) Reader(blockRoot common.Hash) (database.Reader, error) {
+	var (
+		reader database.Reader
+		err    error
+	)
 	switch b := db.backend.(type) {
 	case *hashdb.Database:
-		return b.Reader(blockRoot)
+		reader, err = b.Reader(blockRoot)
 	case *pathdb.Database:
-		return b.Reader(blockRoot)
+		reader, err = b.Reader(blockRoot)
+	default:
+		return nil, errors.New("unknown backend")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cache := db.nodeCache.Load(); cache != nil {
+		return &cachedReader{cache: cache, reader: reader}, nil
+	}
+	return reader, nil
+}
+
+// SetNodeCache installs a node cache shared by all the state readers opened
+// afterwards, replacing the previous one. Readers already opened keep using the
+// cache they were opened with. A nil cache disables the sharing.
+//
+// The node cache is not supported for verkle trees, whose nodes are resolved
+// without hash verification.
+func (db *Database) SetNodeCache(cache *NodeCache) {
+	if db.config.IsVerkle {
+		return
 	}
-	return nil, errors.New("unknown backend")
+	db.nodeCache.Store(cache)
 }
 
 // Update performs a state transition by committing dirty nodes contained in the
@@ -257,6 +285,29 @@ func (db *Database) Dereference(root common.Hash) error {
 	return nil
 }
 
+// SetFlushHook installs a callback which is invoked with the hash of every trie
+// node persisted to disk. It's only supported by hash-based database and will
+// return an error for others.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.SetFlushHook(hook)
+	return nil
+}
+
+// DeleteNodes removes the given trie nodes from the disk, except the ones still
+// held in memory or reported as live by the keep callback. It's only supported
+// by hash-based database and will return an error for others.
+func (db *Database) DeleteNodes(hashes []common.Hash, keep func(hash common.Hash) bool) (int, error) {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return 0, errors.New("not supported")
+	}
+	return hdb.DeleteNodes(hashes, keep)
+}
+
 // Recover rollbacks the database to a specified historical point. The state is
 // supported as the rollback destination only if it's canonical state and the
 // corresponding trie histories are existent. It's only supported by path-based
@@ -102,6 +102,8 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 
+	onFlush func(hash common.Hash) // Callback invoked for every node persisted to disk
+
 	lock sync.RWMutex
 }
 
@@ -351,6 +353,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
 		rawdb.WriteLegacyTrieNode(batch, oldest, node.node)
+		if db.onFlush != nil {
+			db.onFlush(oldest)
+		}
 
 		// If we exceeded the ideal batch size, commit and reset
 		if batch.ValueSize() >= ethdb.IdealBatchSize {
@@ -474,6 +479,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 	}
 	// If we've reached an optimal batch size, commit and start over
 	rawdb.WriteLegacyTrieNode(batch, hash, node.node)
+	if db.onFlush != nil {
+		db.onFlush(hash)
+	}
 	if batch.ValueSize() >= ethdb.IdealBatchSize {
 		if err := batch.Write(); err != nil {
 			return err
@@ -540,6 +548,50 @@ func (c *cleaner) Delete(key []byte) error {
 	panic("not implemented")
 }
 
+// SetFlushHook installs a callback which is invoked with the hash of every trie
+// node persisted to disk, before the write takes place. The callback is invoked
+// while holding the database lock, so it must not call back into the database.
+// A nil hook removes the previously installed one.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.onFlush = hook
+}
+
+// DeleteNodes removes the given trie nodes from the disk and the clean cache,
+// except the ones still tracked in memory and the ones the keep callback is
+// reporting as live. The deletion is serialized with the flushes of the dirty
+// nodes, so a node which is concurrently persisted (and hence reported to the
+// flush hook) is either checked against the callback after its write, or is
+// rewritten after its deletion. The number of deleted nodes is returned.
+func (db *Database) DeleteNodes(hashes []common.Hash, keep func(hash common.Hash) bool) (int, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var (
+		deleted int
+		batch   = db.diskdb.NewBatch()
+	)
+	for _, hash := range hashes {
+		if _, ok := db.dirties[hash]; ok {
+			continue
+		}
+		if keep(hash) {
+			continue
+		}
+		rawdb.DeleteLegacyTrieNode(batch, hash)
+		if db.cleans != nil {
+			db.cleans.Del(hash[:])
+		}
+		deleted++
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
 // Initialized returns an indicator if state data is already initialized
 // in hash-based scheme by checking the presence of genesis state.
 func (db *Database) Initialized(genesisRoot common.Hash) bool {
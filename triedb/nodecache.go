@@ -0,0 +1,132 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package triedb
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+var (
+	nodeCacheHitMeter       = metrics.NewRegisteredMeter("triedb/nodecache/hit", nil)
+	nodeCacheMissMeter      = metrics.NewRegisteredMeter("triedb/nodecache/miss", nil)
+	nodeCacheHitBytesMeter  = metrics.NewRegisteredMeter("triedb/nodecache/hit/bytes", nil)
+	nodeCacheMissBytesMeter = metrics.NewRegisteredMeter("triedb/nodecache/miss/bytes", nil)
+	nodeCacheSizeGauge      = metrics.NewRegisteredGauge("triedb/nodecache/size", nil)
+)
+
+// cachedNode is a trie node blob along with its hash.
+type cachedNode struct {
+	hash common.Hash
+	blob []byte
+}
+
+// NodeCache is a concurrent read-through cache of trie nodes, shared by all the
+// state readers opened while it's installed in the database. It's meant to be
+// short lived, spanning the processing of a single block: the block execution,
+// the trie prefetcher, the state prefetcher running the next block and the
+// snapshot generator all resolve nodes through it, loading each only once.
+//
+// Nodes are keyed by owner and path, and only served if their hash matches the
+// requested one, so the cache is safe to share between readers of different
+// state roots.
+type NodeCache struct {
+	nodes map[string]*cachedNode // Cached nodes, keyed by owner and path
+	size  int                    // Total size of the cached nodes
+	limit int                    // Maximum size of the cached nodes, beyond which nodes are not cached anymore
+	lock  sync.RWMutex
+}
+
+// NewNodeCache creates a node cache holding nodes up to the given total size
+// in bytes.
+func NewNodeCache(limit int) *NodeCache {
+	return &NodeCache{
+		nodes: make(map[string]*cachedNode),
+		limit: limit,
+	}
+}
+
+// nodeKey constructs the cache key of a trie node.
+func nodeKey(owner common.Hash, path []byte) string {
+	return string(owner.Bytes()) + string(path)
+}
+
+// node retrieves the node with the given hash at the given location, nil if
+// it's not cached.
+func (c *NodeCache) node(owner common.Hash, path []byte, hash common.Hash) []byte {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if n := c.nodes[nodeKey(owner, path)]; n != nil && n.hash == hash {
+		return n.blob
+	}
+	return nil
+}
+
+// add caches a resolved node, unless the cache is already full.
+func (c *NodeCache) add(owner common.Hash, path []byte, hash common.Hash, blob []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := nodeKey(owner, path)
+	if n := c.nodes[key]; n != nil {
+		c.size -= len(key) + len(n.blob)
+	} else if c.size+len(key)+len(blob) > c.limit {
+		return
+	}
+	c.nodes[key] = &cachedNode{hash: hash, blob: blob}
+	c.size += len(key) + len(blob)
+	nodeCacheSizeGauge.Update(int64(c.size))
+}
+
+// Size returns the total size of the cached nodes.
+func (c *NodeCache) Size() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.size
+}
+
+// cachedReader is a state reader resolving trie nodes through a node cache.
+type cachedReader struct {
+	cache  *NodeCache
+	reader database.Reader
+}
+
+// Node implements database.Reader, retrieving the node from the cache if it's
+// present, or from the underlying reader otherwise, caching it.
+func (r *cachedReader) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	if blob := r.cache.node(owner, path, hash); blob != nil {
+		nodeCacheHitMeter.Mark(1)
+		nodeCacheHitBytesMeter.Mark(int64(len(blob)))
+		return blob, nil
+	}
+	blob, err := r.reader.Node(owner, path, hash)
+	if err != nil {
+		return nil, err
+	}
+	nodeCacheMissMeter.Mark(1)
+	nodeCacheMissBytesMeter.Mark(int64(len(blob)))
+
+	if len(blob) > 0 {
+		r.cache.add(owner, path, hash, blob)
+	}
+	return blob, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package triedb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// countingReader is a node reader counting the node retrievals.
+type countingReader struct {
+	nodes map[string][]byte
+	reads int
+}
+
+func (r *countingReader) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	r.reads++
+	blob := r.nodes[nodeKey(owner, path)]
+	if blob == nil || crypto.Keccak256Hash(blob) != hash {
+		return nil, fmt.Errorf("missing node %x:%x", owner, path)
+	}
+	return blob, nil
+}
+
+func TestNodeCache(t *testing.T) {
+	var (
+		owner = common.Hash{0x01}
+		blob  = []byte{0x01, 0x02, 0x03}
+		hash  = crypto.Keccak256Hash(blob)
+		inner = &countingReader{nodes: map[string][]byte{nodeKey(owner, []byte{0x1}): blob}}
+		cache = NewNodeCache(1024)
+	)
+	for i := 0; i < 3; i++ {
+		reader := &cachedReader{cache: cache, reader: inner}
+		have, err := reader.Node(owner, []byte{0x1}, hash)
+		if err != nil {
+			t.Fatalf("read %d: failed to retrieve node: %v", i, err)
+		}
+		if !bytes.Equal(have, blob) {
+			t.Fatalf("read %d: node mismatch: have %x, want %x", i, have, blob)
+		}
+	}
+	if inner.reads != 1 {
+		t.Fatalf("underlying reads mismatch: have %d, want 1", inner.reads)
+	}
+	// Nodes are only served for the matching hash and location
+	reader := &cachedReader{cache: cache, reader: inner}
+	if _, err := reader.Node(owner, []byte{0x1}, common.Hash{0xff}); err == nil {
+		t.Fatal("node served for mismatching hash")
+	}
+	if _, err := reader.Node(common.Hash{}, []byte{0x1}, hash); err == nil {
+		t.Fatal("node served for mismatching owner")
+	}
+	// Nodes beyond the size limit are not cached anymore
+	cache = NewNodeCache(len(nodeKey(owner, []byte{0x1})) + len(blob) - 1)
+	for i := 0; i < 2; i++ {
+		reader := &cachedReader{cache: cache, reader: inner}
+		if _, err := reader.Node(owner, []byte{0x1}, hash); err != nil {
+			t.Fatalf("failed to retrieve node: %v", err)
+		}
+	}
+	if cache.Size() != 0 {
+		t.Fatalf("cache size mismatch: have %d, want 0", cache.Size())
+	}
+}
+
+func TestNodeCacheSharing(t *testing.T) {
+	testNodeCacheSharing(t, HashDefaults)
+	testNodeCacheSharing(t, &Config{PathDB: pathdb.Defaults})
+}
+
+func testNodeCacheSharing(t *testing.T, config *Config) {
+	db := NewDatabase(rawdb.NewMemoryDatabase(), config)
+	defer db.Close()
+
+	tr := trie.NewEmpty(db)
+	for i := 0; i < 256; i++ {
+		tr.MustUpdate(crypto.Keccak256([]byte{byte(i)}), bytes.Repeat([]byte{byte(i)}, 32))
+	}
+	root, nodes, _ := tr.Commit(false)
+	if err := db.Update(root, types.EmptyRootHash, 0, trienode.NewWithNodeSet(nodes), nil); err != nil {
+		t.Fatalf("failed to update trie: %v", err)
+	}
+	if err := db.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	cache := NewNodeCache(1024 * 1024)
+	db.SetNodeCache(cache)
+
+	// All readers opened with the cache installed resolve nodes through it
+	read := func() {
+		tr, err := trie.New(trie.TrieID(root), db)
+		if err != nil {
+			t.Fatalf("failed to open trie: %v", err)
+		}
+		for i := 0; i < 256; i++ {
+			if val := tr.MustGet(crypto.Keccak256([]byte{byte(i)})); !bytes.Equal(val, bytes.Repeat([]byte{byte(i)}, 32)) {
+				t.Fatalf("value %d mismatch: have %x", i, val)
+			}
+		}
+	}
+	read()
+	size := cache.Size()
+	if size == 0 {
+		t.Fatal("no nodes cached")
+	}
+	read()
+	if cache.Size() != size {
+		t.Fatalf("cache size changed on cached reads: have %d, want %d", cache.Size(), size)
+	}
+	// Readers opened after the cache is removed bypass it
+	db.SetNodeCache(nil)
+	reader, err := db.Reader(root)
+	if err != nil {
+		t.Fatalf("failed to open reader: %v", err)
+	}
+	if _, ok := reader.(*cachedReader); ok {
+		t.Fatal("reader opened through removed cache")
+	}
+}
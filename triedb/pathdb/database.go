@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie/trienode"
 	"github.com/ethereum/go-ethereum/trie/triestate"
+	"github.com/ethereum/go-verkle"
 )
 
 const (
@@ -177,6 +178,24 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 	return db
 }
 
+// diskRoot returns the root hash of the state persisted in the key-value store,
+// or the empty root if there is none. Merkle nodes are identified by their hash,
+// verkle nodes by their commitment.
+func (db *Database) diskRoot() common.Hash {
+	blob := rawdb.ReadAccountTrieNode(db.diskdb, nil)
+	if len(blob) == 0 {
+		return types.EmptyRootHash
+	}
+	if !db.isVerkle {
+		return crypto.Keccak256Hash(blob)
+	}
+	node, err := verkle.ParseNode(blob, 0)
+	if err != nil {
+		log.Crit("Failed to decode verkle root node", "err", err)
+	}
+	return node.Commit().Bytes()
+}
+
 // repairHistory truncates leftover state history objects, which may occur due
 // to an unclean shutdown or other unexpected reasons.
 func (db *Database) repairHistory() error {
@@ -190,7 +209,7 @@ func (db *Database) repairHistory() error {
 		// all of them. Fix the tests first.
 		return nil
 	}
-	freezer, err := rawdb.NewStateFreezer(ancient, db.readOnly)
+	freezer, err := rawdb.NewStateFreezer(ancient, db.isVerkle, db.readOnly)
 	if err != nil {
 		log.Crit("Failed to open state history freezer", "err", err)
 	}
@@ -307,10 +326,7 @@ func (db *Database) Enable(root common.Hash) error {
 	}
 	// Ensure the provided state root matches the stored one.
 	root = types.TrieRootHash(root)
-	stored := types.EmptyRootHash
-	if blob := rawdb.ReadAccountTrieNode(db.diskdb, nil); len(blob) > 0 {
-		stored = crypto.Keccak256Hash(blob)
-	}
+	stored := db.diskRoot()
 	if stored != root {
 		return fmt.Errorf("state root mismatch: stored %x, synced %x", stored, root)
 	}